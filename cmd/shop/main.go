@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
 	"shop/internal/config"
 	"shop/internal/db"
 	"shop/internal/http"
+	"shop/internal/metrics"
 	uc "shop/internal/usecase"
 	"shop/pkg/logger"
 )
@@ -21,12 +26,7 @@ func main() {
 	}
 
 	// init logger
-	level, err := logger.ParseLogLevel(cfg.LogLevel)
-	log := logger.New(level)
-
-	if err != nil {
-		log.Warn("Неверный уровень логгирования, используется уровень по умолчанию Info", "error", err, "LogLevel", cfg.LogLevel)
-	}
+	log := logger.NewFromString(cfg.LogLevel)
 
 	log.Info("Конфигурация загружена", "config", cfg)
 
@@ -37,26 +37,119 @@ func main() {
 		os.Exit(1)
 	}
 
+	// shop migrate --dry-run проверяет миграции и завершается, не запуская сервер.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		code := runMigrate(context.Background(), os.Args[2:], database, os.Stdout)
+		database.Close()
+		os.Exit(code)
+	}
+
+	if err := db.CheckSchema(context.Background(), database); err != nil {
+		log.Error("Схема базы данных не готова", "error", err)
+		os.Exit(1)
+	}
+
 	database.SetMaxOpenConns(100)
 	database.SetMaxIdleConns(25)
 
 	userDB := db.NewUserDB(database, log)
+	userDB.MaxItemQuantity = cfg.Inventory.MaxItemQuantity
 	itemDB := db.NewItemDB(database, log)
 	transactionDB := db.NewTransactionDB(database, log)
 
+	if cfg.Catalog.Bootstrap {
+		seeded, err := itemDB.SeedItems(context.Background(), db.DefaultCatalog)
+		if err != nil {
+			log.Error("Ошибка заполнения каталога товаров", "error", err)
+			os.Exit(1)
+		}
+		if seeded > 0 {
+			log.Info("Каталог товаров заполнен встроенным набором", "count", seeded)
+		}
+	}
+
 	userInfoUseCase := uc.NewUserInfoUseCase(cfg.JWT.SecretKey, userDB, transactionDB, log)
-	sendCoinUseCase := uc.NewSendCoinUseCase(userDB, transactionDB, log)
+	userInfoUseCase.ReadOnly = cfg.API.ReadOnly
+	userInfoUseCase.TokenTTL = cfg.JWT.TokenTTL
+	userInfoUseCase.RefreshGrace = cfg.JWT.RefreshGrace
+	userInfoUseCase.RefreshWindow = cfg.JWT.RefreshWindow
+	userInfoUseCase.MaxSessionAge = cfg.JWT.MaxSessionAge
+	userInfoUseCase.AcceptLegacyTokens = cfg.JWT.AcceptLegacyTokens
+	userInfoUseCase.ReservedUsernames = cfg.API.AdminUsernames
+	userInfoUseCase.PasswordPepper = []byte(cfg.Password.Pepper)
+	if len(cfg.JWT.Keys) > 0 {
+		if err := userInfoUseCase.UseKeySet(cfg.JWT.Keys, cfg.JWT.SigningKeyID); err != nil {
+			log.Error("Ошибка настройки ключей JWT", "error", err)
+			os.Exit(1)
+		}
+	}
+	sendCoinUseCase := uc.NewSendCoinUseCase(cfg.Transfer.Denomination, userDB, transactionDB, log)
+	sendCoinUseCase.MinBalance = cfg.Account.MinBalance
+	sendCoinUseCase.ReversalWindow = cfg.Transfer.ReversalWindow
+	sendCoinUseCase.ReadOnly = cfg.API.ReadOnly
 	buyItemUseCase := uc.NewBuyItemUseCase(userDB, itemDB, transactionDB, log)
+	buyItemUseCase.MinBalance = cfg.Account.MinBalance
+	buyItemUseCase.MaxCartItems = cfg.Cart.MaxItems
+	buyItemUseCase.ReadOnly = cfg.API.ReadOnly
+	adminUseCase := uc.NewAdminUseCase(userDB, itemDB, transactionDB, log)
+	adminUseCase.PasswordPepper = []byte(cfg.Password.Pepper)
+	bonusUseCase := uc.NewBonusUseCase(cfg.Bonus.DailyAmount, userDB, log)
+	bonusUseCase.ReadOnly = cfg.API.ReadOnly
+	giftUseCase := uc.NewGiftUseCase(userDB, transactionDB, log)
+	giftUseCase.ReadOnly = cfg.API.ReadOnly
 
-	srv := http.NewServer(userInfoUseCase, sendCoinUseCase, buyItemUseCase, log)
+	// init background workers
+	workers := NewSupervisor(context.Background(), log)
+
+	// init metrics
+	economyGauges, err := metrics.NewEconomyGauges(prometheus.DefaultRegisterer, userDB, cfg.Metrics.RefreshInterval, log)
+	if err != nil {
+		log.Error("Ошибка инициализации метрик", "error", err)
+		os.Exit(1)
+	}
+	workers.Go("economy-metrics", economyGauges.Run)
+	authFailures, err := metrics.NewAuthFailures(prometheus.DefaultRegisterer)
+	if err != nil {
+		log.Error("Ошибка инициализации метрик", "error", err)
+		os.Exit(1)
+	}
+	transactionMetrics, err := metrics.NewTransactions(prometheus.DefaultRegisterer)
+	if err != nil {
+		log.Error("Ошибка инициализации метрик", "error", err)
+		os.Exit(1)
+	}
+	sendCoinUseCase.Transactions = transactionMetrics
+	buyItemUseCase.Transactions = transactionMetrics
+	adminUseCase.Transactions = transactionMetrics
+
+	exitCode := 0
+	srv := http.NewServer(cfg.Server, cfg.API, userInfoUseCase, sendCoinUseCase, buyItemUseCase, adminUseCase, bonusUseCase, giftUseCase, authFailures, log)
 	log.Info("Сервер запущен", "address", srv.Addr)
-	if err := srv.ListenAndServe(); err != nil {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	if err := serveUntilSignal(srv, signals, cfg.Server.ShutdownTimeout, log); err != nil {
 		log.Error("Ошибка сервера", "error", err)
-		os.Exit(1)
+		exitCode = 1
+	}
+	signal.Stop(signals)
+
+	// Фоновые воркеры используют базу данных и останавливаются до ее закрытия.
+	log.Info("Остановка фоновых воркеров")
+	workers.Stop()
+
+	// Подготовленные выражения закрываются до соединения с базой данных.
+	if err := userDB.Close(); err != nil {
+		log.Error("Ошибка закрытия подготовленных выражений UserDB", "error", err)
+	}
+	if err := itemDB.Close(); err != nil {
+		log.Error("Ошибка закрытия подготовленных выражений ItemDB", "error", err)
 	}
 
+	log.Info("Закрытие соединения с базой данных")
 	err = database.Close()
 	if err != nil {
 		log.Error("Ошибка закрытия соединения с базой данных", "error", err)
 	}
+	log.Info("Сервис остановлен", "exitCode", exitCode)
+	os.Exit(exitCode)
 }
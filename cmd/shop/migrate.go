@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+
+	"shop/internal/db"
+)
+
+// Коды завершения команды migrate.
+const (
+	migrateUpToDate = 0
+	migratePending  = 1
+	migrateFailed   = 2
+)
+
+// runMigrate выполняет команду migrate и возвращает код завершения процесса:
+// 0, если все миграции применены, 1, если есть неприменные, и 2 при ошибке.
+// Сейчас поддерживается только проверка --dry-run: сами миграции применяет
+// PostgreSQL при инициализации базы (docker-entrypoint-initdb.d).
+func runMigrate(ctx context.Context, args []string, database *sql.DB, out io.Writer) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(out)
+	dryRun := flags.Bool("dry-run", false, "вывести неприменные миграции, не применяя их")
+	dir := flags.String("dir", "./migrations", "каталог с файлами миграций")
+	if err := flags.Parse(args); err != nil {
+		return migrateFailed
+	}
+	if !*dryRun {
+		fmt.Fprintln(out, "Поддерживается только проверка миграций: shop migrate --dry-run")
+		return migrateFailed
+	}
+
+	pending, err := db.PendingMigrations(ctx, database, *dir)
+	if err != nil {
+		fmt.Fprintf(out, "Ошибка проверки миграций: %v\n", err)
+		return migrateFailed
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(out, "Все миграции применены")
+		return migrateUpToDate
+	}
+	fmt.Fprintf(out, "Неприменные миграции (%d):\n", len(pending))
+	for _, version := range pending {
+		fmt.Fprintln(out, version)
+	}
+	return migratePending
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMigrationsDir создает каталог с файлами миграций.
+func newMigrationsDir(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o600))
+	}
+	return dir
+}
+
+func TestRunMigrate_DryRun(t *testing.T) {
+	dir := newMigrationsDir(t, "001-init.sql", "002-next.sql", "README.md")
+	tests := []struct {
+		name         string
+		applied      []string
+		tableMissing bool
+		expectedCode int
+		expectedOut  string
+	}{
+		{"все применены", []string{"001-init", "002-next"}, false, migrateUpToDate, "Все миграции применены\n"},
+		{"есть неприменные", []string{"001-init"}, false, migratePending, "Неприменные миграции (1):\n002-next\n"},
+		{"нет таблицы учета", nil, true, migratePending, "Неприменные миграции (2):\n001-init\n002-next\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer database.Close()
+
+			query := sqlMock.ExpectQuery("SELECT version FROM schema_migrations")
+			if tt.tableMissing {
+				query.WillReturnError(&pq.Error{Code: "42P01"})
+			} else {
+				rows := sqlmock.NewRows([]string{"version"})
+				for _, version := range tt.applied {
+					rows.AddRow(version)
+				}
+				query.WillReturnRows(rows)
+			}
+
+			var out bytes.Buffer
+			code := runMigrate(context.Background(), []string{"--dry-run", "--dir", dir}, database, &out)
+
+			assert.Equal(t, tt.expectedCode, code, "Неверный код завершения")
+			assert.Equal(t, tt.expectedOut, out.String())
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRunMigrate_RequiresDryRun(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	// Без --dry-run команда ничего не делает с базой и завершается с ошибкой.
+	var out bytes.Buffer
+	code := runMigrate(context.Background(), nil, database, &out)
+
+	assert.Equal(t, migrateFailed, code)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"shop/pkg/logger"
+)
+
+// gracefulServer сервер, который можно плавно остановить.
+type gracefulServer interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+}
+
+// serveUntilSignal запускает srv и ждет сигнала из signals, после чего плавно останавливает
+// сервер, давая активным запросам не больше timeout на завершение. Если сервер остановился
+// сам (например, порт занят), его ошибка возвращается без ожидания сигнала.
+func serveUntilSignal(srv gracefulServer, signals <-chan os.Signal, timeout time.Duration, log *logger.Logger) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("ошибка сервера: %w", err)
+	case sig := <-signals:
+		log.Info("Получен сигнал остановки", "signal", sig.String(), "timeout", timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return fmt.Errorf("ошибка остановки сервера: %w", err)
+	}
+
+	// После Shutdown ListenAndServe сразу возвращает http.ErrServerClosed.
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("ошибка сервера: %w", err)
+	}
+	return nil
+}
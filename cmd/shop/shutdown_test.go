@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"shop/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer имитирует http.Server: ListenAndServe блокируется до вызова Shutdown.
+type fakeServer struct {
+	serveErr    error
+	shutdownErr error
+	stopped     chan struct{}
+	shutdownCtx chan context.Context
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{
+		stopped:     make(chan struct{}),
+		shutdownCtx: make(chan context.Context, 1),
+	}
+}
+
+func (s *fakeServer) ListenAndServe() error {
+	if s.serveErr != nil {
+		return s.serveErr
+	}
+	<-s.stopped
+	return http.ErrServerClosed
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.shutdownCtx <- ctx
+	close(s.stopped)
+	return s.shutdownErr
+}
+
+func TestServeUntilSignal_ShutdownOnSignal(t *testing.T) {
+	srv := newFakeServer()
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGTERM
+
+	start := time.Now()
+	err := serveUntilSignal(srv, signals, 3*time.Second, logger.NewTestLogger())
+	require.NoError(t, err)
+
+	select {
+	case ctx := <-srv.shutdownCtx:
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok, "Shutdown должен получить контекст с таймаутом")
+		assert.WithinDuration(t, start.Add(3*time.Second), deadline, time.Second)
+	default:
+		t.Fatal("Shutdown не был вызван после сигнала")
+	}
+}
+
+func TestServeUntilSignal_ShutdownError(t *testing.T) {
+	srv := newFakeServer()
+	srv.shutdownErr = context.DeadlineExceeded
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGINT
+
+	err := serveUntilSignal(srv, signals, time.Second, logger.NewTestLogger())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestServeUntilSignal_ServeErrorWithoutSignal(t *testing.T) {
+	srv := newFakeServer()
+	srv.serveErr = errors.New("address already in use")
+
+	err := serveUntilSignal(srv, make(chan os.Signal), time.Second, logger.NewTestLogger())
+
+	assert.ErrorIs(t, err, srv.serveErr)
+	assert.Empty(t, srv.shutdownCtx, "Shutdown не должен вызываться, если сервер не запустился")
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"shop/pkg/logger"
+)
+
+// Supervisor управляет жизненным циклом фоновых воркеров: все воркеры получают
+// общий контекст, который отменяется при остановке, а Stop дожидается их завершения.
+type Supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	log    *logger.Logger
+}
+
+// NewSupervisor создает Supervisor, воркеры которого останавливаются при отмене ctx или вызове Stop.
+func NewSupervisor(ctx context.Context, log *logger.Logger) *Supervisor {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Supervisor{ctx: ctx, cancel: cancel, log: log}
+}
+
+// Go запускает воркер в отдельной горутине. Воркер должен завершиться после отмены переданного контекста.
+func (s *Supervisor) Go(name string, worker func(ctx context.Context)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.log.Info("Фоновый воркер запущен", "worker", name)
+		worker(s.ctx)
+		s.log.Info("Фоновый воркер остановлен", "worker", name)
+	}()
+}
+
+// Wait ожидает завершения всех запущенных воркеров.
+func (s *Supervisor) Wait() {
+	s.wg.Wait()
+}
+
+// Stop отменяет общий контекст и ожидает завершения всех воркеров.
+func (s *Supervisor) Stop() {
+	s.cancel()
+	s.Wait()
+}
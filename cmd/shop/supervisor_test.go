@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shop/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startWorkers запускает count воркеров, ожидающих отмены контекста, и возвращает счетчик остановленных.
+func startWorkers(s *Supervisor, count int) *atomic.Int32 {
+	var stopped atomic.Int32
+	for i := 0; i < count; i++ {
+		s.Go("test-worker", func(ctx context.Context) {
+			<-ctx.Done()
+			// Имитируем освобождение ресурсов после отмены.
+			time.Sleep(10 * time.Millisecond)
+			stopped.Add(1)
+		})
+	}
+	return &stopped
+}
+
+func TestSupervisor_StopWaitsForAllWorkers(t *testing.T) {
+	s := NewSupervisor(context.Background(), logger.NewTestLogger())
+	stopped := startWorkers(s, 3)
+
+	s.Stop()
+
+	assert.Equal(t, int32(3), stopped.Load(), "Stop должен дождаться остановки всех воркеров")
+}
+
+func TestSupervisor_ParentContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewSupervisor(ctx, logger.NewTestLogger())
+	stopped := startWorkers(s, 3)
+
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("воркеры не остановились после отмены родительского контекста")
+	}
+	assert.Equal(t, int32(3), stopped.Load())
+}
@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,6 +21,7 @@ import (
 	uc "shop/internal/usecase"
 	"shop/pkg/logger"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
@@ -54,15 +57,39 @@ func TestMain(m *testing.M) {
 
 // setupTestServer настраивает тестовый HTTP-сервер.
 func setupTestServer() *httptest.Server {
+	return setupTestServerWithAPI(testConfig.API)
+}
+
+// setupTestServerWithAPI настраивает тестовый HTTP-сервер с указанными настройками API.
+func setupTestServerWithAPI(apiCfg config.APIConfig) *httptest.Server {
 	userDB := db.NewUserDB(testDB, log)
+	userDB.MaxItemQuantity = testConfig.Inventory.MaxItemQuantity
 	itemDB := db.NewItemDB(testDB, log)
 	transactionDB := db.NewTransactionDB(testDB, log)
 
 	userInfoUseCase := uc.NewUserInfoUseCase(testConfig.JWT.SecretKey, userDB, transactionDB, log)
-	sendCoinUseCase := uc.NewSendCoinUseCase(userDB, transactionDB, log)
+	userInfoUseCase.TokenTTL = testConfig.JWT.TokenTTL
+	userInfoUseCase.RefreshGrace = testConfig.JWT.RefreshGrace
+	userInfoUseCase.RefreshWindow = testConfig.JWT.RefreshWindow
+	userInfoUseCase.MaxSessionAge = testConfig.JWT.MaxSessionAge
+	userInfoUseCase.ReservedUsernames = apiCfg.AdminUsernames
+	userInfoUseCase.PasswordPepper = []byte(testConfig.Password.Pepper)
+	sendCoinUseCase := uc.NewSendCoinUseCase(testConfig.Transfer.Denomination, userDB, transactionDB, log)
+	sendCoinUseCase.MinBalance = testConfig.Account.MinBalance
+	sendCoinUseCase.ReversalWindow = testConfig.Transfer.ReversalWindow
+	sendCoinUseCase.ReadOnly = apiCfg.ReadOnly
 	buyItemUseCase := uc.NewBuyItemUseCase(userDB, itemDB, transactionDB, log)
+	buyItemUseCase.MinBalance = testConfig.Account.MinBalance
+	buyItemUseCase.MaxCartItems = testConfig.Cart.MaxItems
+	buyItemUseCase.ReadOnly = apiCfg.ReadOnly
+	adminUseCase := uc.NewAdminUseCase(userDB, itemDB, transactionDB, log)
+	adminUseCase.PasswordPepper = []byte(testConfig.Password.Pepper)
+	bonusUseCase := uc.NewBonusUseCase(testConfig.Bonus.DailyAmount, userDB, log)
+	bonusUseCase.ReadOnly = apiCfg.ReadOnly
+	giftUseCase := uc.NewGiftUseCase(userDB, transactionDB, log)
+	giftUseCase.ReadOnly = apiCfg.ReadOnly
 
-	server := http2.NewServer(userInfoUseCase, sendCoinUseCase, buyItemUseCase, log)
+	server := http2.NewServer(testConfig.Server, apiCfg, userInfoUseCase, sendCoinUseCase, buyItemUseCase, adminUseCase, bonusUseCase, giftUseCase, nil, log)
 	return httptest.NewServer(server.Handler)
 }
 
@@ -71,6 +98,8 @@ func clearTestData(t *testing.T) {
 	t.Helper()
 	_, err := testDB.Exec(`
 		DELETE FROM coin_transactions;
+		DELETE FROM item_gifts;
+		DELETE FROM admin_audit;
 		DELETE FROM inventory;
 		DELETE FROM users;
 	`)
@@ -83,7 +112,7 @@ func createTestUsers(database *sql.DB) error {
 	users := []struct {
 		username string
 		password string
-		coins    int
+		coins    int64
 	}{
 		{"alice", "password", 1000},
 		{"bob", "password", 1000},
@@ -167,6 +196,25 @@ func getAuthToken(t *testing.T, serverURL, username, password string) string {
 	return authResp.Token
 }
 
+// decodeToken проверяет подпись токена секретом тестовой конфигурации и возвращает его claims.
+func decodeToken(t *testing.T, tokenString string) jwt.MapClaims {
+	t.Helper()
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(testConfig.JWT.SecretKey), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	require.NoError(t, err, "Токен не прошел проверку")
+	claims, ok := token.Claims.(jwt.MapClaims)
+	require.True(t, ok, "Неожиданный тип claims")
+	return claims
+}
+
+// assertTokenUsername проверяет, что токен выдан указанному пользователю.
+func assertTokenUsername(t *testing.T, tokenString string, username string) {
+	t.Helper()
+	claims := decodeToken(t, tokenString)
+	assert.Equal(t, username, claims["username"], "Неверное имя пользователя в токене")
+}
+
 func TestBuyItem(t *testing.T) {
 	t.Run("SuccessfulPurchase", func(t *testing.T) {
 		clearTestData(t)
@@ -178,21 +226,49 @@ func TestBuyItem(t *testing.T) {
 
 		// Покупка товара
 		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/buy/hoody", token, nil)
-		doRequest(t, client, req, http.StatusOK)
+		resp := doRequest(t, client, req, http.StatusOK)
+
+		// Ответ покупки содержит баланс и количество после операции
+		var buyResponse models.BuyItemResponse
+		decodeResponse(t, resp, &buyResponse)
+		assert.Equal(t, models.BuyItemResponse{Coins: 700, Quantity: 1}, buyResponse)
 
 		// Проверка покупки
 		req = newAuthenticatedRequest(t, "GET", server.URL+"/api/info", token, nil)
-		resp := doRequest(t, client, req, http.StatusOK)
+		resp = doRequest(t, client, req, http.StatusOK)
 
 		var info models.InfoResponse
 		decodeResponse(t, resp, &info)
 
-		assert.Equal(t, 700, info.Coins)
+		assert.Equal(t, int64(700), info.Coins)
 		assert.Len(t, info.Inventory, 1)
 		assert.Equal(t, "hoody", info.Inventory[0].Type)
 		assert.Equal(t, 1, info.Inventory[0].Quantity)
 	})
 
+	t.Run("QuantityPurchase", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		token := getAuthToken(t, server.URL, "alice", "password")
+		client := newTestClient()
+
+		// Покупка нескольких предметов: количество в параметре и в теле совпадает.
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/buy/cup?qty=3", token, models.BuyItemRequest{Quantity: &[]int{3}[0]})
+		doRequest(t, client, req, http.StatusOK)
+
+		req = newAuthenticatedRequest(t, "GET", server.URL+"/api/info", token, nil)
+		resp := doRequest(t, client, req, http.StatusOK)
+
+		var info models.InfoResponse
+		decodeResponse(t, resp, &info)
+
+		assert.Equal(t, int64(940), info.Coins)
+		assert.Len(t, info.Inventory, 1)
+		assert.Equal(t, 3, info.Inventory[0].Quantity)
+	})
+
 	t.Run("InsufficientFunds", func(t *testing.T) {
 		clearTestData(t)
 		server := setupTestServer()
@@ -232,6 +308,90 @@ func TestBuyItem(t *testing.T) {
 		decodeResponse(t, resp, &errorResp)
 		assert.Contains(t, errorResp.Errors, "товар не найден")
 	})
+
+	t.Run("FailedTransactionKeepsBalance", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		// Триггер, из-за которого добавление в инвентарь завершается ошибкой внутри транзакции.
+		_, err := testDB.Exec(`
+			CREATE OR REPLACE FUNCTION fail_inventory_insert() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'inventory insert disabled';
+			END;
+			$$ LANGUAGE plpgsql;
+			CREATE TRIGGER fail_inventory_insert BEFORE INSERT ON inventory
+				FOR EACH ROW EXECUTE FUNCTION fail_inventory_insert();
+		`)
+		require.NoError(t, err)
+		defer func() {
+			_, err := testDB.Exec(`
+				DROP TRIGGER IF EXISTS fail_inventory_insert ON inventory;
+				DROP FUNCTION IF EXISTS fail_inventory_insert();
+			`)
+			require.NoError(t, err)
+		}()
+
+		token := getAuthToken(t, server.URL, "alice", "password")
+		client := newTestClient()
+
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/buy/hoody", token, nil)
+		doRequest(t, client, req, http.StatusInternalServerError).Body.Close()
+
+		// Списание монет откатилось вместе с транзакцией.
+		req = newAuthenticatedRequest(t, "GET", server.URL+"/api/info", token, nil)
+		resp := doRequest(t, client, req, http.StatusOK)
+
+		var info models.InfoResponse
+		decodeResponse(t, resp, &info)
+		assert.Equal(t, int64(1000), info.Coins)
+		assert.Empty(t, info.Inventory)
+	})
+
+	t.Run("ConcurrentSameItem", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		token := getAuthToken(t, server.URL, "alice", "password")
+
+		// Монет хватает только на 20 книг из 25: часть покупок отклоняется,
+		// но каждая успешная должна добавить ровно одну книгу.
+		const attempts = 25
+		var wg sync.WaitGroup
+		statuses := make([]int, attempts)
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				req := newAuthenticatedRequest(t, "POST", server.URL+"/api/buy/book", token, nil)
+				resp, err := newTestClient().Do(req)
+				if err != nil {
+					return
+				}
+				resp.Body.Close()
+				statuses[i] = resp.StatusCode
+			}(i)
+		}
+		wg.Wait()
+
+		succeeded := 0
+		for _, status := range statuses {
+			if status == http.StatusOK {
+				succeeded++
+			} else {
+				assert.Equal(t, http.StatusBadRequest, status, "Покупка может быть отклонена только из-за нехватки монет")
+			}
+		}
+		assert.Equal(t, 20, succeeded)
+
+		var quantity int
+		require.NoError(t, testDB.QueryRow(`
+			SELECT inv.quantity FROM inventory inv JOIN users u ON u.id = inv.user_id
+			WHERE u.username = $1 AND inv.item_type = $2`, "alice", "book").Scan(&quantity))
+		assert.Equal(t, succeeded, quantity, "Количество предметов должно совпадать с числом успешных покупок")
+	})
 }
 
 func TestSendCoins(t *testing.T) {
@@ -249,15 +409,20 @@ func TestSendCoins(t *testing.T) {
 			ToUser: "bob",
 			Amount: 50,
 		})
-		doRequest(t, client, sendReq, http.StatusOK)
+		resp := doRequest(t, client, sendReq, http.StatusOK)
+
+		// Ответ перевода содержит баланс отправителя
+		var sendResponse models.SendCoinResponse
+		decodeResponse(t, resp, &sendResponse)
+		assert.Equal(t, int64(950), sendResponse.Coins)
 
 		// Проверка баланса отправителя
 		senderInfoReq := newAuthenticatedRequest(t, "GET", server.URL+"/api/info", senderToken, nil)
-		resp := doRequest(t, client, senderInfoReq, http.StatusOK)
+		resp = doRequest(t, client, senderInfoReq, http.StatusOK)
 
 		var senderInfo models.InfoResponse
 		decodeResponse(t, resp, &senderInfo)
-		assert.Equal(t, 950, senderInfo.Coins)
+		assert.Equal(t, int64(950), senderInfo.Coins)
 
 		// Проверка баланса получателя
 		receiverInfoReq := newAuthenticatedRequest(t, "GET", server.URL+"/api/info", receiverToken, nil)
@@ -265,7 +430,141 @@ func TestSendCoins(t *testing.T) {
 
 		var receiverInfo models.InfoResponse
 		decodeResponse(t, resp, &receiverInfo)
-		assert.Equal(t, 1050, receiverInfo.Coins)
+		assert.Equal(t, int64(1050), receiverInfo.Coins)
+	})
+
+	t.Run("TransferWithMemo", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		aliceToken := getAuthToken(t, server.URL, "alice", "password")
+		bobToken := getAuthToken(t, server.URL, "bob", "password")
+		client := newTestClient()
+
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/sendCoin", aliceToken, models.SendCoinRequest{
+			ToUser: "bob",
+			Amount: 50,
+			Memo:   "за обед",
+		})
+		doRequest(t, client, req, http.StatusOK).Body.Close()
+
+		// Комментарий виден в истории обоих участников.
+		req = newAuthenticatedRequest(t, "GET", server.URL+"/api/info", aliceToken, nil)
+		var aliceInfo models.InfoResponse
+		decodeResponse(t, doRequest(t, client, req, http.StatusOK), &aliceInfo)
+		require.Len(t, aliceInfo.CoinHistory.Sent, 1)
+		assert.Equal(t, "за обед", aliceInfo.CoinHistory.Sent[0].Memo)
+
+		req = newAuthenticatedRequest(t, "GET", server.URL+"/api/info", bobToken, nil)
+		var bobInfo models.InfoResponse
+		decodeResponse(t, doRequest(t, client, req, http.StatusOK), &bobInfo)
+		require.Len(t, bobInfo.CoinHistory.Received, 1)
+		assert.Equal(t, "за обед", bobInfo.CoinHistory.Received[0].Memo)
+	})
+
+	t.Run("ConcurrentBidirectionalTransfers", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		tokens := map[string]string{
+			"alice": getAuthToken(t, server.URL, "alice", "password"),
+			"bob":   getAuthToken(t, server.URL, "bob", "password"),
+		}
+		client := newTestClient()
+
+		// Встречные переводы A→B и B→A одновременно блокируют обе строки пользователей.
+		// Без единого порядка блокировок часть из них завершалась бы ошибкой deadlock.
+		const transfers = 20
+		statuses := make(chan int, transfers)
+		var wg sync.WaitGroup
+		for i := 0; i < transfers; i++ {
+			from, to := "alice", "bob"
+			if i%2 == 1 {
+				from, to = to, from
+			}
+			req := newAuthenticatedRequest(t, "POST", server.URL+"/api/sendCoin", tokens[from], models.SendCoinRequest{
+				ToUser: to,
+				Amount: 10,
+			})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := client.Do(req)
+				if err != nil {
+					statuses <- 0
+					return
+				}
+				resp.Body.Close()
+				statuses <- resp.StatusCode
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+
+		for status := range statuses {
+			assert.Equal(t, http.StatusOK, status, "Все переводы должны выполниться без ошибок")
+		}
+
+		// Сумма балансов сохраняется, а при равном числе переводов в обе стороны балансы не меняются.
+		var aliceCoins, bobCoins int64
+		require.NoError(t, testDB.QueryRow("SELECT coins FROM users WHERE username = 'alice'").Scan(&aliceCoins))
+		require.NoError(t, testDB.QueryRow("SELECT coins FROM users WHERE username = 'bob'").Scan(&bobCoins))
+		assert.Equal(t, int64(2000), aliceCoins+bobCoins)
+		assert.Equal(t, int64(1000), aliceCoins)
+		assert.Equal(t, int64(1000), bobCoins)
+	})
+
+	t.Run("ConcurrentOverdraw", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		token := getAuthToken(t, server.URL, "alice", "password")
+		client := newTestClient()
+
+		// Оба перевода проходят предварительную проверку баланса, но вместе превышают его.
+		// Балансы перечитываются под блокировкой строк, поэтому выполняется только один.
+		const transfers = 2
+		statuses := make(chan int, transfers)
+		var wg sync.WaitGroup
+		for i := 0; i < transfers; i++ {
+			req := newAuthenticatedRequest(t, "POST", server.URL+"/api/sendCoin", token, models.SendCoinRequest{
+				ToUser: "bob",
+				Amount: 600,
+			})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := client.Do(req)
+				if err != nil {
+					statuses <- 0
+					return
+				}
+				resp.Body.Close()
+				statuses <- resp.StatusCode
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+
+		succeeded := 0
+		for status := range statuses {
+			if status == http.StatusOK {
+				succeeded++
+			} else {
+				assert.Equal(t, http.StatusBadRequest, status)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+
+		var aliceCoins, bobCoins int64
+		require.NoError(t, testDB.QueryRow("SELECT coins FROM users WHERE username = 'alice'").Scan(&aliceCoins))
+		require.NoError(t, testDB.QueryRow("SELECT coins FROM users WHERE username = 'bob'").Scan(&bobCoins))
+		assert.GreaterOrEqual(t, aliceCoins, int64(0), "Баланс не должен уходить в минус")
+		assert.Equal(t, int64(400), aliceCoins)
+		assert.Equal(t, int64(1600), bobCoins)
 	})
 
 	t.Run("InsufficientFunds", func(t *testing.T) {
@@ -318,6 +617,27 @@ func TestAuth(t *testing.T) {
 		var authResp models.AuthResponse
 		decodeResponse(t, resp, &authResp)
 		assert.NotEmpty(t, authResp.Token)
+		assertTokenUsername(t, authResp.Token, "alice")
+	})
+
+	t.Run("Registration", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/auth", "", models.AuthRequest{
+			Username: "newcomer",
+			Password: "password",
+		})
+
+		// Ответ на регистрацию содержит начальный баланс.
+		resp := doRequest(t, newTestClient(), req, http.StatusOK)
+		var registerResp models.RegisterResponse
+		decodeResponse(t, resp, &registerResp)
+		assert.NotEmpty(t, registerResp.Token)
+		assert.True(t, registerResp.Created)
+		assertTokenUsername(t, registerResp.Token, "newcomer")
+		assert.Equal(t, models.UserSummary{Username: "newcomer", Coins: uc.InitialCoins}, registerResp.User)
 	})
 
 	t.Run("InvalidCredentials", func(t *testing.T) {
@@ -332,4 +652,438 @@ func TestAuth(t *testing.T) {
 
 		doRequest(t, newTestClient(), req, http.StatusUnauthorized)
 	})
+
+	t.Run("DeactivatedUser", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		aliceToken := getAuthToken(t, server.URL, "alice", "password")
+
+		userDB := db.NewUserDB(testDB, log)
+		bobID, err := userDB.GetUserIDByUsername(context.Background(), "bob")
+		require.NoError(t, err)
+		require.NoError(t, userDB.DeactivateUser(context.Background(), bobID, nil))
+
+		// Деактивированный пользователь не может войти и не регистрируется заново.
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/auth", "", models.AuthRequest{
+			Username: "bob",
+			Password: "password",
+		})
+		doRequest(t, newTestClient(), req, http.StatusUnauthorized)
+
+		// Деактивированному пользователю нельзя перевести монеты.
+		req = newAuthenticatedRequest(t, "POST", server.URL+"/api/sendCoin", aliceToken, models.SendCoinRequest{
+			ToUser: "bob",
+			Amount: 50,
+		})
+		resp := doRequest(t, newTestClient(), req, http.StatusBadRequest)
+		var errorResp models.ErrorResponse
+		decodeResponse(t, resp, &errorResp)
+		assert.Contains(t, errorResp.Errors, "получатель не найден")
+	})
+
+	t.Run("ConcurrentRegistration", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		// Два одновременных первых входа нового пользователя создают один аккаунт.
+		const attempts = 2
+		var wg sync.WaitGroup
+		tokens := make([]string, attempts)
+		statuses := make([]int, attempts)
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				req := newAuthenticatedRequest(t, "POST", server.URL+"/api/auth", "", models.AuthRequest{
+					Username: "dave",
+					Password: "password",
+				})
+				resp, err := newTestClient().Do(req)
+				if err != nil {
+					return
+				}
+				defer resp.Body.Close()
+				statuses[i] = resp.StatusCode
+				var authResp models.AuthResponse
+				if json.NewDecoder(resp.Body).Decode(&authResp) == nil {
+					tokens[i] = authResp.Token
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		for i := 0; i < attempts; i++ {
+			assert.Equal(t, http.StatusOK, statuses[i], "Каждый запрос должен завершиться успешно")
+			req := newAuthenticatedRequest(t, "GET", server.URL+"/api/info", tokens[i], nil)
+			doRequest(t, newTestClient(), req, http.StatusOK).Body.Close()
+		}
+
+		var count int
+		require.NoError(t, testDB.QueryRow("SELECT COUNT(*) FROM users WHERE username = $1", "dave").Scan(&count))
+		assert.Equal(t, 1, count, "Должен быть создан ровно один аккаунт")
+	})
+}
+
+func TestClaimBonus(t *testing.T) {
+	t.Run("OncePerDay", func(t *testing.T) {
+		clearTestData(t)
+		server := setupTestServer()
+		defer server.Close()
+
+		token := getAuthToken(t, server.URL, "alice", "password")
+
+		// Первое получение бонуса за день начисляет монеты.
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/claim-bonus", token, nil)
+		resp := doRequest(t, newTestClient(), req, http.StatusOK)
+		var bonusResp models.ClaimBonusResponse
+		decodeResponse(t, resp, &bonusResp)
+		assert.Equal(t, testConfig.Bonus.DailyAmount, bonusResp.Amount)
+		assert.Equal(t, 1000+testConfig.Bonus.DailyAmount, bonusResp.Coins)
+
+		// Повторное получение в тот же день отклоняется, баланс не меняется.
+		req = newAuthenticatedRequest(t, "POST", server.URL+"/api/claim-bonus", token, nil)
+		resp = doRequest(t, newTestClient(), req, http.StatusConflict)
+		resp.Body.Close()
+
+		req = newAuthenticatedRequest(t, "GET", server.URL+"/api/info", token, nil)
+		resp = doRequest(t, newTestClient(), req, http.StatusOK)
+		var infoResp models.InfoResponse
+		decodeResponse(t, resp, &infoResp)
+		assert.Equal(t, 1000+testConfig.Bonus.DailyAmount, infoResp.Coins)
+	})
+}
+
+func TestInventoryPrefix(t *testing.T) {
+	clearTestData(t)
+	server := setupTestServer()
+	defer server.Close()
+
+	token := getAuthToken(t, server.URL, "alice", "password")
+	client := newTestClient()
+
+	for _, item := range []string{"pen", "powerbank", "pink-hoody", "cup"} {
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/buy/"+item, token, nil)
+		doRequest(t, client, req, http.StatusOK).Body.Close()
+	}
+
+	tests := []struct {
+		name          string
+		prefix        string
+		expectedItems []string
+	}{
+		{"совпадающий префикс", "pi", []string{"pink-hoody"}},
+		{"несколько совпадений", "p", []string{"pen", "pink-hoody", "powerbank"}},
+		{"спецсимвол экранируется", "%", nil},
+		{"нет совпадений", "zzz", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newAuthenticatedRequest(t, "GET", server.URL+"/api/inventory?prefix="+url.QueryEscape(tt.prefix), token, nil)
+			resp := doRequest(t, client, req, http.StatusOK)
+
+			var inventoryResp models.InventoryResponse
+			decodeResponse(t, resp, &inventoryResp)
+
+			var items []string
+			for _, item := range inventoryResp.Inventory {
+				items = append(items, item.Type)
+			}
+			assert.Equal(t, tt.expectedItems, items)
+		})
+	}
+}
+
+func TestInfoItemCount(t *testing.T) {
+	clearTestData(t)
+	server := setupTestServer()
+	defer server.Close()
+
+	token := getAuthToken(t, server.URL, "alice", "password")
+	client := newTestClient()
+
+	itemCount := func() int {
+		req := newAuthenticatedRequest(t, "GET", server.URL+"/api/info?fields=itemCount", token, nil)
+		resp := doRequest(t, client, req, http.StatusOK)
+		var infoResp models.InfoResponse
+		decodeResponse(t, resp, &infoResp)
+		return infoResp.ItemCount
+	}
+
+	// У нового пользователя инвентарь пуст: сумма по нему равна 0, а не NULL.
+	assert.Equal(t, 0, itemCount())
+
+	for _, item := range []string{"pen", "pen", "cup"} {
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/buy/"+item, token, nil)
+		doRequest(t, client, req, http.StatusOK).Body.Close()
+	}
+	assert.Equal(t, 3, itemCount())
+}
+
+func TestTransactionsBetween(t *testing.T) {
+	clearTestData(t)
+	server := setupTestServer()
+	defer server.Close()
+
+	client := newTestClient()
+	transfers := []struct {
+		from, to string
+		amount   int64
+	}{
+		{"alice", "bob", 50},
+		{"bob", "alice", 20},
+		{"charlie", "bob", 5},
+	}
+	for _, tr := range transfers {
+		token := getAuthToken(t, server.URL, tr.from, "password")
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/sendCoin", token, models.SendCoinRequest{
+			ToUser: tr.to,
+			Amount: tr.amount,
+		})
+		doRequest(t, client, req, http.StatusOK)
+	}
+
+	// В выборку попадают переводы в обе стороны и только между указанными пользователями.
+	transactionDB := db.NewTransactionDB(testDB, log)
+	transactions, err := transactionDB.GetTransactionsBetween(context.Background(), "bob", "alice")
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "alice", transactions[0].SenderUsername)
+	assert.Equal(t, "bob", transactions[0].ReceiverUsername)
+	assert.Equal(t, int64(50), transactions[0].Amount)
+	assert.Equal(t, "bob", transactions[1].SenderUsername)
+	assert.Equal(t, "alice", transactions[1].ReceiverUsername)
+	assert.Equal(t, int64(20), transactions[1].Amount)
+}
+
+func TestGiftItem(t *testing.T) {
+	clearTestData(t)
+	server := setupTestServer()
+	defer server.Close()
+
+	aliceToken := getAuthToken(t, server.URL, "alice", "password")
+	bobToken := getAuthToken(t, server.URL, "bob", "password")
+	client := newTestClient()
+
+	req := newAuthenticatedRequest(t, "POST", server.URL+"/api/buy/pen?qty=3", aliceToken, nil)
+	doRequest(t, client, req, http.StatusOK).Body.Close()
+
+	inventory := func(token string) map[string]int {
+		req := newAuthenticatedRequest(t, "GET", server.URL+"/api/info", token, nil)
+		resp := doRequest(t, client, req, http.StatusOK)
+		var info models.InfoResponse
+		decodeResponse(t, resp, &info)
+		items := map[string]int{}
+		for _, item := range info.Inventory {
+			items[item.Type] = item.Quantity
+		}
+		return items
+	}
+
+	t.Run("SuccessfulGift", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/gift", aliceToken, models.GiftRequest{
+			ToUser: "bob", Item: "pen", Quantity: 2,
+		})
+		doRequest(t, client, req, http.StatusOK).Body.Close()
+
+		assert.Equal(t, map[string]int{"pen": 1}, inventory(aliceToken))
+		assert.Equal(t, map[string]int{"pen": 2}, inventory(bobToken))
+	})
+
+	t.Run("MoreThanOwned", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/gift", aliceToken, models.GiftRequest{
+			ToUser: "bob", Item: "pen", Quantity: 5,
+		})
+		doRequest(t, client, req, http.StatusBadRequest).Body.Close()
+
+		// Инвентарь не изменился.
+		assert.Equal(t, map[string]int{"pen": 1}, inventory(aliceToken))
+		assert.Equal(t, map[string]int{"pen": 2}, inventory(bobToken))
+	})
+
+	t.Run("NonExistentRecipient", func(t *testing.T) {
+		req := newAuthenticatedRequest(t, "POST", server.URL+"/api/gift", aliceToken, models.GiftRequest{
+			ToUser: "ghost", Item: "pen", Quantity: 1,
+		})
+		resp := doRequest(t, client, req, http.StatusBadRequest)
+		var errorResp models.ErrorResponse
+		decodeResponse(t, resp, &errorResp)
+		assert.Contains(t, errorResp.Errors, "получатель не найден")
+	})
+}
+
+func TestUserRank(t *testing.T) {
+	clearTestData(t)
+	server := setupTestServer()
+	defer server.Close()
+	client := newTestClient()
+
+	tokens := map[string]string{}
+	for _, username := range []string{"alice", "bob", "carol", "dave"} {
+		tokens[username] = getAuthToken(t, server.URL, username, "password")
+	}
+
+	// У bob больше всех монет, у carol и dave равный баланс, у alice меньше всех.
+	req := newAuthenticatedRequest(t, "POST", server.URL+"/api/sendCoin", tokens["alice"], models.SendCoinRequest{
+		ToUser: "bob",
+		Amount: 100,
+	})
+	doRequest(t, client, req, http.StatusOK)
+
+	// Равный баланс дает одно место, следующее место пропускается.
+	expected := map[string]models.RankResponse{
+		"bob":   {Rank: 1, Coins: 1100},
+		"carol": {Rank: 2, Coins: 1000},
+		"dave":  {Rank: 2, Coins: 1000},
+		"alice": {Rank: 4, Coins: 900},
+	}
+	for username, want := range expected {
+		req := newAuthenticatedRequest(t, "GET", server.URL+"/api/rank", tokens[username], nil)
+		resp := doRequest(t, client, req, http.StatusOK)
+
+		var rank models.RankResponse
+		decodeResponse(t, resp, &rank)
+		assert.Equal(t, want, rank, "Неверное место пользователя %s", username)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	clearTestData(t)
+	server := setupTestServer()
+	defer server.Close()
+	client := newTestClient()
+
+	aliceToken := getAuthToken(t, server.URL, "alice", "password")
+	req := newAuthenticatedRequest(t, "POST", server.URL+"/api/buy/pen?qty=3", aliceToken, nil)
+	doRequest(t, client, req, http.StatusOK).Body.Close()
+
+	// У bob и charlie нет инвентаря: LEFT JOIN оставляет их в рейтинге с нулем предметов.
+	req = newAuthenticatedRequest(t, "GET", server.URL+"/api/leaderboard", aliceToken, nil)
+	var response models.LeaderboardResponse
+	decodeResponse(t, doRequest(t, client, req, http.StatusOK), &response)
+	assert.Equal(t, []models.LeaderboardEntry{
+		{Username: "bob", Coins: 1000, ItemCount: 0},
+		{Username: "alice", Coins: 970, ItemCount: 3},
+		{Username: "charlie", Coins: 10, ItemCount: 0},
+	}, response.Entries)
+}
+
+func TestUpdateItemPrices(t *testing.T) {
+	itemDB := db.NewItemDB(testDB, log)
+	adminUseCase := uc.NewAdminUseCase(db.NewUserDB(testDB, log), itemDB, db.NewTransactionDB(testDB, log), log)
+	ctx := context.Background()
+
+	// Каталог общий для всех тестов: возвращаем исходные цены.
+	t.Cleanup(func() {
+		_, err := testDB.Exec(`
+			UPDATE items SET price = 10 WHERE item_name = 'pen';
+			UPDATE items SET price = 20 WHERE item_name = 'cup';
+			DELETE FROM items WHERE item_name = 'scarf';
+		`)
+		require.NoError(t, err, "Не удалось восстановить каталог")
+	})
+
+	t.Run("SuccessfulBulkUpdate", func(t *testing.T) {
+		response, err := adminUseCase.UpdateItemPrices(ctx, []models.ItemPriceUpdate{
+			{ItemName: "pen", Price: 15},
+			{ItemName: "scarf", Price: 120},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []models.ItemPriceResult{
+			{ItemName: "pen", Price: 15, Created: false},
+			{ItemName: "scarf", Price: 120, Created: true},
+		}, response.Items)
+
+		price, err := itemDB.GetItemPrice(ctx, "pen")
+		require.NoError(t, err)
+		assert.Equal(t, int64(15), price)
+		price, err = itemDB.GetItemPrice(ctx, "scarf")
+		require.NoError(t, err)
+		assert.Equal(t, int64(120), price)
+	})
+
+	t.Run("PartiallyInvalidBatchRejected", func(t *testing.T) {
+		_, err := adminUseCase.UpdateItemPrices(ctx, []models.ItemPriceUpdate{
+			{ItemName: "cup", Price: 99},
+			{ItemName: "pen", Price: 0},
+		})
+		assert.ErrorIs(t, err, uc.ErrInvalidItemPrices)
+
+		// Корректная запись из отклоненного списка тоже не применена.
+		price, err := itemDB.GetItemPrice(ctx, "cup")
+		require.NoError(t, err)
+		assert.Equal(t, int64(20), price)
+	})
+}
+
+func TestRequireExistingUser(t *testing.T) {
+	clearTestData(t)
+	apiCfg := testConfig.API
+	apiCfg.RequireExistingUser = true
+	server := setupTestServerWithAPI(apiCfg)
+	defer server.Close()
+	client := newTestClient()
+
+	token := getAuthToken(t, server.URL, "bob", "password")
+	req := newAuthenticatedRequest(t, "GET", server.URL+"/api/info", token, nil)
+	doRequest(t, client, req, http.StatusOK)
+
+	userDB := db.NewUserDB(testDB, log)
+	bobID, err := userDB.GetUserIDByUsername(context.Background(), "bob")
+	require.NoError(t, err)
+	require.NoError(t, userDB.DeactivateUser(context.Background(), bobID, nil))
+
+	// Подпись токена по-прежнему верна, но пользователь больше не существует.
+	req = newAuthenticatedRequest(t, "GET", server.URL+"/api/info", token, nil)
+	doRequest(t, client, req, http.StatusUnauthorized)
+}
+
+func TestAdminAudit(t *testing.T) {
+	clearTestData(t)
+	// Учетная запись администратора создается до того, как имя попадает в список администраторов:
+	// после этого зарегистрировать его уже нельзя.
+	registration := setupTestServer()
+	getAuthToken(t, registration.URL, "alice", "password")
+	registration.Close()
+
+	apiCfg := testConfig.API
+	apiCfg.AdminUsernames = []string{"alice", "bob"}
+	server := setupTestServerWithAPI(apiCfg)
+	defer server.Close()
+	client := newTestClient()
+
+	// Незанятое имя администратора нельзя получить регистрацией.
+	body, err := json.Marshal(models.AuthRequest{Username: "bob", Password: "password"})
+	require.NoError(t, err)
+	resp, err := client.Post(server.URL+"/api/auth", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	t.Cleanup(func() {
+		_, err := testDB.Exec(`UPDATE items SET price = 10 WHERE item_name = 'pen'`)
+		require.NoError(t, err, "Не удалось восстановить каталог")
+	})
+
+	token := getAuthToken(t, server.URL, "alice", "password")
+
+	// Изменение цены администратором записывается в журнал.
+	prices := []models.ItemPriceUpdate{{ItemName: "pen", Price: 15}}
+	req := newAuthenticatedRequest(t, "PUT", server.URL+"/api/admin/items", token, prices)
+	doRequest(t, client, req, http.StatusOK).Body.Close()
+
+	req = newAuthenticatedRequest(t, "GET", server.URL+"/api/admin/audit?limit=10", token, nil)
+	resp = doRequest(t, client, req, http.StatusOK)
+	var audit models.AdminAuditResponse
+	decodeResponse(t, resp, &audit)
+
+	require.Len(t, audit.Entries, 1)
+	entry := audit.Entries[0]
+	assert.Equal(t, "alice", entry.Actor)
+	assert.Equal(t, uc.AuditActionUpdateItemPrices, entry.Action)
+	assert.JSONEq(t, `[{"item_name":"pen","price":15}]`, string(entry.Details))
+	assert.Equal(t, 10, audit.Limit)
 }
@@ -4,7 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/netip"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -12,9 +18,19 @@ import (
 type (
 	// Config содержит конфигурацию приложения.
 	Config struct {
-		Database DatabaseConfig
-		JWT      JWTConfig
-		LogLevel string `env:"LOG_LEVEL" env-default:"INFO"`
+		Database  DatabaseConfig
+		JWT       JWTConfig
+		Server    ServerConfig
+		API       APIConfig
+		Metrics   MetricsConfig
+		Bonus     BonusConfig
+		Transfer  TransferConfig
+		Inventory InventoryConfig
+		Account   AccountConfig
+		Password  PasswordConfig
+		Cart      CartConfig
+		Catalog   CatalogConfig
+		LogLevel  string `env:"LOG_LEVEL" env-default:"INFO"`
 	}
 
 	// DatabaseConfig содержит конфигурацию базы данных.
@@ -24,14 +40,260 @@ type (
 		User     string `env:"DATABASE_USER" env-default:"shop"`
 		Password string `env:"DATABASE_PASSWORD" env-default:"shop"`
 		Name     string `env:"DATABASE_NAME" env-default:"shop"`
+		// Options дополнительные параметры строки подключения в формате
+		// "connect_timeout:5,statement_timeout:30000". Допускаются только параметры
+		// из списка в db.ConnectDB; application_name по умолчанию равен shop-api.
+		Options map[string]string `env:"DATABASE_OPTIONS" env-separator:","`
 	}
 
 	// JWTConfig содержит конфигурацию JWT.
 	JWTConfig struct {
 		SecretKey string `env:"JWT_SECRET_KEY" env-default:"secret"`
+		// Keys набор ключей подписи для ротации в формате "kid:secret,kid2:secret2".
+		// Если задан, токены подписываются ключом SigningKeyID и проверяются ключом из заголовка kid;
+		// токены без kid отклоняются, если не включен AcceptLegacyTokens.
+		Keys map[string]string `env:"JWT_KEYS" env-separator:","`
+		// AcceptLegacyTokens при заданном Keys разрешает токены без kid, проверяя их ключом SecretKey.
+		// Включается на время перехода на набор ключей, пока не истекут ранее выданные токены.
+		AcceptLegacyTokens bool `env:"JWT_ACCEPT_LEGACY_TOKENS" env-default:"false"`
+		// SigningKeyID идентификатор ключа из Keys, которым подписываются новые токены.
+		SigningKeyID string `env:"JWT_SIGNING_KEY_ID"`
+		// TokenTTL срок действия выдаваемых токенов.
+		TokenTTL time.Duration `env:"JWT_TOKEN_TTL" env-default:"24h"`
+		// RefreshGrace время после истечения токена, в течение которого его можно обменять на новый через /api/refresh.
+		RefreshGrace time.Duration `env:"JWT_REFRESH_GRACE" env-default:"5m"`
+		// RefreshWindow время до истечения токена, начиная с которого его можно обменять на новый. 0 снимает ограничение.
+		RefreshWindow time.Duration `env:"JWT_REFRESH_WINDOW" env-default:"1h"`
+		// MaxSessionAge максимальная длительность сессии от входа по паролю: дольше токен не обновляется.
+		// 0 снимает ограничение.
+		MaxSessionAge time.Duration `env:"JWT_MAX_SESSION_AGE" env-default:"168h"`
+	}
+
+	// ServerConfig содержит настройки HTTP сервера.
+	ServerConfig struct {
+		// Port порт, на котором сервер принимает подключения.
+		Port string `env:"SERVER_PORT" env-default:"8080"`
+		// ShutdownTimeout время, которое при остановке по SIGINT/SIGTERM дается активным запросам
+		// на завершение. Должно быть меньше времени, через которое оркестратор завершает процесс принудительно.
+		ShutdownTimeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT" env-default:"5s"`
+		// ReadHeaderTimeout ограничивает время чтения заголовков запроса.
+		ReadHeaderTimeout time.Duration `env:"SERVER_READ_HEADER_TIMEOUT" env-default:"5s"`
+		// ReadTimeout ограничивает время чтения всего запроса вместе с телом.
+		ReadTimeout time.Duration `env:"SERVER_READ_TIMEOUT" env-default:"15s"`
+		// TrustedProxies подсети (CIDR) или адреса прокси, которым разрешено
+		// передавать IP адрес клиента в заголовках X-Forwarded-For и X-Real-IP.
+		// С некорректным значением конфигурация не загружается.
+		TrustedProxies []string `env:"SERVER_TRUSTED_PROXIES" env-separator:","`
+		// DocsEnabled включает раздачу Swagger UI (/docs/) и спецификации (/schema.json).
+		DocsEnabled bool `env:"SERVER_DOCS_ENABLED" env-default:"true"`
+		// DocsDir каталог со Swagger UI и спецификацией.
+		DocsDir string `env:"SERVER_DOCS_DIR" env-default:"./swagger"`
+	}
+
+	// APIConfig содержит настройки поведения HTTP API.
+	APIConfig struct {
+		// BasePath префикс пути, под которым обслуживаются API и документация, например "/shop".
+		// Пустое значение оставляет маршруты от корня.
+		BasePath string `env:"API_BASE_PATH"`
+		// ResolveUserID включает загрузку пользователя (ID и баланса) в middleware авторизации одним запросом;
+		// usecase'ы текущего пользователя берут его из контекста и не ищут по имени повторно.
+		ResolveUserID bool `env:"API_RESOLVE_USER_ID" env-default:"false"`
+		// RequireExistingUser включает проверку в middleware авторизации, что пользователь из токена
+		// существует и не деактивирован; иначе запрос отклоняется с 401. Требует дополнительного запроса к БД.
+		RequireExistingUser bool `env:"API_REQUIRE_EXISTING_USER" env-default:"false"`
+		// PaymentRequired включает ответ 402 Payment Required при нехватке монет вместо 400.
+		PaymentRequired bool `env:"API_PAYMENT_REQUIRED" env-default:"false"`
+		// ItemNotFound404 включает ответ 404 Not Found при покупке несуществующего товара вместо 400.
+		// Некорректное название товара по-прежнему отклоняется с 400.
+		ItemNotFound404 bool `env:"API_ITEM_NOT_FOUND_404" env-default:"false"`
+		// NoContentOnSuccess включает ответ 204 No Content без тела на успешные sendCoin и buy вместо 200 с балансом.
+		NoContentOnSuccess bool `env:"API_NO_CONTENT_ON_SUCCESS" env-default:"false"`
+		// AdminUsernames список пользователей с доступом к /api/admin. Пробелы вокруг имен
+		// отбрасываются. Имена из списка нельзя занять регистрацией через /api/auth, поэтому
+		// учетную запись администратора нужно создать до добавления ее в список.
+		AdminUsernames []string `env:"API_ADMIN_USERNAMES" env-separator:","`
+		// MaxAuthHeaderLength максимальная длина заголовка Authorization в байтах; более длинные
+		// отклоняются с 401 до проверки токена. 0 снимает ограничение.
+		MaxAuthHeaderLength int `env:"API_MAX_AUTH_HEADER_LENGTH" env-default:"4096"`
+		// APIKeys API-ключи для схемы авторизации ApiKey в формате ключ:имя_пользователя.
+		// Пустой набор отключает схему.
+		APIKeys map[string]string `env:"API_KEYS" env-separator:","`
+		// StrictAccept включает ответ 406, если клиент не принимает application/json.
+		StrictAccept bool `env:"API_STRICT_ACCEPT" env-default:"false"`
+		// StrictContentLength включает ответ 411 на запросы с телом без заголовка Content-Length
+		// (в том числе с Transfer-Encoding: chunked).
+		StrictContentLength bool `env:"API_STRICT_CONTENT_LENGTH" env-default:"false"`
+		// LogBodies включает запись тел запросов и ответов в лог на уровне Debug.
+		// Пароли и токены в JSON заменяются на "***".
+		LogBodies bool `env:"API_LOG_BODIES" env-default:"false"`
+		// PaginationEnvelope включает единый формат ответов со списками {data: [...], page: {limit, offset, total}}
+		// для /api/inventory, /api/items, /api/history, /api/leaderboard, /api/admin/transactions и /api/admin/audit.
+		// По умолчанию списки возвращаются в прежнем формате.
+		PaginationEnvelope bool `env:"API_PAGINATION_ENVELOPE" env-default:"false"`
+		// BalanceStreamInterval период проверки баланса для потока /api/balance/stream.
+		BalanceStreamInterval time.Duration `env:"API_BALANCE_STREAM_INTERVAL" env-default:"2s"`
+		// StreamKeepAliveInterval период отправки keep-alive комментариев в потоки событий.
+		StreamKeepAliveInterval time.Duration `env:"API_STREAM_KEEPALIVE_INTERVAL" env-default:"15s"`
+		// StreamIdleIntervals количество интервалов keep-alive без событий, после которого поток закрывается.
+		// 0 отключает закрытие простаивающих потоков.
+		StreamIdleIntervals int `env:"API_STREAM_IDLE_INTERVALS" env-default:"20"`
+		// ReadOnly включает режим только для чтения: запросы, изменяющие данные, и регистрация
+		// новых пользователей отклоняются с 503, чтение и вход существующих пользователей работают.
+		ReadOnly bool `env:"READ_ONLY" env-default:"false"`
+		// Features включает и выключает отдельные функции API, например "daily-bonus:false".
+		// Функции, не указанные в списке, включены.
+		Features map[string]bool `env:"API_FEATURES" env-separator:","`
+	}
+
+	// BonusConfig содержит настройки ежедневного бонуса.
+	BonusConfig struct {
+		// DailyAmount размер ежедневного бонуса; должен быть положительным.
+		DailyAmount int64 `env:"BONUS_DAILY_AMOUNT" env-default:"100"`
+	}
+
+	// TransferConfig содержит настройки переводов монет.
+	TransferConfig struct {
+		// Denomination шаг суммы перевода: сумма должна быть ему кратна. 1 разрешает любую сумму.
+		Denomination int64 `env:"TRANSFER_DENOMINATION" env-default:"1"`
+		// ReversalWindow время после перевода, в течение которого отправитель может его отменить.
+		// 0 запрещает отмену переводов.
+		ReversalWindow time.Duration `env:"TRANSFER_REVERSAL_WINDOW" env-default:"5m"`
+	}
+
+	// InventoryConfig содержит настройки инвентаря.
+	InventoryConfig struct {
+		// MaxItemQuantity максимальное количество одного предмета у пользователя. 0 снимает ограничение.
+		MaxItemQuantity int `env:"MAX_ITEM_QUANTITY" env-default:"10000"`
+	}
+
+	// AccountConfig содержит настройки баланса счетов.
+	AccountConfig struct {
+		// MinBalance минимальный баланс: перевод или покупка, после которых баланс опустится ниже, отклоняются.
+		MinBalance int64 `env:"MIN_ACCOUNT_BALANCE" env-default:"0"`
+	}
+
+	// PasswordConfig содержит настройки хранения паролей.
+	PasswordConfig struct {
+		// Pepper секрет, подмешиваемый в пароли перед bcrypt (HMAC-SHA256). Хранится только
+		// в конфигурации, не в базе. Включение или смена pepper делает недействительными все
+		// существующие хеши: пользователям придется сбросить пароли.
+		Pepper string `env:"PASSWORD_PEPPER"`
+	}
+
+	// CartConfig содержит настройки корзины.
+	CartConfig struct {
+		// MaxItems максимальное количество позиций в корзине. 0 снимает ограничение.
+		MaxItems int `env:"MAX_CART_ITEMS" env-default:"50"`
+	}
+
+	// CatalogConfig содержит настройки каталога товаров.
+	CatalogConfig struct {
+		// Bootstrap включает заполнение пустого каталога встроенным набором товаров при запуске.
+		// Удобно для локальной разработки и тестов; в env.template включено.
+		Bootstrap bool `env:"CATALOG_BOOTSTRAP" env-default:"false"`
+	}
+
+	// MetricsConfig содержит настройки метрик Prometheus.
+	MetricsConfig struct {
+		// RefreshInterval период обновления метрик экономики; должен быть положительным.
+		RefreshInterval time.Duration `env:"METRICS_REFRESH_INTERVAL" env-default:"30s"`
 	}
 )
 
+// ErrInvalidConfig значение конфигурации недопустимо.
+var ErrInvalidConfig = errors.New("недопустимое значение конфигурации")
+
+// validate проверяет значения, которые нельзя ограничить тегами cleanenv.
+// normalize приводит значения, прочитанные из окружения, к каноническому виду:
+// список "alice, bob" дает имена "alice" и "bob".
+func (c *Config) normalize() {
+	admins := c.API.AdminUsernames[:0]
+	for _, username := range c.API.AdminUsernames {
+		if username = strings.TrimSpace(username); username != "" {
+			admins = append(admins, username)
+		}
+	}
+	c.API.AdminUsernames = admins
+}
+
+func (c Config) validate() error {
+	if c.Bonus.DailyAmount <= 0 {
+		return fmt.Errorf("%w: BONUS_DAILY_AMOUNT должен быть положительным, получено %d", ErrInvalidConfig, c.Bonus.DailyAmount)
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			return fmt.Errorf("%w: SERVER_TRUSTED_PROXIES содержит некорректный адрес '%s': %w", ErrInvalidConfig, proxy, err)
+		}
+	}
+	return nil
+}
+
+// FeatureEnabled сообщает, включена ли функция API с указанным именем.
+func (c APIConfig) FeatureEnabled(name string) bool {
+	enabled, ok := c.Features[name]
+	return !ok || enabled
+}
+
+// loggedConfig Config без методов: через него LogValue выводит поля, не вызывая себя повторно.
+type loggedConfig Config
+
+// LogValue реализует slog.LogValuer: конфигурация попадает в лог со скрытыми секретами.
+// Новые секреты в конфигурации нужно скрывать здесь же.
+func (c Config) LogValue() slog.Value {
+	redacted := c
+	redacted.Database.Password = redact(c.Database.Password)
+	redacted.JWT.SecretKey = redact(c.JWT.SecretKey)
+	redacted.JWT.Keys = redactValues(c.JWT.Keys)
+	redacted.API.APIKeys = redactKeys(c.API.APIKeys)
+	redacted.Password.Pepper = redact(c.Password.Pepper)
+	return slog.AnyValue(loggedConfig(redacted))
+}
+
+// redactValues возвращает копию набора, в которой видны только ключи (например, kid), а значения скрыты.
+func redactValues(secrets map[string]string) map[string]string {
+	if secrets == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(secrets))
+	for key, secret := range secrets {
+		redacted[key] = redact(secret)
+	}
+	return redacted
+}
+
+// redactKeys возвращает копию набора, в котором секретом являются ключи (например, API-ключи):
+// ключи заменяются на "***1", "***2"... в порядке значений, значения (владельцы) остаются видны.
+func redactKeys(secrets map[string]string) map[string]string {
+	if secrets == nil {
+		return nil
+	}
+	owners := make([]string, 0, len(secrets))
+	for _, owner := range secrets {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	redacted := make(map[string]string, len(owners))
+	for i, owner := range owners {
+		redacted["***"+strconv.Itoa(i+1)] = owner
+	}
+	return redacted
+}
+
+// redact заменяет непустой секрет на "***".
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "***"
+}
+
 // LoadConfig загружает конфигурацию из переменных окружения и .env файла.
 func LoadConfig() (Config, error) {
 	var errFile error
@@ -41,7 +303,8 @@ func LoadConfig() (Config, error) {
 	errEnv := cleanenv.ReadEnv(cfg)
 
 	if errEnv == nil {
-		return *cfg, nil
+		cfg.normalize()
+		return *cfg, cfg.validate()
 	}
 
 	// Если переменные окружения не заданы, читаем из .env файла
@@ -53,7 +316,8 @@ func LoadConfig() (Config, error) {
 		return *cfg, errors.Join(errEnv, errFile)
 	}
 
-	return *cfg, nil
+	cfg.normalize()
+	return *cfg, cfg.validate()
 }
 
 // LoadConfigFrom загружает конфигурацию из указанного файла .env.
@@ -68,5 +332,6 @@ func LoadConfigFrom(envFile string) (Config, error) {
 	if err != nil {
 		return *cfg, fmt.Errorf("ошибка чтения конфигурации из файла: %w", err)
 	}
-	return *cfg, nil
+	cfg.normalize()
+	return *cfg, cfg.validate()
 }
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// logConfig выводит cfg в лог обработчиком newHandler и возвращает результат.
+func logConfig(cfg Config, newHandler func(*bytes.Buffer) slog.Handler) string {
+	var buf bytes.Buffer
+	slog.New(newHandler(&buf)).Info("Конфигурация загружена", "config", cfg)
+	return buf.String()
+}
+
+func TestConfig_LogValue_RedactsSecrets(t *testing.T) {
+	cfg := Config{
+		Database: DatabaseConfig{Host: "db.internal", Password: "DBPASSWORDSECRET"},
+		JWT: JWTConfig{
+			SecretKey: "JWTSECRETKEY",
+			Keys:      map[string]string{"k1": "JWTKEYSECRET1", "k2": "JWTKEYSECRET2"},
+		},
+		API:      APIConfig{APIKeys: map[string]string{"APIKEYSECRET1": "alice", "APIKEYSECRET2": "bob"}},
+		Password: PasswordConfig{Pepper: "PEPPERSECRET"},
+		LogLevel: "INFO",
+	}
+	secrets := []string{"DBPASSWORDSECRET", "JWTSECRETKEY", "JWTKEYSECRET1", "JWTKEYSECRET2", "APIKEYSECRET1", "APIKEYSECRET2", "PEPPERSECRET"}
+
+	handlers := map[string]func(*bytes.Buffer) slog.Handler{
+		"text": func(buf *bytes.Buffer) slog.Handler { return slog.NewTextHandler(buf, nil) },
+		"json": func(buf *bytes.Buffer) slog.Handler { return slog.NewJSONHandler(buf, nil) },
+	}
+	for name, newHandler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			out := logConfig(cfg, newHandler)
+
+			for _, secret := range secrets {
+				assert.NotContains(t, out, secret)
+			}
+			assert.Contains(t, out, "db.internal", "несекретные поля должны оставаться в логе")
+			assert.Contains(t, out, "k1", "идентификаторы ключей JWT должны оставаться в логе")
+			assert.Contains(t, out, "k2")
+			assert.Contains(t, out, "alice", "владельцы API-ключей должны оставаться в логе")
+			assert.Contains(t, out, "bob")
+			assert.Contains(t, out, "***")
+		})
+	}
+}
+
+func TestConfig_LogValue_DoesNotModifyConfig(t *testing.T) {
+	cfg := Config{JWT: JWTConfig{SecretKey: "JWTSECRETKEY", Keys: map[string]string{"k1": "JWTKEYSECRET"}}}
+
+	_ = cfg.LogValue()
+
+	assert.Equal(t, "JWTSECRETKEY", cfg.JWT.SecretKey)
+	assert.Equal(t, "JWTKEYSECRET", cfg.JWT.Keys["k1"], "LogValue не должен менять исходный набор ключей")
+}
+
+func TestRedactKeys(t *testing.T) {
+	redacted := redactKeys(map[string]string{"APIKEYSECRET2": "bob", "APIKEYSECRET1": "alice"})
+
+	assert.Equal(t, map[string]string{"***1": "alice", "***2": "bob"}, redacted)
+	assert.Nil(t, redactKeys(nil))
+}
+
+func TestLoadConfig_BonusDailyAmount(t *testing.T) {
+	tests := []struct {
+		value       string
+		expectedErr error
+	}{
+		{"100", nil},
+		{"0", ErrInvalidConfig},
+		{"-5", ErrInvalidConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("BONUS_DAILY_AMOUNT", tt.value)
+
+			_, err := LoadConfig()
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLoadConfig_TrustedProxies(t *testing.T) {
+	tests := []struct {
+		value       string
+		expectedErr error
+	}{
+		{"10.0.0.0/8,192.168.1.10", nil},
+		{"10.0.0.1x", ErrInvalidConfig},
+		{"10.0.0.0/8,300.0.0.1", ErrInvalidConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("SERVER_TRUSTED_PROXIES", tt.value)
+
+			_, err := LoadConfig()
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLoadConfig_AdminUsernamesTrimmed(t *testing.T) {
+	t.Setenv("API_ADMIN_USERNAMES", "alice, bob ,,")
+
+	cfg, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, cfg.API.AdminUsernames)
+}
@@ -4,14 +4,30 @@ import (
 	"database/sql"
 	"fmt"
 	"shop/internal/config"
+	"slices"
+	"strings"
 )
 
+// defaultApplicationName имя приложения в pg_stat_activity, если application_name не задан в DATABASE_OPTIONS.
+const defaultApplicationName = "shop-api"
+
+// allowedDSNOptions параметры, которые можно передать в строку подключения через DATABASE_OPTIONS.
+// Хост, порт, пользователь, пароль и база задаются отдельными полями и здесь не допускаются.
+var allowedDSNOptions = []string{
+	"application_name",
+	"connect_timeout",
+	"idle_in_transaction_session_timeout",
+	"lock_timeout",
+	"sslmode",
+	"statement_timeout",
+}
+
 func ConnectDB(dbCfg config.DatabaseConfig) (*sql.DB, error) {
 	// Строка подключения к базе данных PostgreSQL
-	connStr := fmt.Sprintf(
-		"port=%s user=%s password=%s dbname=%s host=%s sslmode=disable",
-		dbCfg.Port, dbCfg.User, dbCfg.Password, dbCfg.Name, dbCfg.Host,
-	)
+	connStr, err := buildDSN(dbCfg)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка конфигурации подключения к базе данных: %w", err)
+	}
 
 	database, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -25,3 +41,45 @@ func ConnectDB(dbCfg config.DatabaseConfig) (*sql.DB, error) {
 	}
 	return database, nil
 }
+
+// buildDSN собирает строку подключения из конфигурации. Дополнительные параметры из
+// dbCfg.Options проверяются по allowedDSNOptions и добавляются в порядке имен.
+func buildDSN(dbCfg config.DatabaseConfig) (string, error) {
+	params := [][2]string{
+		{"port", dbCfg.Port},
+		{"user", dbCfg.User},
+		{"password", dbCfg.Password},
+		{"dbname", dbCfg.Name},
+		{"host", dbCfg.Host},
+	}
+
+	options := map[string]string{"sslmode": "disable", "application_name": defaultApplicationName}
+	for key, value := range dbCfg.Options {
+		if !slices.Contains(allowedDSNOptions, key) {
+			return "", fmt.Errorf("недопустимый параметр подключения '%s'", key)
+		}
+		options[key] = value
+	}
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		params = append(params, [2]string{key, options[key]})
+	}
+
+	parts := make([]string, len(params))
+	for i, param := range params {
+		parts[i] = param[0] + "=" + quoteDSNValue(param[1])
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// quoteDSNValue заключает значение в кавычки, чтобы пробелы и кавычки в нем
+// не позволили добавить в строку подключения другие параметры.
+func quoteDSNValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+
+	"shop/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDSN(t *testing.T) {
+	cfg := config.DatabaseConfig{Host: "db", Port: "5432", User: "shop", Password: "pa ss'word", Name: "shop"}
+
+	dsn, err := buildDSN(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, `port='5432' user='shop' password='pa ss\'word' dbname='shop' host='db' application_name='shop-api' sslmode='disable'`, dsn)
+
+	cfg.Options = map[string]string{"connect_timeout": "5", "statement_timeout": "30000", "application_name": "shop-worker"}
+	dsn, err = buildDSN(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, `port='5432' user='shop' password='pa ss\'word' dbname='shop' host='db' application_name='shop-worker' connect_timeout='5' sslmode='disable' statement_timeout='30000'`, dsn)
+}
+
+func TestBuildDSN_RejectsUnexpectedOptions(t *testing.T) {
+	cfg := config.DatabaseConfig{Host: "db", Port: "5432", User: "shop", Password: "shop", Name: "shop"}
+
+	for _, key := range []string{"host", "password", "user", "dbname", "unknown"} {
+		cfg.Options = map[string]string{key: "x"}
+		_, err := buildDSN(cfg)
+		assert.Error(t, err, key)
+	}
+
+	// Значение не может добавить в строку подключения другой параметр.
+	cfg.Options = map[string]string{"application_name": "x host=evil"}
+	dsn, err := buildDSN(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, dsn, `application_name='x host=evil'`)
+	assert.Contains(t, dsn, `host='db'`)
+}
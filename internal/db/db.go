@@ -3,43 +3,95 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"shop/internal/models"
 	"shop/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// Ошибки
+var (
+	ErrNotEnoughItems     = errors.New("недостаточно предметов в инвентаре")
+	ErrItemNotFound       = errors.New("товар не найден")
+	ErrItemQuantityCapped = errors.New("превышено максимальное количество предмета в инвентаре")
+	ErrNotEnoughCoins     = errors.New("недостаточно монет")
 )
 
 // Интерфейсы для взаимодействия с данными пользователей, товаров и транзакций.
 type UserDBInterface interface {
 	GetUserByUsername(ctx context.Context, username string) (*models.DBUser, error)
 	CreateUser(ctx context.Context, username string, passwordHash string) error
-	UpdateUserCoins(ctx context.Context, userID int, coins int) error
-	GetUserInventory(ctx context.Context, userID int) ([]models.DBInventoryItem, error)
-	UpdateUserInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) error
+	UpdateUserCoins(ctx context.Context, userID int, coins int64, tx *sql.Tx) error
+	DeductUserCoins(ctx context.Context, userID int, amount int64, tx *sql.Tx) (int64, error)
+	LockUserBalances(ctx context.Context, tx *sql.Tx, userIDs ...int) (map[int]int64, error)
+	GetUserInventory(ctx context.Context, userID int, limit int) ([]models.DBInventoryItem, error)
+	GetUserInventoryByPrefix(ctx context.Context, userID int, prefix string) ([]models.DBInventoryItem, error)
+	GetUserInventoryWithPrices(ctx context.Context, userID int) ([]models.DBInventoryItem, error)
+	GetInventoryItemCount(ctx context.Context, userID int) (int, error)
+	UpdateUserInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) (int, error)
+	RemoveFromInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) error
 	GetUserIDByUsername(ctx context.Context, username string) (int, error)
-	SetInitialCoins(ctx context.Context, userID int, initialCoins int) error
+	GetBalance(ctx context.Context, userID int) (int64, error)
+	GetUserRank(ctx context.Context, userID int) (int, error)
+	GetLeaderboard(ctx context.Context, limit int) ([]models.LeaderboardEntry, error)
+	SetInitialCoins(ctx context.Context, userID int, initialCoins int64) error
+	UpdateUserPassword(ctx context.Context, userID int, passwordHash string, tx *sql.Tx) error
+	GetUserStats(ctx context.Context) (*models.DBUserStats, error)
+	DeactivateUser(ctx context.Context, userID int, tx *sql.Tx) error
+	IsUserDeactivated(ctx context.Context, username string) (bool, error)
+	ClaimDailyBonus(ctx context.Context, userID int, amount int64) (int64, bool, error)
 }
 
 type ItemDBInterface interface {
-	GetItemPrice(ctx context.Context, itemName string) (int, error)
+	GetItemPrice(ctx context.Context, itemName string) (int64, error)
+	GetItems(ctx context.Context) ([]models.DBItem, error)
+	UpsertItemPrice(ctx context.Context, itemName string, price int64, tx *sql.Tx) (bool, error)
+	SeedItems(ctx context.Context, items []models.ItemPriceUpdate) (int, error)
 }
 
 type TransactionDBInterface interface {
-	RecordTransaction(ctx context.Context, senderUserID int, receiverUserID int, amount int, tx *sql.Tx) error
+	RecordTransaction(ctx context.Context, senderUserID int, receiverUserID int, amount int64, memo string, category string, tx *sql.Tx) error
+	GetTransactionForUpdate(ctx context.Context, transactionID int, tx *sql.Tx) (*models.DBTransaction, error)
+	RecordReversal(ctx context.Context, transactionID int, senderUserID int, receiverUserID int, amount int64, tx *sql.Tx) error
 	GetDB() *sql.DB
-	GetCoinHistory(ctx context.Context, userID int) (*models.CoinHistory, error)
+	GetCoinHistory(ctx context.Context, userID int, category string) (*models.CoinHistory, error)
+	StreamCoinHistory(ctx context.Context, userID int, category string, fn func(models.Transaction) error) error
+	GetTransactionsBetween(ctx context.Context, usernameA string, usernameB string) ([]models.DBTransaction, error)
+	GetUserTransactions(ctx context.Context, userID int) ([]models.DBTransaction, error)
+	RecordGift(ctx context.Context, senderUserID int, receiverUserID int, itemType string, quantity int, tx *sql.Tx) error
+	RecordAdminAction(ctx context.Context, entry models.AdminAuditEntry, tx *sql.Tx) error
+	GetAdminAudit(ctx context.Context, limit int, offset int) ([]models.AdminAuditEntry, error)
+	CountAdminAudit(ctx context.Context) (int, error)
+	ServerVersion(ctx context.Context) (string, error)
 }
 
+// Частые запросы, которые выполняются через подготовленные выражения.
+const (
+	queryGetUserByUsername = "SELECT id, username, password_hash, coins FROM users WHERE username = $1 AND deleted_at IS NULL"
+	queryUpdateUserCoins   = "UPDATE users SET coins = $1 WHERE id = $2"
+	queryGetItemPrice      = "SELECT price FROM items WHERE item_name = $1"
+)
+
 // Реализации для PostgreSQL.
 type UserDB struct {
-	Db  *sql.DB
-	log *logger.Logger
+	Db *sql.DB
+	// MaxItemQuantity максимальное количество одного предмета в инвентаре пользователя.
+	// 0 снимает ограничение.
+	MaxItemQuantity int
+	log             *logger.Logger
+	stmts           *stmtCache
 }
 
 type ItemDB struct {
-	Db  *sql.DB
-	log *logger.Logger
+	Db    *sql.DB
+	log   *logger.Logger
+	stmts *stmtCache
 }
 
 type TransactionDB struct {
@@ -48,34 +100,75 @@ type TransactionDB struct {
 }
 
 // Функции создания новых экземпляров.
+// Паникуют, если соединение с БД не передано: ошибка конфигурации должна
+// проявиться при запуске, а не при первом запросе.
 func NewUserDB(db *sql.DB, log *logger.Logger) *UserDB {
-	return &UserDB{Db: db, log: log}
+	mustHaveDB(db, "NewUserDB")
+	return &UserDB{Db: db, log: log, stmts: newStmtCache(db)}
 }
 
 func NewItemDB(db *sql.DB, log *logger.Logger) *ItemDB {
-	return &ItemDB{Db: db, log: log}
+	mustHaveDB(db, "NewItemDB")
+	return &ItemDB{Db: db, log: log, stmts: newStmtCache(db)}
+}
+
+// Close закрывает подготовленные выражения UserDB. Соединение с БД не закрывается.
+func (udb *UserDB) Close() error {
+	return udb.stmts.Close()
+}
+
+// Close закрывает подготовленные выражения ItemDB. Соединение с БД не закрывается.
+func (idb *ItemDB) Close() error {
+	return idb.stmts.Close()
 }
 
 func NewTransactionDB(db *sql.DB, log *logger.Logger) *TransactionDB {
+	mustHaveDB(db, "NewTransactionDB")
 	return &TransactionDB{Db: db, log: log}
 }
 
+// mustHaveDB паникует с понятным сообщением, если соединение с БД равно nil.
+func mustHaveDB(db *sql.DB, constructor string) {
+	if db == nil {
+		panic(constructor + ": соединение с базой данных (*sql.DB) не должно быть nil")
+	}
+}
+
 // GetDB возвращает базовое соединение sql.DB.
 func (tdb *TransactionDB) GetDB() *sql.DB {
 	return tdb.Db
 }
 
-// GetUserByUsername получает пользователя из базы данных по имени пользователя.
+// execer выполняет запросы в транзакции tx или, если она не передана, напрямую в базе данных.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// execer возвращает tx, если транзакция передана, иначе соединение с базой данных.
+func (udb *UserDB) execer(tx *sql.Tx) execer {
+	if tx != nil {
+		return tx
+	}
+	return udb.Db
+}
+
+// GetUserByUsername получает активного пользователя из базы данных по имени пользователя.
+// Деактивированные пользователи не возвращаются.
 func (udb *UserDB) GetUserByUsername(ctx context.Context, username string) (*models.DBUser, error) {
 	udb.log.Debug("GetUserByUsername", "username", username)
+	stmt, err := udb.stmts.get(ctx, queryGetUserByUsername)
+	if err != nil {
+		udb.log.Error("Ошибка подготовки запроса GetUserByUsername", "error", err)
+		return nil, fmt.Errorf("ошибка при получении пользователя по имени: %w", wrapError(err))
+	}
 	user := &models.DBUser{}
-	err := udb.Db.QueryRowContext(ctx, "SELECT id, username, password_hash, coins FROM users WHERE username = $1", username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Coins)
+	err = stmt.QueryRowContext(ctx, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Coins)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Пользователь не найден
 		}
 		udb.log.Error("Ошибка SQL запроса GetUserByUsername", "username", username, "error", err)
-		return nil, fmt.Errorf("ошибка при получении пользователя по имени: %w", err)
+		return nil, fmt.Errorf("ошибка при получении пользователя по имени: %w", wrapError(err))
 	}
 	return user, nil
 }
@@ -84,190 +177,816 @@ func (udb *UserDB) GetUserByUsername(ctx context.Context, username string) (*mod
 func (udb *UserDB) CreateUser(ctx context.Context, username string, passwordHash string) error {
 	udb.log.Debug("CreateUser", "username", username)
 	_, err := udb.Db.ExecContext(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ($1, $2, 0)", username, passwordHash) // Монеты устанавливаются в 0 при создании
+	if err = wrapError(err); IsUniqueViolation(err) {
+		udb.log.Warn("Пользователь уже создан", "username", username)
+		return fmt.Errorf("ошибка при создании пользователя: %w", err)
+	}
 	if err != nil {
 		udb.log.Error("Ошибка SQL запроса CreateUser", "username", username, "error", err)
-		return fmt.Errorf("ошибка при создании пользователя: %w", err)
+		return fmt.Errorf("ошибка при создании пользователя: %w", wrapError(err))
 	}
 	return nil
 }
 
 // UpdateUserCoins обновляет баланс монет пользователя в базе данных.
-func (udb *UserDB) UpdateUserCoins(ctx context.Context, userID int, coins int) error {
-	_, err := udb.Db.ExecContext(ctx, "UPDATE users SET coins = $1 WHERE id = $2", coins, userID)
+// Если передана транзакция tx, обновление выполняется в ней.
+func (udb *UserDB) UpdateUserCoins(ctx context.Context, userID int, coins int64, tx *sql.Tx) error {
+	stmt, err := udb.stmts.get(ctx, queryUpdateUserCoins)
+	if err != nil {
+		udb.log.Error("Ошибка подготовки запроса UpdateUserCoins", "error", err)
+		return fmt.Errorf("ошибка при обновлении монет пользователя: %w", wrapError(err))
+	}
+	if tx != nil {
+		// Выражение привязывается к соединению транзакции.
+		stmt = tx.StmtContext(ctx, stmt)
+		defer stmt.Close()
+	}
+	_, err = stmt.ExecContext(ctx, coins, userID)
 	udb.log.Debug("UpdateUserCoins", "userID", userID, "coins", coins)
 	if err != nil {
 		udb.log.Error("Ошибка SQL запроса UpdateUserCoins", "userID", userID, "coins", coins, "error", err)
-		return fmt.Errorf("ошибка при обновлении монет пользователя: %w", err)
+		return fmt.Errorf("ошибка при обновлении монет пользователя: %w", wrapError(err))
 	}
 	return nil
 }
 
-// GetUserInventory получает инвентарь пользователя из базы данных.
-func (udb *UserDB) GetUserInventory(ctx context.Context, userID int) ([]models.DBInventoryItem, error) {
-	rows, err := udb.Db.QueryContext(ctx, "SELECT id, user_id, item_type, quantity FROM inventory WHERE user_id = $1", userID)
+// DeductUserCoins атомарно списывает amount монет в транзакции tx и возвращает новый баланс.
+// Если монет недостаточно, баланс не изменяется и возвращается ErrNotEnoughCoins.
+func (udb *UserDB) DeductUserCoins(ctx context.Context, userID int, amount int64, tx *sql.Tx) (int64, error) {
+	udb.log.Debug("DeductUserCoins", "userID", userID, "amount", amount)
+	var coins int64
+	err := tx.QueryRowContext(ctx, "UPDATE users SET coins = coins - $2 WHERE id = $1 AND coins >= $2 RETURNING coins", userID, amount).Scan(&coins)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("ошибка при списании монет: %w", ErrNotEnoughCoins)
+	}
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса DeductUserCoins", "userID", userID, "amount", amount, "error", err)
+		return 0, fmt.Errorf("ошибка при списании монет: %w", wrapError(err))
+	}
+	return coins, nil
+}
+
+// LockUserBalances блокирует строки пользователей в транзакции tx (SELECT ... FOR UPDATE)
+// и возвращает их балансы по ID. Строки блокируются по возрастанию ID независимо от порядка
+// аргументов: встречные переводы A→B и B→A захватывают блокировки в одном порядке
+// и не могут взаимно заблокировать друг друга.
+func (udb *UserDB) LockUserBalances(ctx context.Context, tx *sql.Tx, userIDs ...int) (map[int]int64, error) {
+	udb.log.Debug("LockUserBalances", "userIDs", userIDs)
+	ids := slices.Clone(userIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	balances := make(map[int]int64, len(ids))
+	for _, id := range ids {
+		var coins int64
+		err := tx.QueryRowContext(ctx, "SELECT coins FROM users WHERE id = $1 FOR UPDATE", id).Scan(&coins)
+		if err != nil {
+			udb.log.Error("Ошибка SQL запроса LockUserBalances", "userID", id, "error", err)
+			return nil, fmt.Errorf("ошибка при блокировке баланса пользователя: %w", wrapError(err))
+		}
+		balances[id] = coins
+	}
+	return balances, nil
+}
+
+// GetUserInventory получает инвентарь пользователя из базы данных, упорядоченный по названию предмета.
+// limit ограничивает число строк, 0 снимает ограничение.
+func (udb *UserDB) GetUserInventory(ctx context.Context, userID int, limit int) ([]models.DBInventoryItem, error) {
+	// LIMIT NULL в PostgreSQL означает отсутствие ограничения.
+	return udb.queryInventory(ctx, "GetUserInventory", userID,
+		"SELECT id, user_id, item_type, quantity FROM inventory WHERE user_id = $1 ORDER BY item_type LIMIT NULLIF($2, 0)", userID, limit)
+}
+
+// GetUserInventoryByPrefix получает предметы инвентаря пользователя, название которых начинается с prefix.
+// Символы шаблона LIKE в prefix экранируются и сравниваются буквально.
+func (udb *UserDB) GetUserInventoryByPrefix(ctx context.Context, userID int, prefix string) ([]models.DBInventoryItem, error) {
+	return udb.queryInventory(ctx, "GetUserInventoryByPrefix", userID,
+		`SELECT id, user_id, item_type, quantity FROM inventory WHERE user_id = $1 AND item_type LIKE $2 ESCAPE '\' ORDER BY item_type`,
+		userID, escapeLike(prefix)+"%")
+}
+
+// GetUserInventoryWithPrices получает инвентарь пользователя вместе с текущими ценами из каталога.
+// Для предметов, которых больше нет в каталоге, Price равен nil.
+func (udb *UserDB) GetUserInventoryWithPrices(ctx context.Context, userID int) ([]models.DBInventoryItem, error) {
+	rows, err := udb.Db.QueryContext(ctx,
+		`SELECT inv.id, inv.user_id, inv.item_type, inv.quantity, i.price
+		FROM inventory inv LEFT JOIN items i ON i.item_name = inv.item_type
+		WHERE inv.user_id = $1
+		ORDER BY inv.item_type`, userID)
 	if err != nil {
-		udb.log.Error("Ошибка SQL запроса GetUserInventory", "userID", userID, "error", err)
-		return nil, fmt.Errorf("ошибка при получении инвентаря пользователя: %w", err)
+		udb.log.Error("Ошибка SQL запроса GetUserInventoryWithPrices", "userID", userID, "error", err)
+		return nil, fmt.Errorf("ошибка при получении инвентаря пользователя: %w", wrapError(err))
 	}
 	defer rows.Close()
 
 	inventory := []models.DBInventoryItem{}
 	for rows.Next() {
 		item := models.DBInventoryItem{}
-		if err := rows.Scan(&item.ID, &item.UserID, &item.ItemType, &item.Quantity); err != nil {
-			udb.log.Error("Ошибка сканирования строки GetUserInventory", "userID", userID, "error", err)
-			return nil, fmt.Errorf("ошибка при сканировании элемента инвентаря: %w", err)
+		var price sql.NullInt64
+		if err := rows.Scan(&item.ID, &item.UserID, &item.ItemType, &item.Quantity, &price); err != nil {
+			udb.log.Error("Ошибка сканирования строки GetUserInventoryWithPrices", "userID", userID, "error", err)
+			return nil, fmt.Errorf("ошибка при сканировании элемента инвентаря: %w", wrapError(err))
+		}
+		if price.Valid {
+			p := price.Int64
+			item.Price = &p
 		}
 		inventory = append(inventory, item)
 	}
 	if err := rows.Err(); err != nil {
-		udb.log.Error("Ошибка итерации строк GetUserInventory", "userID", userID, "error", err)
-		return nil, fmt.Errorf("ошибка при итерации строк инвентаря: %w", err)
+		udb.log.Error("Ошибка итерации строк GetUserInventoryWithPrices", "userID", userID, "error", err)
+		return nil, fmt.Errorf("ошибка при итерации строк инвентаря: %w", wrapError(err))
 	}
 	return inventory, nil
 }
 
-// UpdateUserInventory обновляет инвентарь пользователя в базе данных.
-func (udb *UserDB) UpdateUserInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) error {
-	var existingQuantity int
-	err := tx.QueryRowContext(ctx, "SELECT quantity FROM inventory WHERE user_id = $1 AND item_type = $2", userID, itemType).Scan(&existingQuantity)
-	if err == nil { // Элемент существует, обновляем количество
-		udb.log.Debug("UpdateUserInventory: Element exists, updating quantity", "userID", userID, "itemType", itemType, "quantity", quantity)
-		_, err := tx.ExecContext(ctx, "UPDATE inventory SET quantity = $1 WHERE user_id = $2 AND item_type = $3", existingQuantity+quantity, userID, itemType)
-		if err != nil {
-			udb.log.Error("Ошибка SQL запроса UpdateUserInventory (update existing)", "userID", userID, "itemType", itemType, "quantity", quantity, "error", err)
-			return fmt.Errorf("ошибка при обновлении существующего элемента инвентаря: %w", err)
-		}
-	} else if err == sql.ErrNoRows { // Элемент не существует, добавляем новый
-		udb.log.Debug("UpdateUserInventory: Element does not exist, adding new", "userID", userID, "itemType", itemType, "quantity", quantity)
-		_, err := tx.ExecContext(ctx, "INSERT INTO inventory (user_id, item_type, quantity) VALUES ($1, $2, $3)", userID, itemType, quantity)
-		if err != nil {
-			udb.log.Error("Ошибка SQL запроса UpdateUserInventory (insert new)", "userID", userID, "itemType", itemType, "quantity", quantity, "error", err)
-			return fmt.Errorf("ошибка при добавлении нового элемента инвентаря: %w", err)
+// queryInventory выполняет запрос к инвентарю и сканирует результат.
+func (udb *UserDB) queryInventory(ctx context.Context, method string, userID int, query string, args ...any) ([]models.DBInventoryItem, error) {
+	rows, err := udb.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса "+method, "userID", userID, "error", err)
+		return nil, fmt.Errorf("ошибка при получении инвентаря пользователя: %w", wrapError(err))
+	}
+	defer rows.Close()
+
+	inventory := []models.DBInventoryItem{}
+	for rows.Next() {
+		item := models.DBInventoryItem{}
+		if err := rows.Scan(&item.ID, &item.UserID, &item.ItemType, &item.Quantity); err != nil {
+			udb.log.Error("Ошибка сканирования строки "+method, "userID", userID, "error", err)
+			return nil, fmt.Errorf("ошибка при сканировании элемента инвентаря: %w", wrapError(err))
 		}
-	} else if err != nil {
-		udb.log.Error("Ошибка проверки существования элемента инвентаря", "userID", userID, "itemType", itemType, "error", err)
-		return fmt.Errorf("ошибка при проверке существующего элемента инвентаря: %w", err)
+		inventory = append(inventory, item)
+	}
+	if err := rows.Err(); err != nil {
+		udb.log.Error("Ошибка итерации строк "+method, "userID", userID, "error", err)
+		return nil, fmt.Errorf("ошибка при итерации строк инвентаря: %w", wrapError(err))
+	}
+	return inventory, nil
+}
+
+// escapeLike экранирует специальные символы шаблона LIKE.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// GetInventoryItemCount получает суммарное количество предметов в инвентаре пользователя.
+func (udb *UserDB) GetInventoryItemCount(ctx context.Context, userID int) (int, error) {
+	udb.log.Debug("GetInventoryItemCount", "userID", userID)
+	var count int
+	err := udb.Db.QueryRowContext(ctx, "SELECT COALESCE(SUM(quantity), 0) FROM inventory WHERE user_id = $1", userID).Scan(&count)
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса GetInventoryItemCount", "userID", userID, "error", err)
+		return 0, fmt.Errorf("ошибка при подсчете предметов инвентаря: %w", wrapError(err))
+	}
+	return count, nil
+}
+
+// UpdateUserInventory добавляет quantity предметов в инвентарь пользователя и возвращает их итоговое количество.
+// Если итоговое количество превысит MaxItemQuantity, инвентарь не изменяется и возвращается ErrItemQuantityCapped.
+// Для товара, которого нет в каталоге, возвращается ErrItemNotFound.
+func (udb *UserDB) UpdateUserInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) (int, error) {
+	if udb.exceedsItemCap(0, quantity) {
+		udb.log.Warn("Превышено максимальное количество предмета", "userID", userID, "itemType", itemType, "quantity", quantity, "max", udb.MaxItemQuantity)
+		return 0, fmt.Errorf("ошибка при обновлении инвентаря: %w", ErrItemQuantityCapped)
+	}
+
+	// Вставка и увеличение количества выполняются одним запросом: одновременные покупки
+	// одного предмета не теряют обновления и не конфликтуют на UNIQUE (user_id, item_type).
+	// Если лимит MaxItemQuantity был бы превышен, строка не обновляется и запрос не возвращает строк.
+	var newQuantity int
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO inventory (user_id, item_type, quantity) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, item_type) DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity
+		WHERE $4 = 0 OR inventory.quantity + EXCLUDED.quantity <= $4
+		RETURNING quantity`, userID, itemType, quantity, udb.MaxItemQuantity).Scan(&newQuantity)
+	if err == sql.ErrNoRows {
+		udb.log.Warn("Превышено максимальное количество предмета", "userID", userID, "itemType", itemType, "quantity", quantity, "max", udb.MaxItemQuantity)
+		return 0, fmt.Errorf("ошибка при обновлении инвентаря: %w", ErrItemQuantityCapped)
+	}
+	if isConstraintViolation(err, foreignKeyViolation, inventoryItemTypeFK) {
+		udb.log.Warn("Попытка добавить в инвентарь товар не из каталога", "userID", userID, "itemType", itemType)
+		return 0, fmt.Errorf("товар '%s': %w", itemType, ErrItemNotFound)
+	}
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса UpdateUserInventory", "userID", userID, "itemType", itemType, "quantity", quantity, "error", err)
+		return 0, fmt.Errorf("ошибка при обновлении инвентаря: %w", wrapError(err))
+	}
+	return newQuantity, nil
+}
+
+// exceedsItemCap проверяет, превысит ли добавление quantity предметов к existing лимит MaxItemQuantity.
+func (udb *UserDB) exceedsItemCap(existing int, quantity int) bool {
+	return udb.MaxItemQuantity > 0 && quantity > udb.MaxItemQuantity-existing
+}
+
+// RemoveFromInventory списывает quantity предметов itemType из инвентаря пользователя в транзакции tx.
+// Если предметов меньше, чем требуется, инвентарь не изменяется и возвращается ErrNotEnoughItems.
+func (udb *UserDB) RemoveFromInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) error {
+	udb.log.Debug("RemoveFromInventory", "userID", userID, "itemType", itemType, "quantity", quantity)
+	result, err := tx.ExecContext(ctx, "UPDATE inventory SET quantity = quantity - $3 WHERE user_id = $1 AND item_type = $2 AND quantity >= $3", userID, itemType, quantity)
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса RemoveFromInventory", "userID", userID, "itemType", itemType, "quantity", quantity, "error", err)
+		return fmt.Errorf("ошибка при списании предметов из инвентаря: %w", wrapError(err))
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		udb.log.Error("Ошибка RowsAffected в RemoveFromInventory", "userID", userID, "error", err)
+		return fmt.Errorf("ошибка при списании предметов из инвентаря: %w", wrapError(err))
+	}
+	if rows == 0 {
+		return fmt.Errorf("ошибка при списании предметов из инвентаря: %w", ErrNotEnoughItems)
+	}
+
+	// Закончившиеся предметы удаляются из инвентаря.
+	_, err = tx.ExecContext(ctx, "DELETE FROM inventory WHERE user_id = $1 AND item_type = $2 AND quantity = 0", userID, itemType)
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса RemoveFromInventory (delete empty)", "userID", userID, "itemType", itemType, "error", err)
+		return fmt.Errorf("ошибка при удалении пустого элемента инвентаря: %w", wrapError(err))
 	}
 	return nil
 }
 
 // GetItemPrice получает цену товара из базы данных.
-func (idb *ItemDB) GetItemPrice(ctx context.Context, itemName string) (int, error) {
+func (idb *ItemDB) GetItemPrice(ctx context.Context, itemName string) (int64, error) {
 	idb.log.Debug("GetItemPrice", "itemName", itemName)
-	var price int
-	err := idb.Db.QueryRowContext(ctx, "SELECT price FROM items WHERE item_name = $1", itemName).Scan(&price)
+	stmt, err := idb.stmts.get(ctx, queryGetItemPrice)
+	if err != nil {
+		idb.log.Error("Ошибка подготовки запроса GetItemPrice", "error", err)
+		return 0, fmt.Errorf("ошибка при получении цены товара: %w", wrapError(err))
+	}
+	var price int64
+	err = stmt.QueryRowContext(ctx, itemName).Scan(&price)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			idb.log.Warn("Товар не найден", "itemName", itemName)
-			return 0, fmt.Errorf("товар '%s' не найден", itemName)
+			return 0, fmt.Errorf("товар '%s': %w", itemName, ErrItemNotFound)
 		}
 		idb.log.Error("Ошибка SQL запроса GetItemPrice", "itemName", itemName, "error", err)
-		return 0, fmt.Errorf("ошибка при получении цены товара: %w", err)
+		return 0, fmt.Errorf("ошибка при получении цены товара: %w", wrapError(err))
 	}
 	return price, nil
 }
 
+// GetItems получает все товары каталога, упорядоченные по названию.
+func (idb *ItemDB) GetItems(ctx context.Context) ([]models.DBItem, error) {
+	idb.log.Debug("GetItems")
+
+	rows, err := idb.Db.QueryContext(ctx, "SELECT id, item_name, price, updated_at FROM items ORDER BY item_name")
+	if err != nil {
+		idb.log.Error("Ошибка SQL запроса GetItems", "error", err)
+		return nil, fmt.Errorf("ошибка при получении каталога товаров: %w", wrapError(err))
+	}
+	defer rows.Close()
+
+	items := []models.DBItem{}
+	for rows.Next() {
+		var item models.DBItem
+		if err := rows.Scan(&item.ID, &item.ItemName, &item.Price, &item.UpdatedAt); err != nil {
+			idb.log.Error("Ошибка сканирования строки GetItems", "error", err)
+			return nil, fmt.Errorf("ошибка при чтении товара: %w", wrapError(err))
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		idb.log.Error("Ошибка итерации строк GetItems", "error", err)
+		return nil, fmt.Errorf("ошибка при итерации строк каталога: %w", wrapError(err))
+	}
+	return items, nil
+}
+
+// UpsertItemPrice устанавливает цену товара, добавляя его в каталог, если его еще нет.
+// Возвращает true, если товар был добавлен.
+func (idb *ItemDB) UpsertItemPrice(ctx context.Context, itemName string, price int64, tx *sql.Tx) (bool, error) {
+	idb.log.Debug("UpsertItemPrice", "itemName", itemName, "price", price)
+	var created bool
+	// xmax = 0 только у строки, созданной вставкой, а не обновленной при конфликте.
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO items (item_name, price) VALUES ($1, $2)
+		ON CONFLICT (item_name) DO UPDATE SET price = EXCLUDED.price, updated_at = CURRENT_TIMESTAMP
+		RETURNING xmax = 0`, itemName, price).Scan(&created)
+	if err != nil {
+		idb.log.Error("Ошибка SQL запроса UpsertItemPrice", "itemName", itemName, "price", price, "error", err)
+		return false, fmt.Errorf("ошибка при изменении цены товара '%s': %w", itemName, wrapError(err))
+	}
+	return created, nil
+}
+
+// DefaultCatalog встроенный каталог товаров, которым заполняется пустая таблица items
+// при CATALOG_BOOTSTRAP=true. Совпадает с товарами из миграции 001-init.
+var DefaultCatalog = []models.ItemPriceUpdate{
+	{ItemName: "t-shirt", Price: 80},
+	{ItemName: "cup", Price: 20},
+	{ItemName: "book", Price: 50},
+	{ItemName: "pen", Price: 10},
+	{ItemName: "powerbank", Price: 200},
+	{ItemName: "hoody", Price: 300},
+	{ItemName: "umbrella", Price: 200},
+	{ItemName: "socks", Price: 10},
+	{ItemName: "wallet", Price: 50},
+	{ItemName: "pink-hoody", Price: 500},
+}
+
+// SeedItems добавляет товары items в каталог, только если каталог пуст.
+// Возвращает количество добавленных товаров: 0, если в каталоге уже были товары.
+func (idb *ItemDB) SeedItems(ctx context.Context, items []models.ItemPriceUpdate) (int, error) {
+	idb.log.Debug("SeedItems", "count", len(items))
+	names := make([]string, len(items))
+	prices := make([]int64, len(items))
+	for i, item := range items {
+		names[i] = item.ItemName
+		prices[i] = item.Price
+	}
+	// Проверка и вставка выполняются одним запросом; ON CONFLICT защищает от
+	// одновременного заполнения несколькими экземплярами сервиса.
+	result, err := idb.Db.ExecContext(ctx, `
+		INSERT INTO items (item_name, price)
+		SELECT * FROM unnest($1::text[], $2::bigint[])
+		WHERE NOT EXISTS (SELECT 1 FROM items)
+		ON CONFLICT (item_name) DO NOTHING`, pq.Array(names), pq.Array(prices))
+	if err != nil {
+		idb.log.Error("Ошибка SQL запроса SeedItems", "error", err)
+		return 0, fmt.Errorf("ошибка при заполнении каталога товаров: %w", wrapError(err))
+	}
+	seeded, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при заполнении каталога товаров: %w", wrapError(err))
+	}
+	return int(seeded), nil
+}
+
 // RecordTransaction записывает транзакцию монет в базу данных.
-func (tdb *TransactionDB) RecordTransaction(ctx context.Context, senderUserID int, receiverUserID int, amount int, tx *sql.Tx) error {
-	_, err := tx.ExecContext(ctx, "INSERT INTO coin_transactions (sender_user_id, receiver_user_id, amount, transaction_date) VALUES ($1, $2, $3, $4)", senderUserID, receiverUserID, amount, time.Now())
+func (tdb *TransactionDB) RecordTransaction(ctx context.Context, senderUserID int, receiverUserID int, amount int64, memo string, category string, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO coin_transactions (sender_user_id, receiver_user_id, amount, memo, category, transaction_date) VALUES ($1, $2, $3, $4, $5, $6)", senderUserID, receiverUserID, amount, memo, category, time.Now())
 	tdb.log.Debug("RecordTransaction", "senderUserID", senderUserID, "receiverUserID", receiverUserID, "amount", amount)
+	if err = wrapError(err); IsForeignKeyViolation(err) {
+		// Участник перевода удален между проверкой и записью.
+		tdb.log.Warn("Участник перевода не существует", "senderUserID", senderUserID, "receiverUserID", receiverUserID, "error", err)
+		return fmt.Errorf("ошибка при записи транзакции: %w", err)
+	}
 	if err != nil {
 		tdb.log.Error("Ошибка SQL запроса RecordTransaction", "senderUserID", senderUserID, "receiverUserID", receiverUserID, "amount", amount, "error", err)
-		return fmt.Errorf("ошибка при записи транзакции: %w", err)
+		return fmt.Errorf("ошибка при записи транзакции: %w", wrapError(err))
 	}
 	return nil
 }
 
+// GetTransactionForUpdate получает перевод по ID и блокирует его строку до конца транзакции tx,
+// чтобы перевод нельзя было отменить одновременно дважды. Возвращает nil, если перевода нет.
+func (tdb *TransactionDB) GetTransactionForUpdate(ctx context.Context, transactionID int, tx *sql.Tx) (*models.DBTransaction, error) {
+	tdb.log.Debug("GetTransactionForUpdate", "transactionID", transactionID)
+	var t models.DBTransaction
+	var reversalOf sql.NullInt64
+	err := tx.QueryRowContext(ctx, `
+		SELECT ct.id, ct.sender_user_id, ct.receiver_user_id, ct.amount, ct.memo, ct.transaction_date, ct.reversal_of,
+		       EXISTS (SELECT 1 FROM coin_transactions r WHERE r.reversal_of = ct.id)
+		FROM coin_transactions ct
+		WHERE ct.id = $1
+		FOR UPDATE OF ct`, transactionID).
+		Scan(&t.ID, &t.SenderUserID, &t.ReceiverUserID, &t.Amount, &t.Memo, &t.TransactionDate, &reversalOf, &t.Reversed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		tdb.log.Error("Ошибка SQL запроса GetTransactionForUpdate", "transactionID", transactionID, "error", err)
+		return nil, fmt.Errorf("ошибка при получении перевода: %w", wrapError(err))
+	}
+	if reversalOf.Valid {
+		id := int(reversalOf.Int64)
+		t.ReversalOf = &id
+	}
+	return &t, nil
+}
+
+// RecordReversal записывает обратный перевод, отменяющий перевод transactionID, в транзакции tx.
+// Повторная отмена того же перевода возвращает ошибку нарушения уникальности.
+func (tdb *TransactionDB) RecordReversal(ctx context.Context, transactionID int, senderUserID int, receiverUserID int, amount int64, tx *sql.Tx) error {
+	tdb.log.Debug("RecordReversal", "transactionID", transactionID, "senderUserID", senderUserID, "receiverUserID", receiverUserID, "amount", amount)
+	_, err := tx.ExecContext(ctx, "INSERT INTO coin_transactions (sender_user_id, receiver_user_id, amount, transaction_date, reversal_of) VALUES ($1, $2, $3, $4, $5)", senderUserID, receiverUserID, amount, time.Now(), transactionID)
+	if err = wrapError(err); IsUniqueViolation(err) {
+		tdb.log.Warn("Перевод уже отменен", "transactionID", transactionID, "error", err)
+		return fmt.Errorf("ошибка при записи отмены перевода: %w", err)
+	}
+	if err != nil {
+		tdb.log.Error("Ошибка SQL запроса RecordReversal", "transactionID", transactionID, "error", err)
+		return fmt.Errorf("ошибка при записи отмены перевода: %w", err)
+	}
+	return nil
+}
+
+// RecordGift записывает передачу предметов между пользователями в транзакции tx.
+func (tdb *TransactionDB) RecordGift(ctx context.Context, senderUserID int, receiverUserID int, itemType string, quantity int, tx *sql.Tx) error {
+	tdb.log.Debug("RecordGift", "senderUserID", senderUserID, "receiverUserID", receiverUserID, "itemType", itemType, "quantity", quantity)
+	_, err := tx.ExecContext(ctx, "INSERT INTO item_gifts (sender_user_id, receiver_user_id, item_type, quantity, gift_date) VALUES ($1, $2, $3, $4, $5)", senderUserID, receiverUserID, itemType, quantity, time.Now())
+	if err = wrapError(err); IsForeignKeyViolation(err) {
+		tdb.log.Warn("Участник подарка не существует", "senderUserID", senderUserID, "receiverUserID", receiverUserID, "error", err)
+		return fmt.Errorf("ошибка при записи подарка: %w", err)
+	}
+	if err != nil {
+		tdb.log.Error("Ошибка SQL запроса RecordGift", "senderUserID", senderUserID, "receiverUserID", receiverUserID, "error", err)
+		return fmt.Errorf("ошибка при записи подарка: %w", wrapError(err))
+	}
+	return nil
+}
+
+// coinHistoryQuery выбирает полученные и отправленные переводы пользователя $1 одним запросом,
+// направление в колонке dir. Непустой $2 оставляет только переводы этой категории.
+const coinHistoryQuery = `
+        SELECT 'received' AS dir, ct.amount, ct.memo, ct.category, u_sender.username, ct.transaction_date
+        FROM coin_transactions ct
+        INNER JOIN users u_sender ON ct.sender_user_id = u_sender.id
+        WHERE ct.receiver_user_id = $1 AND ($2 = '' OR ct.category = $2)
+        UNION ALL
+        SELECT 'sent' AS dir, ct.amount, ct.memo, ct.category, u_receiver.username, ct.transaction_date
+        FROM coin_transactions ct
+        INNER JOIN users u_receiver ON ct.receiver_user_id = u_receiver.id
+        WHERE ct.sender_user_id = $1 AND ($2 = '' OR ct.category = $2)
+        ORDER BY transaction_date DESC`
+
 // GetCoinHistory получает историю транзакций монет для пользователя.
-func (tdb *TransactionDB) GetCoinHistory(ctx context.Context, userID int) (*models.CoinHistory, error) {
+// Непустая category оставляет в истории только переводы этой категории.
+func (tdb *TransactionDB) GetCoinHistory(ctx context.Context, userID int, category string) (*models.CoinHistory, error) {
 	history := &models.CoinHistory{
 		Received: []models.Transaction{},
 		Sent:     []models.Transaction{},
 	}
 
-	// Полученные транзакции
+	rows, err := tdb.Db.QueryContext(ctx, coinHistoryQuery, userID, category)
+	if err != nil {
+		tdb.log.Error("Ошибка SQL запроса GetCoinHistory", "userID", userID, "error", err)
+		return nil, fmt.Errorf("ошибка при получении истории транзакций: %w", wrapError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction models.Transaction
+		var dir, counterparty string
+		var transactionDate time.Time
+		if err := rows.Scan(&dir, &transaction.Amount, &transaction.Memo, &transaction.Category, &counterparty, &transactionDate); err != nil {
+			tdb.log.Error("Ошибка сканирования строки GetCoinHistory", "userID", userID, "error", err)
+			continue
+		}
+		switch dir {
+		case "received":
+			transaction.FromUser = counterparty
+			history.Received = append(history.Received, transaction)
+		case "sent":
+			transaction.ToUser = counterparty
+			history.Sent = append(history.Sent, transaction)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		tdb.log.Error("Ошибка итерации строк GetCoinHistory", "userID", userID, "error", err)
+		return nil, fmt.Errorf("ошибка при итерации строк истории транзакций: %w", wrapError(err))
+	}
+
+	return history, nil
+}
+
+// StreamCoinHistory передает переводы пользователя в fn по мере чтения строк, новые первыми.
+// В отличие от GetCoinHistory история не собирается в памяти целиком. Полученные переводы
+// заполняют FromUser, отправленные — ToUser. Ошибка fn прерывает чтение и возвращается как есть.
+func (tdb *TransactionDB) StreamCoinHistory(ctx context.Context, userID int, category string, fn func(models.Transaction) error) error {
+	rows, err := tdb.Db.QueryContext(ctx, coinHistoryQuery, userID, category)
+	if err != nil {
+		tdb.log.Error("Ошибка SQL запроса StreamCoinHistory", "userID", userID, "error", err)
+		return fmt.Errorf("ошибка при получении истории транзакций: %w", wrapError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction models.Transaction
+		var dir, counterparty string
+		var transactionDate time.Time
+		if err := rows.Scan(&dir, &transaction.Amount, &transaction.Memo, &transaction.Category, &counterparty, &transactionDate); err != nil {
+			tdb.log.Error("Ошибка сканирования строки StreamCoinHistory", "userID", userID, "error", err)
+			return fmt.Errorf("ошибка при чтении перевода: %w", wrapError(err))
+		}
+		if dir == "received" {
+			transaction.FromUser = counterparty
+		} else {
+			transaction.ToUser = counterparty
+		}
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		tdb.log.Error("Ошибка итерации строк StreamCoinHistory", "userID", userID, "error", err)
+		return fmt.Errorf("ошибка при итерации строк истории транзакций: %w", wrapError(err))
+	}
+	return nil
+}
+
+// GetTransactionsBetween получает переводы между двумя пользователями в обе стороны,
+// отсортированные по дате. Деактивированные пользователи не исключаются.
+func (tdb *TransactionDB) GetTransactionsBetween(ctx context.Context, usernameA string, usernameB string) ([]models.DBTransaction, error) {
+	tdb.log.Debug("GetTransactionsBetween", "usernameA", usernameA, "usernameB", usernameB)
+
 	rows, err := tdb.Db.QueryContext(ctx, `
-        SELECT ct.amount, u_sender.username
+        SELECT ct.id, ct.sender_user_id, u_sender.username, ct.receiver_user_id, u_receiver.username,
+               ct.amount, ct.memo, ct.category, ct.transaction_date
         FROM coin_transactions ct
         INNER JOIN users u_sender ON ct.sender_user_id = u_sender.id
-        WHERE ct.receiver_user_id = $1
-        ORDER BY ct.transaction_date DESC`, userID)
+        INNER JOIN users u_receiver ON ct.receiver_user_id = u_receiver.id
+        WHERE (u_sender.username = $1 AND u_receiver.username = $2)
+           OR (u_sender.username = $2 AND u_receiver.username = $1)
+        ORDER BY ct.transaction_date, ct.id`, usernameA, usernameB)
 	if err != nil {
-		tdb.log.Error("Ошибка SQL запроса GetCoinHistory (received)", "userID", userID, "error", err)
-		return nil, fmt.Errorf("ошибка при получении полученных транзакций: %w", err)
+		tdb.log.Error("Ошибка SQL запроса GetTransactionsBetween", "usernameA", usernameA, "usernameB", usernameB, "error", err)
+		return nil, fmt.Errorf("ошибка при получении переводов между пользователями: %w", wrapError(err))
 	}
 	defer rows.Close()
 
+	transactions := []models.DBTransaction{}
 	for rows.Next() {
-		var transaction models.Transaction
-		var senderUsername string
-		if err := rows.Scan(&transaction.Amount, &senderUsername); err != nil {
-			tdb.log.Error("Ошибка сканирования строки GetCoinHistory (received)", "userID", userID, "error", err)
-			continue
+		var t models.DBTransaction
+		if err := rows.Scan(&t.ID, &t.SenderUserID, &t.SenderUsername, &t.ReceiverUserID, &t.ReceiverUsername, &t.Amount, &t.Memo, &t.Category, &t.TransactionDate); err != nil {
+			tdb.log.Error("Ошибка сканирования строки GetTransactionsBetween", "error", err)
+			return nil, fmt.Errorf("ошибка при чтении перевода: %w", wrapError(err))
 		}
-		transaction.FromUser = senderUsername
-		history.Received = append(history.Received, transaction)
+		transactions = append(transactions, t)
 	}
 	if err = rows.Err(); err != nil {
-		tdb.log.Error("Ошибка итерации строк GetCoinHistory (received)", "userID", userID, "error", err)
-		return nil, fmt.Errorf("ошибка при итерации строк полученных транзакций: %w", err)
+		tdb.log.Error("Ошибка итерации строк GetTransactionsBetween", "error", err)
+		return nil, fmt.Errorf("ошибка при итерации строк переводов: %w", wrapError(err))
 	}
 
-	// Отправленные транзакции
-	rows, err = tdb.Db.QueryContext(ctx, `
-        SELECT ct.amount, u_receiver.username
+	return transactions, nil
+}
+
+// GetUserTransactions получает все переводы, отправленные или полученные пользователем,
+// в хронологическом порядке.
+func (tdb *TransactionDB) GetUserTransactions(ctx context.Context, userID int) ([]models.DBTransaction, error) {
+	tdb.log.Debug("GetUserTransactions", "userID", userID)
+
+	rows, err := tdb.Db.QueryContext(ctx, `
+        SELECT ct.id, ct.sender_user_id, u_sender.username, ct.receiver_user_id, u_receiver.username,
+               ct.amount, ct.memo, ct.category, ct.transaction_date
         FROM coin_transactions ct
+        INNER JOIN users u_sender ON ct.sender_user_id = u_sender.id
         INNER JOIN users u_receiver ON ct.receiver_user_id = u_receiver.id
-        WHERE ct.sender_user_id = $1
-        ORDER BY ct.transaction_date DESC`, userID)
+        WHERE ct.sender_user_id = $1 OR ct.receiver_user_id = $1
+        ORDER BY ct.transaction_date, ct.id`, userID)
 	if err != nil {
-		tdb.log.Error("Ошибка SQL запроса GetCoinHistory (sent)", "userID", userID, "error", err)
-		return nil, fmt.Errorf("ошибка при получении отправленных транзакций: %w", err)
+		tdb.log.Error("Ошибка SQL запроса GetUserTransactions", "userID", userID, "error", err)
+		return nil, fmt.Errorf("ошибка при получении переводов пользователя: %w", wrapError(err))
 	}
 	defer rows.Close()
 
+	transactions := []models.DBTransaction{}
 	for rows.Next() {
-		var transaction models.Transaction
-		var receiverUsername string
-		if err := rows.Scan(&transaction.Amount, &receiverUsername); err != nil {
-			tdb.log.Error("Ошибка сканирования строки GetCoinHistory (sent)", "userID", userID, "error", err)
-			continue
+		var t models.DBTransaction
+		if err := rows.Scan(&t.ID, &t.SenderUserID, &t.SenderUsername, &t.ReceiverUserID, &t.ReceiverUsername, &t.Amount, &t.Memo, &t.Category, &t.TransactionDate); err != nil {
+			tdb.log.Error("Ошибка сканирования строки GetUserTransactions", "error", err)
+			return nil, fmt.Errorf("ошибка при чтении перевода: %w", wrapError(err))
 		}
-		transaction.ToUser = receiverUsername
-		history.Sent = append(history.Sent, transaction)
+		transactions = append(transactions, t)
 	}
 	if err = rows.Err(); err != nil {
-		tdb.log.Error("Ошибка итерации строк GetCoinHistory (sent)", "userID", userID, "error", err)
-		return nil, fmt.Errorf("ошибка при итерации строк отправленных транзакций: %w", err)
+		tdb.log.Error("Ошибка итерации строк GetUserTransactions", "error", err)
+		return nil, fmt.Errorf("ошибка при итерации строк переводов: %w", wrapError(err))
 	}
 
-	return history, nil
+	return transactions, nil
+}
+
+// RecordAdminAction записывает действие администратора в журнал в транзакции tx.
+func (tdb *TransactionDB) RecordAdminAction(ctx context.Context, entry models.AdminAuditEntry, tx *sql.Tx) error {
+	tdb.log.Debug("RecordAdminAction", "actor", entry.Actor, "action", entry.Action, "target", entry.Target)
+	var details any
+	if len(entry.Details) > 0 {
+		details = []byte(entry.Details)
+	}
+	_, err := tx.ExecContext(ctx, "INSERT INTO admin_audit (actor, action, target, details) VALUES ($1, $2, $3, $4)",
+		entry.Actor, entry.Action, entry.Target, details)
+	if err != nil {
+		tdb.log.Error("Ошибка SQL запроса RecordAdminAction", "actor", entry.Actor, "action", entry.Action, "error", err)
+		return fmt.Errorf("ошибка при записи действия администратора: %w", wrapError(err))
+	}
+	return nil
+}
+
+// GetAdminAudit получает страницу журнала действий администраторов, новые записи первыми.
+func (tdb *TransactionDB) GetAdminAudit(ctx context.Context, limit int, offset int) ([]models.AdminAuditEntry, error) {
+	tdb.log.Debug("GetAdminAudit", "limit", limit, "offset", offset)
+
+	rows, err := tdb.Db.QueryContext(ctx, `
+        SELECT id, actor, action, target, details, created_at
+        FROM admin_audit
+        ORDER BY created_at DESC, id DESC
+        LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		tdb.log.Error("Ошибка SQL запроса GetAdminAudit", "error", err)
+		return nil, fmt.Errorf("ошибка при получении журнала действий администраторов: %w", wrapError(err))
+	}
+	defer rows.Close()
+
+	entries := []models.AdminAuditEntry{}
+	for rows.Next() {
+		var entry models.AdminAuditEntry
+		var details []byte
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Target, &details, &entry.CreatedAt); err != nil {
+			tdb.log.Error("Ошибка сканирования строки GetAdminAudit", "error", err)
+			return nil, fmt.Errorf("ошибка при чтении записи журнала: %w", wrapError(err))
+		}
+		entry.Details = details
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		tdb.log.Error("Ошибка итерации строк GetAdminAudit", "error", err)
+		return nil, fmt.Errorf("ошибка при итерации строк журнала: %w", wrapError(err))
+	}
+	return entries, nil
+}
+
+// CountAdminAudit возвращает количество записей журнала действий администраторов.
+func (tdb *TransactionDB) CountAdminAudit(ctx context.Context) (int, error) {
+	tdb.log.Debug("CountAdminAudit")
+	var count int
+	if err := tdb.Db.QueryRowContext(ctx, "SELECT COUNT(*) FROM admin_audit").Scan(&count); err != nil {
+		tdb.log.Error("Ошибка SQL запроса CountAdminAudit", "error", err)
+		return 0, fmt.Errorf("ошибка при подсчете записей журнала действий администраторов: %w", wrapError(err))
+	}
+	return count, nil
+}
+
+// ServerVersion возвращает версию сервера PostgreSQL.
+func (tdb *TransactionDB) ServerVersion(ctx context.Context) (string, error) {
+	tdb.log.Debug("ServerVersion")
+	var version string
+	if err := tdb.Db.QueryRowContext(ctx, "SHOW server_version").Scan(&version); err != nil {
+		tdb.log.Error("Ошибка SQL запроса ServerVersion", "error", err)
+		return "", fmt.Errorf("ошибка при получении версии сервера базы данных: %w", wrapError(err))
+	}
+	return version, nil
 }
 
 // GetUserIDByUsername получает ID пользователя из базы данных по имени пользователя.
 func (udb *UserDB) GetUserIDByUsername(ctx context.Context, username string) (int, error) {
 	udb.log.Debug("GetUserIDByUsername", "username", username)
 	var userID int
-	err := udb.Db.QueryRowContext(ctx, "SELECT id FROM users WHERE username = $1", username).Scan(&userID)
+	err := udb.Db.QueryRowContext(ctx, "SELECT id FROM users WHERE username = $1 AND deleted_at IS NULL", username).Scan(&userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			udb.log.Warn("Пользователь не найден", "username", username)
-			return 0, fmt.Errorf("пользователь не найден")
+			return 0, fmt.Errorf("пользователь не найден: %w", err)
 		}
 		udb.log.Error("Ошибка SQL запроса GetUserIDByUsername", "username", username, "error", err)
-		return 0, fmt.Errorf("ошибка при получении ID пользователя по имени: %w", err)
+		return 0, fmt.Errorf("ошибка при получении ID пользователя по имени: %w", wrapError(err))
 	}
 	return userID, nil
 }
 
+// GetBalance получает баланс монет пользователя по его ID.
+func (udb *UserDB) GetBalance(ctx context.Context, userID int) (int64, error) {
+	udb.log.Debug("GetBalance", "userID", userID)
+	var coins int64
+	err := udb.Db.QueryRowContext(ctx, "SELECT coins FROM users WHERE id = $1", userID).Scan(&coins)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			udb.log.Warn("Пользователь не найден", "userID", userID)
+		} else {
+			udb.log.Error("Ошибка SQL запроса GetBalance", "userID", userID, "error", err)
+		}
+		return 0, fmt.Errorf("ошибка при получении баланса пользователя: %w", wrapError(err))
+	}
+	return coins, nil
+}
+
+// GetUserRank возвращает место пользователя в рейтинге активных пользователей по балансу.
+// Используется стандартное ранжирование: пользователи с равным балансом делят место,
+// а следующее место пропускается (1, 2, 2, 4).
+func (udb *UserDB) GetUserRank(ctx context.Context, userID int) (int, error) {
+	udb.log.Debug("GetUserRank", "userID", userID)
+	var rank int
+	err := udb.Db.QueryRowContext(ctx, `
+		SELECT 1 + (SELECT COUNT(*) FROM users o WHERE o.deleted_at IS NULL AND o.coins > u.coins)
+		FROM users u
+		WHERE u.id = $1 AND u.deleted_at IS NULL`, userID).Scan(&rank)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			udb.log.Warn("Пользователь не найден", "userID", userID)
+		} else {
+			udb.log.Error("Ошибка SQL запроса GetUserRank", "userID", userID, "error", err)
+		}
+		return 0, fmt.Errorf("ошибка при получении места пользователя в рейтинге: %w", wrapError(err))
+	}
+	return rank, nil
+}
+
+// GetLeaderboard возвращает limit активных пользователей с наибольшим балансом вместе
+// с суммарным количеством предметов в их инвентаре одним запросом. У пользователей
+// без инвентаря количество равно 0. При равном балансе пользователи упорядочены по имени.
+func (udb *UserDB) GetLeaderboard(ctx context.Context, limit int) ([]models.LeaderboardEntry, error) {
+	udb.log.Debug("GetLeaderboard", "limit", limit)
+	rows, err := udb.Db.QueryContext(ctx, `
+		SELECT u.username, u.coins, COALESCE(i.item_count, 0)
+		FROM users u
+		LEFT JOIN (
+			SELECT user_id, SUM(quantity) AS item_count FROM inventory GROUP BY user_id
+		) i ON i.user_id = u.id
+		WHERE u.deleted_at IS NULL
+		ORDER BY u.coins DESC, u.username
+		LIMIT $1`, limit)
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса GetLeaderboard", "limit", limit, "error", err)
+		return nil, fmt.Errorf("ошибка при получении рейтинга пользователей: %w", wrapError(err))
+	}
+	defer rows.Close()
+
+	entries := []models.LeaderboardEntry{}
+	for rows.Next() {
+		var entry models.LeaderboardEntry
+		if err := rows.Scan(&entry.Username, &entry.Coins, &entry.ItemCount); err != nil {
+			udb.log.Error("Ошибка сканирования строки GetLeaderboard", "error", err)
+			return nil, fmt.Errorf("ошибка при чтении рейтинга пользователей: %w", wrapError(err))
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		udb.log.Error("Ошибка итерации строк GetLeaderboard", "error", err)
+		return nil, fmt.Errorf("ошибка при итерации строк рейтинга пользователей: %w", wrapError(err))
+	}
+	return entries, nil
+}
+
 // SetInitialCoins устанавливает начальный баланс монет для пользователя.
-func (udb *UserDB) SetInitialCoins(ctx context.Context, userID int, initialCoins int) error {
+func (udb *UserDB) SetInitialCoins(ctx context.Context, userID int, initialCoins int64) error {
 	_, err := udb.Db.ExecContext(ctx, "UPDATE users SET coins = $1 WHERE id = $2", initialCoins, userID)
 	udb.log.Debug("SetInitialCoins", "userID", userID, "initialCoins", initialCoins)
 	if err != nil {
 		udb.log.Error("Ошибка SQL запроса SetInitialCoins", "userID", userID, "initialCoins", initialCoins, "error", err)
-		return fmt.Errorf("ошибка при установке начального количества монет для пользователя: %w", err)
+		return fmt.Errorf("ошибка при установке начального количества монет для пользователя: %w", wrapError(err))
 	}
 	return nil
 }
+
+// UpdateUserPassword обновляет хэш пароля пользователя.
+// Если передана транзакция tx, обновление выполняется в ней.
+func (udb *UserDB) UpdateUserPassword(ctx context.Context, userID int, passwordHash string, tx *sql.Tx) error {
+	_, err := udb.execer(tx).ExecContext(ctx, "UPDATE users SET password_hash = $1 WHERE id = $2", passwordHash, userID)
+	udb.log.Debug("UpdateUserPassword", "userID", userID)
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса UpdateUserPassword", "userID", userID, "error", err)
+		return fmt.Errorf("ошибка при обновлении пароля пользователя: %w", wrapError(err))
+	}
+	return nil
+}
+
+// GetUserStats получает количество активных пользователей и их суммарный баланс монет.
+func (udb *UserDB) GetUserStats(ctx context.Context) (*models.DBUserStats, error) {
+	udb.log.Debug("GetUserStats")
+	stats := &models.DBUserStats{}
+	err := udb.Db.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(SUM(coins), 0) FROM users WHERE deleted_at IS NULL").Scan(&stats.UserCount, &stats.TotalCoins)
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса GetUserStats", "error", err)
+		return nil, fmt.Errorf("ошибка при получении статистики пользователей: %w", wrapError(err))
+	}
+	return stats, nil
+}
+
+// DeactivateUser деактивирует пользователя, сохраняя его историю транзакций.
+// Если передана транзакция tx, обновление выполняется в ней.
+func (udb *UserDB) DeactivateUser(ctx context.Context, userID int, tx *sql.Tx) error {
+	_, err := udb.execer(tx).ExecContext(ctx, "UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", userID)
+	udb.log.Debug("DeactivateUser", "userID", userID)
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса DeactivateUser", "userID", userID, "error", err)
+		return fmt.Errorf("ошибка при деактивации пользователя: %w", wrapError(err))
+	}
+	return nil
+}
+
+// IsUserDeactivated проверяет, деактивирован ли пользователь с указанным именем.
+func (udb *UserDB) IsUserDeactivated(ctx context.Context, username string) (bool, error) {
+	udb.log.Debug("IsUserDeactivated", "username", username)
+	var deactivated bool
+	err := udb.Db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 AND deleted_at IS NOT NULL)", username).Scan(&deactivated)
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса IsUserDeactivated", "username", username, "error", err)
+		return false, fmt.Errorf("ошибка при проверке деактивации пользователя: %w", wrapError(err))
+	}
+	return deactivated, nil
+}
+
+// ClaimDailyBonus начисляет пользователю ежедневный бонус, если он еще не получен сегодня.
+// Возвращает новый баланс и признак того, что бонус был начислен.
+func (udb *UserDB) ClaimDailyBonus(ctx context.Context, userID int, amount int64) (int64, bool, error) {
+	udb.log.Debug("ClaimDailyBonus", "userID", userID, "amount", amount)
+	var coins int64
+	err := udb.Db.QueryRowContext(ctx, `
+		UPDATE users SET coins = coins + $2, last_bonus_date = CURRENT_DATE
+		WHERE id = $1 AND (last_bonus_date IS NULL OR last_bonus_date < CURRENT_DATE)
+		RETURNING coins`, userID, amount).Scan(&coins)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		udb.log.Error("Ошибка SQL запроса ClaimDailyBonus", "userID", userID, "error", err)
+		return 0, false, fmt.Errorf("ошибка при начислении ежедневного бонуса: %w", wrapError(err))
+	}
+	return coins, true, nil
+}
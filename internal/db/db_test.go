@@ -0,0 +1,726 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"shop/internal/models"
+	"shop/pkg/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionDB_GetCoinHistory(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	now := time.Now()
+	// Один запрос возвращает обе стороны истории, отсортированные по дате.
+	rows := sqlmock.NewRows([]string{"dir", "amount", "memo", "category", "username", "transaction_date"}).
+		AddRow("received", 30, "обед", "payment", "bob", now).
+		AddRow("sent", 50, "", "", "charlie", now.Add(-time.Minute)).
+		AddRow("received", 10, "", "", "charlie", now.Add(-2*time.Minute)).
+		AddRow("sent", 5, "кофе", "gift", "bob", now.Add(-3*time.Minute))
+	sqlMock.ExpectQuery("UNION ALL").WithArgs(1, "").WillReturnRows(rows)
+
+	history, err := tdb.GetCoinHistory(context.Background(), 1, "")
+	require.NoError(t, err)
+
+	// Результат совпадает с прежним форматом: отдельные списки полученных и отправленных.
+	expected := &models.CoinHistory{
+		Received: []models.Transaction{
+			{FromUser: "bob", Amount: 30, Memo: "обед", Category: "payment"},
+			{FromUser: "charlie", Amount: 10},
+		},
+		Sent: []models.Transaction{
+			{ToUser: "charlie", Amount: 50},
+			{ToUser: "bob", Amount: 5, Memo: "кофе", Category: "gift"},
+		},
+	}
+	assert.Equal(t, expected, history)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestTransactionDB_GetCoinHistory_Category(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	// Категория передается в запрос и фильтрует обе стороны истории.
+	rows := sqlmock.NewRows([]string{"dir", "amount", "memo", "category", "username", "transaction_date"}).
+		AddRow("sent", 5, "", "gift", "bob", time.Now())
+	sqlMock.ExpectQuery(`ct.receiver_user_id = \$1 AND \(\$2 = '' OR ct.category = \$2\)`).WithArgs(1, "gift").WillReturnRows(rows)
+
+	history, err := tdb.GetCoinHistory(context.Background(), 1, "gift")
+	require.NoError(t, err)
+	assert.Equal(t, []models.Transaction{{ToUser: "bob", Amount: 5, Category: "gift"}}, history.Sent)
+	assert.Empty(t, history.Received)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestTransactionDB_GetCoinHistory_Empty(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	sqlMock.ExpectQuery("UNION ALL").WithArgs(1, "").
+		WillReturnRows(sqlmock.NewRows([]string{"dir", "amount", "memo", "category", "username", "transaction_date"}))
+
+	history, err := tdb.GetCoinHistory(context.Background(), 1, "")
+	require.NoError(t, err)
+
+	// Пустые списки, а не nil: в JSON ответе должны быть [].
+	assert.Equal(t, &models.CoinHistory{Received: []models.Transaction{}, Sent: []models.Transaction{}}, history)
+}
+
+func TestTransactionDB_StreamCoinHistory(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"dir", "amount", "memo", "category", "username", "transaction_date"}).
+		AddRow("received", 30, "обед", "payment", "bob", now).
+		AddRow("sent", 50, "", "", "charlie", now.Add(-time.Minute))
+	sqlMock.ExpectQuery("UNION ALL").WithArgs(1, "").WillReturnRows(rows)
+
+	var streamed []models.Transaction
+	err = tdb.StreamCoinHistory(context.Background(), 1, "", func(transaction models.Transaction) error {
+		streamed = append(streamed, transaction)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []models.Transaction{
+		{FromUser: "bob", Amount: 30, Memo: "обед", Category: "payment"},
+		{ToUser: "charlie", Amount: 50},
+	}, streamed)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestTransactionDB_StreamCoinHistory_CallbackError(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	rows := sqlmock.NewRows([]string{"dir", "amount", "memo", "category", "username", "transaction_date"}).
+		AddRow("received", 30, "", "", "bob", time.Now()).
+		AddRow("sent", 50, "", "", "charlie", time.Now())
+	sqlMock.ExpectQuery("UNION ALL").WithArgs(1, "").WillReturnRows(rows).RowsWillBeClosed()
+
+	// Ошибка записи ответа прерывает чтение после первой строки.
+	errClientGone := errors.New("клиент отключился")
+	calls := 0
+	err = tdb.StreamCoinHistory(context.Background(), 1, "", func(models.Transaction) error {
+		calls++
+		return errClientGone
+	})
+	assert.ErrorIs(t, err, errClientGone)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_GetUserInventoryByPrefix(t *testing.T) {
+	tests := []struct {
+		name            string
+		prefix          string
+		expectedPattern string
+		rows            [][]driver.Value
+	}{
+		{"совпадающий префикс", "sw", "sw%", [][]driver.Value{{1, 1, "sweater", 2}, {2, 1, "sword", 1}}},
+		{"спецсимволы экранируются", `50%_a\`, `50\%\_a\\%`, nil},
+		{"нет совпадений", "zzz", "zzz%", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer database.Close()
+
+			udb := NewUserDB(database, logger.NewTestLogger())
+
+			rows := sqlmock.NewRows([]string{"id", "user_id", "item_type", "quantity"})
+			expected := []models.DBInventoryItem{}
+			for _, row := range tt.rows {
+				rows.AddRow(row...)
+				expected = append(expected, models.DBInventoryItem{
+					ID: row[0].(int), UserID: row[1].(int), ItemType: row[2].(string), Quantity: row[3].(int),
+				})
+			}
+			sqlMock.ExpectQuery("LIKE").WithArgs(1, tt.expectedPattern).WillReturnRows(rows)
+
+			inventory, err := udb.GetUserInventoryByPrefix(context.Background(), 1, tt.prefix)
+			require.NoError(t, err)
+			assert.Equal(t, expected, inventory)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserDB_GetUserInventory_OrderAndLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+	}{
+		{"без ограничения", 0},
+		{"с ограничением", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer database.Close()
+
+			udb := NewUserDB(database, logger.NewTestLogger())
+
+			rows := sqlmock.NewRows([]string{"id", "user_id", "item_type", "quantity"}).
+				AddRow(3, 1, "book", 1).
+				AddRow(1, 1, "cup", 2)
+			sqlMock.ExpectQuery(`ORDER BY item_type LIMIT NULLIF\(\$2, 0\)`).WithArgs(1, tt.limit).WillReturnRows(rows)
+
+			inventory, err := udb.GetUserInventory(context.Background(), 1, tt.limit)
+			require.NoError(t, err)
+			assert.Equal(t, []models.DBInventoryItem{
+				{ID: 3, UserID: 1, ItemType: "book", Quantity: 1},
+				{ID: 1, UserID: 1, ItemType: "cup", Quantity: 2},
+			}, inventory)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserDB_GetUserInventoryWithPrices(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+
+	// Предмет "relic" удален из каталога, LEFT JOIN возвращает для него NULL.
+	rows := sqlmock.NewRows([]string{"id", "user_id", "item_type", "quantity", "price"}).
+		AddRow(1, 1, "cup", 2, 20).
+		AddRow(2, 1, "relic", 1, nil)
+	sqlMock.ExpectQuery("LEFT JOIN items").WithArgs(1).WillReturnRows(rows)
+
+	inventory, err := udb.GetUserInventoryWithPrices(context.Background(), 1)
+	require.NoError(t, err)
+	price := int64(20)
+	assert.Equal(t, []models.DBInventoryItem{
+		{ID: 1, UserID: 1, ItemType: "cup", Quantity: 2, Price: &price},
+		{ID: 2, UserID: 1, ItemType: "relic", Quantity: 1},
+	}, inventory)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_DeductUserCoins_NotEnoughCoins(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+
+	// Условие coins >= amount не выполнено, UPDATE не возвращает строк.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("UPDATE users SET coins = coins -").WithArgs(1, 500).
+		WillReturnRows(sqlmock.NewRows([]string{"coins"}))
+
+	tx, err := database.Begin()
+	require.NoError(t, err)
+
+	_, err = udb.DeductUserCoins(context.Background(), 1, 500, tx)
+	assert.ErrorIs(t, err, ErrNotEnoughCoins)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_DeductUserCoins_LargeBalance(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+
+	// Балансы больше 2^31 не переполняются.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("UPDATE users SET coins = coins -").WithArgs(1, int64(3_000_000_000)).
+		WillReturnRows(sqlmock.NewRows([]string{"coins"}).AddRow(int64(5_000_000_000)))
+
+	tx, err := database.Begin()
+	require.NoError(t, err)
+
+	coins, err := udb.DeductUserCoins(context.Background(), 1, 3_000_000_000, tx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5_000_000_000), coins)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_LockUserBalances_AscendingOrder(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+
+	// Строки блокируются по возрастанию ID, даже если отправитель имеет больший ID.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("SELECT coins FROM users WHERE id = \\$1 FOR UPDATE").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"coins"}).AddRow(50))
+	sqlMock.ExpectQuery("SELECT coins FROM users WHERE id = \\$1 FOR UPDATE").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"coins"}).AddRow(100))
+
+	tx, err := database.Begin()
+	require.NoError(t, err)
+
+	balances, err := udb.LockUserBalances(context.Background(), tx, 5, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int64{2: 50, 5: 100}, balances)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_UpdateUserInventory_NonCatalogItem(t *testing.T) {
+	tests := []struct {
+		name        string
+		insertErr   error
+		expectedErr error
+	}{
+		{"товар не из каталога", &pq.Error{Code: "23503", Constraint: "inventory_item_type_fkey"}, ErrItemNotFound},
+		{"другой внешний ключ", &pq.Error{Code: "23503", Constraint: "inventory_user_id_fkey"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer database.Close()
+
+			udb := NewUserDB(database, logger.NewTestLogger())
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectQuery("INSERT INTO inventory").WithArgs(1, "unicorn", 1, 0).WillReturnError(tt.insertErr)
+
+			tx, err := database.Begin()
+			require.NoError(t, err)
+
+			_, err = udb.UpdateUserInventory(context.Background(), 1, "unicorn", 1, tx)
+			require.Error(t, err)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NotErrorIs(t, err, ErrItemNotFound)
+				assert.True(t, IsForeignKeyViolation(err))
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTransactionDB_AdminAudit(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	// Запись журнала выполняется в транзакции действия.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("INSERT INTO admin_audit").
+		WithArgs("admin", "deactivate_user", "bob", nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, err := database.Begin()
+	require.NoError(t, err)
+	err = tdb.RecordAdminAction(context.Background(), models.AdminAuditEntry{Actor: "admin", Action: "deactivate_user", Target: "bob"}, tx)
+	require.NoError(t, err)
+
+	createdAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	sqlMock.ExpectQuery("FROM admin_audit").WithArgs(10, 0).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "actor", "action", "target", "details", "created_at"}).
+			AddRow(1, "admin", "deactivate_user", "bob", nil, createdAt))
+
+	entries, err := tdb.GetAdminAudit(context.Background(), 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []models.AdminAuditEntry{
+		{ID: 1, Actor: "admin", Action: "deactivate_user", Target: "bob", CreatedAt: createdAt},
+	}, entries)
+
+	sqlMock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM admin_audit").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	count, err := tdb.CountAdminAudit(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestTransactionDB_RecordTransaction_Memo(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("INSERT INTO coin_transactions").
+		WithArgs(1, 2, 50, "обед", "payment", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, err := database.Begin()
+	require.NoError(t, err)
+
+	err = tdb.RecordTransaction(context.Background(), 1, 2, 50, "обед", "payment", tx)
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestTransactionDB_RecordTransaction_ForeignKeyViolation(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	// Получатель удален между проверкой и записью транзакции.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec("INSERT INTO coin_transactions").
+		WillReturnError(&pq.Error{Code: "23503", Message: "violates foreign key constraint"})
+
+	tx, err := database.Begin()
+	require.NoError(t, err)
+
+	err = tdb.RecordTransaction(context.Background(), 1, 2, 50, "", "", tx)
+	assert.True(t, IsForeignKeyViolation(err))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestTransactionDB_GetTransactionsBetween(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	now := time.Now()
+	// Переводы в обе стороны, отсортированные по дате.
+	rows := sqlmock.NewRows([]string{"id", "sender_user_id", "sender_username", "receiver_user_id", "receiver_username", "amount", "memo", "category", "transaction_date"}).
+		AddRow(1, 1, "alice", 2, "bob", 10, "долг", "refund", now.Add(-time.Minute)).
+		AddRow(2, 2, "bob", 1, "alice", 5, "", "", now)
+	sqlMock.ExpectQuery("FROM coin_transactions").WithArgs("alice", "bob").WillReturnRows(rows)
+
+	transactions, err := tdb.GetTransactionsBetween(context.Background(), "alice", "bob")
+	require.NoError(t, err)
+
+	expected := []models.DBTransaction{
+		{ID: 1, SenderUserID: 1, SenderUsername: "alice", ReceiverUserID: 2, ReceiverUsername: "bob", Amount: 10, Memo: "долг", Category: "refund", TransactionDate: now.Add(-time.Minute)},
+		{ID: 2, SenderUserID: 2, SenderUsername: "bob", ReceiverUserID: 1, ReceiverUsername: "alice", Amount: 5, TransactionDate: now},
+	}
+	assert.Equal(t, expected, transactions)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestTransactionDB_GetUserTransactions(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	tdb := NewTransactionDB(database, logger.NewTestLogger())
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "sender_user_id", "sender_username", "receiver_user_id", "receiver_username", "amount", "memo", "category", "transaction_date"}).
+		AddRow(1, 1, "alice", 2, "bob", 10, "", "", now.Add(-time.Minute)).
+		AddRow(2, 3, "carol", 1, "alice", 5, "", "", now)
+	sqlMock.ExpectQuery("WHERE ct.sender_user_id = \\$1 OR ct.receiver_user_id = \\$1\\s+ORDER BY ct.transaction_date, ct.id").WithArgs(1).WillReturnRows(rows)
+
+	transactions, err := tdb.GetUserTransactions(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "bob", transactions[0].ReceiverUsername)
+	assert.Equal(t, "carol", transactions[1].SenderUsername)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_RemoveFromInventory(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+
+	sqlMock.ExpectBegin()
+	// Списание не затрагивает строки, если предметов меньше, чем требуется.
+	sqlMock.ExpectExec("UPDATE inventory SET quantity = quantity - \\$3").
+		WithArgs(1, "pen", 5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectExec("UPDATE inventory SET quantity = quantity - \\$3").
+		WithArgs(1, "pen", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec("DELETE FROM inventory").
+		WithArgs(1, "pen").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := database.Begin()
+	require.NoError(t, err)
+
+	err = udb.RemoveFromInventory(context.Background(), 1, "pen", 5, tx)
+	assert.ErrorIs(t, err, ErrNotEnoughItems)
+
+	err = udb.RemoveFromInventory(context.Background(), 1, "pen", 2, tx)
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_UpdateUserInventory_QuantityCap(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+	udb.MaxItemQuantity = 10
+
+	sqlMock.ExpectBegin()
+	// Добавление до лимита разрешено.
+	sqlMock.ExpectQuery("ON CONFLICT \\(user_id, item_type\\) DO UPDATE").WithArgs(1, "pen", 3, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(10))
+	// Добавление сверх лимита не обновляет строку, и запрос не возвращает строк.
+	sqlMock.ExpectQuery("ON CONFLICT \\(user_id, item_type\\) DO UPDATE").WithArgs(1, "pen", 1, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity"}))
+	// Новый предмет в количестве больше лимита отклоняется без запроса.
+
+	tx, err := database.Begin()
+	require.NoError(t, err)
+
+	quantity, err := udb.UpdateUserInventory(context.Background(), 1, "pen", 3, tx)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, quantity)
+	_, err = udb.UpdateUserInventory(context.Background(), 1, "pen", 1, tx)
+	assert.ErrorIs(t, err, ErrItemQuantityCapped)
+	_, err = udb.UpdateUserInventory(context.Background(), 1, "cup", 11, tx)
+	assert.ErrorIs(t, err, ErrItemQuantityCapped)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_GetInventoryItemCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		sum      int
+		expected int
+	}{
+		// SUM по пустому инвентарю дает NULL, который COALESCE заменяет на 0.
+		{"пустой инвентарь", 0, 0},
+		{"несколько предметов", 6, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			require.NoError(t, err)
+			defer database.Close()
+
+			udb := NewUserDB(database, logger.NewTestLogger())
+
+			sqlMock.ExpectQuery("SELECT COALESCE(SUM(quantity), 0) FROM inventory WHERE user_id = $1").WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(tt.sum))
+
+			count, err := udb.GetInventoryItemCount(context.Background(), 1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, count)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserDB_GetInventoryItemCount_Error(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+
+	sqlMock.ExpectQuery("SELECT COALESCE").WithArgs(1).WillReturnError(sql.ErrConnDone)
+
+	_, err = udb.GetInventoryItemCount(context.Background(), 1)
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_GetUserRank(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+
+	sqlMock.ExpectQuery("SELECT 1 \\+ \\(SELECT COUNT\\(\\*\\) FROM users o").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"rank"}).AddRow(2))
+
+	rank, err := udb.GetUserRank(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rank)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_GetLeaderboard(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+
+	// Пользователи без инвентаря остаются в рейтинге с нулевым количеством предметов.
+	sqlMock.ExpectQuery(`SELECT u.username, u.coins, COALESCE\(i.item_count, 0\)\s+FROM users u\s+LEFT JOIN`).WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "coins", "item_count"}).
+			AddRow("bob", 1000, 0).
+			AddRow("alice", 970, 3))
+
+	entries, err := udb.GetLeaderboard(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.LeaderboardEntry{
+		{Username: "bob", Coins: 1000, ItemCount: 0},
+		{Username: "alice", Coins: 970, ItemCount: 3},
+	}, entries)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserDB_GetUserRank_NotFound(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	udb := NewUserDB(database, logger.NewTestLogger())
+
+	// Деактивированный или несуществующий пользователь не имеет места в рейтинге.
+	sqlMock.ExpectQuery("FROM users u").WithArgs(42).WillReturnRows(sqlmock.NewRows([]string{"rank"}))
+
+	_, err = udb.GetUserRank(context.Background(), 42)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestItemDB_UpsertItemPrice(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	idb := NewItemDB(database, logger.NewTestLogger())
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("INSERT INTO items .* ON CONFLICT \\(item_name\\) DO UPDATE").WithArgs("pen", 15).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(false))
+	sqlMock.ExpectQuery("INSERT INTO items").WithArgs("scarf", 120).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(true))
+	sqlMock.ExpectCommit()
+
+	tx, err := database.Begin()
+	require.NoError(t, err)
+	created, err := idb.UpsertItemPrice(context.Background(), "pen", 15, tx)
+	require.NoError(t, err)
+	assert.False(t, created, "Существующий товар должен обновиться")
+	created, err = idb.UpsertItemPrice(context.Background(), "scarf", 120, tx)
+	require.NoError(t, err)
+	assert.True(t, created, "Новый товар должен добавиться")
+	require.NoError(t, tx.Commit())
+
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestItemDB_GetItems(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	idb := NewItemDB(database, logger.NewTestLogger())
+	updatedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	sqlMock.ExpectQuery("SELECT id, item_name, price, updated_at FROM items ORDER BY item_name").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_name", "price", "updated_at"}).
+			AddRow(2, "cup", 20, updatedAt).
+			AddRow(4, "pen", 10, updatedAt))
+
+	items, err := idb.GetItems(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []models.DBItem{
+		{ID: 2, ItemName: "cup", Price: 20, UpdatedAt: updatedAt},
+		{ID: 4, ItemName: "pen", Price: 10, UpdatedAt: updatedAt},
+	}, items)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestItemDB_SeedItems(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	idb := NewItemDB(database, logger.NewTestLogger())
+	items := []models.ItemPriceUpdate{{ItemName: "pen", Price: 10}, {ItemName: "hoody", Price: 300}}
+
+	// Пустой каталог заполняется.
+	sqlMock.ExpectExec("INSERT INTO items .* WHERE NOT EXISTS \\(SELECT 1 FROM items\\)").
+		WithArgs(pq.Array([]string{"pen", "hoody"}), pq.Array([]int64{10, 300})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// В непустой каталог ничего не добавляется.
+	sqlMock.ExpectExec("INSERT INTO items .* WHERE NOT EXISTS \\(SELECT 1 FROM items\\)").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	seeded, err := idb.SeedItems(context.Background(), items)
+	require.NoError(t, err)
+	assert.Equal(t, 2, seeded)
+	seeded, err = idb.SeedItems(context.Background(), items)
+	require.NoError(t, err)
+	assert.Equal(t, 0, seeded)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestConstructors_NilDB(t *testing.T) {
+	log := logger.NewTestLogger()
+
+	// Ошибка конфигурации проявляется сразу при создании хранилища.
+	assert.PanicsWithValue(t, "NewUserDB: соединение с базой данных (*sql.DB) не должно быть nil", func() { NewUserDB(nil, log) })
+	assert.PanicsWithValue(t, "NewItemDB: соединение с базой данных (*sql.DB) не должно быть nil", func() { NewItemDB(nil, log) })
+	assert.PanicsWithValue(t, "NewTransactionDB: соединение с базой данных (*sql.DB) не должно быть nil", func() { NewTransactionDB(nil, log) })
+}
+
+func TestCheckSchema(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	// Миграции применены: отсутствующих таблиц нет.
+	sqlMock.ExpectQuery("to_regclass").WillReturnRows(sqlmock.NewRows([]string{"t"}))
+	assert.NoError(t, CheckSchema(context.Background(), database))
+
+	// Миграции не применены.
+	sqlMock.ExpectQuery("to_regclass").WillReturnRows(sqlmock.NewRows([]string{"t"}).AddRow("users").AddRow("items"))
+	err = CheckSchema(context.Background(), database)
+	assert.ErrorIs(t, err, ErrSchemaNotInitialized)
+	assert.Contains(t, err.Error(), "users, items")
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestItemDB_GetItemPrice_SchemaNotInitialized(t *testing.T) {
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+
+	idb := NewItemDB(database, logger.NewTestLogger())
+
+	sqlMock.ExpectPrepare("SELECT price FROM items").
+		WillReturnError(&pq.Error{Code: "42P01", Message: `relation "items" does not exist`})
+
+	_, err = idb.GetItemPrice(context.Background(), "pen")
+	assert.ErrorIs(t, err, ErrSchemaNotInitialized)
+	assert.Contains(t, err.Error(), "примените миграции")
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+// ErrSchemaNotInitialized таблицы приложения отсутствуют: миграции не применены.
+var ErrSchemaNotInitialized = errors.New("схема базы данных не создана, примените миграции из каталога migrations")
+
+// ErrorKind категория ошибки базы данных.
+type ErrorKind int
+
+const (
+	// KindUnknown ошибка, не относящаяся к известным категориям.
+	KindUnknown ErrorKind = iota
+	// KindUniqueViolation нарушение ограничения уникальности.
+	KindUniqueViolation
+	// KindForeignKeyViolation нарушение внешнего ключа.
+	KindForeignKeyViolation
+	// KindCheckViolation нарушение ограничения CHECK.
+	KindCheckViolation
+	// KindSerializationFailure конфликт сериализации или взаимоблокировка; запрос можно повторить.
+	KindSerializationFailure
+	// KindConnection ошибка соединения с базой данных.
+	KindConnection
+	// KindUndefinedTable обращение к несуществующей таблице, обычно из-за непримененных миграций.
+	KindUndefinedTable
+)
+
+// String возвращает название категории.
+func (k ErrorKind) String() string {
+	switch k {
+	case KindUniqueViolation:
+		return "unique_violation"
+	case KindForeignKeyViolation:
+		return "foreign_key_violation"
+	case KindCheckViolation:
+		return "check_violation"
+	case KindSerializationFailure:
+		return "serialization_failure"
+	case KindConnection:
+		return "connection"
+	case KindUndefinedTable:
+		return "undefined_table"
+	default:
+		return "unknown"
+	}
+}
+
+// DBError ошибка базы данных с категорией. Исходная ошибка драйвера доступна через Unwrap.
+type DBError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// Error возвращает текст ошибки с категорией.
+func (e *DBError) Error() string {
+	if e.Kind == KindUndefinedTable {
+		return fmt.Sprintf("ошибка базы данных (%s): %v: %v", e.Kind, ErrSchemaNotInitialized, e.Err)
+	}
+	return fmt.Sprintf("ошибка базы данных (%s): %v", e.Kind, e.Err)
+}
+
+// Is сопоставляет ошибку отсутствующей таблицы с ErrSchemaNotInitialized.
+func (e *DBError) Is(target error) bool {
+	return target == ErrSchemaNotInitialized && e.Kind == KindUndefinedTable
+}
+
+// Unwrap возвращает исходную ошибку драйвера.
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// Коды ошибок PostgreSQL.
+const (
+	uniqueViolation      pq.ErrorCode = "23505"
+	foreignKeyViolation  pq.ErrorCode = "23503"
+	checkViolation       pq.ErrorCode = "23514"
+	serializationFailure pq.ErrorCode = "40001"
+	deadlockDetected     pq.ErrorCode = "40P01"
+	undefinedTable       pq.ErrorCode = "42P01"
+	// connectionExceptionClass класс кодов ошибок соединения.
+	connectionExceptionClass pq.ErrorClass = "08"
+)
+
+// inventoryItemTypeFK внешний ключ inventory.item_type -> items.item_name.
+const inventoryItemTypeFK = "inventory_item_type_fkey"
+
+// isConstraintViolation проверяет, что err нарушает ограничение constraint с кодом code.
+func isConstraintViolation(err error, code pq.ErrorCode, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code && pqErr.Constraint == constraint
+}
+
+// wrapError оборачивает ошибку драйвера в DBError с определенной категорией.
+// nil, sql.ErrNoRows и уже обернутые ошибки возвращаются без изменений.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+	kind := classify(err)
+	if kind == KindUnknown {
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) {
+			// Не ошибка драйвера, например sql.ErrNoRows.
+			return err
+		}
+	}
+	return &DBError{Kind: kind, Err: err}
+}
+
+// classify определяет категорию ошибки драйвера.
+func classify(err error) ErrorKind {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == uniqueViolation:
+			return KindUniqueViolation
+		case pqErr.Code == foreignKeyViolation:
+			return KindForeignKeyViolation
+		case pqErr.Code == checkViolation:
+			return KindCheckViolation
+		case pqErr.Code == serializationFailure, pqErr.Code == deadlockDetected:
+			return KindSerializationFailure
+		case pqErr.Code.Class() == connectionExceptionClass:
+			return KindConnection
+		case pqErr.Code == undefinedTable:
+			return KindUndefinedTable
+		}
+		return KindUnknown
+	}
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return KindConnection
+	}
+	return KindUnknown
+}
+
+// kindOf возвращает категорию ошибки базы данных в цепочке err.
+// Необернутые ошибки драйвера, например ошибка tx.Commit, классифицируются напрямую.
+func kindOf(err error) ErrorKind {
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return dbErr.Kind
+	}
+	return classify(err)
+}
+
+// IsUniqueViolation сообщает, вызвана ли ошибка нарушением ограничения уникальности.
+func IsUniqueViolation(err error) bool {
+	return kindOf(err) == KindUniqueViolation
+}
+
+// IsForeignKeyViolation сообщает, вызвана ли ошибка нарушением внешнего ключа,
+// например ссылкой на несуществующего пользователя.
+func IsForeignKeyViolation(err error) bool {
+	return kindOf(err) == KindForeignKeyViolation
+}
+
+// IsCheckViolation сообщает, вызвана ли ошибка нарушением ограничения CHECK.
+func IsCheckViolation(err error) bool {
+	return kindOf(err) == KindCheckViolation
+}
+
+// IsSerializationFailure сообщает, можно ли повторить транзакцию, завершившуюся ошибкой.
+func IsSerializationFailure(err error) bool {
+	return kindOf(err) == KindSerializationFailure
+}
+
+// IsSchemaNotInitialized сообщает, вызвана ли ошибка отсутствием таблицы, то есть непримененными миграциями.
+func IsSchemaNotInitialized(err error) bool {
+	return kindOf(err) == KindUndefinedTable
+}
+
+// IsConnectionError сообщает, вызвана ли ошибка проблемой соединения с базой данных.
+func IsConnectionError(err error) bool {
+	return kindOf(err) == KindConnection
+}
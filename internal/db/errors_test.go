@@ -0,0 +1,79 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapError_Classification(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorKind
+	}{
+		{"уникальность", &pq.Error{Code: "23505"}, KindUniqueViolation},
+		{"внешний ключ", &pq.Error{Code: "23503"}, KindForeignKeyViolation},
+		{"ограничение CHECK", &pq.Error{Code: "23514"}, KindCheckViolation},
+		{"конфликт сериализации", &pq.Error{Code: "40001"}, KindSerializationFailure},
+		{"взаимоблокировка", &pq.Error{Code: "40P01"}, KindSerializationFailure},
+		{"обрыв соединения", &pq.Error{Code: "08006"}, KindConnection},
+		{"недоступное соединение", driver.ErrBadConn, KindConnection},
+		{"нет таблицы", &pq.Error{Code: "42P01"}, KindUndefinedTable},
+		{"прочая ошибка драйвера", &pq.Error{Code: "42601"}, KindUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("ошибка запроса: %w", wrapError(tt.err))
+
+			var dbErr *DBError
+			assert.True(t, errors.As(err, &dbErr))
+			assert.Equal(t, tt.expected, dbErr.Kind)
+			// Исходная ошибка драйвера остается в цепочке.
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestWrapError_Predicates(t *testing.T) {
+	err := wrapError(&pq.Error{Code: "23505"})
+	assert.True(t, IsUniqueViolation(err))
+	assert.False(t, IsForeignKeyViolation(err))
+	assert.False(t, IsSerializationFailure(err))
+
+	assert.True(t, IsForeignKeyViolation(wrapError(&pq.Error{Code: "23503"})))
+	assert.True(t, IsCheckViolation(wrapError(&pq.Error{Code: "23514"})))
+	assert.True(t, IsSerializationFailure(wrapError(&pq.Error{Code: "40001"})))
+	assert.True(t, IsConnectionError(wrapError(driver.ErrBadConn)))
+	// Ошибка коммита приходит от драйвера без обертки.
+	assert.True(t, IsSerializationFailure(fmt.Errorf("ошибка коммита транзакции: %w", &pq.Error{Code: "40001"})))
+}
+
+func TestWrapError_Passthrough(t *testing.T) {
+	assert.NoError(t, wrapError(nil))
+	// sql.ErrNoRows и прочие ошибки, не относящиеся к драйверу, не оборачиваются.
+	assert.Same(t, sql.ErrNoRows, wrapError(sql.ErrNoRows))
+
+	// Повторное оборачивание не меняет категорию.
+	wrapped := wrapError(&pq.Error{Code: "23505"})
+	assert.Same(t, wrapped, wrapError(wrapped))
+}
+
+func TestWrapError_SchemaNotInitialized(t *testing.T) {
+	// Так драйвер сообщает о запросе к таблице, которую не создали миграции.
+	err := fmt.Errorf("ошибка при получении цены товара: %w",
+		wrapError(&pq.Error{Code: "42P01", Message: `relation "items" does not exist`}))
+
+	assert.True(t, IsSchemaNotInitialized(err))
+	assert.ErrorIs(t, err, ErrSchemaNotInitialized)
+	assert.Contains(t, err.Error(), ErrSchemaNotInitialized.Error())
+	assert.Contains(t, err.Error(), `relation "items" does not exist`)
+
+	assert.NotErrorIs(t, wrapError(&pq.Error{Code: "23505"}), ErrSchemaNotInitialized)
+}
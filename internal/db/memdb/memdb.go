@@ -0,0 +1,709 @@
+// Package memdb содержит хранилище в памяти, реализующее интерфейсы пакета db.
+// Предназначено для тестов, которым не нужен PostgreSQL: откат транзакций поддерживается,
+// но изоляции между транзакциями нет, а SELECT ... FOR UPDATE ничего не блокирует.
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"shop/internal/db"
+	"shop/internal/models"
+)
+
+var (
+	_ db.UserDBInterface        = (*Store)(nil)
+	_ db.ItemDBInterface        = (*Store)(nil)
+	_ db.TransactionDBInterface = (*Store)(nil)
+)
+
+// user пользователь в хранилище.
+type user struct {
+	models.DBUser
+	deactivated bool
+	// lastBonusDate дата последнего ежедневного бонуса в формате 2006-01-02.
+	lastBonusDate string
+}
+
+// inventoryRow элемент инвентаря пользователя.
+type inventoryRow struct {
+	id       int
+	quantity int
+}
+
+// gift запись о передаче предметов.
+type gift struct {
+	senderUserID   int
+	receiverUserID int
+	itemType       string
+	quantity       int
+}
+
+// Store хранилище пользователей, товаров и транзакций в памяти.
+// Реализует UserDBInterface, ItemDBInterface и TransactionDBInterface.
+type Store struct {
+	// MaxItemQuantity максимальное количество одного предмета в инвентаре пользователя.
+	// 0 снимает ограничение.
+	MaxItemQuantity int
+
+	mu           sync.Mutex
+	db           *sql.DB
+	nextID       int
+	users        map[int]*user
+	items        map[string]models.DBItem
+	inventory    map[int]map[string]*inventoryRow
+	transactions []models.DBTransaction
+	gifts        []gift
+	audit        []models.AdminAuditEntry
+
+	// txs связывает открытые транзакции с их журналами отката undo.
+	nextTxID int64
+	txs      map[*sql.Tx]int64
+	undo     map[int64][]func()
+}
+
+// New создает пустое хранилище.
+func New() *Store {
+	s := &Store{
+		users:     make(map[int]*user),
+		items:     make(map[string]models.DBItem),
+		inventory: make(map[int]map[string]*inventoryRow),
+		txs:       make(map[*sql.Tx]int64),
+		undo:      make(map[int64][]func()),
+	}
+	s.db = sql.OpenDB(connector{store: s})
+	return s
+}
+
+// Close закрывает соединение, возвращаемое GetDB.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// AddUser добавляет пользователя с указанным балансом и возвращает его ID.
+func (s *Store) AddUser(username string, passwordHash string, coins int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addUser(username, passwordHash, coins)
+}
+
+func (s *Store) addUser(username string, passwordHash string, coins int64) int {
+	s.nextID++
+	s.users[s.nextID] = &user{DBUser: models.DBUser{ID: s.nextID, Username: username, PasswordHash: passwordHash, Coins: coins}}
+	return s.nextID
+}
+
+// AddItem добавляет товар в каталог.
+func (s *Store) AddItem(itemName string, price int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.items[itemName] = models.DBItem{ID: s.nextID, ItemName: itemName, Price: price, UpdatedAt: time.Now()}
+}
+
+// activeUser возвращает активного пользователя по имени. Вызывается под s.mu.
+func (s *Store) activeUser(username string) *user {
+	for _, u := range s.users {
+		if u.Username == username && !u.deactivated {
+			return u
+		}
+	}
+	return nil
+}
+
+// GetUserByUsername получает активного пользователя по имени; для неизвестного возвращает nil.
+func (s *Store) GetUserByUsername(_ context.Context, username string) (*models.DBUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.activeUser(username)
+	if u == nil {
+		return nil, nil
+	}
+	dbUser := u.DBUser
+	return &dbUser, nil
+}
+
+// CreateUser создает пользователя с нулевым балансом.
+func (s *Store) CreateUser(_ context.Context, username string, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Username == username {
+			return fmt.Errorf("ошибка при создании пользователя: %w", &db.DBError{Kind: db.KindUniqueViolation, Err: fmt.Errorf("пользователь %q уже существует", username)})
+		}
+	}
+	s.addUser(username, passwordHash, 0)
+	return nil
+}
+
+// setCoins устанавливает баланс пользователя с возможностью отката. Вызывается под s.mu.
+func (s *Store) setCoins(ctx context.Context, u *user, coins int64, tx *sql.Tx) error {
+	previous := u.Coins
+	return s.change(ctx, tx, func() { u.Coins = coins }, func() { u.Coins = previous })
+}
+
+// UpdateUserCoins обновляет баланс пользователя.
+func (s *Store) UpdateUserCoins(ctx context.Context, userID int, coins int64, tx *sql.Tx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return nil // UPDATE без подходящих строк не считается ошибкой.
+	}
+	return s.setCoins(ctx, u, coins, tx)
+}
+
+// DeductUserCoins списывает amount монет и возвращает новый баланс.
+func (s *Store) DeductUserCoins(ctx context.Context, userID int, amount int64, tx *sql.Tx) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok || u.Coins < amount {
+		return 0, fmt.Errorf("ошибка при списании монет: %w", db.ErrNotEnoughCoins)
+	}
+	if err := s.setCoins(ctx, u, u.Coins-amount, tx); err != nil {
+		return 0, err
+	}
+	return u.Coins, nil
+}
+
+// LockUserBalances возвращает балансы пользователей по ID. Строки не блокируются.
+func (s *Store) LockUserBalances(_ context.Context, _ *sql.Tx, userIDs ...int) (map[int]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	balances := make(map[int]int64, len(userIDs))
+	for _, id := range userIDs {
+		u, ok := s.users[id]
+		if !ok {
+			return nil, fmt.Errorf("ошибка при блокировке баланса пользователя: %w", sql.ErrNoRows)
+		}
+		balances[id] = u.Coins
+	}
+	return balances, nil
+}
+
+// userInventory возвращает инвентарь пользователя, отсортированный по типу предмета.
+func (s *Store) userInventory(userID int, keep func(itemType string) bool) []models.DBInventoryItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inventory := []models.DBInventoryItem{}
+	for itemType, row := range s.inventory[userID] {
+		if !keep(itemType) {
+			continue
+		}
+		item := models.DBInventoryItem{ID: row.id, UserID: userID, ItemType: itemType, Quantity: row.quantity}
+		if catalogItem, ok := s.items[itemType]; ok {
+			price := catalogItem.Price
+			item.Price = &price
+		}
+		inventory = append(inventory, item)
+	}
+	sort.Slice(inventory, func(i, j int) bool { return inventory[i].ItemType < inventory[j].ItemType })
+	return inventory
+}
+
+// GetUserInventory получает инвентарь пользователя, не больше limit предметов (0 снимает ограничение).
+func (s *Store) GetUserInventory(_ context.Context, userID int, limit int) ([]models.DBInventoryItem, error) {
+	inventory := s.userInventory(userID, func(string) bool { return true })
+	if limit > 0 && len(inventory) > limit {
+		inventory = inventory[:limit]
+	}
+	for i := range inventory {
+		inventory[i].Price = nil
+	}
+	return inventory, nil
+}
+
+// GetUserInventoryByPrefix получает предметы инвентаря, название которых начинается с prefix.
+func (s *Store) GetUserInventoryByPrefix(_ context.Context, userID int, prefix string) ([]models.DBInventoryItem, error) {
+	inventory := s.userInventory(userID, func(itemType string) bool { return strings.HasPrefix(itemType, prefix) })
+	for i := range inventory {
+		inventory[i].Price = nil
+	}
+	return inventory, nil
+}
+
+// GetUserInventoryWithPrices получает инвентарь пользователя с текущими ценами каталога.
+func (s *Store) GetUserInventoryWithPrices(_ context.Context, userID int) ([]models.DBInventoryItem, error) {
+	return s.userInventory(userID, func(string) bool { return true }), nil
+}
+
+// GetInventoryItemCount получает суммарное количество предметов в инвентаре пользователя.
+func (s *Store) GetInventoryItemCount(_ context.Context, userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, row := range s.inventory[userID] {
+		count += row.quantity
+	}
+	return count, nil
+}
+
+// UpdateUserInventory добавляет quantity предметов в инвентарь и возвращает их итоговое количество.
+func (s *Store) UpdateUserInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inventory := s.inventory[userID]
+	if inventory == nil {
+		inventory = make(map[string]*inventoryRow)
+		s.inventory[userID] = inventory
+	}
+	row, exists := inventory[itemType]
+	existing := 0
+	if exists {
+		existing = row.quantity
+	}
+	if s.MaxItemQuantity > 0 && quantity > s.MaxItemQuantity-existing {
+		return 0, fmt.Errorf("ошибка при обновлении инвентаря: %w", db.ErrItemQuantityCapped)
+	}
+
+	if exists {
+		err := s.change(ctx, tx, func() { row.quantity = existing + quantity }, func() { row.quantity = existing })
+		return existing + quantity, err
+	}
+	if _, ok := s.items[itemType]; !ok {
+		return 0, fmt.Errorf("товар '%s': %w", itemType, db.ErrItemNotFound)
+	}
+	s.nextID++
+	row = &inventoryRow{id: s.nextID, quantity: quantity}
+	err := s.change(ctx, tx, func() { inventory[itemType] = row }, func() { delete(inventory, itemType) })
+	return quantity, err
+}
+
+// RemoveFromInventory списывает quantity предметов itemType из инвентаря пользователя.
+func (s *Store) RemoveFromInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inventory := s.inventory[userID]
+	row, ok := inventory[itemType]
+	if !ok || row.quantity < quantity {
+		return fmt.Errorf("ошибка при списании предметов из инвентаря: %w", db.ErrNotEnoughItems)
+	}
+	previous := row.quantity
+	return s.change(ctx, tx, func() {
+		row.quantity -= quantity
+		if row.quantity == 0 {
+			delete(inventory, itemType)
+		}
+	}, func() {
+		row.quantity = previous
+		inventory[itemType] = row
+	})
+}
+
+// GetUserIDByUsername получает ID активного пользователя по имени.
+func (s *Store) GetUserIDByUsername(_ context.Context, username string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.activeUser(username)
+	if u == nil {
+		return 0, fmt.Errorf("пользователь не найден: %w", sql.ErrNoRows)
+	}
+	return u.ID, nil
+}
+
+// GetBalance получает баланс пользователя по ID.
+func (s *Store) GetBalance(_ context.Context, userID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return 0, fmt.Errorf("ошибка при получении баланса пользователя: %w", sql.ErrNoRows)
+	}
+	return u.Coins, nil
+}
+
+// GetUserRank возвращает место активного пользователя в рейтинге по балансу.
+func (s *Store) GetUserRank(_ context.Context, userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok || u.deactivated {
+		return 0, fmt.Errorf("ошибка при получении места пользователя в рейтинге: %w", sql.ErrNoRows)
+	}
+	rank := 1
+	for _, other := range s.users {
+		if !other.deactivated && other.Coins > u.Coins {
+			rank++
+		}
+	}
+	return rank, nil
+}
+
+// GetLeaderboard возвращает limit активных пользователей с наибольшим балансом
+// и суммарным количеством предметов в их инвентаре.
+func (s *Store) GetLeaderboard(_ context.Context, limit int) ([]models.LeaderboardEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := []models.LeaderboardEntry{}
+	for id, u := range s.users {
+		if u.deactivated {
+			continue
+		}
+		entry := models.LeaderboardEntry{Username: u.Username, Coins: u.Coins}
+		for _, row := range s.inventory[id] {
+			entry.ItemCount += row.quantity
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Coins != entries[j].Coins {
+			return entries[i].Coins > entries[j].Coins
+		}
+		return entries[i].Username < entries[j].Username
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// SetInitialCoins устанавливает начальный баланс пользователя.
+func (s *Store) SetInitialCoins(ctx context.Context, userID int, initialCoins int64) error {
+	return s.UpdateUserCoins(ctx, userID, initialCoins, nil)
+}
+
+// UpdateUserPassword обновляет хэш пароля пользователя.
+func (s *Store) UpdateUserPassword(ctx context.Context, userID int, passwordHash string, tx *sql.Tx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return nil
+	}
+	previous := u.PasswordHash
+	return s.change(ctx, tx, func() { u.PasswordHash = passwordHash }, func() { u.PasswordHash = previous })
+}
+
+// GetUserStats получает количество активных пользователей и их суммарный баланс.
+func (s *Store) GetUserStats(context.Context) (*models.DBUserStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := &models.DBUserStats{}
+	for _, u := range s.users {
+		if !u.deactivated {
+			stats.UserCount++
+			stats.TotalCoins += u.Coins
+		}
+	}
+	return stats, nil
+}
+
+// DeactivateUser деактивирует пользователя.
+func (s *Store) DeactivateUser(ctx context.Context, userID int, tx *sql.Tx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok || u.deactivated {
+		return nil
+	}
+	return s.change(ctx, tx, func() { u.deactivated = true }, func() { u.deactivated = false })
+}
+
+// IsUserDeactivated проверяет, деактивирован ли пользователь с указанным именем.
+func (s *Store) IsUserDeactivated(_ context.Context, username string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Username == username && u.deactivated {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ClaimDailyBonus начисляет ежедневный бонус, если он еще не получен сегодня.
+func (s *Store) ClaimDailyBonus(_ context.Context, userID int, amount int64) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	today := time.Now().Format(time.DateOnly)
+	if !ok || u.lastBonusDate == today {
+		return 0, false, nil
+	}
+	u.Coins += amount
+	u.lastBonusDate = today
+	return u.Coins, true, nil
+}
+
+// GetItemPrice получает цену товара.
+func (s *Store) GetItemPrice(_ context.Context, itemName string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[itemName]
+	if !ok {
+		return 0, fmt.Errorf("товар '%s': %w", itemName, db.ErrItemNotFound)
+	}
+	return item.Price, nil
+}
+
+// GetItems получает все товары каталога, упорядоченные по названию.
+func (s *Store) GetItems(context.Context) ([]models.DBItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]models.DBItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ItemName < items[j].ItemName })
+	return items, nil
+}
+
+// UpsertItemPrice устанавливает цену товара; возвращает true, если товар был добавлен.
+func (s *Store) UpsertItemPrice(ctx context.Context, itemName string, price int64, tx *sql.Tx) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, exists := s.items[itemName]
+	undo := func() { s.items[itemName] = previous }
+	updated := previous
+	if !exists {
+		undo = func() { delete(s.items, itemName) }
+		s.nextID++
+		updated = models.DBItem{ID: s.nextID, ItemName: itemName}
+	}
+	updated.Price = price
+	updated.UpdatedAt = time.Now()
+	err := s.change(ctx, tx, func() { s.items[itemName] = updated }, undo)
+	return !exists, err
+}
+
+// SeedItems добавляет товары items в каталог, только если каталог пуст.
+func (s *Store) SeedItems(_ context.Context, items []models.ItemPriceUpdate) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.items) > 0 {
+		return 0, nil
+	}
+	for _, item := range items {
+		s.nextID++
+		s.items[item.ItemName] = models.DBItem{ID: s.nextID, ItemName: item.ItemName, Price: item.Price, UpdatedAt: time.Now()}
+	}
+	return len(s.items), nil
+}
+
+// GetDB возвращает соединение, через которое use case'ы начинают транзакции.
+func (s *Store) GetDB() *sql.DB {
+	return s.db
+}
+
+// foreignKeyViolation возвращает ошибку нарушения внешнего ключа, если одного из пользователей нет.
+// Вызывается под s.mu.
+func (s *Store) foreignKeyViolation(userIDs ...int) error {
+	for _, id := range userIDs {
+		if _, ok := s.users[id]; !ok {
+			return &db.DBError{Kind: db.KindForeignKeyViolation, Err: fmt.Errorf("пользователь %d не существует", id)}
+		}
+	}
+	return nil
+}
+
+// RecordTransaction записывает перевод монет.
+func (s *Store) RecordTransaction(ctx context.Context, senderUserID int, receiverUserID int, amount int64, memo string, category string, tx *sql.Tx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.foreignKeyViolation(senderUserID, receiverUserID); err != nil {
+		return fmt.Errorf("ошибка при записи транзакции: %w", err)
+	}
+	s.nextID++
+	t := models.DBTransaction{
+		ID:               s.nextID,
+		SenderUserID:     senderUserID,
+		SenderUsername:   s.users[senderUserID].Username,
+		ReceiverUserID:   receiverUserID,
+		ReceiverUsername: s.users[receiverUserID].Username,
+		Amount:           amount,
+		Memo:             memo,
+		Category:         category,
+		TransactionDate:  time.Now(),
+	}
+	return s.change(ctx, tx, func() { s.transactions = append(s.transactions, t) }, func() {
+		s.transactions = slices.DeleteFunc(s.transactions, func(other models.DBTransaction) bool { return other.ID == t.ID })
+	})
+}
+
+// GetTransactionForUpdate получает перевод по ID. Возвращает nil, если перевода нет.
+func (s *Store) GetTransactionForUpdate(_ context.Context, transactionID int, _ *sql.Tx) (*models.DBTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found *models.DBTransaction
+	reversed := false
+	for _, t := range s.transactions {
+		if t.ID == transactionID {
+			found = &t
+		}
+		if t.ReversalOf != nil && *t.ReversalOf == transactionID {
+			reversed = true
+		}
+	}
+	if found != nil {
+		found.Reversed = reversed
+	}
+	return found, nil
+}
+
+// RecordReversal записывает обратный перевод, отменяющий перевод transactionID.
+func (s *Store) RecordReversal(ctx context.Context, transactionID int, senderUserID int, receiverUserID int, amount int64, tx *sql.Tx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.foreignKeyViolation(senderUserID, receiverUserID); err != nil {
+		return fmt.Errorf("ошибка при записи отмены перевода: %w", err)
+	}
+	for _, t := range s.transactions {
+		if t.ReversalOf != nil && *t.ReversalOf == transactionID {
+			return fmt.Errorf("ошибка при записи отмены перевода: %w", &db.DBError{Kind: db.KindUniqueViolation, Err: fmt.Errorf("перевод %d уже отменен", transactionID)})
+		}
+	}
+	s.nextID++
+	t := models.DBTransaction{
+		ID:               s.nextID,
+		SenderUserID:     senderUserID,
+		SenderUsername:   s.users[senderUserID].Username,
+		ReceiverUserID:   receiverUserID,
+		ReceiverUsername: s.users[receiverUserID].Username,
+		Amount:           amount,
+		TransactionDate:  time.Now(),
+		ReversalOf:       &transactionID,
+	}
+	return s.change(ctx, tx, func() { s.transactions = append(s.transactions, t) }, func() {
+		s.transactions = slices.DeleteFunc(s.transactions, func(other models.DBTransaction) bool { return other.ID == t.ID })
+	})
+}
+
+// RecordGift записывает передачу предметов между пользователями.
+func (s *Store) RecordGift(ctx context.Context, senderUserID int, receiverUserID int, itemType string, quantity int, tx *sql.Tx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.foreignKeyViolation(senderUserID, receiverUserID); err != nil {
+		return fmt.Errorf("ошибка при записи подарка: %w", err)
+	}
+	n := len(s.gifts)
+	g := gift{senderUserID: senderUserID, receiverUserID: receiverUserID, itemType: itemType, quantity: quantity}
+	return s.change(ctx, tx, func() { s.gifts = append(s.gifts, g) }, func() { s.gifts = s.gifts[:n] })
+}
+
+// GetCoinHistory получает историю переводов пользователя, новые переводы первыми.
+// Непустая category оставляет только переводы этой категории.
+func (s *Store) GetCoinHistory(_ context.Context, userID int, category string) (*models.CoinHistory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := &models.CoinHistory{
+		Received: []models.Transaction{},
+		Sent:     []models.Transaction{},
+	}
+	for i := len(s.transactions) - 1; i >= 0; i-- {
+		t := s.transactions[i]
+		if category != "" && t.Category != category {
+			continue
+		}
+		if t.ReceiverUserID == userID {
+			history.Received = append(history.Received, models.Transaction{FromUser: t.SenderUsername, Amount: t.Amount, Memo: t.Memo, Category: t.Category})
+		}
+		if t.SenderUserID == userID {
+			history.Sent = append(history.Sent, models.Transaction{ToUser: t.ReceiverUsername, Amount: t.Amount, Memo: t.Memo, Category: t.Category})
+		}
+	}
+	return history, nil
+}
+
+// StreamCoinHistory передает переводы пользователя в fn, новые первыми. fn вызывается
+// без блокировки хранилища, поэтому может обращаться к нему.
+func (s *Store) StreamCoinHistory(ctx context.Context, userID int, category string, fn func(models.Transaction) error) error {
+	for _, t := range s.coinHistory(userID, category) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// coinHistory возвращает снимок переводов пользователя в порядке StreamCoinHistory.
+func (s *Store) coinHistory(userID int, category string) []models.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := []models.Transaction{}
+	for i := len(s.transactions) - 1; i >= 0; i-- {
+		t := s.transactions[i]
+		if category != "" && t.Category != category {
+			continue
+		}
+		if t.ReceiverUserID == userID {
+			history = append(history, models.Transaction{FromUser: t.SenderUsername, Amount: t.Amount, Memo: t.Memo, Category: t.Category})
+		}
+		if t.SenderUserID == userID {
+			history = append(history, models.Transaction{ToUser: t.ReceiverUsername, Amount: t.Amount, Memo: t.Memo, Category: t.Category})
+		}
+	}
+	return history
+}
+
+// GetTransactionsBetween получает переводы между двумя пользователями в обе стороны по порядку.
+func (s *Store) GetTransactionsBetween(_ context.Context, usernameA string, usernameB string) ([]models.DBTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transactions := []models.DBTransaction{}
+	for _, t := range s.transactions {
+		if (t.SenderUsername == usernameA && t.ReceiverUsername == usernameB) ||
+			(t.SenderUsername == usernameB && t.ReceiverUsername == usernameA) {
+			transactions = append(transactions, t)
+		}
+	}
+	return transactions, nil
+}
+
+// GetUserTransactions получает все переводы пользователя в хронологическом порядке.
+func (s *Store) GetUserTransactions(_ context.Context, userID int) ([]models.DBTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transactions := []models.DBTransaction{}
+	for _, t := range s.transactions {
+		if t.SenderUserID == userID || t.ReceiverUserID == userID {
+			transactions = append(transactions, t)
+		}
+	}
+	return transactions, nil
+}
+
+// RecordAdminAction записывает действие администратора в журнал.
+func (s *Store) RecordAdminAction(ctx context.Context, entry models.AdminAuditEntry, tx *sql.Tx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	entry.ID = s.nextID
+	entry.CreatedAt = time.Now()
+	n := len(s.audit)
+	return s.change(ctx, tx, func() { s.audit = append(s.audit, entry) }, func() { s.audit = s.audit[:n] })
+}
+
+// GetAdminAudit получает страницу журнала действий администраторов, новые записи первыми.
+func (s *Store) GetAdminAudit(_ context.Context, limit int, offset int) ([]models.AdminAuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := []models.AdminAuditEntry{}
+	for i := len(s.audit) - 1 - offset; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, s.audit[i])
+	}
+	return entries, nil
+}
+
+// CountAdminAudit возвращает количество записей журнала действий администраторов.
+func (s *Store) CountAdminAudit(context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.audit), nil
+}
+
+// ServerVersion возвращает условную версию хранилища.
+func (s *Store) ServerVersion(context.Context) (string, error) {
+	return "memdb", nil
+}
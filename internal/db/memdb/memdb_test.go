@@ -0,0 +1,95 @@
+package memdb
+
+import (
+	"context"
+	"testing"
+
+	"shop/internal/db"
+	"shop/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_TransactionRollback(t *testing.T) {
+	store := New()
+	defer store.Close()
+	store.AddItem("pen", 10)
+	userID := store.AddUser("alice", "hash", 100)
+	ctx := context.Background()
+
+	// Изменения откатываются вместе с транзакцией.
+	tx, err := store.GetDB().BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = store.DeductUserCoins(ctx, userID, 10, tx)
+	require.NoError(t, err)
+	_, err = store.UpdateUserInventory(ctx, userID, "pen", 1, tx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	coins, err := store.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), coins)
+	count, err := store.GetInventoryItemCount(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// После коммита изменения сохраняются.
+	tx, err = store.GetDB().BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = store.DeductUserCoins(ctx, userID, 10, tx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	coins, err = store.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(90), coins)
+}
+
+func TestStore_SeedItems(t *testing.T) {
+	store := New()
+	defer store.Close()
+	ctx := context.Background()
+
+	seeded, err := store.SeedItems(ctx, db.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, len(db.DefaultCatalog), seeded)
+	price, err := store.GetItemPrice(ctx, "hoody")
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), price)
+
+	// Непустой каталог не меняется.
+	seeded, err = store.SeedItems(ctx, []models.ItemPriceUpdate{{ItemName: "scarf", Price: 120}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, seeded)
+	_, err = store.GetItemPrice(ctx, "scarf")
+	assert.ErrorIs(t, err, db.ErrItemNotFound)
+}
+
+func TestStore_GetUserInventory_OrderAndLimit(t *testing.T) {
+	store := New()
+	defer store.Close()
+	userID := store.AddUser("alice", "hash", 100)
+	ctx := context.Background()
+	for _, item := range []string{"umbrella", "cup", "pen", "book"} {
+		store.AddItem(item, 10)
+		_, err := store.UpdateUserInventory(ctx, userID, item, 1, nil)
+		require.NoError(t, err)
+	}
+
+	// Порядок по названию не зависит от порядка покупок и одинаков при повторных вызовах.
+	first, err := store.GetUserInventory(ctx, userID, 0)
+	require.NoError(t, err)
+	second, err := store.GetUserInventory(ctx, userID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	types := make([]string, len(first))
+	for i, item := range first {
+		types[i] = item.ItemType
+	}
+	assert.Equal(t, []string{"book", "cup", "pen", "umbrella"}, types)
+
+	limited, err := store.GetUserInventory(ctx, userID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, first[:2], limited)
+}
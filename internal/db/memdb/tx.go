@@ -0,0 +1,134 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// bindQuery служебный запрос, которым Store связывает *sql.Tx с транзакцией драйвера.
+// Use case'ы получают транзакции через GetDB().BeginTx, поэтому хранилищу нужен
+// настоящий *sql.DB; драйвер ниже поддерживает только начало и завершение транзакций.
+const bindQuery = "memdb: bind"
+
+// errUnsupported возвращается на любые SQL-запросы: данные хранятся в памяти.
+var errUnsupported = errors.New("memdb: SQL-запросы не поддерживаются")
+
+// connector создает соединения, привязанные к хранилищу.
+type connector struct {
+	store *Store
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{store: c.store}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return memDriver{}
+}
+
+// memDriver нужен только для реализации driver.Connector.
+type memDriver struct{}
+
+func (memDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("memdb: соединение открывается только через Store.GetDB")
+}
+
+// conn соединение драйвера. В каждый момент в нем открыта не больше одной транзакции.
+type conn struct {
+	store *Store
+	tx    *memTx
+}
+
+func (c *conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errUnsupported
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.tx = &memTx{conn: c}
+	return c.tx, nil
+}
+
+// ExecContext принимает только bindQuery: запоминает идентификатор транзакции хранилища,
+// чтобы при откате или коммите передать его Store.
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query != bindQuery || c.tx == nil || len(args) != 1 {
+		return nil, errUnsupported
+	}
+	id, ok := args[0].Value.(int64)
+	if !ok {
+		return nil, fmt.Errorf("memdb: неверный идентификатор транзакции %v", args[0].Value)
+	}
+	c.tx.id = id
+	return driver.RowsAffected(0), nil
+}
+
+// memTx транзакция драйвера. id равен 0, пока в транзакции не было изменений.
+type memTx struct {
+	conn *conn
+	id   int64
+}
+
+func (t *memTx) Commit() error {
+	t.conn.tx = nil
+	t.conn.store.finish(t.id, false)
+	return nil
+}
+
+func (t *memTx) Rollback() error {
+	t.conn.tx = nil
+	t.conn.store.finish(t.id, true)
+	return nil
+}
+
+// change применяет изменение do. Если передана транзакция tx, изменение откатывается
+// вызовом undo при ее откате, иначе оно сразу становится окончательным.
+// Вызывается под s.mu.
+func (s *Store) change(ctx context.Context, tx *sql.Tx, do func(), undo func()) error {
+	if tx != nil {
+		id, ok := s.txs[tx]
+		if !ok {
+			s.nextTxID++
+			id = s.nextTxID
+			if _, err := tx.ExecContext(ctx, bindQuery, id); err != nil {
+				return fmt.Errorf("memdb: ошибка привязки транзакции: %w", err)
+			}
+			s.txs[tx] = id
+		}
+		s.undo[id] = append(s.undo[id], undo)
+	}
+	do()
+	return nil
+}
+
+// finish завершает транзакцию хранилища id, при rollback отменяя ее изменения в обратном порядке.
+func (s *Store) finish(id int64, rollback bool) {
+	if id == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rollback {
+		changes := s.undo[id]
+		for i := len(changes) - 1; i >= 0; i-- {
+			changes[i]()
+		}
+	}
+	delete(s.undo, id)
+	for tx, txID := range s.txs {
+		if txID == id {
+			delete(s.txs, tx)
+		}
+	}
+}
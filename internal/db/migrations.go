@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// migrationExt расширение файлов миграций.
+const migrationExt = ".sql"
+
+// PendingMigrations возвращает версии миграций из каталога dir, которых нет в таблице
+// schema_migrations, в порядке применения. Версия миграции — имя файла без .sql.
+// Если таблицы schema_migrations еще нет, неприменными считаются все миграции.
+func PendingMigrations(ctx context.Context, database *sql.DB, dir string) ([]string, error) {
+	versions, err := migrationVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []string{}
+	for _, version := range versions {
+		if !applied[version] {
+			pending = append(pending, version)
+		}
+	}
+	return pending, nil
+}
+
+// migrationVersions возвращает отсортированные версии миграций из каталога dir.
+func migrationVersions(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога миграций: %w", err)
+	}
+	versions := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != migrationExt {
+			continue
+		}
+		versions = append(versions, strings.TrimSuffix(entry.Name(), migrationExt))
+	}
+	slices.Sort(versions)
+	return versions, nil
+}
+
+// appliedMigrations возвращает множество версий из таблицы schema_migrations.
+func appliedMigrations(ctx context.Context, database *sql.DB) (map[string]bool, error) {
+	rows, err := database.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == undefinedTable {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении примененных миграций: %w", wrapError(err))
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении версии миграции: %w", wrapError(err))
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации строк миграций: %w", wrapError(err))
+	}
+	return applied, nil
+}
+
+// requiredTables таблицы, без которых сервис не может обрабатывать запросы.
+var requiredTables = []string{"users", "items", "inventory", "coin_transactions"}
+
+// CheckSchema проверяет, что таблицы приложения созданы. Если какой-то из них нет,
+// возвращает ErrSchemaNotInitialized со списком отсутствующих таблиц.
+func CheckSchema(ctx context.Context, database *sql.DB) error {
+	rows, err := database.QueryContext(ctx,
+		"SELECT t FROM unnest($1::text[]) AS t WHERE to_regclass(t) IS NULL", pq.Array(requiredTables))
+	if err != nil {
+		return fmt.Errorf("ошибка при проверке схемы базы данных: %w", wrapError(err))
+	}
+	defer rows.Close()
+
+	missing := []string{}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return fmt.Errorf("ошибка при проверке схемы базы данных: %w", wrapError(err))
+		}
+		missing = append(missing, table)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при проверке схемы базы данных: %w", wrapError(err))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: нет таблиц %s", ErrSchemaNotInitialized, strings.Join(missing, ", "))
+	}
+	return nil
+}
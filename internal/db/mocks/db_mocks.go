@@ -36,6 +36,22 @@ func (m *MockUserDBInterface) EXPECT() *MockUserDBInterfaceMockRecorder {
 	return m.recorder
 }
 
+// ClaimDailyBonus mocks base method.
+func (m *MockUserDBInterface) ClaimDailyBonus(arg0 context.Context, arg1 int, arg2 int64) (int64, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDailyBonus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ClaimDailyBonus indicates an expected call of ClaimDailyBonus.
+func (mr *MockUserDBInterfaceMockRecorder) ClaimDailyBonus(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDailyBonus", reflect.TypeOf((*MockUserDBInterface)(nil).ClaimDailyBonus), arg0, arg1, arg2)
+}
+
 // CreateUser mocks base method.
 func (m *MockUserDBInterface) CreateUser(arg0 context.Context, arg1, arg2 string) error {
 	m.ctrl.T.Helper()
@@ -50,6 +66,80 @@ func (mr *MockUserDBInterfaceMockRecorder) CreateUser(arg0, arg1, arg2 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserDBInterface)(nil).CreateUser), arg0, arg1, arg2)
 }
 
+// DeactivateUser mocks base method.
+func (m *MockUserDBInterface) DeactivateUser(arg0 context.Context, arg1 int, arg2 *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeactivateUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeactivateUser indicates an expected call of DeactivateUser.
+func (mr *MockUserDBInterfaceMockRecorder) DeactivateUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeactivateUser", reflect.TypeOf((*MockUserDBInterface)(nil).DeactivateUser), arg0, arg1, arg2)
+}
+
+// DeductUserCoins mocks base method.
+func (m *MockUserDBInterface) DeductUserCoins(arg0 context.Context, arg1 int, arg2 int64, arg3 *sql.Tx) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeductUserCoins", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeductUserCoins indicates an expected call of DeductUserCoins.
+func (mr *MockUserDBInterfaceMockRecorder) DeductUserCoins(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeductUserCoins", reflect.TypeOf((*MockUserDBInterface)(nil).DeductUserCoins), arg0, arg1, arg2, arg3)
+}
+
+// GetBalance mocks base method.
+func (m *MockUserDBInterface) GetBalance(arg0 context.Context, arg1 int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalance", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalance indicates an expected call of GetBalance.
+func (mr *MockUserDBInterfaceMockRecorder) GetBalance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockUserDBInterface)(nil).GetBalance), arg0, arg1)
+}
+
+// GetInventoryItemCount mocks base method.
+func (m *MockUserDBInterface) GetInventoryItemCount(arg0 context.Context, arg1 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInventoryItemCount", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInventoryItemCount indicates an expected call of GetInventoryItemCount.
+func (mr *MockUserDBInterfaceMockRecorder) GetInventoryItemCount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInventoryItemCount", reflect.TypeOf((*MockUserDBInterface)(nil).GetInventoryItemCount), arg0, arg1)
+}
+
+// GetLeaderboard mocks base method.
+func (m *MockUserDBInterface) GetLeaderboard(arg0 context.Context, arg1 int) ([]models.LeaderboardEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLeaderboard", arg0, arg1)
+	ret0, _ := ret[0].([]models.LeaderboardEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLeaderboard indicates an expected call of GetLeaderboard.
+func (mr *MockUserDBInterfaceMockRecorder) GetLeaderboard(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeaderboard", reflect.TypeOf((*MockUserDBInterface)(nil).GetLeaderboard), arg0, arg1)
+}
+
 // GetUserByUsername mocks base method.
 func (m *MockUserDBInterface) GetUserByUsername(arg0 context.Context, arg1 string) (*models.DBUser, error) {
 	m.ctrl.T.Helper()
@@ -81,22 +171,131 @@ func (mr *MockUserDBInterfaceMockRecorder) GetUserIDByUsername(arg0, arg1 interf
 }
 
 // GetUserInventory mocks base method.
-func (m *MockUserDBInterface) GetUserInventory(arg0 context.Context, arg1 int) ([]models.DBInventoryItem, error) {
+func (m *MockUserDBInterface) GetUserInventory(arg0 context.Context, arg1, arg2 int) ([]models.DBInventoryItem, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserInventory", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetUserInventory", arg0, arg1, arg2)
 	ret0, _ := ret[0].([]models.DBInventoryItem)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserInventory indicates an expected call of GetUserInventory.
-func (mr *MockUserDBInterfaceMockRecorder) GetUserInventory(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserDBInterfaceMockRecorder) GetUserInventory(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserInventory", reflect.TypeOf((*MockUserDBInterface)(nil).GetUserInventory), arg0, arg1, arg2)
+}
+
+// GetUserInventoryByPrefix mocks base method.
+func (m *MockUserDBInterface) GetUserInventoryByPrefix(arg0 context.Context, arg1 int, arg2 string) ([]models.DBInventoryItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserInventoryByPrefix", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.DBInventoryItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserInventoryByPrefix indicates an expected call of GetUserInventoryByPrefix.
+func (mr *MockUserDBInterfaceMockRecorder) GetUserInventoryByPrefix(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserInventory", reflect.TypeOf((*MockUserDBInterface)(nil).GetUserInventory), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserInventoryByPrefix", reflect.TypeOf((*MockUserDBInterface)(nil).GetUserInventoryByPrefix), arg0, arg1, arg2)
+}
+
+// GetUserInventoryWithPrices mocks base method.
+func (m *MockUserDBInterface) GetUserInventoryWithPrices(arg0 context.Context, arg1 int) ([]models.DBInventoryItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserInventoryWithPrices", arg0, arg1)
+	ret0, _ := ret[0].([]models.DBInventoryItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserInventoryWithPrices indicates an expected call of GetUserInventoryWithPrices.
+func (mr *MockUserDBInterfaceMockRecorder) GetUserInventoryWithPrices(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserInventoryWithPrices", reflect.TypeOf((*MockUserDBInterface)(nil).GetUserInventoryWithPrices), arg0, arg1)
+}
+
+// GetUserRank mocks base method.
+func (m *MockUserDBInterface) GetUserRank(arg0 context.Context, arg1 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserRank", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserRank indicates an expected call of GetUserRank.
+func (mr *MockUserDBInterfaceMockRecorder) GetUserRank(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserRank", reflect.TypeOf((*MockUserDBInterface)(nil).GetUserRank), arg0, arg1)
+}
+
+// GetUserStats mocks base method.
+func (m *MockUserDBInterface) GetUserStats(arg0 context.Context) (*models.DBUserStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserStats", arg0)
+	ret0, _ := ret[0].(*models.DBUserStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserStats indicates an expected call of GetUserStats.
+func (mr *MockUserDBInterfaceMockRecorder) GetUserStats(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserStats", reflect.TypeOf((*MockUserDBInterface)(nil).GetUserStats), arg0)
+}
+
+// IsUserDeactivated mocks base method.
+func (m *MockUserDBInterface) IsUserDeactivated(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsUserDeactivated", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsUserDeactivated indicates an expected call of IsUserDeactivated.
+func (mr *MockUserDBInterfaceMockRecorder) IsUserDeactivated(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsUserDeactivated", reflect.TypeOf((*MockUserDBInterface)(nil).IsUserDeactivated), arg0, arg1)
+}
+
+// LockUserBalances mocks base method.
+func (m *MockUserDBInterface) LockUserBalances(arg0 context.Context, arg1 *sql.Tx, arg2 ...int) (map[int]int64, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "LockUserBalances", varargs...)
+	ret0, _ := ret[0].(map[int]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockUserBalances indicates an expected call of LockUserBalances.
+func (mr *MockUserDBInterfaceMockRecorder) LockUserBalances(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUserBalances", reflect.TypeOf((*MockUserDBInterface)(nil).LockUserBalances), varargs...)
+}
+
+// RemoveFromInventory mocks base method.
+func (m *MockUserDBInterface) RemoveFromInventory(arg0 context.Context, arg1 int, arg2 string, arg3 int, arg4 *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveFromInventory", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveFromInventory indicates an expected call of RemoveFromInventory.
+func (mr *MockUserDBInterfaceMockRecorder) RemoveFromInventory(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFromInventory", reflect.TypeOf((*MockUserDBInterface)(nil).RemoveFromInventory), arg0, arg1, arg2, arg3, arg4)
 }
 
 // SetInitialCoins mocks base method.
-func (m *MockUserDBInterface) SetInitialCoins(arg0 context.Context, arg1, arg2 int) error {
+func (m *MockUserDBInterface) SetInitialCoins(arg0 context.Context, arg1 int, arg2 int64) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SetInitialCoins", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
@@ -110,25 +309,26 @@ func (mr *MockUserDBInterfaceMockRecorder) SetInitialCoins(arg0, arg1, arg2 inte
 }
 
 // UpdateUserCoins mocks base method.
-func (m *MockUserDBInterface) UpdateUserCoins(arg0 context.Context, arg1, arg2 int) error {
+func (m *MockUserDBInterface) UpdateUserCoins(arg0 context.Context, arg1 int, arg2 int64, arg3 *sql.Tx) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUserCoins", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "UpdateUserCoins", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateUserCoins indicates an expected call of UpdateUserCoins.
-func (mr *MockUserDBInterfaceMockRecorder) UpdateUserCoins(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockUserDBInterfaceMockRecorder) UpdateUserCoins(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserCoins", reflect.TypeOf((*MockUserDBInterface)(nil).UpdateUserCoins), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserCoins", reflect.TypeOf((*MockUserDBInterface)(nil).UpdateUserCoins), arg0, arg1, arg2, arg3)
 }
 
 // UpdateUserInventory mocks base method.
-func (m *MockUserDBInterface) UpdateUserInventory(arg0 context.Context, arg1 int, arg2 string, arg3 int, arg4 *sql.Tx) error {
+func (m *MockUserDBInterface) UpdateUserInventory(arg0 context.Context, arg1 int, arg2 string, arg3 int, arg4 *sql.Tx) (int, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateUserInventory", arg0, arg1, arg2, arg3, arg4)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // UpdateUserInventory indicates an expected call of UpdateUserInventory.
@@ -137,6 +337,20 @@ func (mr *MockUserDBInterfaceMockRecorder) UpdateUserInventory(arg0, arg1, arg2,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserInventory", reflect.TypeOf((*MockUserDBInterface)(nil).UpdateUserInventory), arg0, arg1, arg2, arg3, arg4)
 }
 
+// UpdateUserPassword mocks base method.
+func (m *MockUserDBInterface) UpdateUserPassword(arg0 context.Context, arg1 int, arg2 string, arg3 *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword.
+func (mr *MockUserDBInterfaceMockRecorder) UpdateUserPassword(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockUserDBInterface)(nil).UpdateUserPassword), arg0, arg1, arg2, arg3)
+}
+
 // MockItemDBInterface is a mock of ItemDBInterface interface.
 type MockItemDBInterface struct {
 	ctrl     *gomock.Controller
@@ -161,10 +375,10 @@ func (m *MockItemDBInterface) EXPECT() *MockItemDBInterfaceMockRecorder {
 }
 
 // GetItemPrice mocks base method.
-func (m *MockItemDBInterface) GetItemPrice(arg0 context.Context, arg1 string) (int, error) {
+func (m *MockItemDBInterface) GetItemPrice(arg0 context.Context, arg1 string) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetItemPrice", arg0, arg1)
-	ret0, _ := ret[0].(int)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -175,6 +389,51 @@ func (mr *MockItemDBInterfaceMockRecorder) GetItemPrice(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemPrice", reflect.TypeOf((*MockItemDBInterface)(nil).GetItemPrice), arg0, arg1)
 }
 
+// GetItems mocks base method.
+func (m *MockItemDBInterface) GetItems(arg0 context.Context) ([]models.DBItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetItems", arg0)
+	ret0, _ := ret[0].([]models.DBItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetItems indicates an expected call of GetItems.
+func (mr *MockItemDBInterfaceMockRecorder) GetItems(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItems", reflect.TypeOf((*MockItemDBInterface)(nil).GetItems), arg0)
+}
+
+// SeedItems mocks base method.
+func (m *MockItemDBInterface) SeedItems(arg0 context.Context, arg1 []models.ItemPriceUpdate) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SeedItems", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SeedItems indicates an expected call of SeedItems.
+func (mr *MockItemDBInterfaceMockRecorder) SeedItems(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SeedItems", reflect.TypeOf((*MockItemDBInterface)(nil).SeedItems), arg0, arg1)
+}
+
+// UpsertItemPrice mocks base method.
+func (m *MockItemDBInterface) UpsertItemPrice(arg0 context.Context, arg1 string, arg2 int64, arg3 *sql.Tx) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertItemPrice", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertItemPrice indicates an expected call of UpsertItemPrice.
+func (mr *MockItemDBInterfaceMockRecorder) UpsertItemPrice(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertItemPrice", reflect.TypeOf((*MockItemDBInterface)(nil).UpsertItemPrice), arg0, arg1, arg2, arg3)
+}
+
 // MockTransactionDBInterface is a mock of TransactionDBInterface interface.
 type MockTransactionDBInterface struct {
 	ctrl     *gomock.Controller
@@ -198,19 +457,49 @@ func (m *MockTransactionDBInterface) EXPECT() *MockTransactionDBInterfaceMockRec
 	return m.recorder
 }
 
+// CountAdminAudit mocks base method.
+func (m *MockTransactionDBInterface) CountAdminAudit(arg0 context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountAdminAudit", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountAdminAudit indicates an expected call of CountAdminAudit.
+func (mr *MockTransactionDBInterfaceMockRecorder) CountAdminAudit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountAdminAudit", reflect.TypeOf((*MockTransactionDBInterface)(nil).CountAdminAudit), arg0)
+}
+
+// GetAdminAudit mocks base method.
+func (m *MockTransactionDBInterface) GetAdminAudit(arg0 context.Context, arg1, arg2 int) ([]models.AdminAuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdminAudit", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.AdminAuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAdminAudit indicates an expected call of GetAdminAudit.
+func (mr *MockTransactionDBInterfaceMockRecorder) GetAdminAudit(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdminAudit", reflect.TypeOf((*MockTransactionDBInterface)(nil).GetAdminAudit), arg0, arg1, arg2)
+}
+
 // GetCoinHistory mocks base method.
-func (m *MockTransactionDBInterface) GetCoinHistory(arg0 context.Context, arg1 int) (*models.CoinHistory, error) {
+func (m *MockTransactionDBInterface) GetCoinHistory(arg0 context.Context, arg1 int, arg2 string) (*models.CoinHistory, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCoinHistory", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetCoinHistory", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.CoinHistory)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetCoinHistory indicates an expected call of GetCoinHistory.
-func (mr *MockTransactionDBInterfaceMockRecorder) GetCoinHistory(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockTransactionDBInterfaceMockRecorder) GetCoinHistory(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoinHistory", reflect.TypeOf((*MockTransactionDBInterface)(nil).GetCoinHistory), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoinHistory", reflect.TypeOf((*MockTransactionDBInterface)(nil).GetCoinHistory), arg0, arg1, arg2)
 }
 
 // GetDB mocks base method.
@@ -227,16 +516,132 @@ func (mr *MockTransactionDBInterfaceMockRecorder) GetDB() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDB", reflect.TypeOf((*MockTransactionDBInterface)(nil).GetDB))
 }
 
+// GetTransactionForUpdate mocks base method.
+func (m *MockTransactionDBInterface) GetTransactionForUpdate(arg0 context.Context, arg1 int, arg2 *sql.Tx) (*models.DBTransaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactionForUpdate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.DBTransaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransactionForUpdate indicates an expected call of GetTransactionForUpdate.
+func (mr *MockTransactionDBInterfaceMockRecorder) GetTransactionForUpdate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionForUpdate", reflect.TypeOf((*MockTransactionDBInterface)(nil).GetTransactionForUpdate), arg0, arg1, arg2)
+}
+
+// GetTransactionsBetween mocks base method.
+func (m *MockTransactionDBInterface) GetTransactionsBetween(arg0 context.Context, arg1, arg2 string) ([]models.DBTransaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactionsBetween", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.DBTransaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransactionsBetween indicates an expected call of GetTransactionsBetween.
+func (mr *MockTransactionDBInterfaceMockRecorder) GetTransactionsBetween(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionsBetween", reflect.TypeOf((*MockTransactionDBInterface)(nil).GetTransactionsBetween), arg0, arg1, arg2)
+}
+
+// GetUserTransactions mocks base method.
+func (m *MockTransactionDBInterface) GetUserTransactions(arg0 context.Context, arg1 int) ([]models.DBTransaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTransactions", arg0, arg1)
+	ret0, _ := ret[0].([]models.DBTransaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTransactions indicates an expected call of GetUserTransactions.
+func (mr *MockTransactionDBInterfaceMockRecorder) GetUserTransactions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTransactions", reflect.TypeOf((*MockTransactionDBInterface)(nil).GetUserTransactions), arg0, arg1)
+}
+
+// RecordAdminAction mocks base method.
+func (m *MockTransactionDBInterface) RecordAdminAction(arg0 context.Context, arg1 models.AdminAuditEntry, arg2 *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAdminAction", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAdminAction indicates an expected call of RecordAdminAction.
+func (mr *MockTransactionDBInterfaceMockRecorder) RecordAdminAction(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAdminAction", reflect.TypeOf((*MockTransactionDBInterface)(nil).RecordAdminAction), arg0, arg1, arg2)
+}
+
+// RecordGift mocks base method.
+func (m *MockTransactionDBInterface) RecordGift(arg0 context.Context, arg1, arg2 int, arg3 string, arg4 int, arg5 *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordGift", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordGift indicates an expected call of RecordGift.
+func (mr *MockTransactionDBInterfaceMockRecorder) RecordGift(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordGift", reflect.TypeOf((*MockTransactionDBInterface)(nil).RecordGift), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// RecordReversal mocks base method.
+func (m *MockTransactionDBInterface) RecordReversal(arg0 context.Context, arg1, arg2, arg3 int, arg4 int64, arg5 *sql.Tx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordReversal", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordReversal indicates an expected call of RecordReversal.
+func (mr *MockTransactionDBInterfaceMockRecorder) RecordReversal(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordReversal", reflect.TypeOf((*MockTransactionDBInterface)(nil).RecordReversal), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
 // RecordTransaction mocks base method.
-func (m *MockTransactionDBInterface) RecordTransaction(arg0 context.Context, arg1, arg2, arg3 int, arg4 *sql.Tx) error {
+func (m *MockTransactionDBInterface) RecordTransaction(arg0 context.Context, arg1, arg2 int, arg3 int64, arg4, arg5 string, arg6 *sql.Tx) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RecordTransaction", arg0, arg1, arg2, arg3, arg4)
+	ret := m.ctrl.Call(m, "RecordTransaction", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RecordTransaction indicates an expected call of RecordTransaction.
-func (mr *MockTransactionDBInterfaceMockRecorder) RecordTransaction(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+func (mr *MockTransactionDBInterfaceMockRecorder) RecordTransaction(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTransaction", reflect.TypeOf((*MockTransactionDBInterface)(nil).RecordTransaction), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
+// ServerVersion mocks base method.
+func (m *MockTransactionDBInterface) ServerVersion(arg0 context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ServerVersion", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ServerVersion indicates an expected call of ServerVersion.
+func (mr *MockTransactionDBInterfaceMockRecorder) ServerVersion(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ServerVersion", reflect.TypeOf((*MockTransactionDBInterface)(nil).ServerVersion), arg0)
+}
+
+// StreamCoinHistory mocks base method.
+func (m *MockTransactionDBInterface) StreamCoinHistory(arg0 context.Context, arg1 int, arg2 string, arg3 func(models.Transaction) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamCoinHistory", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamCoinHistory indicates an expected call of StreamCoinHistory.
+func (mr *MockTransactionDBInterfaceMockRecorder) StreamCoinHistory(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTransaction", reflect.TypeOf((*MockTransactionDBInterface)(nil).RecordTransaction), arg0, arg1, arg2, arg3, arg4)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamCoinHistory", reflect.TypeOf((*MockTransactionDBInterface)(nil).StreamCoinHistory), arg0, arg1, arg2, arg3)
 }
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+
+	"shop/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingConnector драйвер-заглушка, подсчитывающий подготовку выражений.
+// Каждый запрос возвращает одну строку с одним значением 42.
+type countingConnector struct {
+	prepares atomic.Int64
+}
+
+func (c *countingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &countingConn{c}, nil
+}
+func (c *countingConnector) Driver() driver.Driver { return nil }
+
+type countingConn struct{ connector *countingConnector }
+
+func (c *countingConn) Prepare(string) (driver.Stmt, error) {
+	c.connector.prepares.Add(1)
+	return countingStmt{}, nil
+}
+func (c *countingConn) Close() error { return nil }
+func (c *countingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("транзакции не поддерживаются")
+}
+
+type countingStmt struct{}
+
+func (countingStmt) Close() error                               { return nil }
+func (countingStmt) NumInput() int                              { return -1 }
+func (countingStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (countingStmt) Query([]driver.Value) (driver.Rows, error)  { return &singleRow{}, nil }
+
+type singleRow struct{ done bool }
+
+func (r *singleRow) Columns() []string { return []string{"value"} }
+func (r *singleRow) Close() error      { return nil }
+func (r *singleRow) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(42)
+	return nil
+}
+
+func newCountingDB(t testing.TB) (*sql.DB, *countingConnector) {
+	connector := &countingConnector{}
+	database := sql.OpenDB(connector)
+	database.SetMaxOpenConns(1)
+	t.Cleanup(func() { database.Close() })
+	return database, connector
+}
+
+func TestStmtCache_ReusesPreparedStatements(t *testing.T) {
+	database, connector := newCountingDB(t)
+	ctx := context.Background()
+
+	itemDB := NewItemDB(database, logger.NewTestLogger())
+	userDB := NewUserDB(database, logger.NewTestLogger())
+	for i := 0; i < 3; i++ {
+		price, err := itemDB.GetItemPrice(ctx, "pen")
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), price)
+		require.NoError(t, userDB.UpdateUserCoins(ctx, 1, 100, nil))
+	}
+	// Каждое выражение подготовлено один раз, несмотря на повторные вызовы.
+	assert.Equal(t, int64(2), connector.prepares.Load())
+
+	// После Close выражения подготавливаются заново.
+	require.NoError(t, itemDB.Close())
+	require.NoError(t, userDB.Close())
+	_, err := itemDB.GetItemPrice(ctx, "pen")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), connector.prepares.Load())
+}
+
+// BenchmarkItemDB_GetItemPrice сравнивает запрос через кэш выражений с обычным запросом.
+// Заглушка не моделирует разбор SQL на сервере, поэтому основной показатель — prepares/op:
+// без кэша каждый вызов заново подготавливает выражение.
+func BenchmarkItemDB_GetItemPrice(b *testing.B) {
+	ctx := context.Background()
+	// Отладочные сообщения не форматируются, чтобы не искажать замер.
+	quietLog := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))}
+
+	b.Run("prepared", func(b *testing.B) {
+		database, connector := newCountingDB(b)
+		itemDB := NewItemDB(database, quietLog)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := itemDB.GetItemPrice(ctx, "pen"); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(connector.prepares.Load())/float64(b.N), "prepares/op")
+	})
+
+	b.Run("unprepared", func(b *testing.B) {
+		database, connector := newCountingDB(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			var price int
+			if err := database.QueryRowContext(ctx, queryGetItemPrice, "pen").Scan(&price); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(connector.prepares.Load())/float64(b.N), "prepares/op")
+	})
+}
@@ -3,59 +3,262 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"shop/internal/config"
 	"shop/internal/http/helpers"
 	"shop/internal/http/middlewares"
+	"shop/internal/metrics"
 	"shop/internal/models"
 	"shop/internal/usecase"
 	"shop/pkg/logger"
 )
 
+// Имена функций API, которые можно отключить через config.APIConfig.Features.
+const (
+	FeatureDailyBonus = "daily-bonus"
+	FeatureAdmin      = "admin"
+)
+
 // ApiHandler структура для обработки всех API запросов.
 type ApiHandler struct {
 	userUseCase     usecase.UserUseCaseInterface
 	sendCoinUseCase usecase.SendCoinUseCaseInterface
 	buyItemUseCase  usecase.BuyItemUseCaseInterface
+	adminUseCase    usecase.AdminUseCaseInterface
+	bonusUseCase    usecase.BonusUseCaseInterface
+	giftUseCase     usecase.GiftUseCaseInterface
+	authFailures    *metrics.AuthFailures
 	authMiddleware  middlewares.AuthMiddlewareHandler
+	adminMiddleware middlewares.AdminMiddlewareHandler
+	cfg             config.APIConfig
 	log             *logger.Logger
+	// streamsDone закрывается при остановке сервера, чтобы завершить длительные потоки.
+	streamsDone      chan struct{}
+	closeStreamsOnce sync.Once
 }
 
 // NewApiHandler создает новый ApiHandler.
 func NewApiHandler(
+	cfg config.APIConfig,
 	userUseCase usecase.UserUseCaseInterface,
 	sendCoinUseCase usecase.SendCoinUseCaseInterface,
 	buyItemUseCase usecase.BuyItemUseCaseInterface,
+	adminUseCase usecase.AdminUseCaseInterface,
+	bonusUseCase usecase.BonusUseCaseInterface,
+	giftUseCase usecase.GiftUseCaseInterface,
+	authFailures *metrics.AuthFailures,
 	log *logger.Logger,
 ) *ApiHandler {
+	authMiddleware := middlewares.NewAuthMiddlewareHandler(userUseCase, cfg.ResolveUserID, cfg.RequireExistingUser, cfg.APIKeys, authFailures)
+	authMiddleware.MaxHeaderLength = cfg.MaxAuthHeaderLength
 	return &ApiHandler{
 		userUseCase:     userUseCase,
 		sendCoinUseCase: sendCoinUseCase,
 		buyItemUseCase:  buyItemUseCase,
-		authMiddleware:  middlewares.NewAuthMiddlewareHandler(userUseCase),
+		adminUseCase:    adminUseCase,
+		bonusUseCase:    bonusUseCase,
+		giftUseCase:     giftUseCase,
+		authFailures:    authFailures,
+		authMiddleware:  authMiddleware,
+		adminMiddleware: middlewares.NewAdminMiddlewareHandler(userUseCase, cfg.AdminUsernames),
+		cfg:             cfg,
 		log:             log,
+		streamsDone:     make(chan struct{}),
 	}
 }
 
 // RegisterRoutes регистрирует обработчики для API маршрутов.
 func (h *ApiHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/info", h.authMiddleware.AuthMiddleware(h.handleInfo))
-	mux.HandleFunc("/api/sendCoin", h.authMiddleware.AuthMiddleware(h.handleSendCoin))
-	mux.HandleFunc("/api/buy/", h.authMiddleware.AuthMiddleware(h.handleBuyItem))
+	mux.HandleFunc("GET /api/inventory", h.authMiddleware.AuthMiddleware(h.handleInventory))
+	mux.HandleFunc("GET /api/history", h.authMiddleware.AuthMiddleware(h.handleHistory))
+	mux.HandleFunc("GET /api/statement", h.authMiddleware.AuthMiddleware(h.handleStatement))
+	mux.HandleFunc("GET /api/rank", h.authMiddleware.AuthMiddleware(h.handleRank))
+	mux.HandleFunc("GET /api/leaderboard", h.authMiddleware.AuthMiddleware(h.handleLeaderboard))
+	mux.HandleFunc("GET /api/balance/stream", h.authMiddleware.AuthMiddleware(h.handleBalanceStream))
+	mux.HandleFunc("POST /api/sendCoin", h.authMiddleware.AuthMiddleware(h.handleSendCoin))
+	mux.HandleFunc("POST /api/transactions/{id}/reverse", h.authMiddleware.AuthMiddleware(h.handleReverseTransfer))
+	// Покупка по спецификации выполняется GET запросом; POST поддерживается для клиентов,
+	// отправляющих количество в теле. Режим только для чтения проверяется в usecase.
+	mux.HandleFunc("GET /api/buy/", h.authMiddleware.AuthMiddleware(h.handleBuyItem))
+	mux.HandleFunc("POST /api/buy/", h.authMiddleware.AuthMiddleware(h.handleBuyItem))
+	mux.HandleFunc("POST /api/gift", h.authMiddleware.AuthMiddleware(h.handleGift))
+	mux.HandleFunc("GET /api/items", h.authMiddleware.AuthMiddleware(h.handleItems))
+	mux.HandleFunc("POST /api/cart/quote", h.authMiddleware.AuthMiddleware(h.handleCartQuote))
 	mux.HandleFunc("/api/auth", h.handleAuth)
+	mux.HandleFunc("POST /api/auth/validate", h.handleValidateToken)
+	mux.HandleFunc("POST /api/refresh", h.handleRefresh)
+
+	h.handleFeature(mux, FeatureDailyBonus, "POST /api/claim-bonus", h.authMiddleware.AuthMiddleware(h.handleClaimBonus))
+
+	h.handleFeature(mux, FeatureAdmin, "POST /api/admin/users/{username}/reset-password", h.adminOnly(h.handleResetPassword))
+	h.handleFeature(mux, FeatureAdmin, "POST /api/admin/users/{username}/deactivate", h.adminOnly(h.handleDeactivateUser))
+	h.handleFeature(mux, FeatureAdmin, "GET /api/admin/transactions", h.adminOnly(h.handleTransactionsBetween))
+	h.handleFeature(mux, FeatureAdmin, "PUT /api/admin/items", h.adminOnly(h.handleUpdateItemPrices))
+	h.handleFeature(mux, FeatureAdmin, "GET /api/admin/audit", h.adminOnly(h.handleAdminAudit))
+	h.handleFeature(mux, FeatureAdmin, "GET /api/admin/debug/info", h.adminOnly(h.handleDebugInfo))
+}
+
+// handleFeature регистрирует маршрут, только если функция включена в конфигурации.
+// Маршруты отключенных функций не регистрируются, и на них возвращается 404.
+func (h *ApiHandler) handleFeature(mux *http.ServeMux, feature string, pattern string, handler http.HandlerFunc) {
+	if !h.cfg.FeatureEnabled(feature) {
+		h.log.Info("Функция отключена, маршрут не зарегистрирован", "feature", feature, "pattern", pattern)
+		return
+	}
+	mux.HandleFunc(pattern, handler)
+}
+
+// adminOnly оборачивает обработчик проверками авторизации и прав администратора.
+func (h *ApiHandler) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return h.authMiddleware.AuthMiddleware(h.adminMiddleware.AdminMiddleware(next))
 }
 
 // handleInfo обрабатывает запросы на получение информации о пользователе.
+// Параметр details=true добавляет к предметам инвентаря текущие цены из каталога,
+// параметр fields=coins,inventory ограничивает ответ выбранными разделами.
 func (h *ApiHandler) handleInfo(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
-	log.Debug("Обработка запроса handleInfo", "path", r.URL.Path, "method", r.Method)
+	log.Debug("Обработка запроса handleInfo", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
+
+	var opts usecase.InfoOptions
+	if raw := r.URL.Query().Get("details"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusBadRequest, "Параметр details должен быть true или false")
+			return
+		}
+		opts.ItemDetails = parsed
+	}
+	if raw := r.URL.Query().Get("fields"); raw != "" {
+		sections, err := usecase.ParseInfoSections(raw)
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.Sections = sections
+	}
+	inventoryLimit, ok := queryInt(w, r, "inventoryLimit")
+	if !ok {
+		return
+	}
+	if inventoryLimit < 0 {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Параметр inventoryLimit не может быть отрицательным")
+		return
+	}
+	opts.InventoryLimit = inventoryLimit
+	opts.Category = r.URL.Query().Get("category")
+
+	response, err := h.userUseCase.GetUserInfo(r.Context(), username, opts)
+	if err != nil {
+		log.Error("Ошибка usecase GetUserInfo", "username", logger.Sanitize(username), "error", err)
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			helpers.RespondWithError(w, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, usecase.ErrInvalidCategory) {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	if opts.Sections == nil {
+		helpers.RespondWithJSON(w, http.StatusOK, response)
+		return
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, infoSections(response, opts))
+}
+
+// infoSections оставляет в ответе только выбранные разделы.
+func infoSections(response *models.InfoResponse, opts usecase.InfoOptions) map[usecase.InfoSection]any {
+	all := map[usecase.InfoSection]any{
+		usecase.InfoSectionCoins:       response.Coins,
+		usecase.InfoSectionInventory:   response.Inventory,
+		usecase.InfoSectionItemCount:   response.ItemCount,
+		usecase.InfoSectionCoinHistory: response.CoinHistory,
+	}
+	selected := make(map[usecase.InfoSection]any, len(opts.Sections))
+	for section, value := range all {
+		if opts.Includes(section) {
+			selected[section] = value
+		}
+	}
+	return selected
+}
+
+// handleInventory обрабатывает запросы на получение инвентаря с фильтром по префиксу названия.
+func (h *ApiHandler) handleInventory(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleInventory", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+
+	response, err := h.userUseCase.GetInventory(r.Context(), username, prefix)
+	if err != nil {
+		log.Error("Ошибка usecase GetInventory", "username", logger.Sanitize(username), "prefix", logger.Sanitize(prefix), "error", err)
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			helpers.RespondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	respondList(h, w, response, response.Inventory, wholeList(len(response.Inventory)))
+}
+
+// handleStatement обрабатывает запросы на получение выписки по переводам с балансом после каждого перевода.
+func (h *ApiHandler) handleStatement(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleStatement", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := h.userUseCase.GetStatement(r.Context(), username)
+	if err != nil {
+		log.Error("Ошибка usecase GetStatement", "username", logger.Sanitize(username), "error", err)
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			helpers.RespondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// handleRank обрабатывает запросы на получение места пользователя в рейтинге по балансу.
+func (h *ApiHandler) handleRank(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleRank", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
 
-	username := helpers.UsernameFromContext(r.Context())
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
 
-	response, err := h.userUseCase.GetUserInfo(r.Context(), username)
+	response, err := h.userUseCase.GetRank(r.Context(), username)
 	if err != nil {
-		log.Error("Ошибка usecase GetUserInfo", "username", username, "error", err)
+		log.Error("Ошибка usecase GetRank", "username", logger.Sanitize(username), "error", err)
 		if errors.Is(err, usecase.ErrUserNotFound) {
 			helpers.RespondWithError(w, http.StatusNotFound, err.Error())
 		} else {
@@ -67,33 +270,138 @@ func (h *ApiHandler) handleInfo(w http.ResponseWriter, r *http.Request) {
 	helpers.RespondWithJSON(w, http.StatusOK, response)
 }
 
+// handleLeaderboard обрабатывает запросы на получение рейтинга пользователей по балансу.
+// Параметр limit ограничивает количество пользователей в рейтинге.
+func (h *ApiHandler) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleLeaderboard", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	limit, ok := queryInt(w, r, "limit")
+	if !ok {
+		return
+	}
+
+	response, err := h.userUseCase.GetLeaderboard(r.Context(), limit)
+	if err != nil {
+		log.Error("Ошибка usecase GetLeaderboard", "limit", limit, "error", err)
+		if errors.Is(err, usecase.ErrInvalidPagination) {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	respondList(h, w, response, response.Entries, wholeList(len(response.Entries)))
+}
+
 // handleSendCoin обрабатывает запросы на отправку монет.
 func (h *ApiHandler) handleSendCoin(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
-	log.Debug("Обработка запроса handleSendCoin", "path", r.URL.Path, "method", r.Method)
+	log.Debug("Обработка запроса handleSendCoin", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
 
-	username := helpers.UsernameFromContext(r.Context())
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
 
 	var req models.SendCoinRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Error("Ошибка декодирования запроса handleSendCoin", "error", err)
-		helpers.RespondWithError(w, http.StatusBadRequest, "Неверный запрос.")
+	if !helpers.DecodeJSONBody(w, r, &req) {
 		return
 	}
 	defer r.Body.Close()
 
-	if req.Amount <= 0 {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Неверный запрос.")
+	result, err := h.sendCoinUseCase.SendCoin(r.Context(), username, req.ToUser, req.Amount, req.Memo, req.Category)
+	if err != nil {
+		log.Error("Ошибка usecase SendCoin", "username", logger.Sanitize(username), "error", err)
+		if errors.Is(err, usecase.ErrReadOnly) {
+			helpers.RespondWithError(w, http.StatusServiceUnavailable, err.Error())
+		} else if errors.Is(err, usecase.ErrInsufficientFunds) {
+			helpers.RespondWithError(w, h.insufficientFundsStatus(), err.Error())
+		} else if errors.Is(err, usecase.ErrInvalidAmount) ||
+			errors.Is(err, usecase.ErrMemoTooLong) ||
+			errors.Is(err, usecase.ErrInvalidCategory) ||
+			errors.Is(err, usecase.ErrSelfTransfer) ||
+			errors.Is(err, usecase.ErrReceiverNotFound) ||
+			errors.Is(err, usecase.ErrReceiverInactive) ||
+			errors.Is(err, usecase.ErrUserNotFound) {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	h.respondStateChanged(w, models.SendCoinResponse{Coins: result.SenderBalance, ToUser: result.ReceiverUsername, Amount: result.Amount})
+}
+
+// handleReverseTransfer обрабатывает запросы на отмену недавнего перевода его отправителем.
+func (h *ApiHandler) handleReverseTransfer(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleReverseTransfer", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
+
+	transactionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || transactionID <= 0 {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Неверный идентификатор перевода.")
 		return
 	}
 
-	err := h.sendCoinUseCase.SendCoin(r.Context(), username, req.ToUser, req.Amount)
+	response, err := h.sendCoinUseCase.ReverseTransfer(r.Context(), username, transactionID)
 	if err != nil {
-		log.Error("Ошибка usecase SendCoin", "username", username, "error", err)
-		if errors.Is(err, usecase.ErrInvalidAmount) ||
-			errors.Is(err, usecase.ErrInsufficientFunds) ||
-			errors.Is(err, usecase.ErrSelfTransfer) ||
+		log.Error("Ошибка usecase ReverseTransfer", "username", logger.Sanitize(username), "transactionID", transactionID, "error", err)
+		if errors.Is(err, usecase.ErrReadOnly) {
+			helpers.RespondWithError(w, http.StatusServiceUnavailable, err.Error())
+		} else if errors.Is(err, usecase.ErrTransferNotFound) {
+			helpers.RespondWithError(w, http.StatusNotFound, err.Error())
+		} else if errors.Is(err, usecase.ErrNotTransferSender) {
+			helpers.RespondWithError(w, http.StatusForbidden, err.Error())
+		} else if errors.Is(err, usecase.ErrTransferNotReversible) ||
+			errors.Is(err, usecase.ErrReversalWindowExpired) ||
+			errors.Is(err, usecase.ErrReceiverInsufficientFunds) ||
+			errors.Is(err, usecase.ErrUserNotFound) {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	h.respondStateChanged(w, response)
+}
+
+// handleGift обрабатывает запросы на передачу предметов другому пользователю.
+func (h *ApiHandler) handleGift(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleGift", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.GiftRequest
+	if !helpers.DecodeJSONBody(w, r, &req) {
+		return
+	}
+	defer r.Body.Close()
+
+	err := h.giftUseCase.GiftItem(r.Context(), username, req.ToUser, req.Item, req.Quantity)
+	if err != nil {
+		log.Error("Ошибка usecase GiftItem", "username", logger.Sanitize(username), "error", err)
+		if errors.Is(err, usecase.ErrReadOnly) {
+			helpers.RespondWithError(w, http.StatusServiceUnavailable, err.Error())
+		} else if errors.Is(err, usecase.ErrNotEnoughItems) ||
+			errors.Is(err, usecase.ErrSelfGift) ||
 			errors.Is(err, usecase.ErrReceiverNotFound) ||
+			errors.Is(err, usecase.ErrItemRequired) ||
+			errors.Is(err, usecase.ErrItemNameLength) ||
+			errors.Is(err, usecase.ErrInvalidQuantity) ||
+			errors.Is(err, usecase.ErrItemQuantityCapped) ||
 			errors.Is(err, usecase.ErrUserNotFound) {
 			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
 		} else {
@@ -106,9 +414,12 @@ func (h *ApiHandler) handleSendCoin(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleBuyItem обрабатывает запросы на покупку предмета за монеты.
+// Название предмета должно быть одним сегментом пути: запросы вида
+// /api/buy/a/b (в том числе с закодированным %2F) отклоняются с кодом 400,
+// а не передаются в usecase как предмет "a/b".
 func (h *ApiHandler) handleBuyItem(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
-	log.Debug("Обработка запроса handleBuyItem", "path", r.URL.Path, "method", r.Method)
+	log.Debug("Обработка запроса handleBuyItem", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
 
 	itemPath := strings.TrimPrefix(r.URL.Path, "/api/buy/")
 
@@ -116,15 +427,44 @@ func (h *ApiHandler) handleBuyItem(w http.ResponseWriter, r *http.Request) {
 		helpers.RespondWithError(w, http.StatusBadRequest, "Название предмета обязательно в пути /api/buy/{itemName}")
 		return
 	}
+	// Название из одних пробелов (например, /api/buy/%20) считается отсутствующим.
+	if strings.TrimSpace(itemPath) == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, usecase.ErrItemRequired.Error())
+		return
+	}
+	if strings.Contains(itemPath, "/") {
+		log.Warn("Название предмета содержит несколько сегментов пути", "item", logger.Sanitize(itemPath))
+		helpers.RespondWithError(w, http.StatusBadRequest, "Название предмета не может содержать '/'")
+		return
+	}
+
+	quantity, err := buyQuantity(r)
+	if err != nil {
+		log.Warn("Неверное количество в запросе handleBuyItem", "error", err)
+		helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	username := helpers.UsernameFromContext(r.Context())
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
 
-	err := h.buyItemUseCase.BuyItem(r.Context(), username, itemPath)
+	response, err := h.buyItemUseCase.BuyItem(r.Context(), username, itemPath, quantity)
 	if err != nil {
-		log.Error("Ошибка usecase BuyItem", "username", username, "item", itemPath, "error", err)
-		if errors.Is(err, usecase.ErrItemNotFound) ||
-			errors.Is(err, usecase.ErrItemRequired) ||
-			errors.Is(err, usecase.ErrNotEnoughCoins) ||
+		log.Error("Ошибка usecase BuyItem", "username", logger.Sanitize(username), "item", logger.Sanitize(itemPath), "quantity", quantity, "error", err)
+		if errors.Is(err, usecase.ErrReadOnly) {
+			helpers.RespondWithError(w, http.StatusServiceUnavailable, err.Error())
+		} else if errors.Is(err, usecase.ErrNotEnoughCoins) {
+			helpers.RespondWithError(w, h.insufficientFundsStatus(), err.Error())
+		} else if errors.Is(err, usecase.ErrItemNotFound) {
+			helpers.RespondWithError(w, h.itemNotFoundStatus(), err.Error())
+		} else if errors.Is(err, usecase.ErrItemRequired) ||
+			errors.Is(err, usecase.ErrItemNameLength) ||
+			errors.Is(err, usecase.ErrInvalidQuantity) ||
+			errors.Is(err, usecase.ErrQuantityTooLarge) ||
+			errors.Is(err, usecase.ErrTotalTooLarge) ||
+			errors.Is(err, usecase.ErrItemQuantityCapped) ||
 			errors.Is(err, usecase.ErrUserNotFound) {
 			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
 		} else {
@@ -132,33 +472,492 @@ func (h *ApiHandler) handleBuyItem(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	helpers.RespondWithOK(w)
+	h.respondStateChanged(w, response)
+}
+
+// handleItems обрабатывает запросы на получение каталога товаров.
+// Каталог меняется редко, поэтому поддерживаются условные запросы If-None-Match
+// и If-Modified-Since: если каталог не изменился, возвращается 304 Not Modified без тела.
+func (h *ApiHandler) handleItems(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleItems", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	catalog, err := h.buyItemUseCase.GetCatalog(r.Context())
+	if err != nil {
+		log.Error("Ошибка usecase GetCatalog", "error", err)
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		return
+	}
+
+	etag := `"` + catalog.Version + `"`
+	w.Header().Set("ETag", etag)
+	// Ответ зависит от авторизации: кешировать только у клиента и перепроверять при каждом запросе.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if !catalog.UpdatedAt.IsZero() {
+		w.Header().Set("Last-Modified", catalog.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, catalog.UpdatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respondList(h, w, catalog, catalog.Items, wholeList(len(catalog.Items)))
+}
+
+// notModified сообщает, что у клиента уже есть актуальная версия ресурса с тегом etag,
+// измененного в момент modified. If-None-Match имеет приоритет над If-Modified-Since
+// (RFC 9110, раздел 13.2.2); теги сравниваются слабым сравнением.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if values := r.Header.Values("If-None-Match"); len(values) > 0 {
+		for _, value := range values {
+			for _, tag := range strings.Split(value, ",") {
+				tag = strings.TrimSpace(tag)
+				if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.IsZero() {
+		return false
+	}
+	// Last-Modified передается с точностью до секунды.
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// handleCartQuote обрабатывает запросы на расчет стоимости корзины без покупки.
+func (h *ApiHandler) handleCartQuote(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleCartQuote", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	var req models.CartQuoteRequest
+	if !helpers.DecodeJSONBody(w, r, &req) {
+		return
+	}
+	defer r.Body.Close()
+
+	response, err := h.buyItemUseCase.QuoteCart(r.Context(), req.Items)
+	if err != nil {
+		log.Error("Ошибка usecase QuoteCart", "count", len(req.Items), "error", err)
+		if errors.Is(err, usecase.ErrItemNotFound) {
+			helpers.RespondWithError(w, h.itemNotFoundStatus(), err.Error())
+		} else if errors.Is(err, usecase.ErrEmptyCart) ||
+			errors.Is(err, usecase.ErrCartTooLarge) ||
+			errors.Is(err, usecase.ErrItemRequired) ||
+			errors.Is(err, usecase.ErrItemNameLength) ||
+			errors.Is(err, usecase.ErrInvalidQuantity) ||
+			errors.Is(err, usecase.ErrQuantityTooLarge) ||
+			errors.Is(err, usecase.ErrTotalTooLarge) {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// Ошибки разбора количества покупаемых предметов.
+var (
+	errQuantityNotInteger = errors.New("параметр qty должен быть целым числом")
+	errQuantityConflict   = errors.New("количество в параметре qty и в теле запроса не совпадает")
+)
+
+// buyQuantity определяет количество покупаемых предметов из параметра запроса qty
+// и поля quantity тела запроса. Если указаны оба значения, они должны совпадать.
+// Если количество не указано, покупается один предмет.
+func buyQuantity(r *http.Request) (int, error) {
+	var fromQuery *int
+	if qty := r.URL.Query().Get("qty"); qty != "" {
+		n, err := strconv.Atoi(qty)
+		if err != nil {
+			return 0, errQuantityNotInteger
+		}
+		fromQuery = &n
+	}
+
+	var req models.BuyItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return 0, errors.New(helpers.DecodeErrorMessage(err))
+	}
+
+	switch {
+	case fromQuery != nil && req.Quantity != nil && *fromQuery != *req.Quantity:
+		return 0, errQuantityConflict
+	case req.Quantity != nil:
+		return *req.Quantity, nil
+	case fromQuery != nil:
+		return *fromQuery, nil
+	default:
+		return 1, nil
+	}
+}
+
+// insufficientFundsStatus возвращает код ответа для ошибок нехватки монет.
+func (h *ApiHandler) insufficientFundsStatus() int {
+	if h.cfg.PaymentRequired {
+		return http.StatusPaymentRequired
+	}
+	return http.StatusBadRequest
+}
+
+// respondStateChanged отправляет ответ на успешное изменение состояния:
+// 200 с телом response или 204 без тела, если это включено в конфигурации.
+func (h *ApiHandler) respondStateChanged(w http.ResponseWriter, response any) {
+	if h.cfg.NoContentOnSuccess {
+		helpers.RespondWithNoContent(w)
+		return
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// respondList отправляет ответ со списком items. При включенной настройке PaginationEnvelope
+// список оборачивается в {data, page}, иначе отправляется прежний ответ legacy.
+func respondList[T any](h *ApiHandler, w http.ResponseWriter, legacy any, items []T, page models.PageInfo) {
+	if !h.cfg.PaginationEnvelope {
+		helpers.RespondWithJSON(w, http.StatusOK, legacy)
+		return
+	}
+	if items == nil {
+		items = []T{}
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, models.PageResponse{Data: items, Page: page})
+}
+
+// wholeList возвращает параметры страницы для списка из total элементов, возвращаемого целиком.
+func wholeList(total int) models.PageInfo {
+	return models.PageInfo{Limit: total, Offset: 0, Total: total}
+}
+
+// itemNotFoundStatus возвращает код ответа при покупке несуществующего товара.
+func (h *ApiHandler) itemNotFoundStatus() int {
+	if h.cfg.ItemNotFound404 {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
 }
 
 // handleAuth обрабатывает запросы аутентификации.
 func (h *ApiHandler) handleAuth(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
-	log.Debug("Обработка запроса handleAuth", "path", r.URL.Path, "method", r.Method)
+	log.Debug("Обработка запроса handleAuth", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
 
 	var req models.AuthRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Error("Ошибка декодирования запроса handleAuth", "error", err)
-		helpers.RespondWithError(w, http.StatusBadRequest, "Неверный запрос.")
+	if !helpers.DecodeJSONBody(w, r, &req) {
 		return
 	}
 	defer r.Body.Close()
 
-	token, err := h.userUseCase.Auth(r.Context(), req.Username, req.Password)
+	token, registered, err := h.userUseCase.Auth(r.Context(), req.Username, req.Password)
 	if err != nil {
-		log.Warn("Ошибка аутентификации", "username", req.Username, "error", err)
-		if errors.Is(err, usecase.ErrInvalidPassword) {
+		log.Warn("Ошибка аутентификации", "username", logger.Sanitize(req.Username), "error", err)
+		switch {
+		case errors.Is(err, usecase.ErrInvalidPassword):
+			h.authFailures.Inc(metrics.AuthFailureInvalidPassword)
+		case errors.Is(err, usecase.ErrUserDeactivated):
+			h.authFailures.Inc(metrics.AuthFailureDeactivated)
+		}
+		if errors.Is(err, usecase.ErrInvalidPassword) || errors.Is(err, usecase.ErrUserDeactivated) {
 			helpers.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		} else if errors.Is(err, usecase.ErrReadOnly) {
+			helpers.RespondWithError(w, http.StatusServiceUnavailable, err.Error())
+		} else if errors.Is(err, usecase.ErrReservedUsername) {
+			helpers.RespondWithError(w, http.StatusForbidden, err.Error())
+		} else if errors.Is(err, usecase.ErrInvalidRequest) {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
 		} else {
 			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
 		}
 		return
 	}
 
+	// При регистрации клиент сразу получает начальный баланс без запроса /api/info.
+	if registered != nil {
+		helpers.RespondWithJSON(w, http.StatusOK, models.RegisterResponse{Token: token, Created: true, User: *registered})
+		return
+	}
 	response := models.AuthResponse{Token: token}
 	helpers.RespondWithJSON(w, http.StatusOK, response)
 }
+
+// handleValidateToken проверяет токен для внешних сервисов (шлюзов, sidecar-прокси).
+// Токен берется из заголовка Authorization: Bearer <token>, а при его отсутствии из тела запроса.
+func (h *ApiHandler) handleValidateToken(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleValidateToken", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	var token string
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		var ok bool
+		if token, ok = bearerToken(authHeader); !ok {
+			helpers.RespondWithError(w, http.StatusUnauthorized, "Не авторизован: ожидается схема Bearer")
+			return
+		}
+	} else {
+		var req models.ValidateTokenRequest
+		if !helpers.DecodeJSONBody(w, r, &req) {
+			return
+		}
+		defer r.Body.Close()
+		token = req.Token
+	}
+	if token == "" {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Не авторизован: токен не передан")
+		return
+	}
+
+	claims, err := h.userUseCase.ParseJWTToken(token)
+	if err != nil {
+		log.Warn("Недействительный токен", "error", err)
+		if errors.Is(err, usecase.ErrTokenExpired) {
+			helpers.RespondWithError(w, http.StatusUnauthorized, "Не авторизован: срок действия токена истек")
+		} else {
+			helpers.RespondWithError(w, http.StatusUnauthorized, "Не авторизован: недействительный токен")
+		}
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, models.ValidateTokenResponse{
+		Username: claims.Username,
+		Exp:      claims.ExpiresAt.Unix(),
+	})
+}
+
+// handleRefresh выдает новый токен взамен переданного в заголовке Authorization: Bearer <token>.
+// Маршрут не защищен AuthMiddleware: токен, истекший в пределах окна обновления, здесь еще принимается.
+func (h *ApiHandler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleRefresh", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	token, ok := bearerToken(r.Header.Get("Authorization"))
+	if !ok || token == "" {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Не авторизован: ожидается заголовок Authorization: Bearer <token>")
+		return
+	}
+
+	refreshed, err := h.userUseCase.RefreshToken(r.Context(), token)
+	if err != nil {
+		log.Warn("Ошибка обновления токена", "error", err)
+		switch {
+		case errors.Is(err, usecase.ErrTokenExpired):
+			helpers.RespondWithError(w, http.StatusUnauthorized, "Не авторизован: срок действия токена истек, войдите заново")
+		case errors.Is(err, usecase.ErrTokenUserNotFound), errors.Is(err, usecase.ErrSessionExpired):
+			helpers.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		case errors.Is(err, usecase.ErrRefreshTooEarly):
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, usecase.ErrUnauthorized):
+			helpers.RespondWithError(w, http.StatusUnauthorized, "Не авторизован: недействительный токен")
+		default:
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, models.RefreshResponse{Token: refreshed})
+}
+
+// bearerToken извлекает токен из значения заголовка Authorization со схемой Bearer.
+func bearerToken(authHeader string) (string, bool) {
+	scheme, credentials, _ := strings.Cut(strings.TrimSpace(authHeader), " ")
+	if !strings.EqualFold(scheme, middlewares.SchemeBearer) {
+		return "", false
+	}
+	return strings.TrimSpace(credentials), true
+}
+
+// handleClaimBonus обрабатывает запросы на получение ежедневного бонуса.
+func (h *ApiHandler) handleClaimBonus(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleClaimBonus", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := h.bonusUseCase.ClaimDailyBonus(r.Context(), username)
+	if err != nil {
+		log.Error("Ошибка usecase ClaimDailyBonus", "username", logger.Sanitize(username), "error", err)
+		if errors.Is(err, usecase.ErrReadOnly) {
+			helpers.RespondWithError(w, http.StatusServiceUnavailable, err.Error())
+		} else if errors.Is(err, usecase.ErrBonusAlreadyClaimed) {
+			helpers.RespondWithError(w, http.StatusConflict, err.Error())
+		} else if errors.Is(err, usecase.ErrUserNotFound) {
+			helpers.RespondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// handleResetPassword обрабатывает запросы администратора на сброс пароля пользователя.
+func (h *ApiHandler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleResetPassword", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username := r.PathValue("username")
+
+	// Тело запроса необязательно: без пароля будет сгенерирован временный.
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.Error("Ошибка декодирования запроса handleResetPassword", "error", err)
+		helpers.RespondWithError(w, http.StatusBadRequest, "Неверный запрос.")
+		return
+	}
+	defer r.Body.Close()
+
+	temporaryPassword, err := h.adminUseCase.ResetPassword(r.Context(), username, req.Password)
+	if err != nil {
+		log.Error("Ошибка usecase ResetPassword", "username", logger.Sanitize(username), "error", err)
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			helpers.RespondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	if temporaryPassword == "" {
+		helpers.RespondWithOK(w)
+		return
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, models.ResetPasswordResponse{TemporaryPassword: temporaryPassword})
+}
+
+// handleDeactivateUser обрабатывает запросы администратора на деактивацию пользователя.
+func (h *ApiHandler) handleDeactivateUser(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleDeactivateUser", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username := r.PathValue("username")
+
+	err := h.adminUseCase.DeactivateUser(r.Context(), username)
+	if err != nil {
+		log.Error("Ошибка usecase DeactivateUser", "username", logger.Sanitize(username), "error", err)
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			helpers.RespondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+	helpers.RespondWithOK(w)
+}
+
+// handleTransactionsBetween обрабатывает запросы администратора на получение переводов
+// между двумя пользователями, указанными в параметрах a и b.
+func (h *ApiHandler) handleTransactionsBetween(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleTransactionsBetween", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	usernameA := r.URL.Query().Get("a")
+	usernameB := r.URL.Query().Get("b")
+
+	response, err := h.adminUseCase.GetTransactionsBetween(r.Context(), usernameA, usernameB)
+	if err != nil {
+		log.Error("Ошибка usecase GetTransactionsBetween", "usernameA", logger.Sanitize(usernameA), "usernameB", logger.Sanitize(usernameB), "error", err)
+		if errors.Is(err, usecase.ErrTransactionPartiesRequired) {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	respondList(h, w, response, response.Transactions, wholeList(len(response.Transactions)))
+}
+
+// handleUpdateItemPrices обрабатывает запросы администратора на изменение цен товаров.
+// Тело запроса содержит массив [{item_name, price}], цены применяются атомарно.
+func (h *ApiHandler) handleUpdateItemPrices(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleUpdateItemPrices", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	var prices []models.ItemPriceUpdate
+	if err := json.NewDecoder(r.Body).Decode(&prices); err != nil {
+		log.Warn("Ошибка декодирования запроса handleUpdateItemPrices", "error", err)
+		helpers.RespondWithError(w, http.StatusBadRequest, helpers.DecodeErrorMessage(err))
+		return
+	}
+	defer r.Body.Close()
+
+	response, err := h.adminUseCase.UpdateItemPrices(r.Context(), prices)
+	if err != nil {
+		log.Error("Ошибка usecase UpdateItemPrices", "count", len(prices), "error", err)
+		if errors.Is(err, usecase.ErrInvalidItemPrices) {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// handleAdminAudit обрабатывает запросы администратора на просмотр журнала действий администраторов.
+// Параметры limit и offset задают страницу журнала.
+func (h *ApiHandler) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleAdminAudit", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	limit, ok := queryInt(w, r, "limit")
+	if !ok {
+		return
+	}
+	offset, ok := queryInt(w, r, "offset")
+	if !ok {
+		return
+	}
+
+	response, err := h.adminUseCase.GetAudit(r.Context(), limit, offset)
+	if err != nil {
+		log.Error("Ошибка usecase GetAudit", "limit", limit, "offset", offset, "error", err)
+		if errors.Is(err, usecase.ErrInvalidPagination) {
+			helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	respondList(h, w, response, response.Entries, models.PageInfo{Limit: response.Limit, Offset: response.Offset, Total: response.Total})
+}
+
+// handleDebugInfo обрабатывает запросы администратора на получение отладочной информации:
+// версий Go и PostgreSQL и времени работы сервиса.
+func (h *ApiHandler) handleDebugInfo(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleDebugInfo", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	response, err := h.adminUseCase.DebugInfo(r.Context())
+	if err != nil {
+		log.Error("Ошибка usecase DebugInfo", "error", err)
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// queryInt разбирает необязательный целочисленный параметр запроса, отсутствующий параметр равен 0.
+// При ошибке отправляет ответ 400 и возвращает false.
+func queryInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return 0, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Параметр "+name+" должен быть целым числом")
+		return 0, false
+	}
+	return value, true
+}
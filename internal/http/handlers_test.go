@@ -4,17 +4,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"shop/internal/config"
+	"shop/internal/metrics"
 	"shop/internal/models"
 	"shop/internal/usecase"
 	ucmocks "shop/internal/usecase/mocks"
 	"shop/pkg/logger"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -22,6 +32,9 @@ var (
 	mockUserUseCase     *ucmocks.MockUserUseCaseInterface
 	mockSendCoinUseCase *ucmocks.MockSendCoinUseCaseInterface
 	mockBuyItemUseCase  *ucmocks.MockBuyItemUseCaseInterface
+	mockAdminUseCase    *ucmocks.MockAdminUseCaseInterface
+	mockBonusUseCase    *ucmocks.MockBonusUseCaseInterface
+	mockGiftUseCase     *ucmocks.MockGiftUseCaseInterface
 	// Обработчик API
 	handler *ApiHandler
 	// Контроллер для моков
@@ -38,7 +51,10 @@ func setupHandlerTest(t *testing.T) {
 	mockUserUseCase = ucmocks.NewMockUserUseCaseInterface(ctrl)
 	mockSendCoinUseCase = ucmocks.NewMockSendCoinUseCaseInterface(ctrl)
 	mockBuyItemUseCase = ucmocks.NewMockBuyItemUseCaseInterface(ctrl)
-	handler = NewApiHandler(mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, log)
+	mockAdminUseCase = ucmocks.NewMockAdminUseCaseInterface(ctrl)
+	mockBonusUseCase = ucmocks.NewMockBonusUseCaseInterface(ctrl)
+	mockGiftUseCase = ucmocks.NewMockGiftUseCaseInterface(ctrl)
+	handler = NewApiHandler(config.APIConfig{}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
 }
 
 // Функция завершения окружения для тестирования обработчиков.
@@ -61,7 +77,7 @@ func TestApiHandler_handleInfo_Success(t *testing.T) {
 	}
 
 	// Ожидаем вызов метода GetUserInfo usecase'а с любым контекстом и именем пользователя "testuser".
-	mockUserUseCase.EXPECT().GetUserInfo(gomock.Any(), "testuser").Return(expectedResponse, nil)
+	mockUserUseCase.EXPECT().GetUserInfo(gomock.Any(), "testuser", usecase.InfoOptions{}).Return(expectedResponse, nil)
 
 	// Создаем тестовый запрос.
 	req := httptest.NewRequest("GET", "/api/info", nil)
@@ -85,12 +101,98 @@ func TestApiHandler_handleInfo_Success(t *testing.T) {
 	assert.Equal(t, expectedResponse, &actualResponse, "Тело ответа должно соответствовать ожидаемому")
 }
 
+func TestApiHandler_handleInfo_Fields(t *testing.T) {
+	fullResponse := &models.InfoResponse{
+		Coins:       100,
+		Inventory:   []models.InventoryItem{{Type: "cup", Quantity: 1}},
+		ItemCount:   1,
+		CoinHistory: models.CoinHistory{Received: []models.Transaction{}, Sent: []models.Transaction{}},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		opts           usecase.InfoOptions
+		expectedStatus int
+		expectedKeys   []string
+	}{
+		{"по умолчанию все разделы", "", usecase.InfoOptions{}, http.StatusOK, []string{"coins", "inventory", "itemCount", "coinHistory"}},
+		{
+			"только баланс", "?fields=coins",
+			usecase.InfoOptions{Sections: map[usecase.InfoSection]bool{usecase.InfoSectionCoins: true}},
+			http.StatusOK, []string{"coins"},
+		},
+		{"неизвестный раздел", "?fields=coins,secret", usecase.InfoOptions{}, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			if tt.expectedStatus == http.StatusOK {
+				mockUserUseCase.EXPECT().GetUserInfo(gomock.Any(), "testuser", tt.opts).Return(fullResponse, nil)
+			}
+
+			req := httptest.NewRequest("GET", "/api/info"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleInfo(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedKeys == nil {
+				return
+			}
+			var body map[string]json.RawMessage
+			assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&body))
+			keys := []string{}
+			for key := range body {
+				keys = append(keys, key)
+			}
+			assert.ElementsMatch(t, tt.expectedKeys, keys)
+		})
+	}
+}
+
+func TestApiHandler_handleInfo_Details(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		withItemDetails bool
+		expectedStatus  int
+	}{
+		{"по умолчанию без подробностей", "", false, http.StatusOK},
+		{"с подробностями", "?details=true", true, http.StatusOK},
+		{"некорректное значение", "?details=maybe", false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			if tt.expectedStatus == http.StatusOK {
+				mockUserUseCase.EXPECT().GetUserInfo(gomock.Any(), "testuser", usecase.InfoOptions{ItemDetails: tt.withItemDetails}).Return(&models.InfoResponse{}, nil)
+			}
+
+			req := httptest.NewRequest("GET", "/api/info"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleInfo(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+		})
+	}
+}
+
 func TestApiHandler_handleInfo_UserNotFound(t *testing.T) {
 	setupHandlerTest(t)
 	defer teardownHandlerTest()
 
 	// Ожидаем, что GetUserInfo вернет ошибку ErrUserNotFound.
-	mockUserUseCase.EXPECT().GetUserInfo(gomock.Any(), "testuser").Return(nil, usecase.ErrUserNotFound)
+	mockUserUseCase.EXPECT().GetUserInfo(gomock.Any(), "testuser", usecase.InfoOptions{}).Return(nil, usecase.ErrUserNotFound)
 
 	req := httptest.NewRequest("GET", "/api/info", nil)
 	reqCtx := context.WithValue(req.Context(), "username", "testuser")
@@ -109,12 +211,82 @@ func TestApiHandler_handleInfo_UserNotFound(t *testing.T) {
 	assert.Contains(t, errorResponse.Errors, "пользователь не найден", "Сообщение об ошибке должно быть корректным")
 }
 
+func TestApiHandler_handleHistory_Errors(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	request := func() *http.Request {
+		req := httptest.NewRequest("GET", "/api/history", nil)
+		return req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+	}
+
+	// Ошибка до первого перевода возвращается кодом ответа.
+	mockUserUseCase.EXPECT().StreamCoinHistory(gomock.Any(), "testuser", "", gomock.Any()).Return(errors.New("база недоступна"))
+	recorder := httptest.NewRecorder()
+	handler.handleHistory(recorder, request())
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+	// После начала ответа код уже отправлен: массив остается незакрытым, чтобы клиент
+	// не принял обрезанную историю за полную.
+	mockUserUseCase.EXPECT().StreamCoinHistory(gomock.Any(), "testuser", "", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, fn func(models.Transaction) error) error {
+			require.NoError(t, fn(models.Transaction{FromUser: "bob", Amount: 5}))
+			return errors.New("соединение с базой разорвано")
+		})
+	recorder = httptest.NewRecorder()
+	handler.handleHistory(recorder, request())
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var history []models.Transaction
+	assert.Error(t, json.Unmarshal(recorder.Body.Bytes(), &history))
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), `[{"fromUser":"bob","amount":5}`), recorder.Body.String())
+}
+
+func TestApiHandler_handleInfo_NoUsernameInContext(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	// Обработчик вызван без AuthMiddleware: usecase не вызывается с пустым именем
+	// (мок завершил бы тест при неожиданном вызове), клиент получает 401.
+	req := httptest.NewRequest("GET", "/api/info", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.handleInfo(recorder, req)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	var errorResponse models.ErrorResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&errorResponse))
+	assert.Equal(t, "Не авторизован: пользователь не определен", errorResponse.Errors)
+}
+
+func TestApiHandler_ProtectedHandlers_NoUsernameInContext(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	handlers := map[string]http.HandlerFunc{
+		"inventory":   handler.handleInventory,
+		"statement":   handler.handleStatement,
+		"rank":        handler.handleRank,
+		"history":     handler.handleHistory,
+		"sendCoin":    handler.handleSendCoin,
+		"buy/pen":     handler.handleBuyItem,
+		"gift":        handler.handleGift,
+		"claim-bonus": handler.handleClaimBonus,
+	}
+	for name, handle := range handlers {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handle(recorder, httptest.NewRequest("POST", "/api/"+name, strings.NewReader(`{}`)))
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		})
+	}
+}
+
 func TestApiHandler_handleSendCoin_Success(t *testing.T) {
 	setupHandlerTest(t)
 	defer teardownHandlerTest()
 
 	// Ожидаем вызов метода SendCoin.
-	mockSendCoinUseCase.EXPECT().SendCoin(gomock.Any(), "senderUser", "receiverUser", 50).Return(nil)
+	mockSendCoinUseCase.EXPECT().SendCoin(gomock.Any(), "senderUser", "receiverUser", int64(50), "", "").Return(&models.SendCoinResult{SenderBalance: 950, ReceiverUsername: "receiverUser", Amount: 50}, nil)
 
 	// Подготавливаем тело запроса.
 	requestBody := models.SendCoinRequest{
@@ -130,6 +302,28 @@ func TestApiHandler_handleSendCoin_Success(t *testing.T) {
 	handler.handleSendCoin(recorder, req)
 
 	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
+
+	// В ответе баланс отправителя после перевода, получатель и сумма.
+	var response models.SendCoinResponse
+	err := json.NewDecoder(recorder.Body).Decode(&response)
+	assert.NoError(t, err, "Ошибка при декодировании ответа")
+	assert.Equal(t, models.SendCoinResponse{Coins: 950, ToUser: "receiverUser", Amount: 50}, response)
+}
+
+func TestApiHandler_handleSendCoin_Memo(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	mockSendCoinUseCase.EXPECT().SendCoin(gomock.Any(), "senderUser", "receiverUser", int64(50), "обед", "").Return(&models.SendCoinResult{SenderBalance: 950, ReceiverUsername: "receiverUser", Amount: 50}, nil)
+
+	jsonBody, _ := json.Marshal(models.SendCoinRequest{ToUser: "receiverUser", Amount: 50, Memo: "обед"})
+	req := httptest.NewRequest("POST", "/api/sendCoin", bytes.NewBuffer(jsonBody))
+	req = req.WithContext(context.WithValue(req.Context(), "username", "senderUser"))
+	recorder := httptest.NewRecorder()
+
+	handler.handleSendCoin(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
 }
 
 func TestApiHandler_handleSendCoin_InvalidAmount(t *testing.T) {
@@ -149,11 +343,76 @@ func TestApiHandler_handleSendCoin_InvalidAmount(t *testing.T) {
 
 	handler.handleSendCoin(recorder, req)
 
-	// Проверяем код статуса (400) и сообщение об ошибке.
+	// Проверяем код статуса (400) и сообщение об ошибке с названием поля.
 	assert.Equal(t, http.StatusBadRequest, recorder.Code, "Код статуса должен быть 400 Bad Request")
 	var errorResponse models.ErrorResponse
 	json.NewDecoder(recorder.Body).Decode(&errorResponse)
-	assert.Contains(t, errorResponse.Errors, "Неверный запрос.", "Сообщение об ошибке должно быть корректным")
+	assert.Contains(t, errorResponse.Errors, "поле amount должно быть не меньше 1", "Сообщение об ошибке должно быть корректным")
+}
+
+func TestApiHandler_handleSendCoin_DecodeErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		expectedMessage string
+	}{
+		{"пустое тело", "", "Пустое тело запроса."},
+		{"некорректный JSON", `{"toUser": "receiverUser",`, "Некорректный JSON."},
+		{"синтаксическая ошибка", `{toUser}`, "Некорректный JSON."},
+		{"неверный тип суммы", `{"toUser": "receiverUser", "amount": "50"}`, "Неверный тип поля amount: ожидается int."},
+		{"неверный тип получателя", `{"toUser": 1, "amount": 50}`, "Неверный тип поля toUser: ожидается string."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			req := httptest.NewRequest("POST", "/api/sendCoin", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "username", "senderUser"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleSendCoin(recorder, req)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, "Код статуса должен быть 400 Bad Request")
+			var errorResponse models.ErrorResponse
+			json.NewDecoder(recorder.Body).Decode(&errorResponse)
+			assert.Equal(t, tt.expectedMessage, errorResponse.Errors, "Сообщение об ошибке должно быть корректным")
+		})
+	}
+}
+
+func TestApiHandler_handleSendCoin_InsufficientFunds(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            config.APIConfig
+		expectedStatus int
+	}{
+		{"по умолчанию 400", config.APIConfig{}, http.StatusBadRequest},
+		{"402 при включенной настройке", config.APIConfig{PaymentRequired: true}, http.StatusPaymentRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+			handler = NewApiHandler(tt.cfg, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+			mockSendCoinUseCase.EXPECT().SendCoin(gomock.Any(), "senderUser", "receiverUser", int64(5000), "", "").Return(nil, usecase.ErrInsufficientFunds)
+
+			jsonBody, _ := json.Marshal(models.SendCoinRequest{ToUser: "receiverUser", Amount: 5000})
+			req := httptest.NewRequest("POST", "/api/sendCoin", bytes.NewBuffer(jsonBody))
+			req = req.WithContext(context.WithValue(req.Context(), "username", "senderUser"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleSendCoin(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			var errorResponse models.ErrorResponse
+			json.NewDecoder(recorder.Body).Decode(&errorResponse)
+			assert.Contains(t, errorResponse.Errors, "недостаточно монет", "Сообщение об ошибке должно быть корректным")
+		})
+	}
 }
 
 func TestApiHandler_handleBuyItem_Success(t *testing.T) {
@@ -161,7 +420,7 @@ func TestApiHandler_handleBuyItem_Success(t *testing.T) {
 	defer teardownHandlerTest()
 
 	// Ожидаем вызов метода BuyItem
-	mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), "testuser", "pen").Return(nil)
+	mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), "testuser", "pen", 1).Return(&models.BuyItemResponse{Coins: 980, Quantity: 3}, nil)
 
 	req := httptest.NewRequest("POST", "/api/buy/pen", nil)
 	reqCtx := context.WithValue(req.Context(), "username", "testuser")
@@ -173,12 +432,141 @@ func TestApiHandler_handleBuyItem_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
 }
 
+func TestApiHandler_StateChangeSuccessStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            config.APIConfig
+		expectedStatus int
+	}{
+		{"по умолчанию 200 с телом", config.APIConfig{}, http.StatusOK},
+		{"204 при включенной настройке", config.APIConfig{NoContentOnSuccess: true}, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+			handler = NewApiHandler(tt.cfg, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+			mockSendCoinUseCase.EXPECT().SendCoin(gomock.Any(), "testuser", "receiver", int64(50), "", "").Return(&models.SendCoinResult{SenderBalance: 950, ReceiverUsername: "receiver", Amount: 50}, nil)
+			mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), "testuser", "pen", 1).Return(&models.BuyItemResponse{Coins: 940, Quantity: 1}, nil)
+
+			jsonBody, _ := json.Marshal(models.SendCoinRequest{ToUser: "receiver", Amount: 50})
+			requests := map[string]*http.Request{
+				"sendCoin": httptest.NewRequest("POST", "/api/sendCoin", bytes.NewBuffer(jsonBody)),
+				"buy":      httptest.NewRequest("POST", "/api/buy/pen", nil),
+			}
+			handlers := map[string]http.HandlerFunc{
+				"sendCoin": handler.handleSendCoin,
+				"buy":      handler.handleBuyItem,
+			}
+
+			for name, req := range requests {
+				req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+				recorder := httptest.NewRecorder()
+
+				handlers[name](recorder, req)
+
+				assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса %s", name)
+				if tt.expectedStatus == http.StatusNoContent {
+					assert.Empty(t, recorder.Body.String(), "Ответ 204 %s не должен содержать тела", name)
+				} else {
+					assert.NotEmpty(t, recorder.Body.String(), "Ответ 200 %s должен содержать баланс", name)
+				}
+			}
+		})
+	}
+}
+
+func TestApiHandler_handleBuyItem_Quantity(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		body             string
+		expectedQuantity int
+		expectedStatus   int
+		expectedMessage  string
+	}{
+		{name: "по умолчанию один предмет", expectedQuantity: 1, expectedStatus: http.StatusOK},
+		{name: "только параметр запроса", query: "?qty=3", expectedQuantity: 3, expectedStatus: http.StatusOK},
+		{name: "только тело запроса", body: `{"quantity": 4}`, expectedQuantity: 4, expectedStatus: http.StatusOK},
+		{name: "совпадающие значения", query: "?qty=2", body: `{"quantity": 2}`, expectedQuantity: 2, expectedStatus: http.StatusOK},
+		{name: "тело без количества", query: "?qty=5", body: `{}`, expectedQuantity: 5, expectedStatus: http.StatusOK},
+		{
+			name: "конфликтующие значения", query: "?qty=2", body: `{"quantity": 3}`,
+			expectedStatus: http.StatusBadRequest, expectedMessage: "не совпадает",
+		},
+		{
+			name: "нечисловой параметр", query: "?qty=many",
+			expectedStatus: http.StatusBadRequest, expectedMessage: "целым числом",
+		},
+		{
+			name: "некорректное тело", body: `{"quantity": "3"}`,
+			expectedStatus: http.StatusBadRequest, expectedMessage: "Неверный тип поля quantity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			if tt.expectedQuantity != 0 {
+				mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), "testuser", "pen", tt.expectedQuantity).Return(&models.BuyItemResponse{Coins: 990, Quantity: tt.expectedQuantity}, nil)
+			}
+
+			req := httptest.NewRequest("POST", "/api/buy/pen"+tt.query, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleBuyItem(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			if tt.expectedMessage != "" {
+				var errorResponse models.ErrorResponse
+				json.NewDecoder(recorder.Body).Decode(&errorResponse)
+				assert.Contains(t, errorResponse.Errors, tt.expectedMessage, "Сообщение об ошибке должно быть корректным")
+			}
+		})
+	}
+}
+
+func TestApiHandler_handleBuyItem_QuantityOutOfRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity int
+		ucErr    error
+	}{
+		{"отрицательное количество", -1, usecase.ErrInvalidQuantity},
+		{"нулевое количество", 0, usecase.ErrInvalidQuantity},
+		{"слишком большое количество", usecase.MaxPurchaseQuantity + 1, usecase.ErrQuantityTooLarge},
+		{"переполнение стоимости", 1000, usecase.ErrTotalTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), "testuser", "pen", tt.quantity).Return(nil, tt.ucErr)
+
+			req := httptest.NewRequest("POST", fmt.Sprintf("/api/buy/pen?qty=%d", tt.quantity), nil)
+			req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleBuyItem(recorder, req)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
+
 func TestApiHandler_handleBuyItem_ItemNotFound(t *testing.T) {
 	setupHandlerTest(t)
 	defer teardownHandlerTest()
 
 	// Ожидаем вызов метода BuyItem, который вернет ошибку ErrItemNotFound
-	mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), gomock.Any(), "nonexistent_item").Return(usecase.ErrItemNotFound)
+	mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), gomock.Any(), "nonexistent_item", 1).Return(nil, usecase.ErrItemNotFound)
 
 	req := httptest.NewRequest("POST", "/api/buy/nonexistent_item", nil)
 	reqCtx := context.WithValue(req.Context(), "username", "testuser")
@@ -193,6 +581,207 @@ func TestApiHandler_handleBuyItem_ItemNotFound(t *testing.T) {
 	assert.Contains(t, errorResponse.Errors, "товар не найден", "Сообщение об ошибке должно быть корректным")
 }
 
+func TestApiHandler_handleBuyItem_ItemNotFound404(t *testing.T) {
+	tests := []struct {
+		name           string
+		item           string
+		ucErr          error
+		expectedStatus int
+	}{
+		{"товар не найден", "nonexistent_item", usecase.ErrItemNotFound, http.StatusNotFound},
+		{"некорректное название", "invalid_name", usecase.ErrItemNameLength, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+			handler = NewApiHandler(config.APIConfig{ItemNotFound404: true}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+			mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), "testuser", tt.item, 1).Return(nil, tt.ucErr)
+
+			req := httptest.NewRequest("POST", "/api/buy/"+tt.item, nil)
+			req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleBuyItem(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
+
+func TestApiHandler_handleBuyItem_NotEnoughCoins(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            config.APIConfig
+		expectedStatus int
+	}{
+		{"по умолчанию 400", config.APIConfig{}, http.StatusBadRequest},
+		{"402 при включенной настройке", config.APIConfig{PaymentRequired: true}, http.StatusPaymentRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+			handler = NewApiHandler(tt.cfg, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+			mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), "testuser", "pink-hoody", 1).Return(nil, usecase.ErrNotEnoughCoins)
+
+			req := httptest.NewRequest("POST", "/api/buy/pink-hoody", nil)
+			req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleBuyItem(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
+
+func TestApiHandler_handleBuyItem_ItemNameTooLong(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	longItem := strings.Repeat("a", usecase.MaxItemNameLength+1)
+	mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), "testuser", longItem, 1).Return(nil, usecase.ErrItemNameLength)
+
+	req := httptest.NewRequest("POST", "/api/buy/"+longItem, nil)
+	req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+	recorder := httptest.NewRecorder()
+
+	handler.handleBuyItem(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code, "Код статуса должен быть 400 Bad Request")
+}
+
+func TestApiHandler_handleBuyItem_WhitespaceItem(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	// Мок без ожиданий: usecase не должен вызываться.
+	for _, path := range []string{"/api/buy/%20", "/api/buy/%20%09%20"} {
+		req := httptest.NewRequest("POST", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+		recorder := httptest.NewRecorder()
+
+		handler.handleBuyItem(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, "Путь %s должен отклоняться с кодом 400", path)
+		var errorResponse models.ErrorResponse
+		assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&errorResponse))
+		assert.Contains(t, errorResponse.Errors, "название предмета обязательно")
+	}
+}
+
+func TestApiHandler_handleBuyItem_MultiSegmentPath(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	// Мок без ожиданий: usecase не должен вызываться.
+	for _, path := range []string{"/api/buy/a/b", "/api/buy/a%2Fb", "/api/buy/pen/"} {
+		req := httptest.NewRequest("POST", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+		recorder := httptest.NewRecorder()
+
+		handler.handleBuyItem(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, "Путь %s должен отклоняться с кодом 400", path)
+		var errorResponse models.ErrorResponse
+		assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&errorResponse))
+		assert.Contains(t, errorResponse.Errors, "не может содержать '/'")
+	}
+}
+
+func TestApiHandler_handleCartQuote(t *testing.T) {
+	cart := []models.CartItem{{Item: "pen", Quantity: 2}, {Item: "unicorn", Quantity: 1}}
+	tests := []struct {
+		name           string
+		response       *models.CartQuoteResponse
+		ucErr          error
+		expectedStatus int
+	}{
+		{"корзина рассчитана", &models.CartQuoteResponse{Items: []models.CartQuoteItem{{Item: "pen", Quantity: 2, Price: 10, Total: 20}}, Total: 20}, nil, http.StatusOK},
+		{"неизвестный товар", nil, fmt.Errorf("товар 'unicorn': %w", usecase.ErrItemNotFound), http.StatusBadRequest},
+		{"пустая корзина", nil, usecase.ErrEmptyCart, http.StatusBadRequest},
+		{"ошибка сервера", nil, errors.New("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			mockBuyItemUseCase.EXPECT().QuoteCart(gomock.Any(), cart).Return(tt.response, tt.ucErr)
+
+			jsonBody, _ := json.Marshal(models.CartQuoteRequest{Items: cart})
+			req := httptest.NewRequest("POST", "/api/cart/quote", bytes.NewBuffer(jsonBody))
+			req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleCartQuote(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			if tt.response != nil {
+				var response models.CartQuoteResponse
+				assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+				assert.Equal(t, *tt.response, response)
+			}
+		})
+	}
+}
+
+func TestApiHandler_handleItems_Conditional(t *testing.T) {
+	updatedAt := time.Date(2025, 3, 1, 12, 30, 15, 500, time.UTC)
+	catalog := &models.Catalog{
+		Items:     []models.CatalogItem{{ItemName: "cup", Price: 20}, {ItemName: "pen", Price: 10}},
+		Version:   "abc123",
+		UpdatedAt: updatedAt,
+	}
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"без условий", nil, http.StatusOK},
+		{"тот же ETag", map[string]string{"If-None-Match": `"abc123"`}, http.StatusNotModified},
+		{"слабый ETag в списке", map[string]string{"If-None-Match": `"old", W/"abc123"`}, http.StatusNotModified},
+		{"другой ETag", map[string]string{"If-None-Match": `"old"`}, http.StatusOK},
+		{"не изменялся с указанного времени", map[string]string{"If-Modified-Since": updatedAt.Format(http.TimeFormat)}, http.StatusNotModified},
+		{"изменялся после указанного времени", map[string]string{"If-Modified-Since": updatedAt.Add(-time.Minute).Format(http.TimeFormat)}, http.StatusOK},
+		{"If-None-Match важнее If-Modified-Since", map[string]string{"If-None-Match": `"old"`, "If-Modified-Since": updatedAt.Format(http.TimeFormat)}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			mockBuyItemUseCase.EXPECT().GetCatalog(gomock.Any()).Return(catalog, nil)
+
+			req := httptest.NewRequest("GET", "/api/items", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+
+			handler.handleItems(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			assert.Equal(t, `"abc123"`, recorder.Header().Get("ETag"))
+			assert.Equal(t, "Sat, 01 Mar 2025 12:30:15 GMT", recorder.Header().Get("Last-Modified"))
+			if tt.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, recorder.Body.String(), "Ответ 304 не должен содержать тела")
+				return
+			}
+			var response models.Catalog
+			assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+			assert.Equal(t, catalog.Items, response.Items)
+		})
+	}
+}
+
 func TestApiHandler_handleAuth_Success(t *testing.T) {
 	setupHandlerTest(t)
 	defer teardownHandlerTest()
@@ -200,7 +789,7 @@ func TestApiHandler_handleAuth_Success(t *testing.T) {
 	// Ожидаемый токен.
 	expectedToken := "test_jwt_token"
 	// Ожидаем вызов метода Auth.
-	mockUserUseCase.EXPECT().Auth(gomock.Any(), "testuser", "password").Return(expectedToken, nil)
+	mockUserUseCase.EXPECT().Auth(gomock.Any(), "testuser", "password").Return(expectedToken, nil, nil)
 
 	// Подготавливаем тело запроса.
 	requestBody := models.AuthRequest{
@@ -218,6 +807,84 @@ func TestApiHandler_handleAuth_Success(t *testing.T) {
 	var response models.AuthResponse
 	json.NewDecoder(recorder.Body).Decode(&response)
 	assert.Equal(t, expectedToken, response.Token, "Токен в ответе должен соответствовать ожидаемому")
+	assert.False(t, response.Created, "Повторный вход не создает пользователя")
+}
+
+func TestApiHandler_handleAuth_Register(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	// Первый вход регистрирует пользователя.
+	mockUserUseCase.EXPECT().Auth(gomock.Any(), "newuser", "password").
+		Return("test_jwt_token", &models.UserSummary{Username: "newuser", Coins: usecase.InitialCoins}, nil)
+
+	jsonBody, _ := json.Marshal(models.AuthRequest{Username: "newuser", Password: "password"})
+	req := httptest.NewRequest("POST", "/api/auth", bytes.NewBuffer(jsonBody))
+	recorder := httptest.NewRecorder()
+
+	handler.handleAuth(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
+	var response models.RegisterResponse
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, models.RegisterResponse{
+		Token:   "test_jwt_token",
+		Created: true,
+		User:    models.UserSummary{Username: "newuser", Coins: usecase.InitialCoins},
+	}, response)
+}
+
+func TestApiHandler_RequestValidation(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		body            string
+		expectedMessage string
+	}{
+		{"auth: нет имени", "/api/auth", `{"password": "password"}`, "поле username обязательно"},
+		{"auth: нет пароля", "/api/auth", `{"username": "alice"}`, "поле password обязательно"},
+		{
+			"auth: длинное имя", "/api/auth", `{"username": "` + strings.Repeat("a", 256) + `", "password": "password"}`,
+			"длина поля username должна быть не больше 255",
+		},
+		{
+			"auth: длинный пароль", "/api/auth", `{"username": "alice", "password": "` + strings.Repeat("a", 73) + `"}`,
+			"длина поля password должна быть не больше 72",
+		},
+		{"sendCoin: нет получателя", "/api/sendCoin", `{"amount": 10}`, "поле toUser обязательно"},
+		{
+			"sendCoin: длинное имя получателя", "/api/sendCoin", `{"toUser": "` + strings.Repeat("a", 256) + `", "amount": 10}`,
+			"длина поля toUser должна быть не больше 255",
+		},
+		{"sendCoin: отрицательная сумма", "/api/sendCoin", `{"toUser": "bob", "amount": -5}`, "поле amount должно быть не меньше 1"},
+		{
+			"sendCoin: длинный комментарий", "/api/sendCoin", `{"toUser": "bob", "amount": 5, "memo": "` + strings.Repeat("a", 141) + `"}`,
+			"длина поля memo должна быть не больше 140",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			// Usecase не вызывается: запрос отклоняется на этапе валидации.
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "username", "alice"))
+			recorder := httptest.NewRecorder()
+
+			if tt.path == "/api/auth" {
+				handler.handleAuth(recorder, req)
+			} else {
+				handler.handleSendCoin(recorder, req)
+			}
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, "Код статуса должен быть 400 Bad Request")
+			var errorResponse models.ErrorResponse
+			json.NewDecoder(recorder.Body).Decode(&errorResponse)
+			assert.Equal(t, tt.expectedMessage, errorResponse.Errors, "Сообщение об ошибке должно указывать поле")
+		})
+	}
 }
 
 func TestApiHandler_handleAuth_InvalidPassword(t *testing.T) {
@@ -225,7 +892,7 @@ func TestApiHandler_handleAuth_InvalidPassword(t *testing.T) {
 	defer teardownHandlerTest()
 
 	// Ожидаем, что Auth вернет ошибку ErrInvalidPassword.
-	mockUserUseCase.EXPECT().Auth(gomock.Any(), "testuser", "wrong_password").Return("", usecase.ErrInvalidPassword)
+	mockUserUseCase.EXPECT().Auth(gomock.Any(), "testuser", "wrong_password").Return("", nil, usecase.ErrInvalidPassword)
 
 	requestBody := models.AuthRequest{
 		Username: "testuser",
@@ -243,3 +910,479 @@ func TestApiHandler_handleAuth_InvalidPassword(t *testing.T) {
 	json.NewDecoder(recorder.Body).Decode(&errorResponse)
 	assert.Contains(t, errorResponse.Errors, "неверный пароль", "Сообщение об ошибке должно быть корректным")
 }
+
+func TestApiHandler_handleAuth_NoLogInjection(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	username := "evil\ntime=2024-01-01T00:00:00Z level=ERROR msg=forged"
+	mockUserUseCase.EXPECT().Auth(gomock.Any(), username, "password").Return("", nil, usecase.ErrInvalidPassword)
+
+	jsonBody, _ := json.Marshal(models.AuthRequest{Username: username, Password: "password"})
+	req := httptest.NewRequest("POST", "/api/auth", bytes.NewBuffer(jsonBody))
+	var buf bytes.Buffer
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+	req = req.WithContext(logger.WithLogger(req.Context(), log))
+	recorder := httptest.NewRecorder()
+
+	handler.handleAuth(recorder, req)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	// Перевод строки из имени пользователя не должен порождать новую запись в логе.
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range lines {
+		assert.True(t, strings.HasPrefix(line, "time="), "Лишняя строка в логе: %q", line)
+	}
+}
+
+func TestApiHandler_handleAuth_CountsFailureReason(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	reg := prometheus.NewRegistry()
+	authFailures, err := metrics.NewAuthFailures(reg)
+	assert.NoError(t, err)
+	handler = NewApiHandler(config.APIConfig{}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, authFailures, log)
+
+	mockUserUseCase.EXPECT().Auth(gomock.Any(), "testuser", "wrong_password").Return("", nil, usecase.ErrInvalidPassword)
+
+	jsonBody, _ := json.Marshal(models.AuthRequest{Username: "testuser", Password: "wrong_password"})
+	req := httptest.NewRequest("POST", "/api/auth", bytes.NewBuffer(jsonBody))
+	recorder := httptest.NewRecorder()
+
+	handler.handleAuth(recorder, req)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	expected := `
+# HELP shop_auth_failures_total Количество неудачных попыток аутентификации по причинам.
+# TYPE shop_auth_failures_total counter
+shop_auth_failures_total{reason="invalid_password"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "shop_auth_failures_total"))
+}
+
+// newAdminMux создает маршрутизатор с обработчиком, где "admin" является администратором.
+func newAdminMux() *http.ServeMux {
+	handler = NewApiHandler(config.APIConfig{AdminUsernames: []string{"admin"}}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	return mux
+}
+
+func TestApiHandler_handleResetPassword_Admin(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+	mux := newAdminMux()
+
+	// Администратор сбрасывает пароль без указания нового: генерируется временный.
+	mockUserUseCase.EXPECT().VerifyJWTToken("admin_token").Return("admin", nil)
+	mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "admin").Return(&models.DBUser{ID: 1, Username: "admin"}, nil)
+	mockAdminUseCase.EXPECT().ResetPassword(gomock.Any(), "bob", "").Return("temp-password", nil)
+
+	req := httptest.NewRequest("POST", "/api/admin/users/bob/reset-password", nil)
+	req.Header.Set("Authorization", "Bearer admin_token")
+	recorder := httptest.NewRecorder()
+
+	mux.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
+	var response models.ResetPasswordResponse
+	err := json.NewDecoder(recorder.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "temp-password", response.TemporaryPassword, "Временный пароль должен вернуться в ответе")
+}
+
+func TestApiHandler_handleResetPassword_NotAdmin(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+	mux := newAdminMux()
+
+	// Обычный пользователь не может сбросить пароль: usecase не вызывается.
+	mockUserUseCase.EXPECT().VerifyJWTToken("user_token").Return("alice", nil)
+
+	jsonBody, _ := json.Marshal(models.ResetPasswordRequest{Password: "new_password"})
+	req := httptest.NewRequest("POST", "/api/admin/users/bob/reset-password", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Authorization", "Bearer user_token")
+	recorder := httptest.NewRecorder()
+
+	mux.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code, "Код статуса должен быть 403 Forbidden")
+}
+
+func TestApiHandler_handleDeactivateUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		ucErr          error
+		expectedStatus int
+	}{
+		{"успешная деактивация", nil, http.StatusOK},
+		{"пользователь не найден", usecase.ErrUserNotFound, http.StatusNotFound},
+		{"ошибка сервера", errors.New("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+			mux := newAdminMux()
+
+			mockUserUseCase.EXPECT().VerifyJWTToken("admin_token").Return("admin", nil)
+			mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "admin").Return(&models.DBUser{ID: 1, Username: "admin"}, nil)
+			mockAdminUseCase.EXPECT().DeactivateUser(gomock.Any(), "bob").Return(tt.ucErr)
+
+			req := httptest.NewRequest("POST", "/api/admin/users/bob/deactivate", nil)
+			req.Header.Set("Authorization", "Bearer admin_token")
+			recorder := httptest.NewRecorder()
+
+			mux.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
+
+func TestApiHandler_handleTransactionsBetween(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       *models.TransactionsResponse
+		ucErr          error
+		expectedStatus int
+	}{
+		{"переводы найдены", &models.TransactionsResponse{Transactions: []models.DBTransaction{{ID: 1, SenderUsername: "alice", ReceiverUsername: "bob", Amount: 10}}}, nil, http.StatusOK},
+		{"не указаны участники", nil, usecase.ErrTransactionPartiesRequired, http.StatusBadRequest},
+		{"ошибка сервера", nil, errors.New("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+			mux := newAdminMux()
+
+			mockUserUseCase.EXPECT().VerifyJWTToken("admin_token").Return("admin", nil)
+			mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "admin").Return(&models.DBUser{ID: 1, Username: "admin"}, nil)
+			mockAdminUseCase.EXPECT().GetTransactionsBetween(gomock.Any(), "alice", "bob").Return(tt.response, tt.ucErr)
+
+			req := httptest.NewRequest("GET", "/api/admin/transactions?a=alice&b=bob", nil)
+			req.Header.Set("Authorization", "Bearer admin_token")
+			recorder := httptest.NewRecorder()
+
+			mux.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			if tt.response != nil {
+				var response models.TransactionsResponse
+				assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				assert.Equal(t, *tt.response, response)
+			}
+		})
+	}
+}
+
+func TestApiHandler_handleAdminAudit(t *testing.T) {
+	auditResponse := &models.AdminAuditResponse{
+		Entries: []models.AdminAuditEntry{{ID: 1, Actor: "admin", Action: usecase.AuditActionDeactivateUser, Target: "bob"}},
+		Limit:   10,
+		Offset:  20,
+	}
+	tests := []struct {
+		name           string
+		query          string
+		ucCalled       bool
+		ucErr          error
+		expectedStatus int
+	}{
+		{"страница журнала", "?limit=10&offset=20", true, nil, http.StatusOK},
+		{"некорректная страница", "?limit=10&offset=20", true, usecase.ErrInvalidPagination, http.StatusBadRequest},
+		{"limit не число", "?limit=ten", false, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+			mux := newAdminMux()
+
+			mockUserUseCase.EXPECT().VerifyJWTToken("admin_token").Return("admin", nil)
+			mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "admin").Return(&models.DBUser{ID: 1, Username: "admin"}, nil)
+			if tt.ucCalled {
+				response := auditResponse
+				if tt.ucErr != nil {
+					response = nil
+				}
+				mockAdminUseCase.EXPECT().GetAudit(gomock.Any(), 10, 20).Return(response, tt.ucErr)
+			}
+
+			req := httptest.NewRequest("GET", "/api/admin/audit"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer admin_token")
+			recorder := httptest.NewRecorder()
+
+			mux.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			if tt.expectedStatus == http.StatusOK {
+				var response models.AdminAuditResponse
+				assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				assert.Equal(t, *auditResponse, response)
+			}
+		})
+	}
+}
+
+func TestApiHandler_handleDebugInfo(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+	mux := newAdminMux()
+
+	mockUserUseCase.EXPECT().VerifyJWTToken("admin_token").Return("admin", nil)
+	mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "admin").Return(&models.DBUser{ID: 1, Username: "admin"}, nil)
+	mockAdminUseCase.EXPECT().DebugInfo(gomock.Any()).Return(&models.DebugInfoResponse{GoVersion: "go1.23.0", PostgresVersion: "16.2", UptimeSeconds: 42}, nil)
+
+	req := httptest.NewRequest("GET", "/api/admin/debug/info", nil)
+	req.Header.Set("Authorization", "Bearer admin_token")
+	recorder := httptest.NewRecorder()
+
+	mux.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
+	assert.JSONEq(t, `{"goVersion":"go1.23.0","postgresVersion":"16.2","uptimeSeconds":42}`, recorder.Body.String())
+}
+
+func TestApiHandler_handleUpdateItemPrices(t *testing.T) {
+	prices := []models.ItemPriceUpdate{{ItemName: "pen", Price: 15}, {ItemName: "scarf", Price: 120}}
+	tests := []struct {
+		name           string
+		response       *models.ItemPricesResponse
+		ucErr          error
+		expectedStatus int
+	}{
+		{"цены изменены", &models.ItemPricesResponse{Items: []models.ItemPriceResult{{ItemName: "pen", Price: 15}, {ItemName: "scarf", Price: 120, Created: true}}}, nil, http.StatusOK},
+		{"некорректный список", nil, fmt.Errorf("%w: запись 1: цена товара 'scarf' должна быть положительной", usecase.ErrInvalidItemPrices), http.StatusBadRequest},
+		{"ошибка сервера", nil, errors.New("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+			mux := newAdminMux()
+
+			mockUserUseCase.EXPECT().VerifyJWTToken("admin_token").Return("admin", nil)
+			mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "admin").Return(&models.DBUser{ID: 1, Username: "admin"}, nil)
+			mockAdminUseCase.EXPECT().UpdateItemPrices(gomock.Any(), prices).Return(tt.response, tt.ucErr)
+
+			body, _ := json.Marshal(prices)
+			req := httptest.NewRequest("PUT", "/api/admin/items", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer admin_token")
+			recorder := httptest.NewRecorder()
+
+			mux.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			if tt.response != nil {
+				var response models.ItemPricesResponse
+				assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				assert.Equal(t, *tt.response, response)
+			}
+		})
+	}
+}
+
+func TestApiHandler_handleUpdateItemPrices_InvalidJSON(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+	mux := newAdminMux()
+
+	// Объект вместо массива отклоняется до вызова usecase.
+	mockUserUseCase.EXPECT().VerifyJWTToken("admin_token").Return("admin", nil)
+	mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "admin").Return(&models.DBUser{ID: 1, Username: "admin"}, nil)
+
+	req := httptest.NewRequest("PUT", "/api/admin/items", strings.NewReader(`{"item_name":"pen","price":15}`))
+	req.Header.Set("Authorization", "Bearer admin_token")
+	recorder := httptest.NewRecorder()
+
+	mux.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code, "Код статуса должен быть 400 Bad Request")
+}
+
+func TestApiHandler_handleClaimBonus(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       *models.ClaimBonusResponse
+		ucErr          error
+		expectedStatus int
+	}{
+		{"бонус начислен", &models.ClaimBonusResponse{Amount: 100, Coins: 1100}, nil, http.StatusOK},
+		{"бонус уже получен", nil, usecase.ErrBonusAlreadyClaimed, http.StatusConflict},
+		{"ошибка сервера", nil, errors.New("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			mockBonusUseCase.EXPECT().ClaimDailyBonus(gomock.Any(), "alice").Return(tt.response, tt.ucErr)
+
+			req := httptest.NewRequest("POST", "/api/claim-bonus", nil)
+			req = req.WithContext(context.WithValue(req.Context(), "username", "alice"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleClaimBonus(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			if tt.response != nil {
+				var response models.ClaimBonusResponse
+				assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+				assert.Equal(t, *tt.response, response)
+			}
+		})
+	}
+}
+
+func TestApiHandler_handleGift(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectCall     bool
+		ucErr          error
+		expectedStatus int
+	}{
+		{"успешный подарок", `{"toUser":"bob","item":"pen","quantity":2}`, true, nil, http.StatusOK},
+		{"недостаточно предметов", `{"toUser":"bob","item":"pen","quantity":2}`, true, usecase.ErrNotEnoughItems, http.StatusBadRequest},
+		{"получатель не найден", `{"toUser":"bob","item":"pen","quantity":2}`, true, usecase.ErrReceiverNotFound, http.StatusBadRequest},
+		{"ошибка сервера", `{"toUser":"bob","item":"pen","quantity":2}`, true, errors.New("db error"), http.StatusInternalServerError},
+		{"не указано количество", `{"toUser":"bob","item":"pen"}`, false, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			if tt.expectCall {
+				mockGiftUseCase.EXPECT().GiftItem(gomock.Any(), "alice", "bob", "pen", 2).Return(tt.ucErr)
+			}
+
+			req := httptest.NewRequest("POST", "/api/gift", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "username", "alice"))
+			recorder := httptest.NewRecorder()
+
+			handler.handleGift(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
+
+func TestApiHandler_RegisterRoutes_FeatureFlags(t *testing.T) {
+	tests := []struct {
+		name           string
+		features       map[string]bool
+		expectedStatus int
+	}{
+		{"функция включена по умолчанию", nil, http.StatusOK},
+		{"функция включена явно", map[string]bool{FeatureDailyBonus: true}, http.StatusOK},
+		{"функция отключена", map[string]bool{FeatureDailyBonus: false}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+			handler = NewApiHandler(config.APIConfig{Features: tt.features}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+			mux := http.NewServeMux()
+			handler.RegisterRoutes(mux)
+
+			if tt.expectedStatus == http.StatusOK {
+				mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("alice", nil)
+				mockBonusUseCase.EXPECT().ClaimDailyBonus(gomock.Any(), "alice").Return(&models.ClaimBonusResponse{Amount: 100, Coins: 1100}, nil)
+			}
+
+			req := httptest.NewRequest("POST", "/api/claim-bonus", nil)
+			req.Header.Set("Authorization", "Bearer valid_token")
+			recorder := httptest.NewRecorder()
+
+			mux.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
+
+func TestApiHandler_handleRank(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	mockUserUseCase.EXPECT().GetRank(gomock.Any(), "testuser").Return(&models.RankResponse{Rank: 2, Coins: 900}, nil)
+
+	req := httptest.NewRequest("GET", "/api/rank", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+	recorder := httptest.NewRecorder()
+
+	handler.handleRank(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
+	var response models.RankResponse
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, models.RankResponse{Rank: 2, Coins: 900}, response)
+}
+
+func TestApiHandler_PaginationEnvelope(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+	handler = NewApiHandler(config.APIConfig{PaginationEnvelope: true}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+	mockUserUseCase.EXPECT().GetInventory(gomock.Any(), "testuser", "").
+		Return(&models.InventoryResponse{Inventory: []models.InventoryItem{{Type: "pen", Quantity: 2}, {Type: "cup", Quantity: 1}}}, nil)
+	mockAdminUseCase.EXPECT().GetAudit(gomock.Any(), 10, 20).
+		Return(&models.AdminAuditResponse{Entries: nil, Limit: 10, Offset: 20, Total: 20}, nil)
+
+	tests := []struct {
+		name     string
+		call     http.HandlerFunc
+		target   string
+		expected string
+	}{
+		{"инвентарь целиком", handler.handleInventory, "/api/inventory",
+			`{"data":[{"type":"pen","quantity":2},{"type":"cup","quantity":1}],"page":{"limit":2,"offset":0,"total":2}}`},
+		// Пустая страница возвращается как [], а не null.
+		{"страница журнала", handler.handleAdminAudit, "/api/admin/audit?limit=10&offset=20",
+			`{"data":[],"page":{"limit":10,"offset":20,"total":20}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+			recorder := httptest.NewRecorder()
+
+			tt.call(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.JSONEq(t, tt.expected, recorder.Body.String())
+		})
+	}
+}
+
+func TestApiHandler_handleInventory(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	expected := &models.InventoryResponse{Inventory: []models.InventoryItem{{Type: "sweater", Quantity: 2}}}
+	mockUserUseCase.EXPECT().GetInventory(gomock.Any(), "testuser", "sw%").Return(expected, nil)
+
+	req := httptest.NewRequest("GET", "/api/inventory?prefix=sw%25", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "username", "testuser"))
+	recorder := httptest.NewRecorder()
+
+	handler.handleInventory(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
+	// Без API_PAGINATION_ENVELOPE ответ сохраняет прежний формат.
+	assert.JSONEq(t, `{"inventory":[{"type":"sweater","quantity":2}]}`, recorder.Body.String())
+	var response models.InventoryResponse
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, *expected, response)
+}
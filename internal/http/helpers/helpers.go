@@ -3,9 +3,15 @@ package helpers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
 
 	"shop/internal/models"
+	"shop/pkg/logger"
+	"shop/pkg/validator"
 )
 
 // RespondWithError отправляет JSON ответ с ошибкой и указанным статус кодом.
@@ -21,6 +27,11 @@ func RespondWithOK(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// RespondWithNoContent отправляет ответ с кодом 204 No Content без тела.
+func RespondWithNoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // RespondWithJSON отправляет JSON ответ с указанным статус кодом и полезной нагрузкой.
 func RespondWithJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -28,6 +39,51 @@ func RespondWithJSON(w http.ResponseWriter, statusCode int, payload interface{})
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+// jsonTypeName возвращает название ожидаемого типа для сообщения клиенту.
+// Разрядность целых чисел в Go клиенту не важна, поэтому все они называются int.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int"
+	}
+	return t.String()
+}
+
+// DecodeErrorMessage возвращает понятное клиенту сообщение об ошибке декодирования JSON тела запроса.
+func DecodeErrorMessage(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "Пустое тело запроса."
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Sprintf("Неверный тип поля %s: ожидается %s.", typeErr.Field, jsonTypeName(typeErr.Type))
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return "Некорректный JSON."
+	default:
+		return "Неверный запрос."
+	}
+}
+
+// DecodeJSONBody декодирует JSON тело запроса в dst и проверяет его по тегам validate.
+// При ошибке отправляет ответ 400 с описанием проблемы и возвращает false.
+func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	log := logger.FromContext(r.Context())
+
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		log.Warn("Ошибка декодирования тела запроса", "path", r.URL.Path, "error", err)
+		RespondWithError(w, http.StatusBadRequest, DecodeErrorMessage(err))
+		return false
+	}
+
+	if err := validator.Validate(dst); err != nil {
+		log.Warn("Ошибка валидации тела запроса", "path", r.URL.Path, "error", err)
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
 // UsernameFromContext извлекает имя пользователя из контекста запроса.
 func UsernameFromContext(ctx context.Context) string {
 	val := ctx.Value("username")
@@ -37,5 +93,33 @@ func UsernameFromContext(ctx context.Context) string {
 	return ""
 }
 
+// RequireUsername возвращает имя пользователя из контекста запроса. Пустое имя означает, что
+// обработчик вызван без AuthMiddleware: вместо запроса к usecase с пустым именем клиент получает
+// 401, а ошибка регистрации маршрута попадает в журнал. Возвращает false, если ответ уже отправлен.
+func RequireUsername(w http.ResponseWriter, r *http.Request) (string, bool) {
+	username := UsernameFromContext(r.Context())
+	if username == "" {
+		logger.FromContext(r.Context()).Error("Имя пользователя отсутствует в контексте запроса", "path", logger.Sanitize(r.URL.Path))
+		RespondWithError(w, http.StatusUnauthorized, "Не авторизован: пользователь не определен")
+		return "", false
+	}
+	return username, true
+}
+
 // ContextKey тип для ключей контекста, чтобы избежать коллизий.
 type ContextKey string
+
+const clientIPKey ContextKey = "client_ip"
+
+// WithClientIP добавляет IP адрес клиента в контекст запроса.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIPFromContext извлекает IP адрес клиента из контекста запроса.
+func ClientIPFromContext(ctx context.Context) string {
+	if ip, ok := ctx.Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return ""
+}
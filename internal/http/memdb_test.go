@@ -0,0 +1,650 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shop/internal/config"
+	"shop/internal/db/memdb"
+	"shop/internal/models"
+	"shop/internal/usecase"
+	"shop/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newMemDBServer собирает сервер с настоящими use case'ами поверх хранилища в памяти.
+func newMemDBServer(t *testing.T) (*Server, *memdb.Store) {
+	t.Helper()
+	return newMemDBServerWithConfig(t, config.APIConfig{})
+}
+
+// newMemDBServerWithConfig собирает сервер поверх хранилища в памяти с настройками API cfg.
+func newMemDBServerWithConfig(t *testing.T, cfg config.APIConfig) (*Server, *memdb.Store) {
+	t.Helper()
+	store := memdb.New()
+	t.Cleanup(func() { store.Close() })
+	store.AddItem("pen", 10)
+	store.AddItem("hoody", 300)
+
+	log := logger.NewTestLogger()
+	sendCoinUseCase := usecase.NewSendCoinUseCase(1, store, store, log)
+	sendCoinUseCase.ReversalWindow = time.Minute
+	sendCoinUseCase.ReadOnly = cfg.ReadOnly
+	userUseCase := usecase.NewUserInfoUseCase("secret", store, store, log)
+	userUseCase.ReadOnly = cfg.ReadOnly
+	userUseCase.ReservedUsernames = cfg.AdminUsernames
+	buyItemUseCase := usecase.NewBuyItemUseCase(store, store, store, log)
+	buyItemUseCase.ReadOnly = cfg.ReadOnly
+	bonusUseCase := usecase.NewBonusUseCase(0, store, log)
+	bonusUseCase.ReadOnly = cfg.ReadOnly
+	giftUseCase := usecase.NewGiftUseCase(store, store, log)
+	giftUseCase.ReadOnly = cfg.ReadOnly
+	srv := NewServer(config.ServerConfig{}, cfg,
+		userUseCase,
+		sendCoinUseCase,
+		buyItemUseCase,
+		usecase.NewAdminUseCase(store, store, store, log),
+		bonusUseCase,
+		giftUseCase,
+		nil, log)
+	return srv, store
+}
+
+// memDBRequest выполняет запрос к серверу и проверяет код ответа.
+func memDBRequest(t *testing.T, srv *Server, method, target, token, body string, expectedStatus int) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(recorder, req)
+	require.Equal(t, expectedStatus, recorder.Code, recorder.Body.String())
+	return recorder
+}
+
+// memDBAuth регистрирует пользователя и возвращает его токен.
+func memDBAuth(t *testing.T, srv *Server, username string) string {
+	t.Helper()
+	recorder := memDBRequest(t, srv, "POST", "/api/auth", "", `{"username":"`+username+`","password":"password"}`, http.StatusOK)
+	var response models.AuthResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	return response.Token
+}
+
+func TestMemDB_BuyItem(t *testing.T) {
+	srv, _ := newMemDBServer(t)
+	token := memDBAuth(t, srv, "alice")
+
+	recorder := memDBRequest(t, srv, "POST", "/api/buy/hoody", token, "", http.StatusOK)
+	var buyResponse models.BuyItemResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&buyResponse))
+	assert.Equal(t, models.BuyItemResponse{Coins: 700, Quantity: 1}, buyResponse)
+
+	// Неизвестный товар не меняет баланс.
+	memDBRequest(t, srv, "POST", "/api/buy/unicorn", token, "", http.StatusBadRequest)
+
+	recorder = memDBRequest(t, srv, "GET", "/api/info", token, "", http.StatusOK)
+	var info models.InfoResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&info))
+	assert.Equal(t, int64(700), info.Coins)
+	assert.Equal(t, []models.InventoryItem{{Type: "hoody", Quantity: 1}}, info.Inventory)
+}
+
+func TestMemDB_SendCoin(t *testing.T) {
+	srv, store := newMemDBServer(t)
+	aliceToken := memDBAuth(t, srv, "alice")
+	memDBAuth(t, srv, "bob")
+
+	recorder := memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":250,"memo":"за обед"}`, http.StatusOK)
+	var sendResponse models.SendCoinResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&sendResponse))
+	assert.Equal(t, models.SendCoinResponse{Coins: 750, ToUser: "bob", Amount: 250}, sendResponse)
+
+	// Перевод сверх баланса отклоняется, балансы не меняются.
+	memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":1000}`, http.StatusBadRequest)
+
+	recorder = memDBRequest(t, srv, "GET", "/api/info", aliceToken, "", http.StatusOK)
+	var info models.InfoResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&info))
+	assert.Equal(t, int64(750), info.Coins)
+	assert.Equal(t, []models.Transaction{{ToUser: "bob", Amount: 250, Memo: "за обед"}}, info.CoinHistory.Sent)
+
+	stats, err := store.GetUserStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), stats.TotalCoins, "Переводы не должны менять суммарный баланс")
+}
+
+func TestMemDB_SendCoin_PaddedSenderSpendsOwnCoins(t *testing.T) {
+	srv, store := newMemDBServer(t)
+	memDBAuth(t, srv, "alice")
+	memDBAuth(t, srv, "bob")
+
+	// Новые имена с пробелами по краям не регистрируются.
+	memDBRequest(t, srv, "POST", "/api/auth", "", `{"username":" alice","password":"password"}`, http.StatusBadRequest)
+
+	// Пользователь " alice" мог быть зарегистрирован до запрета таких имен.
+	ctx := context.Background()
+	require.NoError(t, store.CreateUser(ctx, " alice", "hash"))
+	paddedID, err := store.GetUserIDByUsername(ctx, " alice")
+	require.NoError(t, err)
+	require.NoError(t, store.SetInitialCoins(ctx, paddedID, usecase.InitialCoins))
+	paddedToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": " alice",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	memDBRequest(t, srv, "POST", "/api/sendCoin", paddedToken, `{"toUser":"bob","amount":500}`, http.StatusOK)
+
+	balance := func(username string) int64 {
+		userID, err := store.GetUserIDByUsername(ctx, username)
+		require.NoError(t, err)
+		coins, err := store.GetBalance(ctx, userID)
+		require.NoError(t, err)
+		return coins
+	}
+	assert.Equal(t, int64(500), balance(" alice"), "Монеты списываются со счета владельца токена")
+	assert.Equal(t, int64(usecase.InitialCoins), balance("alice"), "Баланс alice не должен меняться")
+	assert.Equal(t, int64(usecase.InitialCoins+500), balance("bob"))
+}
+
+func TestMemDB_SendCoin_LargeAmounts(t *testing.T) {
+	srv, store := newMemDBServer(t)
+	aliceToken := memDBAuth(t, srv, "alice")
+	memDBAuth(t, srv, "bob")
+
+	// Баланс и сумма перевода больше 2^31.
+	alice, err := store.GetUserByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	require.NoError(t, store.UpdateUserCoins(context.Background(), alice.ID, 6_000_000_000, nil))
+
+	recorder := memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":3000000000}`, http.StatusOK)
+	var sendResponse models.SendCoinResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&sendResponse))
+	assert.Equal(t, int64(3_000_000_000), sendResponse.Coins)
+
+	bob, err := store.GetUserByUsername(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3_000_001_000), bob.Coins)
+}
+
+func TestMemDB_Statement(t *testing.T) {
+	srv, _ := newMemDBServer(t)
+	aliceToken := memDBAuth(t, srv, "alice")
+	bobToken := memDBAuth(t, srv, "bob")
+
+	memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":100}`, http.StatusOK)
+	memDBRequest(t, srv, "POST", "/api/sendCoin", bobToken, `{"toUser":"alice","amount":30}`, http.StatusOK)
+	memDBRequest(t, srv, "POST", "/api/buy/pen", aliceToken, "", http.StatusOK)
+	memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":20}`, http.StatusOK)
+
+	recorder := memDBRequest(t, srv, "GET", "/api/statement", aliceToken, "", http.StatusOK)
+	var statement models.StatementResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&statement))
+
+	recorder = memDBRequest(t, srv, "GET", "/api/info", aliceToken, "", http.StatusOK)
+	var info models.InfoResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&info))
+
+	require.Len(t, statement.Entries, 3)
+	assert.Equal(t, info.Coins, statement.Coins)
+	assert.Equal(t, info.Coins, statement.Entries[2].Balance, "Баланс после последнего перевода равен текущему")
+	assert.Equal(t, []int64{-100, 30, -20}, []int64{statement.Entries[0].Amount, statement.Entries[1].Amount, statement.Entries[2].Amount})
+	for i := 1; i < len(statement.Entries); i++ {
+		assert.Equal(t, statement.Entries[i-1].Balance+statement.Entries[i].Amount, statement.Entries[i].Balance)
+	}
+}
+
+func TestMemDB_ItemsNotModified(t *testing.T) {
+	srv, _ := newMemDBServer(t)
+	token := memDBAuth(t, srv, "alice")
+
+	recorder := memDBRequest(t, srv, "GET", "/api/items", token, "", http.StatusOK)
+	etag := recorder.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// Каталог не менялся: повторный запрос с тем же ETag получает 304.
+	req := httptest.NewRequest("GET", "/api/items", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestMemDB_ReverseTransfer(t *testing.T) {
+	srv, store := newMemDBServer(t)
+	aliceToken := memDBAuth(t, srv, "alice")
+	bobToken := memDBAuth(t, srv, "bob")
+
+	memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":250}`, http.StatusOK)
+	transactions, err := store.GetTransactionsBetween(context.Background(), "alice", "bob")
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	target := "/api/transactions/" + strconv.Itoa(transactions[0].ID) + "/reverse"
+
+	// Получатель не может отменить чужой перевод.
+	memDBRequest(t, srv, "POST", target, bobToken, "", http.StatusForbidden)
+
+	recorder := memDBRequest(t, srv, "POST", target, aliceToken, "", http.StatusOK)
+	var response models.ReverseTransferResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, models.ReverseTransferResponse{Coins: 1000, TransactionID: transactions[0].ID, Amount: 250}, response)
+
+	// Повторная отмена и отмена несуществующего перевода отклоняются.
+	memDBRequest(t, srv, "POST", target, aliceToken, "", http.StatusBadRequest)
+	memDBRequest(t, srv, "POST", "/api/transactions/999/reverse", aliceToken, "", http.StatusNotFound)
+
+	bob, err := store.GetUserByUsername(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), bob.Coins)
+}
+
+func TestMemDB_ReadOnly(t *testing.T) {
+	srv, store := newMemDBServerWithConfig(t, config.APIConfig{ReadOnly: true})
+	// Пользователи зарегистрированы до включения режима только для чтения.
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	for _, username := range []string{"alice", "bob"} {
+		require.NoError(t, store.CreateUser(context.Background(), username, string(hash)))
+		user, err := store.GetUserByUsername(context.Background(), username)
+		require.NoError(t, err)
+		require.NoError(t, store.SetInitialCoins(context.Background(), user.ID, usecase.InitialCoins))
+	}
+
+	// Вход существующего пользователя и чтение работают.
+	token := memDBAuth(t, srv, "alice")
+	memDBRequest(t, srv, "GET", "/api/info", token, "", http.StatusOK)
+	memDBRequest(t, srv, "GET", "/api/items", token, "", http.StatusOK)
+
+	// Изменения и регистрация отклоняются.
+	memDBRequest(t, srv, "POST", "/api/sendCoin", token, `{"toUser":"bob","amount":10}`, http.StatusServiceUnavailable)
+	memDBRequest(t, srv, "POST", "/api/buy/pen", token, "", http.StatusServiceUnavailable)
+	// Безопасный метод не обходит режим только для чтения: покупка по спецификации выполняется
+	// GET запросом, и usecase сам отклоняет ее, а перевод принимается только POST запросом.
+	memDBRequest(t, srv, "GET", "/api/buy/pen", token, "", http.StatusServiceUnavailable)
+	memDBRequest(t, srv, "GET", "/api/sendCoin", token, `{"toUser":"bob","amount":10}`, http.StatusMethodNotAllowed)
+	memDBRequest(t, srv, "GET", "/api/claim-bonus", token, "", http.StatusMethodNotAllowed)
+	memDBRequest(t, srv, "POST", "/api/auth", "", `{"username":"carol","password":"password"}`, http.StatusServiceUnavailable)
+
+	alice, err := store.GetUserByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(usecase.InitialCoins), alice.Coins)
+}
+
+func TestMemDB_AuthCreated(t *testing.T) {
+	srv, _ := newMemDBServer(t)
+	body := `{"username":"alice","password":"password"}`
+
+	recorder := memDBRequest(t, srv, "POST", "/api/auth", "", body, http.StatusOK)
+	var first models.RegisterResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&first))
+	assert.True(t, first.Created, "Первый вход регистрирует пользователя")
+
+	recorder = memDBRequest(t, srv, "POST", "/api/auth", "", body, http.StatusOK)
+	var second models.AuthResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&second))
+	assert.False(t, second.Created, "Повторный вход не создает пользователя")
+	assert.NotEmpty(t, second.Token)
+}
+
+func TestMemDB_ValidateToken(t *testing.T) {
+	srv, _ := newMemDBServer(t)
+	token := memDBAuth(t, srv, "alice")
+
+	// Токен принимается и из заголовка Authorization, и из тела запроса.
+	recorder := memDBRequest(t, srv, "POST", "/api/auth/validate", token, "", http.StatusOK)
+	var response models.ValidateTokenResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, "alice", response.Username)
+	assert.WithinDuration(t, time.Now().Add(usecase.DefaultTokenTTL), time.Unix(response.Exp, 0), time.Minute)
+
+	recorder = memDBRequest(t, srv, "POST", "/api/auth/validate", "", `{"token":"`+token+`"}`, http.StatusOK)
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, "alice", response.Username)
+
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": "alice",
+		"exp":      time.Now().Add(-time.Minute).Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	recorder = memDBRequest(t, srv, "POST", "/api/auth/validate", expired, "", http.StatusUnauthorized)
+	assert.Contains(t, recorder.Body.String(), "срок действия токена истек")
+
+	// Подпись не сходится, если изменить полезную нагрузку.
+	parts := strings.Split(token, ".")
+	forged, err := json.Marshal(map[string]any{"username": "admin", "exp": time.Now().Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	parts[1] = base64.RawURLEncoding.EncodeToString(forged)
+	memDBRequest(t, srv, "POST", "/api/auth/validate", strings.Join(parts, "."), "", http.StatusUnauthorized)
+
+	memDBRequest(t, srv, "POST", "/api/auth/validate", "", `{}`, http.StatusUnauthorized)
+}
+
+func TestMemDB_AdminRequiresAccount(t *testing.T) {
+	srv, store := newMemDBServerWithConfig(t, config.APIConfig{AdminUsernames: []string{"admin"}})
+	ctx := context.Background()
+
+	// Имя администратора нельзя занять первым входом.
+	memDBRequest(t, srv, "POST", "/api/auth", "", `{"username":"admin","password":"password"}`, http.StatusForbidden)
+
+	// Токен с именем администратора без учетной записи прав не дает.
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": "admin",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	memDBRequest(t, srv, "GET", "/api/admin/audit", token, "", http.StatusForbidden)
+
+	require.NoError(t, store.CreateUser(ctx, "admin", "hash"))
+	memDBRequest(t, srv, "GET", "/api/admin/audit", token, "", http.StatusOK)
+
+	// После деактивации учетной записи действующий токен администратора теряет права.
+	admin, err := store.GetUserByUsername(ctx, "admin")
+	require.NoError(t, err)
+	require.NoError(t, store.DeactivateUser(ctx, admin.ID, nil))
+	memDBRequest(t, srv, "GET", "/api/admin/audit", token, "", http.StatusForbidden)
+}
+
+func TestMemDB_Refresh(t *testing.T) {
+	srv, _ := newMemDBServer(t)
+	memDBAuth(t, srv, "alice")
+
+	sign := func(username string, exp time.Time) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"username": username,
+			"exp":      exp.Unix(),
+			"iat":      time.Now().Add(-time.Hour).Unix(),
+		}).SignedString([]byte("secret"))
+		require.NoError(t, err)
+		return signed
+	}
+
+	// Недавно истекший токен обменивается на новый, которым можно пользоваться.
+	recorder := memDBRequest(t, srv, "POST", "/api/refresh", sign("alice", time.Now().Add(-time.Minute)), "", http.StatusOK)
+	var response models.RefreshResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	require.NotEmpty(t, response.Token)
+	memDBRequest(t, srv, "GET", "/api/info", response.Token, "", http.StatusOK)
+
+	memDBRequest(t, srv, "POST", "/api/refresh", sign("alice", time.Now().Add(-time.Hour)), "", http.StatusUnauthorized)
+	// Токен, которому осталось жить дольше окна обновления, не продлевается.
+	memDBRequest(t, srv, "POST", "/api/refresh", sign("alice", time.Now().Add(12*time.Hour)), "", http.StatusBadRequest)
+	// Для несуществующего пользователя сообщение говорит об отсутствии пользователя, а не о деактивации.
+	recorder = memDBRequest(t, srv, "POST", "/api/refresh", sign("ghost", time.Now().Add(time.Minute)), "", http.StatusUnauthorized)
+	assert.Contains(t, recorder.Body.String(), "пользователь из токена не найден")
+	memDBRequest(t, srv, "POST", "/api/refresh", "not-a-token", "", http.StatusUnauthorized)
+	memDBRequest(t, srv, "POST", "/api/refresh", "", "", http.StatusUnauthorized)
+}
+
+func TestMemDB_History_Streamed(t *testing.T) {
+	srv, store := newMemDBServer(t)
+	aliceToken := memDBAuth(t, srv, "alice")
+	bobToken := memDBAuth(t, srv, "bob")
+
+	// История пустая — в ответе пустой массив, а не null.
+	recorder := memDBRequest(t, srv, "GET", "/api/history", bobToken, "", http.StatusOK)
+	assert.JSONEq(t, `[]`, recorder.Body.String())
+
+	ctx := context.Background()
+	aliceID, err := store.GetUserIDByUsername(ctx, "alice")
+	require.NoError(t, err)
+	bobID, err := store.GetUserIDByUsername(ctx, "bob")
+	require.NoError(t, err)
+
+	const historySize = 5000
+	for i := 1; i <= historySize; i++ {
+		category := ""
+		if i%10 == 0 {
+			category = "gift"
+		}
+		require.NoError(t, store.RecordTransaction(ctx, aliceID, bobID, int64(i), "", category, nil))
+	}
+
+	recorder = memDBRequest(t, srv, "GET", "/api/history", bobToken, "", http.StatusOK)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var history []models.Transaction
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &history), "Ответ должен быть корректным JSON массивом")
+	require.Len(t, history, historySize)
+	for i, transaction := range history {
+		// Новые переводы первыми.
+		require.Equal(t, int64(historySize-i), transaction.Amount)
+		require.Equal(t, "alice", transaction.FromUser)
+		require.Empty(t, transaction.ToUser)
+	}
+
+	recorder = memDBRequest(t, srv, "GET", "/api/history?category=gift", aliceToken, "", http.StatusOK)
+	history = nil
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &history))
+	require.Len(t, history, historySize/10)
+	assert.Equal(t, models.Transaction{ToUser: "bob", Amount: historySize, Category: "gift"}, history[0])
+
+	memDBRequest(t, srv, "GET", "/api/history?category=bribe", aliceToken, "", http.StatusBadRequest)
+}
+
+func TestMemDB_History_PaginationEnvelope(t *testing.T) {
+	srv, store := newMemDBServerWithConfig(t, config.APIConfig{PaginationEnvelope: true})
+	aliceToken := memDBAuth(t, srv, "alice")
+	bobToken := memDBAuth(t, srv, "bob")
+
+	recorder := memDBRequest(t, srv, "GET", "/api/history", bobToken, "", http.StatusOK)
+	assert.JSONEq(t, `{"data":[],"page":{"limit":0,"offset":0,"total":0}}`, recorder.Body.String())
+
+	ctx := context.Background()
+	aliceID, err := store.GetUserIDByUsername(ctx, "alice")
+	require.NoError(t, err)
+	bobID, err := store.GetUserIDByUsername(ctx, "bob")
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, store.RecordTransaction(ctx, aliceID, bobID, int64(i), "", "", nil))
+	}
+
+	recorder = memDBRequest(t, srv, "GET", "/api/history", aliceToken, "", http.StatusOK)
+	var response struct {
+		Data []models.Transaction `json:"data"`
+		Page models.PageInfo      `json:"page"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response), recorder.Body.String())
+	assert.Equal(t, models.PageInfo{Limit: 3, Offset: 0, Total: 3}, response.Page)
+	require.Len(t, response.Data, 3)
+	assert.Equal(t, int64(3), response.Data[0].Amount, "Новые переводы первыми")
+}
+
+func TestMemDB_Leaderboard(t *testing.T) {
+	srv, _ := newMemDBServer(t)
+	aliceToken := memDBAuth(t, srv, "alice")
+	memDBAuth(t, srv, "bob")
+	carolToken := memDBAuth(t, srv, "carol")
+
+	memDBRequest(t, srv, "POST", "/api/buy/pen?qty=3", aliceToken, "", http.StatusOK)
+	memDBRequest(t, srv, "POST", "/api/buy/pen", carolToken, "", http.StatusOK)
+
+	// У bob нет инвентаря: количество предметов 0. При равном балансе порядок по имени.
+	recorder := memDBRequest(t, srv, "GET", "/api/leaderboard", aliceToken, "", http.StatusOK)
+	var response models.LeaderboardResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, []models.LeaderboardEntry{
+		{Username: "bob", Coins: 1000, ItemCount: 0},
+		{Username: "carol", Coins: 990, ItemCount: 1},
+		{Username: "alice", Coins: 970, ItemCount: 3},
+	}, response.Entries)
+
+	recorder = memDBRequest(t, srv, "GET", "/api/leaderboard?limit=1", aliceToken, "", http.StatusOK)
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, []models.LeaderboardEntry{{Username: "bob", Coins: 1000}}, response.Entries)
+
+	memDBRequest(t, srv, "GET", "/api/leaderboard?limit=-1", aliceToken, "", http.StatusBadRequest)
+}
+
+func TestMemDB_SendCoin_Category(t *testing.T) {
+	srv, _ := newMemDBServer(t)
+	aliceToken := memDBAuth(t, srv, "alice")
+	memDBAuth(t, srv, "bob")
+
+	memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":10,"category":"gift"}`, http.StatusOK)
+	memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":20,"category":"payment"}`, http.StatusOK)
+	memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":30}`, http.StatusOK)
+	// Недопустимая категория отклоняется.
+	memDBRequest(t, srv, "POST", "/api/sendCoin", aliceToken, `{"toUser":"bob","amount":40,"category":"bribe"}`, http.StatusBadRequest)
+
+	recorder := memDBRequest(t, srv, "GET", "/api/info?category=gift", aliceToken, "", http.StatusOK)
+	var info models.InfoResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&info))
+	assert.Equal(t, []models.Transaction{{ToUser: "bob", Amount: 10, Category: "gift"}}, info.CoinHistory.Sent)
+	assert.Equal(t, int64(940), info.Coins, "Фильтр не влияет на баланс")
+
+	memDBRequest(t, srv, "GET", "/api/info?category=bribe", aliceToken, "", http.StatusBadRequest)
+}
+
+func TestMemDB_SendCoin_ConcurrentOverdraw(t *testing.T) {
+	srv, store := newMemDBServer(t)
+	aliceToken := memDBAuth(t, srv, "alice")
+	memDBAuth(t, srv, "bob")
+
+	// Каждый перевод по отдельности проходит проверку баланса, но вместе им не хватает монет:
+	// второй перевод должен увидеть баланс после первого.
+	const transfers = 2
+	var wg sync.WaitGroup
+	var succeeded atomic.Int64
+	for i := 0; i < transfers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/api/sendCoin", strings.NewReader(`{"toUser":"bob","amount":600}`))
+			req.Header.Set("Authorization", "Bearer "+aliceToken)
+			recorder := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(recorder, req)
+			if recorder.Code == http.StatusOK {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	alice, err := store.GetUserByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	bob, err := store.GetUserByUsername(context.Background(), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), succeeded.Load())
+	assert.GreaterOrEqual(t, alice.Coins, int64(0), "Баланс не должен уходить в минус")
+	assert.Equal(t, int64(400), alice.Coins)
+	assert.Equal(t, int64(1600), bob.Coins)
+}
+
+func TestMemDB_BuyItem_ConcurrentSameItem(t *testing.T) {
+	srv, store := newMemDBServer(t)
+	token := memDBAuth(t, srv, "alice")
+
+	// Монет хватает на 100 ручек из 120.
+	const attempts = 120
+	var wg sync.WaitGroup
+	var succeeded atomic.Int64
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/api/buy/pen", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			recorder := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(recorder, req)
+			if recorder.Code == http.StatusOK {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	alice, err := store.GetUserByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	inventory, err := store.GetUserInventory(context.Background(), alice.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, inventory, 1)
+	assert.Equal(t, int64(100), succeeded.Load())
+	assert.Equal(t, int(succeeded.Load()), inventory[0].Quantity, "Количество предметов должно совпадать с числом успешных покупок")
+	assert.Equal(t, int64(0), alice.Coins)
+}
+
+// countingStore считает запросы хранилища, находящие пользователя по имени или читающие баланс.
+type countingStore struct {
+	*memdb.Store
+	userQueries atomic.Int32
+}
+
+func (s *countingStore) GetUserByUsername(ctx context.Context, username string) (*models.DBUser, error) {
+	s.userQueries.Add(1)
+	return s.Store.GetUserByUsername(ctx, username)
+}
+
+func (s *countingStore) GetUserIDByUsername(ctx context.Context, username string) (int, error) {
+	s.userQueries.Add(1)
+	return s.Store.GetUserIDByUsername(ctx, username)
+}
+
+func (s *countingStore) GetBalance(ctx context.Context, userID int) (int64, error) {
+	s.userQueries.Add(1)
+	return s.Store.GetBalance(ctx, userID)
+}
+
+func TestMemDB_ResolveUser_QueriesPerRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.APIConfig
+	}{
+		{"без загрузки в middleware", config.APIConfig{}},
+		{"API_RESOLVE_USER_ID", config.APIConfig{ResolveUserID: true}},
+		{"API_REQUIRE_EXISTING_USER", config.APIConfig{RequireExistingUser: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &countingStore{Store: memdb.New()}
+			t.Cleanup(func() { store.Close() })
+			store.AddItem("pen", 10)
+
+			log := logger.NewTestLogger()
+			srv := NewServer(config.ServerConfig{}, tt.cfg,
+				usecase.NewUserInfoUseCase("secret", store, store, log),
+				usecase.NewSendCoinUseCase(1, store, store, log),
+				usecase.NewBuyItemUseCase(store, store, store, log),
+				usecase.NewAdminUseCase(store, store, store, log),
+				usecase.NewBonusUseCase(0, store, log),
+				usecase.NewGiftUseCase(store, store, log),
+				nil, log)
+			token := memDBAuth(t, srv, "alice")
+			memDBAuth(t, srv, "bob")
+
+			// Пользователь загружается одним запросом: в middleware или в usecase, но не дважды.
+			store.userQueries.Store(0)
+			memDBRequest(t, srv, "GET", "/api/info", token, "", http.StatusOK)
+			assert.Equal(t, int32(1), store.userQueries.Load(), "/api/info")
+
+			store.userQueries.Store(0)
+			memDBRequest(t, srv, "POST", "/api/buy/pen", token, "", http.StatusOK)
+			assert.Equal(t, int32(1), store.userQueries.Load(), "/api/buy")
+
+			// Отправитель и получатель.
+			store.userQueries.Store(0)
+			memDBRequest(t, srv, "POST", "/api/sendCoin", token, `{"toUser":"bob","amount":5}`, http.StatusOK)
+			assert.Equal(t, int32(2), store.userQueries.Load(), "/api/sendCoin")
+		})
+	}
+}
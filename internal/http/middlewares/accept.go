@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shop/internal/http/helpers"
+	"shop/pkg/logger"
+)
+
+// AcceptJSON middleware функция, отклоняющая запросы с кодом 406,
+// если заголовок Accept не допускает ответ в формате application/json.
+// Запросы без заголовка Accept пропускаются.
+func AcceptJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept")
+		if accept != "" && !acceptsJSON(accept) {
+			log := logger.FromContext(r.Context())
+			log.Warn("Клиент не принимает JSON", "path", logger.Sanitize(r.URL.Path), "accept", accept)
+			helpers.RespondWithError(w, http.StatusNotAcceptable, "Неприемлемый формат: поддерживается только application/json")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acceptsJSON проверяет, допускает ли значение заголовка Accept тип application/json.
+func acceptsJSON(accept string) bool {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(mediaRange, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType != "application/json" && mediaType != "application/*" && mediaType != "*/*" {
+			continue
+		}
+		if !rejectedByQuality(params) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectedByQuality проверяет, запрещен ли тип параметром q=0.
+func rejectedByQuality(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.EqualFold(key, "q") {
+			q, err := strconv.ParseFloat(value, 64)
+			return err == nil && q == 0
+		}
+	}
+	return false
+}
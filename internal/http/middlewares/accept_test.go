@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptJSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		accept         string
+		expectedStatus int
+	}{
+		{"без заголовка", "", http.StatusOK},
+		{"application/json", "application/json", http.StatusOK},
+		{"любой тип", "*/*", http.StatusOK},
+		{"json среди нескольких", "text/html, application/json;q=0.9", http.StatusOK},
+		{"только html", "text/html", http.StatusNotAcceptable},
+		{"json запрещен q=0", "application/json;q=0, text/html", http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/api/info", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			recorder := httptest.NewRecorder()
+
+			AcceptJSON(testHandler).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"shop/internal/http/helpers"
+	"shop/internal/usecase"
+	"shop/pkg/logger"
+)
+
+type AdminMiddlewareHandler struct {
+	userUseCase usecase.UserUseCaseInterface
+	admins      []string
+}
+
+// NewAdminMiddlewareHandler создает middleware прав администратора для пользователей из admins.
+// Права дает только существующая учетная запись: удаленный или деактивированный
+// администратор с действующим токеном получает 403.
+func NewAdminMiddlewareHandler(uc usecase.UserUseCaseInterface, admins []string) AdminMiddlewareHandler {
+	return AdminMiddlewareHandler{userUseCase: uc, admins: admins}
+}
+
+// AdminMiddleware middleware функция для проверки прав администратора.
+// Должна применяться после AuthMiddleware.
+func (h AdminMiddlewareHandler) AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		username := helpers.UsernameFromContext(r.Context())
+		if username == "" || !slices.Contains(h.admins, username) {
+			log.Warn("Доступ к административному методу запрещен", "path", logger.Sanitize(r.URL.Path), "username", logger.Sanitize(username))
+			helpers.RespondWithError(w, http.StatusForbidden, "Доступ запрещен")
+			return
+		}
+
+		// Если AuthMiddleware уже загрузил пользователя, повторный запрос к БД не нужен.
+		if _, ok := usecase.UserIDFromContext(r.Context()); !ok {
+			_, err := h.userUseCase.ResolveUser(r.Context(), username)
+			if errors.Is(err, usecase.ErrUserNotFound) {
+				log.Warn("Учетная запись администратора не найдена", "path", logger.Sanitize(r.URL.Path), "username", logger.Sanitize(username))
+				helpers.RespondWithError(w, http.StatusForbidden, "Доступ запрещен")
+				return
+			}
+			if err != nil {
+				log.Error("Ошибка проверки учетной записи администратора", "username", logger.Sanitize(username), "error", err)
+				helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+				return
+			}
+		}
+
+		// Имя администратора попадает в журнал действий администраторов.
+		next.ServeHTTP(w, r.WithContext(usecase.WithActor(r.Context(), username)))
+	}
+}
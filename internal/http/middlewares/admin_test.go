@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shop/internal/models"
+	"shop/internal/usecase"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	ucmocks "shop/internal/usecase/mocks"
+)
+
+func TestAdminMiddleware_Admin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
+	mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "admin").Return(&models.DBUser{ID: 1, Username: "admin"}, nil)
+	middlewareHandler := NewAdminMiddlewareHandler(mockUserUseCase, []string{"admin"})
+
+	called := false
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		// Имя администратора передается в use case'ы для журнала действий.
+		assert.Equal(t, "admin", usecase.ActorFromContext(r.Context()))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("POST", "/api/admin/test", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "username", "admin"))
+	recorder := httptest.NewRecorder()
+
+	middlewareHandler.AdminMiddleware(testHandler).ServeHTTP(recorder, req)
+
+	assert.True(t, called, "Handler должен быть вызван для администратора")
+	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
+}
+
+func TestAdminMiddleware_UserFromContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Пользователь уже загружен AuthMiddleware: ResolveUser не вызывается.
+	mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
+	middlewareHandler := NewAdminMiddlewareHandler(mockUserUseCase, []string{"admin"})
+
+	called := false
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx := context.WithValue(context.Background(), "username", "admin")
+	ctx = usecase.WithUser(ctx, models.DBUser{ID: 1, Username: "admin"})
+	req := httptest.NewRequest("POST", "/api/admin/test", nil).WithContext(ctx)
+	recorder := httptest.NewRecorder()
+
+	middlewareHandler.AdminMiddleware(testHandler).ServeHTTP(recorder, req)
+
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAdminMiddleware_NotAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
+	middlewareHandler := NewAdminMiddlewareHandler(mockUserUseCase, []string{"admin"})
+
+	// Тестовый обработчик, который *не* должен быть вызван.
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler не должен быть вызван для обычного пользователя")
+	})
+
+	req := httptest.NewRequest("POST", "/api/admin/test", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "username", "alice"))
+	recorder := httptest.NewRecorder()
+
+	middlewareHandler.AdminMiddleware(testHandler).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code, "Код статуса должен быть 403 Forbidden")
+}
+
+func TestAdminMiddleware_AccountNotResolved(t *testing.T) {
+	tests := []struct {
+		name         string
+		resolveErr   error
+		expectedCode int
+	}{
+		// Токен администратора действителен, но учетная запись удалена или деактивирована.
+		{"учетная запись не найдена", usecase.ErrUserNotFound, http.StatusForbidden},
+		{"ошибка базы данных", errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
+			mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "admin").Return(nil, tt.resolveErr)
+			middlewareHandler := NewAdminMiddlewareHandler(mockUserUseCase, []string{"admin"})
+
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("Handler не должен быть вызван без учетной записи администратора")
+			})
+
+			req := httptest.NewRequest("POST", "/api/admin/test", nil)
+			req = req.WithContext(context.WithValue(req.Context(), "username", "admin"))
+			recorder := httptest.NewRecorder()
+
+			middlewareHandler.AdminMiddleware(testHandler).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+		})
+	}
+}
@@ -2,50 +2,168 @@ package middlewares
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"strings"
 
 	"shop/internal/http/helpers"
+	"shop/internal/metrics"
 	"shop/internal/usecase"
 	"shop/pkg/logger"
-	"strings"
+)
+
+// Поддерживаемые схемы заголовка Authorization.
+const (
+	SchemeBearer = "Bearer"
+	SchemeAPIKey = "ApiKey"
+)
+
+// authRealm область защиты, указываемая в заголовке WWW-Authenticate.
+const authRealm = "shop"
+
+// Ошибки проверки учетных данных.
+var (
+	errMalformedAuthorization = errors.New("неверный формат заголовка Authorization")
+	errUnsupportedScheme      = errors.New("неподдерживаемая схема авторизации")
+	errInvalidAPIKey          = errors.New("неверный API-ключ")
 )
 
 type AuthMiddlewareHandler struct {
-	userUseCase usecase.UserUseCaseInterface
+	// MaxHeaderLength максимальная длина заголовка Authorization в байтах. Более длинные
+	// заголовки отклоняются с 401 без разбора токена. 0 снимает ограничение.
+	MaxHeaderLength int
+	userUseCase     usecase.UserUseCaseInterface
+	resolveUserID   bool
+	requireUser     bool
+	apiKeys         map[string]string
+	failures        *metrics.AuthFailures
 }
 
-func NewAuthMiddlewareHandler(uc usecase.UserUseCaseInterface) AuthMiddlewareHandler {
-	return AuthMiddlewareHandler{userUseCase: uc}
+// NewAuthMiddlewareHandler создает middleware авторизации.
+// resolveUserID добавляет пользователя с ID и балансом в контекст запроса, requireUser
+// отклоняет токены удаленных и деактивированных пользователей. apiKeys
+// сопоставляет API-ключи именам пользователей; пустой набор отключает схему ApiKey.
+// failures учитывает отклоненные запросы по причинам и может быть nil.
+func NewAuthMiddlewareHandler(uc usecase.UserUseCaseInterface, resolveUserID bool, requireUser bool, apiKeys map[string]string, failures *metrics.AuthFailures) AuthMiddlewareHandler {
+	return AuthMiddlewareHandler{userUseCase: uc, resolveUserID: resolveUserID, requireUser: requireUser, apiKeys: apiKeys, failures: failures}
 }
 
-// AuthMiddleware middleware функция для проверки JWT токена авторизации.
+// AuthMiddleware middleware функция для проверки заголовка Authorization.
+// Поддерживаются схемы Bearer (JWT) и, если заданы ключи, ApiKey. При отказе
+// в заголовке WWW-Authenticate перечисляются поддерживаемые схемы.
+// Ответы защищенных маршрутов зависят от токена, поэтому им запрещается
+// кэширование в общих кэшах и прокси.
 func (h AuthMiddlewareHandler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := logger.FromContext(r.Context())
-		log.Debug("Проверка авторизации", "path", r.URL.Path, "method", r.Method)
+		log.Debug("Проверка авторизации", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+		w.Header().Add("Vary", "Authorization")
+		w.Header().Set("Cache-Control", "private, no-store")
 
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			log.Warn("Отсутствует токен авторизации")
-			helpers.RespondWithError(w, http.StatusUnauthorized, "Не авторизован: отсутствует токен")
-
+			h.failures.Inc(metrics.AuthFailureMissingToken)
+			h.unauthorized(w, "Не авторизован: отсутствует токен")
+			return
+		}
+		if h.MaxHeaderLength > 0 && len(authHeader) > h.MaxHeaderLength {
+			log.Warn("Слишком длинный заголовок Authorization", "length", len(authHeader), "max", h.MaxHeaderLength)
+			h.failures.Inc(metrics.AuthFailureHeaderTooLong)
+			h.unauthorized(w, "Не авторизован: слишком длинный заголовок Authorization")
 			return
 		}
-		// Извлекаем токен из заголовка, предполагая схему "Bearer {token}".
-		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-		username, err := h.userUseCase.VerifyJWTToken(tokenString)
+		username, err := h.authenticate(authHeader)
 		if err != nil {
-			log.Warn("JWT верификация не удалась", "error", err)
-			helpers.RespondWithError(w, http.StatusUnauthorized, "Не авторизован: "+err.Error())
+			log.Warn("Проверка учетных данных не удалась", "error", err)
+			if errors.Is(err, usecase.ErrTokenExpired) {
+				h.failures.Inc(metrics.AuthFailureExpiredToken)
+				h.unauthorized(w, "Не авторизован: срок действия токена истек")
+				return
+			}
+			if errors.Is(err, errUnsupportedScheme) {
+				h.failures.Inc(metrics.AuthFailureUnsupportedScheme)
+			} else {
+				h.failures.Inc(metrics.AuthFailureInvalidToken)
+			}
+			h.unauthorized(w, "Не авторизован: "+err.Error())
 			return
 		}
 
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, "username", username)
 
+		// Загружаем пользователя один раз, чтобы usecase'ы не искали его повторно.
+		if h.resolveUserID || h.requireUser {
+			user, err := h.userUseCase.ResolveUser(ctx, username)
+			switch {
+			case err == nil:
+				ctx = usecase.WithUser(ctx, *user)
+			case h.requireUser && errors.Is(err, usecase.ErrUserNotFound):
+				// Подпись токена верна, но пользователь удален или деактивирован.
+				log.Warn("Пользователь из токена не найден", "username", logger.Sanitize(username))
+				h.failures.Inc(metrics.AuthFailureUnknownUser)
+				h.unauthorized(w, "Не авторизован: пользователь не найден")
+				return
+			case h.requireUser:
+				log.Error("Ошибка проверки пользователя из токена", "username", logger.Sanitize(username), "error", err)
+				helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+				return
+			default:
+				log.Warn("Не удалось определить ID пользователя", "username", logger.Sanitize(username), "error", err)
+			}
+		}
+
 		// Add logger to context
 		ctx = logger.WithLogger(ctx, log.With("username", username))
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// authenticate разбирает заголовок Authorization вида "<схема> <учетные данные>"
+// и проверяет учетные данные валидатором схемы. Схема сравнивается без учета регистра.
+func (h AuthMiddlewareHandler) authenticate(authHeader string) (string, error) {
+	scheme, credentials, ok := strings.Cut(strings.TrimSpace(authHeader), " ")
+	credentials = strings.TrimSpace(credentials)
+	if !ok || credentials == "" {
+		return "", errMalformedAuthorization
+	}
+
+	switch {
+	case strings.EqualFold(scheme, SchemeBearer):
+		return h.userUseCase.VerifyJWTToken(credentials)
+	case strings.EqualFold(scheme, SchemeAPIKey) && len(h.apiKeys) > 0:
+		return h.verifyAPIKey(credentials)
+	default:
+		return "", errUnsupportedScheme
+	}
+}
+
+// verifyAPIKey возвращает имя пользователя, которому выдан ключ.
+// Ключи сравниваются за постоянное время, чтобы не раскрывать их через тайминги.
+func (h AuthMiddlewareHandler) verifyAPIKey(key string) (string, error) {
+	username := ""
+	for candidate, owner := range h.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			username = owner
+		}
+	}
+	if username == "" {
+		return "", errInvalidAPIKey
+	}
+	return username, nil
+}
+
+// unauthorized отправляет ответ 401 с заголовком WWW-Authenticate, перечисляющим
+// поддерживаемые схемы авторизации.
+func (h AuthMiddlewareHandler) unauthorized(w http.ResponseWriter, message string) {
+	challenges := []string{SchemeBearer + ` realm="` + authRealm + `"`}
+	if len(h.apiKeys) > 0 {
+		challenges = append(challenges, SchemeAPIKey+` realm="`+authRealm+`"`)
+	}
+	w.Header().Set("WWW-Authenticate", strings.Join(challenges, ", "))
+	helpers.RespondWithError(w, http.StatusUnauthorized, message)
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"shop/internal/http/helpers"
+	"shop/internal/models"
+	"shop/internal/usecase"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -18,7 +21,7 @@ func TestAuthMiddleware_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
-	middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase)
+	middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase, false, false, nil, nil)
 
 	// Тестовый обработчик, который будет вызван после middleware.
 	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -40,6 +43,37 @@ func TestAuthMiddleware_Success(t *testing.T) {
 	middleware := middlewareHandler.AuthMiddleware(testHandler)
 	middleware.ServeHTTP(recorder, req)
 
+	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
+	assert.Equal(t, "Authorization", recorder.Header().Get("Vary"))
+	assert.Equal(t, "private, no-store", recorder.Header().Get("Cache-Control"))
+}
+
+func TestAuthMiddleware_ResolveUserID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
+	middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase, true, false, nil, nil)
+
+	// Тестовый обработчик проверяет, что ID пользователя добавлен в контекст.
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := usecase.UserIDFromContext(r.Context())
+		assert.True(t, ok, "ID пользователя должен быть в контексте")
+		assert.Equal(t, 42, userID, "ID пользователя должен совпадать")
+		assert.Equal(t, "testuser", helpers.UsernameFromContext(r.Context()), "Username должен оставаться в контексте")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("testuser", nil)
+	mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "testuser").Return(&models.DBUser{ID: 42, Username: "testuser", Coins: 100}, nil)
+
+	req := httptest.NewRequest("GET", "/api/protected", nil)
+	req.Header.Set("Authorization", "Bearer valid_token")
+	recorder := httptest.NewRecorder()
+
+	middleware := middlewareHandler.AuthMiddleware(testHandler)
+	middleware.ServeHTTP(recorder, req)
+
 	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
 }
 
@@ -48,7 +82,7 @@ func TestAuthMiddleware_NoToken(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
-	middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase)
+	middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase, false, false, nil, nil)
 
 	// Тестовый обработчик, который *не* должен быть вызван.
 	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -62,8 +96,9 @@ func TestAuthMiddleware_NoToken(t *testing.T) {
 	middleware := middlewareHandler.AuthMiddleware(testHandler)
 	middleware.ServeHTTP(recorder, req)
 
-	// Проверяем код статуса (401).
+	// Проверяем код статуса (401) и список поддерживаемых схем.
 	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "Код статуса должен быть 401 Unauthorized")
+	assert.Equal(t, `Bearer realm="shop"`, recorder.Header().Get("WWW-Authenticate"))
 }
 
 func TestAuthMiddleware_InvalidToken(t *testing.T) {
@@ -71,7 +106,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
-	middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase)
+	middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase, false, false, nil, nil)
 
 	// Тестовый обработчик, который *не* должен быть вызван.
 	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -91,3 +126,138 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "Код статуса должен быть 401 Unauthorized")
 }
+
+func TestAuthMiddleware_HeaderTooLong(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Мок без ожиданий: VerifyJWTToken не должен вызываться.
+	mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
+	middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase, false, false, nil, nil)
+	middlewareHandler.MaxHeaderLength = 4096
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler не должен быть вызван при слишком длинном заголовке")
+	})
+
+	req := httptest.NewRequest("GET", "/api/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+strings.Repeat("a", 4096))
+	recorder := httptest.NewRecorder()
+
+	middlewareHandler.AuthMiddleware(testHandler).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "Код статуса должен быть 401 Unauthorized")
+	assert.Contains(t, recorder.Body.String(), "слишком длинный заголовок Authorization")
+}
+
+func TestAuthMiddleware_ExpiredToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
+	middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase, false, false, nil, nil)
+	mockUserUseCase.EXPECT().VerifyJWTToken("expired_token").Return("", usecase.ErrTokenExpired)
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler не должен быть вызван с просроченным токеном")
+	})
+
+	req := httptest.NewRequest("GET", "/api/protected", nil)
+	req.Header.Set("Authorization", "Bearer expired_token")
+	recorder := httptest.NewRecorder()
+
+	middlewareHandler.AuthMiddleware(testHandler).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "Код статуса должен быть 401 Unauthorized")
+	assert.Contains(t, recorder.Body.String(), "Не авторизован: срок действия токена истек")
+}
+
+func TestAuthMiddleware_RequireExistingUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		resolveErr     error
+		expectedStatus int
+	}{
+		{"пользователь существует", nil, http.StatusOK},
+		{"пользователь удален", usecase.ErrUserNotFound, http.StatusUnauthorized},
+		{"ошибка БД", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
+			middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase, false, true, nil, nil)
+
+			called := false
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			})
+
+			// Подпись токена верна независимо от существования пользователя.
+			mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("testuser", nil)
+			var user *models.DBUser
+			if tt.resolveErr == nil {
+				user = &models.DBUser{ID: 42, Username: "testuser"}
+			}
+			mockUserUseCase.EXPECT().ResolveUser(gomock.Any(), "testuser").Return(user, tt.resolveErr)
+
+			req := httptest.NewRequest("GET", "/api/info", nil)
+			req.Header.Set("Authorization", "Bearer valid_token")
+			recorder := httptest.NewRecorder()
+
+			middlewareHandler.AuthMiddleware(testHandler).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			assert.Equal(t, tt.resolveErr == nil, called, "Обработчик должен вызываться только для существующего пользователя")
+		})
+	}
+}
+
+func TestAuthMiddleware_Schemes(t *testing.T) {
+	apiKeys := map[string]string{"key-123": "service"}
+	tests := []struct {
+		name             string
+		apiKeys          map[string]string
+		header           string
+		expectedStatus   int
+		expectedUsername string
+		expectedWWWAuth  string
+	}{
+		{"Bearer", apiKeys, "Bearer valid_token", http.StatusOK, "testuser", ""},
+		{"схема без учета регистра", nil, "bearer valid_token", http.StatusOK, "testuser", ""},
+		{"ApiKey", apiKeys, "ApiKey key-123", http.StatusOK, "service", ""},
+		{"неверный ApiKey", apiKeys, "ApiKey key-456", http.StatusUnauthorized, "", `Bearer realm="shop", ApiKey realm="shop"`},
+		{"ApiKey отключен", nil, "ApiKey key-123", http.StatusUnauthorized, "", `Bearer realm="shop"`},
+		{"неизвестная схема", apiKeys, "Basic dXNlcjpwYXNz", http.StatusUnauthorized, "", `Bearer realm="shop", ApiKey realm="shop"`},
+		{"токен без схемы", nil, "valid_token", http.StatusUnauthorized, "", `Bearer realm="shop"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserUseCase := ucmocks.NewMockUserUseCaseInterface(ctrl)
+			mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("testuser", nil).AnyTimes()
+			middlewareHandler := NewAuthMiddlewareHandler(mockUserUseCase, false, false, tt.apiKeys, nil)
+
+			var username string
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				username = helpers.UsernameFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/api/protected", nil)
+			req.Header.Set("Authorization", tt.header)
+			recorder := httptest.NewRecorder()
+
+			middlewareHandler.AuthMiddleware(testHandler).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+			assert.Equal(t, tt.expectedUsername, username)
+			assert.Equal(t, tt.expectedWWWAuth, recorder.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
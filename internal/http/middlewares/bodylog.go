@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"shop/pkg/logger"
+)
+
+// maxLoggedBody максимальный размер тела запроса или ответа, попадающий в лог.
+// Тела большего размера в лог не пишутся, но обработчику передаются полностью.
+const maxLoggedBody = 64 << 10
+
+// redacted значение, которым в логе заменяются секретные поля.
+const redacted = "***"
+
+// LogBodies middleware функция, записывающая в лог на уровне Debug тела запроса и ответа.
+// Тело запроса буферизуется и возвращается в r.Body, поэтому обработчик читает его как обычно.
+// В JSON телах значения полей с паролями и токенами заменяются на "***",
+// тела в других форматах в лог не пишутся.
+func LogBodies(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+		if !log.Enabled(r.Context(), slog.LevelDebug) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var requestBody []byte
+		if r.Body != nil && r.Body != http.NoBody {
+			var err error
+			requestBody, err = io.ReadAll(io.LimitReader(r.Body, maxLoggedBody+1))
+			if err != nil {
+				log.Warn("Ошибка чтения тела запроса для лога", "path", logger.Sanitize(r.URL.Path), "error", err)
+			}
+			// Прочитанная часть возвращается перед непрочитанным остатком тела.
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body}
+		}
+		log.Debug("Тело запроса", "path", logger.Sanitize(r.URL.Path), "method", r.Method, "body", sanitizeBody(requestBody))
+
+		recorder := &bodyRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		log.Debug("Тело ответа", "path", logger.Sanitize(r.URL.Path), "method", r.Method, "status", recorder.status, "body", sanitizeBody(recorder.body.Bytes()))
+	})
+}
+
+// readCloser читает из Reader, а закрывает исходное тело запроса.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyRecorder запоминает код ответа и начало тела ответа, передавая их дальше без изменений.
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *bodyRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *bodyRecorder) Write(p []byte) (int, error) {
+	if remaining := maxLoggedBody + 1 - r.body.Len(); remaining > 0 {
+		r.body.Write(p[:min(len(p), remaining)])
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Unwrap дает http.ResponseController доступ к исходному ResponseWriter (Flush для потоков событий).
+func (r *bodyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// sanitizeBody готовит тело к записи в лог: JSON со скрытыми секретами либо описание тела.
+func sanitizeBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if len(body) > maxLoggedBody {
+		return "<тело не записано: больше 64 КиБ>"
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "<тело не записано: не JSON>"
+	}
+	sanitized, err := json.Marshal(redact(value))
+	if err != nil {
+		return "<тело не записано: не JSON>"
+	}
+	return string(sanitized)
+}
+
+// redact рекурсивно заменяет значения секретных полей JSON.
+func redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if sensitiveField(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redact(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
+
+// sensitiveField сообщает, содержит ли поле с именем name секрет.
+func sensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "password") || strings.Contains(name, "token") || strings.Contains(name, "secret")
+}
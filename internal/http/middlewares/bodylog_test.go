@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shop/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogBodies_RedactsPassword(t *testing.T) {
+	var logs bytes.Buffer
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Обработчик получает тело запроса целиком.
+		var req struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "alice", req.Username)
+		assert.Equal(t, "s3cret-pass", req.Password)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"token":"jwt-value","coins":1000}`))
+	})
+
+	req := httptest.NewRequest("POST", "/api/auth", strings.NewReader(`{"username":"alice","password":"s3cret-pass"}`))
+	req = req.WithContext(logger.WithLogger(req.Context(), log))
+	recorder := httptest.NewRecorder()
+
+	LogBodies(testHandler).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"token":"jwt-value","coins":1000}`, recorder.Body.String(), "Ответ клиенту не должен изменяться")
+	assert.Contains(t, logs.String(), "alice")
+	assert.Contains(t, logs.String(), "coins")
+	assert.NotContains(t, logs.String(), "s3cret-pass", "Пароль не должен попадать в лог")
+	assert.NotContains(t, logs.String(), "jwt-value", "Токен не должен попадать в лог")
+}
+
+func TestSanitizeBody(t *testing.T) {
+	assert.Equal(t, `{"users":[{"newPassword":"***","username":"bob"}]}`, sanitizeBody([]byte(`{"users":[{"username":"bob","newPassword":"x"}]}`)))
+	assert.Equal(t, "<тело не записано: не JSON>", sanitizeBody([]byte("password=x")))
+	assert.Equal(t, "", sanitizeBody(nil))
+}
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"shop/internal/http/helpers"
+	"shop/pkg/logger"
+)
+
+// RequireContentLength middleware функция, отклоняющая запросы с телом
+// (POST, PUT, PATCH) с кодом 411, если размер тела не указан в заголовке
+// Content-Length, например при передаче Transfer-Encoding: chunked.
+func RequireContentLength(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hasBody(r.Method) && r.ContentLength < 0 {
+			log := logger.FromContext(r.Context())
+			log.Warn("Запрос без Content-Length", "path", logger.Sanitize(r.URL.Path), "method", r.Method, "transferEncoding", r.TransferEncoding)
+			helpers.RespondWithError(w, http.StatusLengthRequired, "Требуется заголовок Content-Length")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasBody проверяет, передает ли метод JSON тело запроса.
+func hasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireContentLength(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		chunked        bool
+		expectedStatus int
+	}{
+		{"POST с Content-Length", "POST", false, http.StatusOK},
+		{"POST chunked", "POST", true, http.StatusLengthRequired},
+		{"PUT chunked", "PUT", true, http.StatusLengthRequired},
+		{"GET без тела", "GET", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/api/auth", strings.NewReader(`{"username":"alice"}`))
+			if tt.chunked {
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+			}
+			recorder := httptest.NewRecorder()
+
+			RequireContentLength(testHandler).ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
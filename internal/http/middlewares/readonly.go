@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+
+	"shop/internal/http/helpers"
+	"shop/pkg/logger"
+)
+
+// ReadOnly middleware функция, отклоняющая с кодом 503 запросы, которые могут изменить данные
+// (все методы, кроме GET, HEAD и OPTIONS). Пути из exempt пропускаются: они не меняют данные
+// сами по себе либо проверяют режим только для чтения в use case'ах.
+func ReadOnly(next http.Handler, exempt ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isSafeMethod(r.Method) && !slices.Contains(exempt, r.URL.Path) {
+			log := logger.FromContext(r.Context())
+			log.Warn("Изменение отклонено в режиме только для чтения", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+			helpers.RespondWithError(w, http.StatusServiceUnavailable, "Сервис временно работает в режиме только для чтения")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isSafeMethod проверяет, что метод не изменяет данные.
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
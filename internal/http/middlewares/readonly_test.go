@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"GET проходит", "GET", "/api/info", http.StatusOK},
+		{"HEAD проходит", "HEAD", "/api/items", http.StatusOK},
+		{"POST отклоняется", "POST", "/api/sendCoin", http.StatusServiceUnavailable},
+		{"PUT отклоняется", "PUT", "/api/admin/items", http.StatusServiceUnavailable},
+		{"исключенный путь проходит", "POST", "/api/auth", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			recorder := httptest.NewRecorder()
+
+			ReadOnly(testHandler, "/api/auth").ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"shop/internal/http/helpers"
+	"shop/pkg/logger"
+)
+
+type RealIPMiddlewareHandler struct {
+	trusted []netip.Prefix
+	log     *logger.Logger
+}
+
+// NewRealIPMiddlewareHandler создает middleware определения IP адреса клиента.
+// trustedProxies содержит подсети (CIDR) или отдельные адреса доверенных прокси,
+// некорректные значения пропускаются с предупреждением. Значения из конфигурации
+// проверяются при ее загрузке, и с некорректными сервис не запускается.
+func NewRealIPMiddlewareHandler(trustedProxies []string, log *logger.Logger) RealIPMiddlewareHandler {
+	trusted := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				log.Warn("Некорректный адрес доверенного прокси пропущен", "proxy", proxy, "error", addrErr)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trusted = append(trusted, prefix.Masked())
+	}
+	return RealIPMiddlewareHandler{trusted: trusted, log: log}
+}
+
+// RealIPMiddleware middleware функция, определяющая IP адрес клиента.
+// Заголовки X-Forwarded-For и X-Real-IP учитываются только если запрос пришел
+// от доверенного прокси, иначе используется r.RemoteAddr.
+// IP адрес сохраняется в контексте запроса и в логгере запроса.
+func (h RealIPMiddlewareHandler) RealIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := h.ClientIP(r)
+
+		ctx := helpers.WithClientIP(r.Context(), ip)
+		ctx = logger.WithLogger(ctx, h.log.With("client_ip", ip))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP возвращает IP адрес клиента с учетом доверенных прокси.
+func (h RealIPMiddlewareHandler) ClientIP(r *http.Request) string {
+	peer, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !h.isTrusted(peer) {
+		return peer.String()
+	}
+
+	// Идем по цепочке X-Forwarded-For справа налево: первый недоверенный адрес
+	// принадлежит клиенту, адреса левее него могли быть подделаны.
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		var client netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, ok := parseAddr(strings.TrimSpace(hops[i]))
+			if !ok {
+				break
+			}
+			client = addr
+			if !h.isTrusted(addr) {
+				break
+			}
+		}
+		if client.IsValid() {
+			return client.String()
+		}
+	}
+
+	if addr, ok := parseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ok {
+		return addr.String()
+	}
+
+	return peer.String()
+}
+
+// isTrusted проверяет, входит ли адрес в одну из доверенных подсетей.
+func (h RealIPMiddlewareHandler) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range h.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr разбирает IP адрес с портом или без него.
+func parseAddr(s string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
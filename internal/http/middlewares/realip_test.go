@@ -0,0 +1,119 @@
+package middlewares
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shop/internal/http/helpers"
+	"shop/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealIPMiddleware_ClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		headers    map[string]string
+		expectedIP string
+	}{
+		{
+			name:       "без доверенных прокси заголовки игнорируются",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.5", "X-Real-IP": "203.0.113.6"},
+			expectedIP: "10.0.0.1",
+		},
+		{
+			name:       "недоверенный источник",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "192.168.1.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.5"},
+			expectedIP: "192.168.1.1",
+		},
+		{
+			name:       "доверенный прокси с X-Forwarded-For",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.5"},
+			expectedIP: "203.0.113.5",
+		},
+		{
+			name:       "цепочка прокси: подделанный адрес слева игнорируется",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.5, 10.0.0.2"},
+			expectedIP: "203.0.113.5",
+		},
+		{
+			name:       "доверенный прокси с X-Real-IP",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Real-IP": "203.0.113.6"},
+			expectedIP: "203.0.113.6",
+		},
+		{
+			name:       "доверенный прокси без заголовков",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			expectedIP: "10.0.0.1",
+		},
+		{
+			name:       "некорректный X-Forwarded-For",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "garbage"},
+			expectedIP: "10.0.0.1",
+		},
+		{
+			name:       "некорректная подсеть пропускается",
+			trusted:    []string{"not-a-cidr"},
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.5"},
+			expectedIP: "10.0.0.1",
+		},
+		{
+			name:       "IPv6",
+			trusted:    []string{"::1/128"},
+			remoteAddr: "[::1]:1234",
+			headers:    map[string]string{"X-Forwarded-For": "2001:db8::1"},
+			expectedIP: "2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middlewareHandler := NewRealIPMiddlewareHandler(tt.trusted, logger.NewTestLogger())
+
+			var clientIP string
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				clientIP = helpers.ClientIPFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest("GET", "/api/info", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			middlewareHandler.RealIPMiddleware(testHandler).ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expectedIP, clientIP, "Неверно определен IP адрес клиента")
+		})
+	}
+}
+
+func TestNewRealIPMiddlewareHandler_InvalidProxyLogged(t *testing.T) {
+	var buf bytes.Buffer
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(&buf, nil))}
+
+	middlewareHandler := NewRealIPMiddlewareHandler([]string{"10.0.0.1x", "10.0.0.0/8"}, log)
+
+	assert.Len(t, middlewareHandler.trusted, 1, "Некорректный адрес должен быть пропущен")
+	// В лог попадает ошибка разбора адреса, а не подсети.
+	assert.Contains(t, buf.String(), `ParseAddr(\"10.0.0.1x\")`)
+	assert.NotContains(t, buf.String(), "ParsePrefix")
+}
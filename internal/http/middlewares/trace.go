@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"shop/pkg/logger"
+)
+
+// TraceContext middleware функция, добавляющая в логгер запроса идентификаторы
+// trace_id и span_id из заголовка traceparent (W3C Trace Context), чтобы логи
+// можно было сопоставить с трассировками. Без заголовка или при некорректном
+// значении логгер не изменяется.
+func TraceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID, spanID, ok := parseTraceparent(r.Header.Get("traceparent"))
+		if ok {
+			log := logger.FromContext(r.Context()).With("trace_id", traceID, "span_id", spanID)
+			r = r.WithContext(logger.WithLogger(r.Context(), log))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseTraceparent разбирает заголовок вида version-traceid-parentid-flags.
+// Версия 00 должна содержать ровно четыре поля, более новые версии могут
+// добавлять поля в конец.
+func parseTraceparent(header string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return "", "", false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return "", "", false
+	}
+	if !isLowerHex(traceID, 32) || isZeroHex(traceID) {
+		return "", "", false
+	}
+	if !isLowerHex(spanID, 16) || isZeroHex(spanID) {
+		return "", "", false
+	}
+	if !isLowerHex(flags, 2) {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// isLowerHex проверяет, что строка заданной длины состоит из строчных шестнадцатеричных цифр.
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZeroHex проверяет, что идентификатор состоит только из нулей (недопустимое значение).
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
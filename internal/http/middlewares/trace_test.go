@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shop/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceContext(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		expectIDs   bool
+	}{
+		{
+			name:        "корректный заголовок",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectIDs:   true,
+		},
+		{
+			name:        "будущая версия с дополнительными полями",
+			traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			expectIDs:   true,
+		},
+		{
+			name: "без заголовка",
+		},
+		{
+			name:        "нулевой trace id",
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+		{
+			name:        "заглавные буквы",
+			traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01",
+		},
+		{
+			name:        "лишние поля в версии 00",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		},
+		{
+			name:        "недопустимая версия",
+			traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:        "мусор",
+			traceparent: "garbage",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(&buf, nil))}
+
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger.FromContext(r.Context()).Info("обработка запроса")
+			})
+
+			req := httptest.NewRequest("GET", "/api/info", nil)
+			req = req.WithContext(logger.WithLogger(req.Context(), log))
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+
+			TraceContext(testHandler).ServeHTTP(httptest.NewRecorder(), req)
+
+			output := buf.String()
+			assert.Contains(t, output, "обработка запроса", "Логгер из контекста должен сохраниться")
+			if tt.expectIDs {
+				assert.Contains(t, output, "trace_id=4bf92f3577b34da6a3ce929d0e0e4736")
+				assert.Contains(t, output, "span_id=00f067aa0ba902b7")
+			} else {
+				assert.NotContains(t, output, "trace_id")
+				assert.NotContains(t, output, "span_id")
+			}
+		})
+	}
+}
@@ -1,42 +1,157 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"shop/internal/config"
+	"shop/internal/http/middlewares"
+	"shop/internal/metrics"
 	uc "shop/internal/usecase"
 	"shop/pkg/logger"
 )
 
+// defaultPort порт сервера, если он не задан в конфигурации.
+const defaultPort = "8080"
+
+// Server HTTP сервер, отслеживающий количество активных запросов.
+type Server struct {
+	*http.Server
+	active atomic.Int64
+	log    *logger.Logger
+}
+
 // NewServer создает и настраивает новый HTTP сервер.
 func NewServer(
+	serverCfg config.ServerConfig,
+	cfg config.APIConfig,
 	userUseCase uc.UserUseCaseInterface,
 	sendCoinUseCase uc.SendCoinUseCaseInterface,
 	buyItemUseCase uc.BuyItemUseCaseInterface,
+	adminUseCase uc.AdminUseCaseInterface,
+	bonusUseCase uc.BonusUseCaseInterface,
+	giftUseCase uc.GiftUseCaseInterface,
+	authFailures *metrics.AuthFailures,
 	log *logger.Logger,
-) *http.Server {
+) *Server {
 	mux := http.NewServeMux()
 
-	apiHandler := NewApiHandler(userUseCase, sendCoinUseCase, buyItemUseCase, log)
-	apiHandler.RegisterRoutes(mux)
+	apiMux := http.NewServeMux()
+	apiHandler := NewApiHandler(cfg, userUseCase, sendCoinUseCase, buyItemUseCase, adminUseCase, bonusUseCase, giftUseCase, authFailures, log)
+	apiHandler.RegisterRoutes(apiMux)
 
-	swaggerDir := "./swagger"
-	swaggerHandler := http.FileServer(http.Dir(swaggerDir))
+	// Middleware, общие для всех маршрутов API.
+	var api http.Handler = apiMux
+	if cfg.ReadOnly {
+		// Вход и расчет корзины не меняют данные; регистрацию при входе отклоняет UserUseCase.
+		api = middlewares.ReadOnly(api, "/api/auth", "/api/auth/validate", "/api/refresh", "/api/cart/quote")
+	}
+	if cfg.StrictAccept {
+		api = middlewares.AcceptJSON(api)
+	}
+	if cfg.StrictContentLength {
+		api = middlewares.RequireContentLength(api)
+	}
+	if cfg.LogBodies {
+		api = middlewares.LogBodies(api)
+	}
+	// Маршруты API и документации обслуживаются под префиксом API_BASE_PATH, метрики — всегда по /metrics.
+	public := http.NewServeMux()
+	public.Handle("/api/", api)
 
-	mux.Handle("/docs/", http.StripPrefix("/docs/", swaggerHandler))
-	mux.Handle("/schema.json", swaggerHandler)
+	if serverCfg.DocsEnabled {
+		swaggerHandler := http.FileServer(http.Dir(serverCfg.DocsDir))
 
-	serverAddress := "http://localhost:8080"
-	slog.Info("Сервер запущен", slog.String("address", serverAddress))
-	slog.Info("Swagger UI доступен", slog.String("address", "http://localhost:8080/docs/"))
-	server := &http.Server{
-		Addr:         ":8080",
-		Handler:      mux,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  30 * time.Second,
+		public.Handle("/docs/", http.StripPrefix("/docs/", swaggerHandler))
+		public.Handle("/schema.json", swaggerHandler)
 	}
 
+	basePath := normalizeBasePath(cfg.BasePath)
+	if basePath == "" {
+		mux.Handle("/", public)
+	} else {
+		mux.Handle(basePath+"/", http.StripPrefix(basePath, public))
+	}
+	mux.Handle("/metrics", promhttp.Handler())
+
+	port := serverCfg.Port
+	if port == "" {
+		port = defaultPort
+	}
+	serverAddress := "http://" + net.JoinHostPort("localhost", port)
+	slog.Info("Сервер запущен", slog.String("address", serverAddress+basePath))
+	if serverCfg.DocsEnabled {
+		slog.Info("Swagger UI доступен", slog.String("address", serverAddress+basePath+"/docs/"), slog.String("dir", serverCfg.DocsDir))
+	}
+
+	realIP := middlewares.NewRealIPMiddlewareHandler(serverCfg.TrustedProxies, log)
+
+	server := &Server{log: log}
+	server.Server = &http.Server{
+		Addr:              net.JoinHostPort("", port),
+		Handler:           server.trackActive(realIP.RealIPMiddleware(middlewares.TraceContext(mux))),
+		ReadHeaderTimeout: serverCfg.ReadHeaderTimeout,
+		ReadTimeout:       serverCfg.ReadTimeout,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       30 * time.Second,
+	}
+	// Длительные потоки событий завершаются в начале остановки сервера.
+	server.RegisterOnShutdown(apiHandler.CloseStreams)
+
 	return server
 }
+
+// normalizeBasePath приводит префикс пути к виду "/prefix" без завершающего "/".
+// Пустой префикс и "/" означают, что маршруты обслуживаются от корня.
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(basePath, "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// trackActive middleware для подсчета запросов, обрабатываемых в данный момент.
+func (s *Server) trackActive(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.active.Add(1)
+		defer s.active.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ActiveRequests возвращает количество запросов, обрабатываемых в данный момент.
+func (s *Server) ActiveRequests() int64 {
+	return s.active.Load()
+}
+
+// Shutdown плавно останавливает сервер, дожидаясь завершения активных запросов
+// до истечения ctx. Запросы, не успевшие завершиться, закрываются принудительно.
+// В лог пишется, сколько запросов было дождано, а сколько прервано.
+func (s *Server) Shutdown(ctx context.Context) error {
+	inFlight := s.ActiveRequests()
+	s.log.Info("Остановка сервера, ожидание завершения активных запросов", "active", inFlight)
+
+	err := s.Server.Shutdown(ctx)
+	if err == nil {
+		s.log.Info("Сервер остановлен", "drained", inFlight, "forced", 0)
+		return nil
+	}
+
+	remaining := s.ActiveRequests()
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		s.log.Warn("Истекло время ожидания, оставшиеся запросы прерваны", "drained", inFlight-remaining, "forced", remaining)
+		if closeErr := s.Server.Close(); closeErr != nil {
+			s.log.Error("Ошибка принудительного закрытия соединений", "error", closeErr)
+		}
+	}
+	return err
+}
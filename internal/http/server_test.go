@@ -0,0 +1,455 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shop/internal/config"
+	"shop/internal/models"
+	"shop/pkg/logger"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer потокобезопасный буфер для перехвата логов.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startSlowServer запускает сервер с обработчиком, который ждет сигнала release.
+func startSlowServer(t *testing.T, logs *syncBuffer) (srv *Server, url string, started, release chan struct{}) {
+	t.Helper()
+	started = make(chan struct{})
+	release = make(chan struct{})
+
+	srv = &Server{log: &logger.Logger{Logger: slog.New(slog.NewTextHandler(logs, nil))}}
+	srv.Server = &http.Server{
+		Handler: srv.trackActive(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusOK)
+		})),
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(ln)
+
+	return srv, "http://" + ln.Addr().String(), started, release
+}
+
+func TestNewServer_StrictAccept(t *testing.T) {
+	tests := []struct {
+		name           string
+		strictAccept   bool
+		expectedStatus int
+	}{
+		// Без проверки запрос доходит до обработчика и отклоняется из-за тела.
+		{"проверка выключена", false, http.StatusBadRequest},
+		{"проверка включена", true, http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			srv := NewServer(config.ServerConfig{}, config.APIConfig{StrictAccept: tt.strictAccept}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+			req := httptest.NewRequest("POST", "/api/auth", strings.NewReader("{"))
+			req.Header.Set("Accept", "text/html")
+			recorder := httptest.NewRecorder()
+
+			srv.Handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
+
+func TestNewServer_StrictContentLength(t *testing.T) {
+	tests := []struct {
+		name                string
+		strictContentLength bool
+		expectedStatus      int
+	}{
+		{"проверка выключена", false, http.StatusOK},
+		{"проверка включена", true, http.StatusLengthRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			// Без проверки chunked запрос обрабатывается как обычно.
+			if !tt.strictContentLength {
+				mockUserUseCase.EXPECT().Auth(gomock.Any(), "alice", "password").Return("token", nil, nil)
+			}
+
+			srv := NewServer(config.ServerConfig{}, config.APIConfig{StrictContentLength: tt.strictContentLength}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+			req := httptest.NewRequest("POST", "/api/auth", strings.NewReader(`{"username":"alice","password":"password"}`))
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+			recorder := httptest.NewRecorder()
+
+			srv.Handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса")
+		})
+	}
+}
+
+func TestNewServer_AuthenticatedResponsesNotCached(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("alice", nil)
+	mockUserUseCase.EXPECT().GetUserInfo(gomock.Any(), "alice", gomock.Any()).Return(&models.InfoResponse{Coins: 1000}, nil)
+
+	srv := NewServer(config.ServerConfig{}, config.APIConfig{}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+	req := httptest.NewRequest("GET", "/api/info", nil)
+	req.Header.Set("Authorization", "Bearer valid_token")
+	recorder := httptest.NewRecorder()
+
+	srv.Handler.ServeHTTP(recorder, req)
+
+	// Ответ зависит от токена и не должен кэшироваться общими кэшами.
+	assert.Equal(t, http.StatusOK, recorder.Code, "Код статуса должен быть 200 OK")
+	assert.Equal(t, "Authorization", recorder.Header().Get("Vary"))
+	assert.Equal(t, "private, no-store", recorder.Header().Get("Cache-Control"))
+}
+
+func TestNewServer_BasePath(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("alice", nil)
+	mockUserUseCase.EXPECT().GetUserInfo(gomock.Any(), "alice", gomock.Any()).Return(&models.InfoResponse{Coins: 1000}, nil)
+	mockBuyItemUseCase.EXPECT().BuyItem(gomock.Any(), "alice", "pen", 1).Return(&models.BuyItemResponse{Coins: 990, Quantity: 1}, nil)
+	mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("alice", nil)
+
+	srv := NewServer(config.ServerConfig{}, config.APIConfig{BasePath: "/shop/"}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+	tests := []struct {
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"GET", "/shop/api/info", http.StatusOK},
+		// Название товара извлекается из пути уже без префикса.
+		{"POST", "/shop/api/buy/pen", http.StatusOK},
+		// Без префикса маршруты API не обслуживаются.
+		{"GET", "/api/info", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+		recorder := httptest.NewRecorder()
+
+		srv.Handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса для %s", tt.path)
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	for input, expected := range map[string]string{"": "", "/": "", "shop": "/shop", "/shop/": "/shop", "/gw/shop": "/gw/shop"} {
+		assert.Equal(t, expected, normalizeBasePath(input), "Префикс %q", input)
+	}
+}
+
+func TestNewServer_Timeouts(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	serverCfg := config.ServerConfig{ReadHeaderTimeout: 3 * time.Second, ReadTimeout: 10 * time.Second}
+	srv := NewServer(serverCfg, config.APIConfig{}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+	assert.Equal(t, 3*time.Second, srv.ReadHeaderTimeout, "ReadHeaderTimeout должен браться из конфигурации")
+	assert.Equal(t, 10*time.Second, srv.ReadTimeout, "ReadTimeout должен браться из конфигурации")
+}
+
+func TestNewServer_Port(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	srv := NewServer(config.ServerConfig{Port: "9090"}, config.APIConfig{}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+	assert.Equal(t, ":9090", srv.Addr, "Порт должен браться из конфигурации")
+
+	// Без порта в конфигурации используется порт по умолчанию.
+	srv = NewServer(config.ServerConfig{}, config.APIConfig{}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+	assert.Equal(t, ":8080", srv.Addr)
+}
+
+func TestServer_ReadHeaderTimeout_ClosesSlowClient(t *testing.T) {
+	srv := &Server{log: log}
+	srv.Server = &http.Server{
+		Handler:           srv.trackActive(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+		ReadHeaderTimeout: 50 * time.Millisecond,
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Клиент передает заголовки по частям и не успевает завершить их вовремя.
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = io.ReadAll(conn)
+	assert.NoError(t, err, "Сервер должен закрыть соединение до истечения дедлайна клиента")
+	assert.Equal(t, int64(0), srv.ActiveRequests(), "Запрос не должен дойти до обработчика")
+}
+
+func TestNewServer_Docs(t *testing.T) {
+	// Каталог с документацией, как в ./swagger.
+	docsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(docsDir, "index.html"), []byte("<html></html>"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(docsDir, "schema.json"), []byte("{}"), 0o600))
+
+	tests := []struct {
+		name           string
+		docsEnabled    bool
+		expectedStatus int
+	}{
+		{"документация включена", true, http.StatusOK},
+		{"документация отключена", false, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlerTest(t)
+			defer teardownHandlerTest()
+
+			serverCfg := config.ServerConfig{DocsEnabled: tt.docsEnabled, DocsDir: docsDir}
+			srv := NewServer(serverCfg, config.APIConfig{}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+			for _, path := range []string{"/docs/", "/schema.json"} {
+				recorder := httptest.NewRecorder()
+				srv.Handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+				assert.Equal(t, tt.expectedStatus, recorder.Code, "Неверный код статуса для %s", path)
+			}
+		})
+	}
+}
+
+func TestServer_Shutdown_DrainsActiveRequests(t *testing.T) {
+	logs := &syncBuffer{}
+	srv, url, started, release := startSlowServer(t, logs)
+
+	// Медленный запрос, который завершится во время остановки.
+	done := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	<-started
+	assert.Equal(t, int64(1), srv.ActiveRequests(), "Должен быть один активный запрос")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, srv.Shutdown(ctx))
+	assert.NoError(t, <-done, "Запрос должен завершиться успешно")
+
+	assert.Contains(t, logs.String(), "drained=1 forced=0")
+}
+
+func TestServer_Shutdown_ForcesCloseAfterDeadline(t *testing.T) {
+	logs := &syncBuffer{}
+	srv, url, started, release := startSlowServer(t, logs)
+	defer close(release)
+
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	// Запрос не успевает завершиться до истечения таймаута.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Contains(t, logs.String(), "drained=0 forced=1")
+}
+
+// readEvent читает одно событие Server-Sent Events и возвращает его имя и данные.
+func readEvent(t *testing.T, r *bufio.Reader) (event, data string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "Поток закрылся раньше ожидаемого события")
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestServer_BalanceStream_ClosesOnShutdown(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("alice", nil)
+	gomock.InOrder(
+		mockUserUseCase.EXPECT().GetBalance(gomock.Any(), "alice").Return(int64(100), nil),
+		mockUserUseCase.EXPECT().GetBalance(gomock.Any(), "alice").Return(int64(150), nil),
+	)
+	mockUserUseCase.EXPECT().GetBalance(gomock.Any(), "alice").Return(int64(150), nil).AnyTimes()
+
+	srv := NewServer(config.ServerConfig{}, config.APIConfig{BalanceStreamInterval: 10 * time.Millisecond}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(ln)
+
+	req, err := http.NewRequest("GET", "http://"+ln.Addr().String()+"/api/balance/stream", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid_token")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	body := bufio.NewReader(resp.Body)
+	event, data := readEvent(t, body)
+	assert.Equal(t, "balance", event)
+	assert.JSONEq(t, `{"coins":100}`, data, "Первое событие должно содержать текущий баланс")
+
+	event, data = readEvent(t, body)
+	assert.Equal(t, "balance", event)
+	assert.JSONEq(t, `{"coins":150}`, data, "Изменение баланса должно приходить в поток")
+
+	// Остановка сервера не ждет отключения клиента, а закрывает поток сама.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, srv.Shutdown(ctx))
+
+	event, _ = readEvent(t, body)
+	assert.Equal(t, "shutdown", event)
+	_, err = body.ReadString('\n')
+	assert.ErrorIs(t, err, io.EOF, "Поток должен закрыться после остановки сервера")
+}
+
+func TestServer_History_OutlivesWriteTimeout(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	// Между переводами проходит больше WriteTimeout сервера: ответ все равно должен прийти целиком.
+	mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("alice", nil)
+	mockUserUseCase.EXPECT().StreamCoinHistory(gomock.Any(), "alice", "", gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, _ string, fn func(models.Transaction) error) error {
+			if err := fn(models.Transaction{FromUser: "bob", Amount: 2}); err != nil {
+				return err
+			}
+			time.Sleep(150 * time.Millisecond)
+			return fn(models.Transaction{FromUser: "bob", Amount: 1})
+		})
+
+	srv := NewServer(config.ServerConfig{}, config.APIConfig{}, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+	srv.WriteTimeout = 50 * time.Millisecond
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", "http://"+ln.Addr().String()+"/api/history", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid_token")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"fromUser":"bob","amount":2},{"fromUser":"bob","amount":1}]`, string(body))
+}
+
+func TestServer_BalanceStream_ClosesWhenIdle(t *testing.T) {
+	setupHandlerTest(t)
+	defer teardownHandlerTest()
+
+	// Баланс не меняется: после трех интервалов keep-alive без событий поток закрывается.
+	mockUserUseCase.EXPECT().VerifyJWTToken("valid_token").Return("alice", nil)
+	mockUserUseCase.EXPECT().GetBalance(gomock.Any(), "alice").Return(int64(100), nil).AnyTimes()
+
+	apiCfg := config.APIConfig{
+		BalanceStreamInterval:   5 * time.Millisecond,
+		StreamKeepAliveInterval: 20 * time.Millisecond,
+		StreamIdleIntervals:     3,
+	}
+	srv := NewServer(config.ServerConfig{}, apiCfg, mockUserUseCase, mockSendCoinUseCase, mockBuyItemUseCase, mockAdminUseCase, mockBonusUseCase, mockGiftUseCase, nil, log)
+
+	req := httptest.NewRequest("GET", "/api/balance/stream", nil)
+	req.Header.Set("Authorization", "Bearer valid_token")
+	recorder := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		srv.Handler.ServeHTTP(recorder, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Простаивающий поток не был закрыт")
+	}
+
+	body := recorder.Body.String()
+	assert.Equal(t, 2, strings.Count(body, ": keep-alive\n\n"), "До закрытия должны прийти keep-alive комментарии")
+	assert.True(t, strings.HasSuffix(body, "event: idle\ndata: {}\n\n"), "Последним должно прийти событие idle: %q", body)
+}
@@ -0,0 +1,244 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"shop/internal/http/helpers"
+	"shop/internal/models"
+	"shop/internal/usecase"
+	"shop/pkg/logger"
+)
+
+// Значения по умолчанию для потоков событий, если они не заданы в конфигурации.
+const (
+	defaultBalanceStreamInterval   = 2 * time.Second
+	defaultStreamKeepAliveInterval = 15 * time.Second
+)
+
+// historyWriteTimeout время на запись в ответ /api/history одного перевода. Общий WriteTimeout
+// сервера для истории снимается, чтобы большая история не обрывалась у медленного клиента,
+// а клиент, переставший читать, отключается и освобождает курсор базы данных.
+const historyWriteTimeout = 15 * time.Second
+
+// CloseStreams завершает все открытые потоки событий. Вызывается при остановке сервера,
+// так как иначе Shutdown ждал бы отключения клиентов до истечения таймаута.
+func (h *ApiHandler) CloseStreams() {
+	h.closeStreamsOnce.Do(func() { close(h.streamsDone) })
+}
+
+// handleBalanceStream отправляет баланс пользователя как поток Server-Sent Events:
+// текущее значение сразу после подключения и новое значение при каждом изменении.
+// При остановке сервера клиент получает событие shutdown, после чего поток закрывается.
+// Пока событий нет, в поток отправляются keep-alive комментарии; после StreamIdleIntervals
+// таких интервалов подряд клиент получает событие idle, и поток закрывается.
+func (h *ApiHandler) handleBalanceStream(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleBalanceStream", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
+
+	coins, err := h.userUseCase.GetBalance(r.Context(), username)
+	if err != nil {
+		log.Error("Ошибка usecase GetBalance", "username", logger.Sanitize(username), "error", err)
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			helpers.RespondWithError(w, http.StatusNotFound, err.Error())
+		} else {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+		}
+		return
+	}
+
+	// Поток длится дольше WriteTimeout сервера, поэтому дедлайн записи снимается.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Warn("Не удалось снять дедлайн записи для потока", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := writeEvent(rc, w, "balance", models.BalanceEvent{Coins: coins}); err != nil {
+		log.Debug("Клиент отключился от потока баланса", "error", err)
+		return
+	}
+
+	interval := h.cfg.BalanceStreamInterval
+	if interval <= 0 {
+		interval = defaultBalanceStreamInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	keepAliveInterval := h.cfg.StreamKeepAliveInterval
+	if keepAliveInterval <= 0 {
+		keepAliveInterval = defaultStreamKeepAliveInterval
+	}
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	idleIntervals := 0
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.streamsDone:
+			log.Debug("Поток баланса закрыт из-за остановки сервера", "username", logger.Sanitize(username))
+			_ = writeEvent(rc, w, "shutdown", struct{}{})
+			return
+		case <-ticker.C:
+			current, err := h.userUseCase.GetBalance(r.Context(), username)
+			if err != nil {
+				log.Error("Ошибка usecase GetBalance в потоке баланса", "username", logger.Sanitize(username), "error", err)
+				return
+			}
+			if current == coins {
+				continue
+			}
+			coins = current
+			if err := writeEvent(rc, w, "balance", models.BalanceEvent{Coins: coins}); err != nil {
+				log.Debug("Клиент отключился от потока баланса", "error", err)
+				return
+			}
+			idleIntervals = 0
+		case <-keepAlive.C:
+			idleIntervals++
+			if h.cfg.StreamIdleIntervals > 0 && idleIntervals >= h.cfg.StreamIdleIntervals {
+				log.Debug("Поток баланса закрыт из-за простоя", "username", logger.Sanitize(username), "intervals", idleIntervals)
+				_ = writeEvent(rc, w, "idle", struct{}{})
+				return
+			}
+			if err := writeComment(rc, w, "keep-alive"); err != nil {
+				log.Debug("Клиент отключился от потока баланса", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// writeEvent записывает событие Server-Sent Events с данными в JSON и сразу отправляет его клиенту.
+func writeEvent(rc *http.ResponseController, w http.ResponseWriter, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// writeComment записывает комментарий Server-Sent Events, который клиенты игнорируют,
+// а прокси считают активностью соединения.
+func writeComment(rc *http.ResponseController, w http.ResponseWriter, comment string) error {
+	if _, err := fmt.Fprintf(w, ": %s\n\n", comment); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// handleHistory отправляет историю переводов пользователя JSON массивом, новые переводы первыми,
+// а при включенной настройке PaginationEnvelope в обертке {data, page}. Переводы записываются
+// в ответ по мере чтения из базы, поэтому память не зависит от размера истории. Параметр category
+// оставляет только переводы этой категории. Если ошибка произошла после начала ответа, сменить
+// код ответа уже нельзя: ответ обрывается, и клиент получает незакрытый массив.
+func (h *ApiHandler) handleHistory(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+	log.Debug("Обработка запроса handleHistory", "path", logger.Sanitize(r.URL.Path), "method", r.Method)
+
+	username, ok := helpers.RequireUsername(w, r)
+	if !ok {
+		return
+	}
+	category := r.URL.Query().Get("category")
+
+	rc := http.NewResponseController(w)
+	array := &jsonArrayWriter{w: w, envelope: h.cfg.PaginationEnvelope}
+	err := h.userUseCase.StreamCoinHistory(r.Context(), username, category, func(t models.Transaction) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(historyWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return fmt.Errorf("не удалось продлить дедлайн записи: %w", err)
+		}
+		return array.Write(t)
+	})
+	switch {
+	case err == nil:
+		if err := array.Close(); err != nil {
+			log.Debug("Клиент отключился во время передачи истории", "error", err)
+		}
+	case array.started:
+		log.Warn("Передача истории прервана", "username", logger.Sanitize(username), "error", err)
+	case errors.Is(err, usecase.ErrInvalidRequest):
+		helpers.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, usecase.ErrUserNotFound):
+		helpers.RespondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Error("Ошибка usecase StreamCoinHistory", "username", logger.Sanitize(username), "error", err)
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Внутренняя ошибка сервера.")
+	}
+}
+
+// jsonArrayWriter записывает элементы JSON массива в ответ по одному. Заголовки ответа
+// отправляются с первым элементом, чтобы до него ошибку еще можно было вернуть кодом ответа.
+// При envelope массив записывается в обертке {data, page}; page известна только после
+// последнего элемента и записывается в Close.
+type jsonArrayWriter struct {
+	w        http.ResponseWriter
+	envelope bool
+	enc      *json.Encoder
+	started  bool
+	count    int
+}
+
+// Write записывает очередной элемент массива.
+func (a *jsonArrayWriter) Write(v any) error {
+	sep := ","
+	if !a.started {
+		a.start()
+		sep = a.opening()
+	}
+	if _, err := io.WriteString(a.w, sep); err != nil {
+		return err
+	}
+	a.count++
+	return a.enc.Encode(v)
+}
+
+// Close завершает массив. Пустой массив записывается целиком.
+func (a *jsonArrayWriter) Close() error {
+	closing := "]"
+	if !a.started {
+		a.start()
+		closing = a.opening() + "]"
+	}
+	if a.envelope {
+		page, err := json.Marshal(wholeList(a.count))
+		if err != nil {
+			return err
+		}
+		closing += `,"page":` + string(page) + "}"
+	}
+	_, err := io.WriteString(a.w, closing+"\n")
+	return err
+}
+
+// opening возвращает начало ответа до первого элемента.
+func (a *jsonArrayWriter) opening() string {
+	if a.envelope {
+		return `{"data":[`
+	}
+	return "["
+}
+
+func (a *jsonArrayWriter) start() {
+	a.w.Header().Set("Content-Type", "application/json")
+	a.w.WriteHeader(http.StatusOK)
+	a.enc = json.NewEncoder(a.w)
+	a.started = true
+}
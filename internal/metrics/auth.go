@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Причины неудачной аутентификации.
+const (
+	AuthFailureInvalidPassword   = "invalid_password"
+	AuthFailureDeactivated       = "deactivated"
+	AuthFailureMissingToken      = "missing_token"
+	AuthFailureInvalidToken      = "invalid_token"
+	AuthFailureExpiredToken      = "expired_token"
+	AuthFailureUnknownUser       = "unknown_user"
+	AuthFailureUnsupportedScheme = "unsupported_scheme"
+	AuthFailureHeaderTooLong     = "header_too_long"
+)
+
+// AuthFailures счетчик неудачных попыток аутентификации с разбивкой по причине.
+// Позволяет отличить подбор пароля от ошибок конфигурации клиентов.
+type AuthFailures struct {
+	failures *prometheus.CounterVec
+}
+
+// NewAuthFailures создает и регистрирует счетчик неудачных попыток аутентификации.
+func NewAuthFailures(reg prometheus.Registerer) (*AuthFailures, error) {
+	a := &AuthFailures{
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "shop",
+			Name:      "auth_failures_total",
+			Help:      "Количество неудачных попыток аутентификации по причинам.",
+		}, []string{"reason"}),
+	}
+	if err := reg.Register(a.failures); err != nil {
+		return nil, fmt.Errorf("ошибка регистрации метрики: %w", err)
+	}
+	return a, nil
+}
+
+// Inc увеличивает счетчик для указанной причины. Вызов на nil ничего не делает.
+func (a *AuthFailures) Inc(reason string) {
+	if a == nil {
+		return
+	}
+	a.failures.WithLabelValues(reason).Inc()
+}
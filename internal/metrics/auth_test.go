@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthFailures_Inc(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	failures, err := NewAuthFailures(reg)
+	require.NoError(t, err)
+
+	failures.Inc(AuthFailureInvalidPassword)
+	failures.Inc(AuthFailureInvalidPassword)
+	failures.Inc(AuthFailureInvalidToken)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(failures.failures.WithLabelValues(AuthFailureInvalidPassword)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(failures.failures.WithLabelValues(AuthFailureInvalidToken)))
+}
+
+func TestAuthFailures_NilIsNoop(t *testing.T) {
+	var failures *AuthFailures
+	assert.NotPanics(t, func() { failures.Inc(AuthFailureInvalidPassword) })
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"shop/internal/models"
+	"shop/pkg/logger"
+)
+
+// ErrInvalidRefreshInterval интервал обновления метрик не положителен.
+var ErrInvalidRefreshInterval = errors.New("интервал обновления метрик должен быть положительным")
+
+// UserStatsProvider источник агрегированных данных о пользователях.
+type UserStatsProvider interface {
+	GetUserStats(ctx context.Context) (*models.DBUserStats, error)
+}
+
+// EconomyGauges gauge-метрики экономики магазина, периодически обновляемые из БД.
+type EconomyGauges struct {
+	coinSupply prometheus.Gauge
+	users      prometheus.Gauge
+	stats      UserStatsProvider
+	interval   time.Duration
+	log        *logger.Logger
+}
+
+// NewEconomyGauges создает и регистрирует gauge-метрики экономики.
+// interval должен быть положительным.
+func NewEconomyGauges(reg prometheus.Registerer, stats UserStatsProvider, interval time.Duration, log *logger.Logger) (*EconomyGauges, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRefreshInterval, interval)
+	}
+	g := &EconomyGauges{
+		coinSupply: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "shop",
+			Name:      "coins_in_circulation",
+			Help:      "Суммарное количество монет на балансах пользователей.",
+		}),
+		users: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "shop",
+			Name:      "users",
+			Help:      "Количество зарегистрированных пользователей.",
+		}),
+		stats:    stats,
+		interval: interval,
+		log:      log,
+	}
+
+	for _, c := range []prometheus.Collector{g.coinSupply, g.users} {
+		if err := reg.Register(c); err != nil {
+			return nil, fmt.Errorf("ошибка регистрации метрики: %w", err)
+		}
+	}
+	return g, nil
+}
+
+// Refresh обновляет значения метрик из агрегирующих запросов.
+func (g *EconomyGauges) Refresh(ctx context.Context) error {
+	stats, err := g.stats.GetUserStats(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка получения статистики пользователей: %w", err)
+	}
+	g.coinSupply.Set(float64(stats.TotalCoins))
+	g.users.Set(float64(stats.UserCount))
+	return nil
+}
+
+// Run обновляет метрики сразу после запуска, а затем с заданным интервалом до отмены ctx.
+func (g *EconomyGauges) Run(ctx context.Context) {
+	g.refresh(ctx)
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.refresh(ctx)
+		}
+	}
+}
+
+// refresh обновляет метрики, записывая ошибку в лог.
+func (g *EconomyGauges) refresh(ctx context.Context) {
+	if err := g.Refresh(ctx); err != nil {
+		g.log.Error("Ошибка обновления метрик экономики", "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shop/internal/models"
+	"shop/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubUserStats заглушка БД, возвращающая фиксированную статистику.
+type stubUserStats struct {
+	stats models.DBUserStats
+}
+
+func (s *stubUserStats) GetUserStats(ctx context.Context) (*models.DBUserStats, error) {
+	return &s.stats, nil
+}
+
+func TestEconomyGauges_RefreshTick(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	stats := &stubUserStats{stats: models.DBUserStats{UserCount: 3, TotalCoins: 2010}}
+
+	gauges, err := NewEconomyGauges(reg, stats, 10*time.Millisecond, logger.NewTestLogger())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go gauges.Run(ctx)
+
+	// После тика значения метрик соответствуют данным из БД.
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(gauges.coinSupply) == 2010 && testutil.ToFloat64(gauges.users) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	count, err := testutil.GatherAndCount(reg, "shop_coins_in_circulation", "shop_users")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "Обе метрики должны быть зарегистрированы")
+}
+
+func TestEconomyGauges_RefreshOnStart(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	stats := &stubUserStats{stats: models.DBUserStats{UserCount: 2, TotalCoins: 1500}}
+
+	// Интервал больше времени теста: значения появляются без ожидания первого тика.
+	gauges, err := NewEconomyGauges(reg, stats, time.Hour, logger.NewTestLogger())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go gauges.Run(ctx)
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(gauges.coinSupply) == 1500 && testutil.ToFloat64(gauges.users) == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestNewEconomyGauges_InvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		gauges, err := NewEconomyGauges(prometheus.NewRegistry(), &stubUserStats{}, interval, logger.NewTestLogger())
+		assert.ErrorIs(t, err, ErrInvalidRefreshInterval, "interval %s", interval)
+		assert.Nil(t, gauges)
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Исходы транзакции.
+const (
+	TransactionCommit   = "commit"
+	TransactionRollback = "rollback"
+)
+
+// Transactions метрики транзакций базы данных с разбивкой по операции и исходу.
+// Показывают, как часто переводы и покупки откатываются под нагрузкой и сколько длятся.
+type Transactions struct {
+	total    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewTransactions создает и регистрирует метрики транзакций.
+func NewTransactions(reg prometheus.Registerer) (*Transactions, error) {
+	t := &Transactions{
+		total: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "shop",
+			Name:      "db_transactions_total",
+			Help:      "Количество транзакций базы данных по операциям и исходам.",
+		}, []string{"operation", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "shop",
+			Name:      "db_transaction_duration_seconds",
+			Help:      "Длительность транзакций базы данных по операциям и исходам.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "outcome"}),
+	}
+	for _, collector := range []prometheus.Collector{t.total, t.duration} {
+		if err := reg.Register(collector); err != nil {
+			return nil, fmt.Errorf("ошибка регистрации метрики: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// Observe учитывает завершенную транзакцию. Вызов на nil ничего не делает.
+func (t *Transactions) Observe(operation string, outcome string, duration time.Duration) {
+	if t == nil {
+		return
+	}
+	t.total.WithLabelValues(operation, outcome).Inc()
+	t.duration.WithLabelValues(operation, outcome).Observe(duration.Seconds())
+}
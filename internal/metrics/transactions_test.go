@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactions_Observe(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	transactions, err := NewTransactions(reg)
+	require.NoError(t, err)
+
+	transactions.Observe("send_coin", TransactionCommit, 10*time.Millisecond)
+	transactions.Observe("send_coin", TransactionRollback, 20*time.Millisecond)
+	transactions.Observe("send_coin", TransactionRollback, 30*time.Millisecond)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(transactions.total.WithLabelValues("send_coin", TransactionCommit)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(transactions.total.WithLabelValues("send_coin", TransactionRollback)))
+	assert.Equal(t, 2, testutil.CollectAndCount(transactions.duration))
+}
+
+func TestTransactions_NilIsNoop(t *testing.T) {
+	var transactions *Transactions
+	assert.NotPanics(t, func() { transactions.Observe("send_coin", TransactionCommit, time.Second) })
+}
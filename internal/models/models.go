@@ -1,18 +1,55 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // InfoResponse соответствует components/schemas/InfoResponse в swagger спецификации.
 type InfoResponse struct {
-	Coins       int             `json:"coins"`
+	Coins       int64           `json:"coins"`
 	Inventory   []InventoryItem `json:"inventory"`
+	ItemCount   int             `json:"itemCount"`
 	CoinHistory CoinHistory     `json:"coinHistory"`
 }
 
+// InventoryResponse представляет ответ со списком предметов инвентаря.
+type InventoryResponse struct {
+	Inventory []InventoryItem `json:"inventory"`
+}
+
+// BalanceEvent событие потока /api/balance/stream с текущим балансом пользователя.
+type BalanceEvent struct {
+	Coins int64 `json:"coins"`
+}
+
+// RankResponse представляет ответ с местом пользователя в рейтинге по балансу.
+type RankResponse struct {
+	Rank  int   `json:"rank"`
+	Coins int64 `json:"coins"`
+}
+
+// LeaderboardEntry строка рейтинга пользователей по балансу.
+type LeaderboardEntry struct {
+	Username string `json:"username"`
+	Coins    int64  `json:"coins"`
+	// ItemCount суммарное количество предметов в инвентаре.
+	ItemCount int `json:"itemCount"`
+}
+
+// LeaderboardResponse представляет рейтинг пользователей с наибольшим балансом.
+type LeaderboardResponse struct {
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
 // InventoryItem описывает предмет инвентаря.
+// Price и Discontinued заполняются только при запросе подробностей о предметах:
+// Discontinued отмечает предметы, которых больше нет в каталоге, и у них нет цены.
 type InventoryItem struct {
-	Type     string `json:"type"`
-	Quantity int    `json:"quantity"`
+	Type         string `json:"type"`
+	Quantity     int    `json:"quantity"`
+	Price        *int64 `json:"price,omitempty"`
+	Discontinued bool   `json:"discontinued,omitempty"`
 }
 
 // CoinHistory описывает историю транзакций монет пользователя.
@@ -25,7 +62,9 @@ type CoinHistory struct {
 type Transaction struct {
 	FromUser string `json:"fromUser,omitempty"`
 	ToUser   string `json:"toUser,omitempty"`
-	Amount   int    `json:"amount"`
+	Amount   int64  `json:"amount"`
+	Memo     string `json:"memo,omitempty"`
+	Category string `json:"category,omitempty"`
 }
 
 // ErrorResponse соответствует components/schemas/ErrorResponse в swagger спецификации.
@@ -35,19 +74,107 @@ type ErrorResponse struct {
 
 // AuthRequest соответствует components/schemas/AuthRequest в swagger спецификации.
 type AuthRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required,maxlen=255"`
+	Password string `json:"password" validate:"required,maxlen=72"`
 }
 
 // AuthResponse соответствует components/schemas/AuthResponse в swagger спецификации.
 type AuthResponse struct {
 	Token string `json:"token"`
+	// Created всегда false: пользователь уже был зарегистрирован.
+	Created bool `json:"created"`
+}
+
+// ValidateTokenRequest запрос на проверку токена. Токен может быть передан
+// вместо тела в заголовке Authorization.
+type ValidateTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// ValidateTokenResponse ответ на проверку действительного токена.
+type ValidateTokenResponse struct {
+	Username string `json:"username"`
+	// Exp время истечения токена в секундах Unix.
+	Exp int64 `json:"exp"`
+}
+
+// RefreshResponse ответ с новым токеном, выданным взамен переданного.
+type RefreshResponse struct {
+	Token string `json:"token"`
+}
+
+// RegisterResponse ответ на первый вход, зарегистрировавший пользователя.
+type RegisterResponse struct {
+	Token string `json:"token"`
+	// Created всегда true: пользователь создан этим запросом.
+	Created bool        `json:"created"`
+	User    UserSummary `json:"user"`
+}
+
+// UserSummary краткие сведения о пользователе.
+type UserSummary struct {
+	Username string `json:"username"`
+	Coins    int64  `json:"coins"`
 }
 
 // SendCoinRequest соответствует components/schemas/SendCoinRequest в swagger спецификации.
 type SendCoinRequest struct {
+	ToUser string `json:"toUser" validate:"required,maxlen=255"`
+	Amount int64  `json:"amount" validate:"min=1"`
+	Memo   string `json:"memo,omitempty" validate:"maxlen=140"`
+	// Category необязательная категория перевода из usecase.TransferCategories.
+	Category string `json:"category,omitempty"`
+}
+
+// PageInfo параметры страницы списка: размер, смещение и общее количество элементов.
+type PageInfo struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
+
+// PageResponse единая обертка ответов со списками при API_PAGINATION_ENVELOPE=true.
+type PageResponse struct {
+	Data any      `json:"data"`
+	Page PageInfo `json:"page"`
+}
+
+// SendCoinResponse ответ на перевод монет с балансом отправителя после перевода.
+type SendCoinResponse struct {
+	Coins  int64  `json:"coins"`
 	ToUser string `json:"toUser"`
-	Amount int    `json:"amount"`
+	Amount int64  `json:"amount"`
+}
+
+// SendCoinResult результат перевода монет.
+type SendCoinResult struct {
+	// SenderBalance баланс отправителя после перевода.
+	SenderBalance    int64
+	ReceiverUsername string
+	Amount           int64
+}
+
+// ReverseTransferResponse ответ на отмену перевода с балансом отправителя после отмены.
+type ReverseTransferResponse struct {
+	Coins         int64 `json:"coins"`
+	TransactionID int   `json:"transactionId"`
+	Amount        int64 `json:"amount"`
+}
+
+// BuyItemResponse ответ на покупку с балансом и количеством купленного предмета после покупки.
+type BuyItemResponse struct {
+	Coins    int64 `json:"coins"`
+	Quantity int   `json:"quantity"`
+}
+
+// ResetPasswordRequest запрос администратора на сброс пароля пользователя.
+type ResetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// ResetPasswordResponse содержит временный пароль, если он был сгенерирован.
+type ResetPasswordResponse struct {
+	TemporaryPassword string `json:"temporaryPassword"`
 }
 
 // DBUser модель пользователя для базы данных.
@@ -55,7 +182,7 @@ type DBUser struct {
 	ID           int    `json:"id"`
 	Username     string `json:"username"`
 	PasswordHash string `json:"password_hash"`
-	Coins        int    `json:"coins"`
+	Coins        int64  `json:"coins"`
 }
 
 // DBInventoryItem модель предмета инвентаря в базе данных.
@@ -64,20 +191,162 @@ type DBInventoryItem struct {
 	UserID   int    `json:"user_id"`
 	ItemType string `json:"item_type"`
 	Quantity int    `json:"quantity"`
+	// Price текущая цена предмета в каталоге, nil если предмета нет в каталоге.
+	// Заполняется только GetUserInventoryWithPrices.
+	Price *int64 `json:"price,omitempty"`
+}
+
+// GiftRequest запрос на передачу предметов другому пользователю.
+type GiftRequest struct {
+	ToUser   string `json:"toUser" validate:"required,maxlen=255"`
+	Item     string `json:"item" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1"`
 }
 
 // DBTransaction модель транзакции для базы данных.
 type DBTransaction struct {
-	ID              int       `json:"id"`
-	SenderUserID    int       `json:"sender_user_id"`
-	ReceiverUserID  int       `json:"receiver_user_id"`
-	Amount          int       `json:"amount"`
-	TransactionDate time.Time `json:"transaction_date"`
+	ID               int       `json:"id"`
+	SenderUserID     int       `json:"sender_user_id"`
+	SenderUsername   string    `json:"sender_username"`
+	ReceiverUserID   int       `json:"receiver_user_id"`
+	ReceiverUsername string    `json:"receiver_username"`
+	Amount           int64     `json:"amount"`
+	Memo             string    `json:"memo,omitempty"`
+	Category         string    `json:"category,omitempty"`
+	TransactionDate  time.Time `json:"transaction_date"`
+	// ReversalOf ID перевода, который отменяет этот перевод.
+	ReversalOf *int `json:"reversal_of,omitempty"`
+	// Reversed перевод был отменен. Заполняется только GetTransactionForUpdate.
+	Reversed bool `json:"reversed,omitempty"`
+}
+
+// StatementEntry перевод в выписке с балансом пользователя после него.
+// Amount положительна для входящих переводов и отрицательна для исходящих.
+type StatementEntry struct {
+	Date         time.Time `json:"date"`
+	Counterparty string    `json:"counterparty"`
+	Amount       int64     `json:"amount"`
+	Memo         string    `json:"memo,omitempty"`
+	Balance      int64     `json:"balance"`
+}
+
+// StatementResponse выписка по переводам пользователя в хронологическом порядке.
+type StatementResponse struct {
+	Coins   int64            `json:"coins"`
+	Entries []StatementEntry `json:"entries"`
+}
+
+// TransactionsResponse ответ со списком переводов.
+type TransactionsResponse struct {
+	Transactions []DBTransaction `json:"transactions"`
+}
+
+// AdminAuditEntry запись журнала действий администраторов.
+type AdminAuditEntry struct {
+	ID        int             `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AdminAuditResponse страница журнала действий администраторов, новые записи первыми.
+type AdminAuditResponse struct {
+	Entries []AdminAuditEntry `json:"entries"`
+	Limit   int               `json:"limit"`
+	Offset  int               `json:"offset"`
+	// Total общее количество записей в журнале.
+	Total int `json:"total"`
+}
+
+// DebugInfoResponse представляет отладочную информацию о сервисе и его зависимостях.
+type DebugInfoResponse struct {
+	GoVersion       string `json:"goVersion"`
+	PostgresVersion string `json:"postgresVersion"`
+	UptimeSeconds   int64  `json:"uptimeSeconds"`
 }
 
 // DBItem модель товара для продажи.
 type DBItem struct {
-	ID       int    `json:"id"`
+	ID        int       `json:"id"`
+	ItemName  string    `json:"item_name"`
+	Price     int64     `json:"price"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CatalogItem товар каталога.
+type CatalogItem struct {
+	ItemName string `json:"item_name"`
+	Price    int64  `json:"price"`
+}
+
+// Catalog каталог товаров.
+// Version меняется при любом изменении состава каталога или цен,
+// UpdatedAt время последнего изменения товара; оба используются для условных запросов.
+type Catalog struct {
+	Items     []CatalogItem `json:"items"`
+	Version   string        `json:"-"`
+	UpdatedAt time.Time     `json:"-"`
+}
+
+// ItemPriceUpdate новая цена товара в запросе администратора на изменение цен.
+type ItemPriceUpdate struct {
+	ItemName string `json:"item_name"`
+	Price    int64  `json:"price"`
+}
+
+// ItemPriceResult результат изменения цены одного товара.
+// Created равен true, если товара не было в каталоге и он был добавлен.
+type ItemPriceResult struct {
 	ItemName string `json:"item_name"`
-	Price    int    `json:"price"`
+	Price    int64  `json:"price"`
+	Created  bool   `json:"created"`
+}
+
+// ItemPricesResponse ответ на изменение цен товаров.
+type ItemPricesResponse struct {
+	Items []ItemPriceResult `json:"items"`
+}
+
+// DBUserStats агрегированная статистика пользователей.
+type DBUserStats struct {
+	UserCount  int   `json:"user_count"`
+	TotalCoins int64 `json:"total_coins"`
+}
+
+// ClaimBonusResponse представляет ответ на получение ежедневного бонуса.
+type ClaimBonusResponse struct {
+	Amount int64 `json:"amount"`
+	Coins  int64 `json:"coins"`
+}
+
+// CartItem позиция корзины: товар и количество.
+type CartItem struct {
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+}
+
+// CartQuoteRequest запрос на расчет стоимости корзины.
+type CartQuoteRequest struct {
+	Items []CartItem `json:"items" validate:"required"`
+}
+
+// CartQuoteItem стоимость одной позиции корзины.
+type CartQuoteItem struct {
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+	Price    int64  `json:"price"`
+	Total    int64  `json:"total"`
+}
+
+// CartQuoteResponse стоимость корзины по текущим ценам.
+type CartQuoteResponse struct {
+	Items []CartQuoteItem `json:"items"`
+	Total int64           `json:"total"`
+}
+
+// BuyItemRequest представляет необязательное тело запроса на покупку предмета.
+type BuyItemRequest struct {
+	Quantity *int `json:"quantity,omitempty"`
 }
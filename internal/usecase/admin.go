@@ -0,0 +1,315 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"shop/internal/metrics"
+	"shop/internal/models"
+	"shop/pkg/logger"
+)
+
+// Ошибки административных use case'ов.
+var (
+	// ErrTransactionPartiesRequired возвращается, если не указаны оба участника переводов.
+	ErrTransactionPartiesRequired = fmt.Errorf("%w: необходимо указать двух разных пользователей", ErrInvalidRequest)
+	// ErrInvalidItemPrices возвращается, если список новых цен пуст или содержит некорректную запись.
+	ErrInvalidItemPrices = fmt.Errorf("%w: некорректный список цен", ErrInvalidRequest)
+	// ErrInvalidPagination возвращается при некорректных параметрах limit и offset.
+	ErrInvalidPagination = fmt.Errorf("%w: некорректные параметры пагинации", ErrInvalidRequest)
+)
+
+// temporaryPasswordBytes количество случайных байт во временном пароле.
+const temporaryPasswordBytes = 12
+
+// Размер страницы журнала действий администраторов.
+const (
+	DefaultAuditLimit = 50
+	MaxAuditLimit     = 100
+)
+
+// Действия администраторов, записываемые в журнал.
+const (
+	AuditActionResetPassword    = "reset_password"
+	AuditActionDeactivateUser   = "deactivate_user"
+	AuditActionUpdateItemPrices = "update_item_prices"
+)
+
+// AdminUseCaseInterface интерфейс для административных use case'ов.
+type AdminUseCaseInterface interface {
+	ResetPassword(ctx context.Context, username string, newPassword string) (string, error)
+	DeactivateUser(ctx context.Context, username string) error
+	GetTransactionsBetween(ctx context.Context, usernameA string, usernameB string) (*models.TransactionsResponse, error)
+	UpdateItemPrices(ctx context.Context, prices []models.ItemPriceUpdate) (*models.ItemPricesResponse, error)
+	GetAudit(ctx context.Context, limit int, offset int) (*models.AdminAuditResponse, error)
+	DebugInfo(ctx context.Context) (*models.DebugInfoResponse, error)
+}
+
+// AdminUseCase реализует AdminUseCaseInterface.
+type AdminUseCase struct {
+	// Transactions метрики транзакций; nil отключает их.
+	Transactions *metrics.Transactions
+	// PasswordPepper секрет, подмешиваемый в пароли перед bcrypt; должен совпадать с UserUseCase.PasswordPepper.
+	PasswordPepper []byte
+	userDB         adminUserDB
+	itemDB         itemPriceWriter
+	transactionDB  adminTransactionDB
+	log            *logger.Logger
+	startedAt      time.Time
+
+	// serverVersion кэширует версию PostgreSQL: она не меняется без перезапуска базы.
+	serverVersionMu sync.Mutex
+	serverVersion   string
+}
+
+// NewAdminUseCase создает новый AdminUseCase.
+func NewAdminUseCase(userDB adminUserDB, itemDB itemPriceWriter, transactionDB adminTransactionDB, log *logger.Logger) *AdminUseCase {
+	return &AdminUseCase{
+		userDB:        userDB,
+		itemDB:        itemDB,
+		transactionDB: transactionDB,
+		log:           log,
+		startedAt:     time.Now(),
+	}
+}
+
+// ResetPassword устанавливает пользователю новый пароль.
+// Если newPassword пуст, генерируется временный пароль, который возвращается один раз.
+func (uc *AdminUseCase) ResetPassword(ctx context.Context, username string, newPassword string) (string, error) {
+	uc.log.Debug("ResetPassword", "username", username)
+
+	user, err := uc.userDB.GetUserByUsername(ctx, username)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserByUsername в ResetPassword", "username", username, "error", err)
+		return "", fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		uc.log.Warn("Пользователь не найден в ResetPassword", "username", username)
+		return "", ErrUserNotFound
+	}
+
+	var temporaryPassword string
+	if newPassword == "" {
+		temporaryPassword, err = generateTemporaryPassword()
+		if err != nil {
+			uc.log.Error("Ошибка генерации временного пароля", "username", username, "error", err)
+			return "", fmt.Errorf("ошибка генерации временного пароля: %w", err)
+		}
+		newPassword = temporaryPassword
+	}
+
+	hashedPassword, err := hashPassword(uc.PasswordPepper, newPassword)
+	if err != nil {
+		uc.log.Error("Ошибка hashPassword в ResetPassword", "username", username, "error", err)
+		return "", fmt.Errorf("ошибка сервера при хешировании пароля: %w", err)
+	}
+
+	err = withTransaction(ctx, uc.transactionDB, uc.log, uc.Transactions, txOperationResetPassword, func(tx *sql.Tx) error {
+		if err := uc.userDB.UpdateUserPassword(ctx, user.ID, string(hashedPassword), tx); err != nil {
+			uc.log.Error("Ошибка UpdateUserPassword в ResetPassword", "userID", user.ID, "error", err)
+			return fmt.Errorf("ошибка при обновлении пароля: %w", err)
+		}
+		// Сам пароль в журнал не попадает.
+		return uc.recordAction(ctx, tx, AuditActionResetPassword, username, map[string]bool{"temporary": temporaryPassword != ""})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	uc.log.Info("Пароль пользователя сброшен администратором", "username", username)
+	return temporaryPassword, nil
+}
+
+// DeactivateUser деактивирует пользователя: он больше не может войти и получать монеты,
+// но его история транзакций сохраняется.
+func (uc *AdminUseCase) DeactivateUser(ctx context.Context, username string) error {
+	uc.log.Debug("DeactivateUser", "username", username)
+
+	user, err := uc.userDB.GetUserByUsername(ctx, username)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserByUsername в DeactivateUser", "username", username, "error", err)
+		return fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		uc.log.Warn("Пользователь не найден в DeactivateUser", "username", username)
+		return ErrUserNotFound
+	}
+
+	err = withTransaction(ctx, uc.transactionDB, uc.log, uc.Transactions, txOperationDeactivateUser, func(tx *sql.Tx) error {
+		if err := uc.userDB.DeactivateUser(ctx, user.ID, tx); err != nil {
+			uc.log.Error("Ошибка DeactivateUser", "userID", user.ID, "error", err)
+			return fmt.Errorf("ошибка при деактивации пользователя: %w", err)
+		}
+		return uc.recordAction(ctx, tx, AuditActionDeactivateUser, username, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.log.Info("Пользователь деактивирован администратором", "username", username)
+	return nil
+}
+
+// GetTransactionsBetween возвращает все переводы между двумя пользователями в обе стороны.
+func (uc *AdminUseCase) GetTransactionsBetween(ctx context.Context, usernameA string, usernameB string) (*models.TransactionsResponse, error) {
+	uc.log.Debug("GetTransactionsBetween", "usernameA", usernameA, "usernameB", usernameB)
+
+	if usernameA == "" || usernameB == "" || usernameA == usernameB {
+		return nil, ErrTransactionPartiesRequired
+	}
+
+	transactions, err := uc.transactionDB.GetTransactionsBetween(ctx, usernameA, usernameB)
+	if err != nil {
+		uc.log.Error("Ошибка GetTransactionsBetween", "usernameA", usernameA, "usernameB", usernameB, "error", err)
+		return nil, fmt.Errorf("ошибка при получении переводов: %w", err)
+	}
+
+	return &models.TransactionsResponse{Transactions: transactions}, nil
+}
+
+// UpdateItemPrices устанавливает новые цены товаров, добавляя отсутствующие в каталог.
+// Все цены применяются в одной транзакции: если хотя бы одна запись некорректна
+// или не может быть сохранена, ни одна цена не изменяется.
+func (uc *AdminUseCase) UpdateItemPrices(ctx context.Context, prices []models.ItemPriceUpdate) (*models.ItemPricesResponse, error) {
+	uc.log.Debug("UpdateItemPrices", "count", len(prices))
+
+	if err := validateItemPrices(prices); err != nil {
+		uc.log.Warn("Некорректный список цен в UpdateItemPrices", "error", err)
+		return nil, err
+	}
+
+	results := make([]models.ItemPriceResult, 0, len(prices))
+	err := withTransaction(ctx, uc.transactionDB, uc.log, uc.Transactions, txOperationUpdateItemPrices, func(tx *sql.Tx) error {
+		for _, price := range prices {
+			created, err := uc.itemDB.UpsertItemPrice(ctx, price.ItemName, price.Price, tx)
+			if err != nil {
+				uc.log.Error("Ошибка UpsertItemPrice", "item", price.ItemName, "price", price.Price, "error", err)
+				return fmt.Errorf("ошибка при изменении цен товаров: %w", err)
+			}
+			results = append(results, models.ItemPriceResult{ItemName: price.ItemName, Price: price.Price, Created: created})
+		}
+		return uc.recordAction(ctx, tx, AuditActionUpdateItemPrices, "items", prices)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Цены товаров изменены администратором", "count", len(results))
+	return &models.ItemPricesResponse{Items: results}, nil
+}
+
+// GetAudit возвращает страницу журнала действий администраторов, новые записи первыми.
+// limit 0 означает размер страницы по умолчанию.
+func (uc *AdminUseCase) GetAudit(ctx context.Context, limit int, offset int) (*models.AdminAuditResponse, error) {
+	uc.log.Debug("GetAudit", "limit", limit, "offset", offset)
+
+	if limit == 0 {
+		limit = DefaultAuditLimit
+	}
+	if limit < 0 || limit > MaxAuditLimit || offset < 0 {
+		return nil, fmt.Errorf("%w: limit должен быть от 1 до %d, offset не может быть отрицательным", ErrInvalidPagination, MaxAuditLimit)
+	}
+
+	entries, err := uc.transactionDB.GetAdminAudit(ctx, limit, offset)
+	if err != nil {
+		uc.log.Error("Ошибка GetAdminAudit", "limit", limit, "offset", offset, "error", err)
+		return nil, fmt.Errorf("ошибка при получении журнала действий администраторов: %w", err)
+	}
+	total, err := uc.transactionDB.CountAdminAudit(ctx)
+	if err != nil {
+		uc.log.Error("Ошибка CountAdminAudit", "error", err)
+		return nil, fmt.Errorf("ошибка при подсчете записей журнала действий администраторов: %w", err)
+	}
+	return &models.AdminAuditResponse{Entries: entries, Limit: limit, Offset: offset, Total: total}, nil
+}
+
+// DebugInfo возвращает версию Go, версию PostgreSQL и время работы сервиса.
+// Версия PostgreSQL запрашивается один раз и затем берется из кэша.
+func (uc *AdminUseCase) DebugInfo(ctx context.Context) (*models.DebugInfoResponse, error) {
+	uc.log.Debug("DebugInfo")
+
+	version, err := uc.postgresVersion(ctx)
+	if err != nil {
+		uc.log.Error("Ошибка ServerVersion", "error", err)
+		return nil, fmt.Errorf("ошибка при получении версии базы данных: %w", err)
+	}
+	return &models.DebugInfoResponse{
+		GoVersion:       runtime.Version(),
+		PostgresVersion: version,
+		UptimeSeconds:   int64(time.Since(uc.startedAt).Seconds()),
+	}, nil
+}
+
+// postgresVersion возвращает версию PostgreSQL из кэша, запрашивая ее при первом вызове.
+// Ошибка не кэшируется, и следующий вызов повторит запрос.
+func (uc *AdminUseCase) postgresVersion(ctx context.Context) (string, error) {
+	uc.serverVersionMu.Lock()
+	defer uc.serverVersionMu.Unlock()
+	if uc.serverVersion != "" {
+		return uc.serverVersion, nil
+	}
+	version, err := uc.transactionDB.ServerVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	uc.serverVersion = version
+	return version, nil
+}
+
+// recordAction записывает действие администратора в журнал в транзакции действия,
+// чтобы запись появлялась только вместе с примененным изменением.
+func (uc *AdminUseCase) recordAction(ctx context.Context, tx *sql.Tx, action string, target string, details any) error {
+	entry := models.AdminAuditEntry{Actor: ActorFromContext(ctx), Action: action, Target: target}
+	if details != nil {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			return fmt.Errorf("ошибка кодирования деталей действия: %w", err)
+		}
+		entry.Details = encoded
+	}
+	if err := uc.transactionDB.RecordAdminAction(ctx, entry, tx); err != nil {
+		uc.log.Error("Ошибка RecordAdminAction", "action", action, "target", target, "error", err)
+		return err
+	}
+	return nil
+}
+
+// validateItemPrices проверяет все записи списка цен до обращения к базе данных.
+func validateItemPrices(prices []models.ItemPriceUpdate) error {
+	if len(prices) == 0 {
+		return fmt.Errorf("%w: список пуст", ErrInvalidItemPrices)
+	}
+	seen := make(map[string]struct{}, len(prices))
+	for i, price := range prices {
+		switch {
+		case price.ItemName == "":
+			return fmt.Errorf("%w: запись %d: название товара обязательно", ErrInvalidItemPrices, i)
+		case utf8.RuneCountInString(price.ItemName) > MaxItemNameLength:
+			return fmt.Errorf("%w: запись %d: название товара длиннее %d символов", ErrInvalidItemPrices, i, MaxItemNameLength)
+		case price.Price <= 0:
+			return fmt.Errorf("%w: запись %d: цена товара '%s' должна быть положительной", ErrInvalidItemPrices, i, price.ItemName)
+		}
+		if _, ok := seen[price.ItemName]; ok {
+			return fmt.Errorf("%w: запись %d: товар '%s' указан повторно", ErrInvalidItemPrices, i, price.ItemName)
+		}
+		seen[price.ItemName] = struct{}{}
+	}
+	return nil
+}
+
+// generateTemporaryPassword генерирует случайный временный пароль.
+func generateTemporaryPassword() (string, error) {
+	buf := make([]byte, temporaryPasswordBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
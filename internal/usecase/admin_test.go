@@ -0,0 +1,275 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	dbmocks "shop/internal/db/mocks"
+	"shop/internal/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// expectAdminTransaction ожидает транзакцию административного действия с записью в журнал.
+func expectAdminTransaction(t *testing.T, mockTransactionDB *dbmocks.MockTransactionDBInterface, action string) {
+	t.Helper()
+	db, sqlMock := newTestSQLMock(t)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	t.Cleanup(func() { assert.NoError(t, sqlMock.ExpectationsWereMet()) })
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockTransactionDB.EXPECT().RecordAdminAction(gomock.Any(), gomock.Any(), gomock.Not(gomock.Nil())).DoAndReturn(
+		func(_ context.Context, entry models.AdminAuditEntry, _ *sql.Tx) error {
+			assert.Equal(t, action, entry.Action)
+			return nil
+		})
+}
+
+func TestAdminUseCase_ResetPassword_NewPassword(t *testing.T) {
+	uc, mockUserDB, _, mockTransactionDB := newTestAdminUseCase(t)
+	expectAdminTransaction(t, mockTransactionDB, AuditActionResetPassword)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "bob").Return(&models.DBUser{ID: 2, Username: "bob"}, nil)
+	mockUserDB.EXPECT().UpdateUserPassword(gomock.Any(), 2, gomock.Any(), gomock.Not(gomock.Nil())).DoAndReturn(
+		func(_ context.Context, _ int, hash string, _ *sql.Tx) error {
+			// Сохраняется хэш именно переданного пароля.
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("new_password")))
+			return nil
+		})
+
+	temporaryPassword, err := uc.ResetPassword(context.Background(), "bob", "new_password")
+	assert.NoError(t, err)
+	assert.Empty(t, temporaryPassword, "Временный пароль не генерируется, если задан новый")
+}
+
+func TestAdminUseCase_ResetPassword_TemporaryPassword(t *testing.T) {
+	uc, mockUserDB, _, mockTransactionDB := newTestAdminUseCase(t)
+	expectAdminTransaction(t, mockTransactionDB, AuditActionResetPassword)
+
+	var savedHash string
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "bob").Return(&models.DBUser{ID: 2, Username: "bob"}, nil)
+	mockUserDB.EXPECT().UpdateUserPassword(gomock.Any(), 2, gomock.Any(), gomock.Not(gomock.Nil())).DoAndReturn(
+		func(_ context.Context, _ int, hash string, _ *sql.Tx) error {
+			savedHash = hash
+			return nil
+		})
+
+	temporaryPassword, err := uc.ResetPassword(context.Background(), "bob", "")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, temporaryPassword)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(savedHash), []byte(temporaryPassword)))
+}
+
+func TestAdminUseCase_ResetPassword_Pepper(t *testing.T) {
+	uc, mockUserDB, _, mockTransactionDB := newTestAdminUseCase(t)
+	uc.PasswordPepper = []byte("pepper")
+	expectAdminTransaction(t, mockTransactionDB, AuditActionResetPassword)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "bob").Return(&models.DBUser{ID: 2, Username: "bob"}, nil)
+	mockUserDB.EXPECT().UpdateUserPassword(gomock.Any(), 2, gomock.Any(), gomock.Not(gomock.Nil())).DoAndReturn(
+		func(_ context.Context, _ int, hash string, _ *sql.Tx) error {
+			// Хеш проверяется так же, как при входе через UserUseCase с тем же pepper.
+			assert.NoError(t, checkPassword([]byte("pepper"), hash, "new_password"))
+			return nil
+		})
+
+	_, err := uc.ResetPassword(context.Background(), "bob", "new_password")
+	assert.NoError(t, err)
+}
+
+func TestAdminUseCase_ResetPassword_UserNotFound(t *testing.T) {
+	uc, mockUserDB, _, _ := newTestAdminUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "ghost").Return(nil, nil)
+
+	_, err := uc.ResetPassword(context.Background(), "ghost", "")
+	assert.True(t, errors.Is(err, ErrUserNotFound))
+}
+
+func TestAdminUseCase_DeactivateUser(t *testing.T) {
+	uc, mockUserDB, _, mockTransactionDB := newTestAdminUseCase(t)
+	expectAdminTransaction(t, mockTransactionDB, AuditActionDeactivateUser)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "bob").Return(&models.DBUser{ID: 2, Username: "bob"}, nil)
+	mockUserDB.EXPECT().DeactivateUser(gomock.Any(), 2, gomock.Not(gomock.Nil())).Return(nil)
+
+	err := uc.DeactivateUser(context.Background(), "bob")
+	assert.NoError(t, err)
+}
+
+func TestAdminUseCase_DeactivateUser_UserNotFound(t *testing.T) {
+	uc, mockUserDB, _, _ := newTestAdminUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "ghost").Return(nil, nil)
+
+	err := uc.DeactivateUser(context.Background(), "ghost")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestAdminUseCase_GetTransactionsBetween(t *testing.T) {
+	uc, _, _, mockTransactionDB := newTestAdminUseCase(t)
+
+	transactions := []models.DBTransaction{
+		{ID: 1, SenderUsername: "alice", ReceiverUsername: "bob", Amount: 10},
+		{ID: 2, SenderUsername: "bob", ReceiverUsername: "alice", Amount: 5},
+	}
+	mockTransactionDB.EXPECT().GetTransactionsBetween(gomock.Any(), "alice", "bob").Return(transactions, nil)
+
+	response, err := uc.GetTransactionsBetween(context.Background(), "alice", "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TransactionsResponse{Transactions: transactions}, response)
+}
+
+func TestAdminUseCase_GetTransactionsBetween_InvalidParties(t *testing.T) {
+	// Моки без ожиданий: любое обращение к БД провалит тест.
+	uc, _, _, _ := newTestAdminUseCase(t)
+
+	for _, parties := range [][2]string{{"", "bob"}, {"alice", ""}, {"alice", "alice"}} {
+		_, err := uc.GetTransactionsBetween(context.Background(), parties[0], parties[1])
+		assert.ErrorIs(t, err, ErrTransactionPartiesRequired)
+	}
+}
+
+func TestAdminUseCase_UpdateItemPrices(t *testing.T) {
+	uc, _, mockItemDB, mockTransactionDB := newTestAdminUseCase(t)
+	db, sqlMock := newTestSQLMock(t)
+
+	// Все цены применяются в одной транзакции.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	gomock.InOrder(
+		mockItemDB.EXPECT().UpsertItemPrice(gomock.Any(), "pen", int64(5), gomock.Not(gomock.Nil())).Return(false, nil),
+		mockItemDB.EXPECT().UpsertItemPrice(gomock.Any(), "scarf", int64(120), gomock.Not(gomock.Nil())).Return(true, nil),
+		// Изменение цен записывается в журнал в той же транзакции.
+		mockTransactionDB.EXPECT().RecordAdminAction(gomock.Any(), gomock.Any(), gomock.Not(gomock.Nil())).DoAndReturn(
+			func(_ context.Context, entry models.AdminAuditEntry, _ *sql.Tx) error {
+				assert.Equal(t, "admin", entry.Actor)
+				assert.Equal(t, AuditActionUpdateItemPrices, entry.Action)
+				assert.JSONEq(t, `[{"item_name":"pen","price":5},{"item_name":"scarf","price":120}]`, string(entry.Details))
+				return nil
+			}),
+	)
+
+	response, err := uc.UpdateItemPrices(WithActor(context.Background(), "admin"), []models.ItemPriceUpdate{
+		{ItemName: "pen", Price: 5},
+		{ItemName: "scarf", Price: 120},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.ItemPricesResponse{Items: []models.ItemPriceResult{
+		{ItemName: "pen", Price: 5, Created: false},
+		{ItemName: "scarf", Price: 120, Created: true},
+	}}, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAdminUseCase_UpdateItemPrices_InvalidBatch(t *testing.T) {
+	// Моки без ожиданий: некорректный список отклоняется целиком до обращения к БД.
+	uc, _, _, _ := newTestAdminUseCase(t)
+
+	batches := map[string][]models.ItemPriceUpdate{
+		"пустой список":            {},
+		"нулевая цена":             {{ItemName: "pen", Price: 5}, {ItemName: "cup", Price: 0}},
+		"отрицательная цена":       {{ItemName: "pen", Price: -1}},
+		"без названия":             {{ItemName: "pen", Price: 5}, {Price: 10}},
+		"повторяющийся товар":      {{ItemName: "pen", Price: 5}, {ItemName: "pen", Price: 7}},
+		"слишком длинное название": {{ItemName: strings.Repeat("a", MaxItemNameLength+1), Price: 5}},
+	}
+	for name, batch := range batches {
+		t.Run(name, func(t *testing.T) {
+			response, err := uc.UpdateItemPrices(context.Background(), batch)
+			assert.ErrorIs(t, err, ErrInvalidItemPrices)
+			assert.ErrorIs(t, err, ErrInvalidRequest)
+			assert.Nil(t, response)
+		})
+	}
+}
+
+func TestAdminUseCase_UpdateItemPrices_StoreFailureRollsBack(t *testing.T) {
+	uc, _, mockItemDB, mockTransactionDB := newTestAdminUseCase(t)
+	db, sqlMock := newTestSQLMock(t)
+
+	// Ошибка на втором товаре откатывает и уже примененную цену первого.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockItemDB.EXPECT().UpsertItemPrice(gomock.Any(), "pen", int64(5), gomock.Any()).Return(false, nil)
+	mockItemDB.EXPECT().UpsertItemPrice(gomock.Any(), "cup", int64(20), gomock.Any()).Return(false, errors.New("connection reset"))
+
+	response, err := uc.UpdateItemPrices(context.Background(), []models.ItemPriceUpdate{
+		{ItemName: "pen", Price: 5},
+		{ItemName: "cup", Price: 20},
+	})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidItemPrices)
+	assert.Nil(t, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAdminUseCase_UpdateItemPrices_AuditFailureRollsBack(t *testing.T) {
+	uc, _, mockItemDB, mockTransactionDB := newTestAdminUseCase(t)
+	db, sqlMock := newTestSQLMock(t)
+
+	// Без записи в журнал изменение цен не применяется.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockItemDB.EXPECT().UpsertItemPrice(gomock.Any(), "pen", int64(5), gomock.Any()).Return(false, nil)
+	mockTransactionDB.EXPECT().RecordAdminAction(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("insert failed"))
+
+	response, err := uc.UpdateItemPrices(context.Background(), []models.ItemPriceUpdate{{ItemName: "pen", Price: 5}})
+	assert.Error(t, err)
+	assert.Nil(t, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAdminUseCase_GetAudit(t *testing.T) {
+	uc, _, _, mockTransactionDB := newTestAdminUseCase(t)
+
+	entries := []models.AdminAuditEntry{{ID: 1, Actor: "admin", Action: AuditActionDeactivateUser, Target: "bob"}}
+	// Без limit используется размер страницы по умолчанию.
+	mockTransactionDB.EXPECT().GetAdminAudit(gomock.Any(), DefaultAuditLimit, 10).Return(entries, nil)
+	mockTransactionDB.EXPECT().CountAdminAudit(gomock.Any()).Return(11, nil)
+
+	response, err := uc.GetAudit(context.Background(), 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.AdminAuditResponse{Entries: entries, Limit: DefaultAuditLimit, Offset: 10, Total: 11}, response)
+}
+
+func TestAdminUseCase_GetAudit_InvalidPagination(t *testing.T) {
+	// Моки без ожиданий: некорректная страница отклоняется до обращения к БД.
+	uc, _, _, _ := newTestAdminUseCase(t)
+
+	for _, page := range [][2]int{{-1, 0}, {MaxAuditLimit + 1, 0}, {10, -1}} {
+		_, err := uc.GetAudit(context.Background(), page[0], page[1])
+		assert.ErrorIs(t, err, ErrInvalidPagination)
+	}
+}
+
+func TestAdminUseCase_DebugInfo(t *testing.T) {
+	uc, _, _, mockTransactionDB := newTestAdminUseCase(t)
+
+	// Ошибка не кэшируется, успешный ответ запрашивается у БД только один раз.
+	gomock.InOrder(
+		mockTransactionDB.EXPECT().ServerVersion(gomock.Any()).Return("", errors.New("connection refused")),
+		mockTransactionDB.EXPECT().ServerVersion(gomock.Any()).Return("16.2", nil),
+	)
+
+	_, err := uc.DebugInfo(context.Background())
+	assert.Error(t, err)
+
+	for i := 0; i < 2; i++ {
+		info, err := uc.DebugInfo(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "16.2", info.PostgresVersion)
+		assert.Equal(t, runtime.Version(), info.GoVersion)
+		assert.GreaterOrEqual(t, info.UptimeSeconds, int64(0))
+	}
+}
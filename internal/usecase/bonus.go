@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"shop/internal/models"
+	"shop/pkg/logger"
+)
+
+// Ошибки
+var (
+	ErrBonusAlreadyClaimed = fmt.Errorf("%w: ежедневный бонус уже получен", ErrInvalidRequest)
+)
+
+// BonusUseCaseInterface интерфейс для use case'а ежедневного бонуса.
+type BonusUseCaseInterface interface {
+	ClaimDailyBonus(ctx context.Context, username string) (*models.ClaimBonusResponse, error)
+}
+
+// BonusUseCase реализует BonusUseCaseInterface.
+type BonusUseCase struct {
+	// ReadOnly запрещает начисление бонуса.
+	ReadOnly    bool
+	dailyAmount int64
+	userDB      bonusUserDB
+	log         *logger.Logger
+}
+
+// NewBonusUseCase создает новый BonusUseCase.
+func NewBonusUseCase(dailyAmount int64, userDB bonusUserDB, log *logger.Logger) *BonusUseCase {
+	return &BonusUseCase{
+		dailyAmount: dailyAmount,
+		userDB:      userDB,
+		log:         log,
+	}
+}
+
+// ClaimDailyBonus начисляет пользователю ежедневный бонус.
+// Бонус можно получить не чаще одного раза в календарный день.
+func (uc *BonusUseCase) ClaimDailyBonus(ctx context.Context, username string) (*models.ClaimBonusResponse, error) {
+	uc.log.Debug("ClaimDailyBonus", "username", username)
+
+	if uc.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	user, err := lookupUser(ctx, uc.userDB, username)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserByUsername в ClaimDailyBonus", "username", username, "error", err)
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		uc.log.Warn("Пользователь не найден в ClaimDailyBonus", "username", username)
+		return nil, ErrUserNotFound
+	}
+
+	coins, claimed, err := uc.userDB.ClaimDailyBonus(ctx, user.ID, uc.dailyAmount)
+	if err != nil {
+		uc.log.Error("Ошибка ClaimDailyBonus", "userID", user.ID, "error", err)
+		return nil, fmt.Errorf("ошибка при начислении бонуса: %w", err)
+	}
+	if !claimed {
+		uc.log.Warn("Ежедневный бонус уже получен", "username", username)
+		return nil, ErrBonusAlreadyClaimed
+	}
+
+	uc.log.Info("Ежедневный бонус начислен", "username", username, "amount", uc.dailyAmount, "coins", coins)
+	return &models.ClaimBonusResponse{Amount: uc.dailyAmount, Coins: coins}, nil
+}
@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"shop/internal/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBonusUseCase_ClaimDailyBonus_Success(t *testing.T) {
+	uc, mockUserDB := newTestBonusUseCase(t, 100)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "alice").Return(&models.DBUser{ID: 1, Username: "alice", Coins: 1000}, nil)
+	mockUserDB.EXPECT().ClaimDailyBonus(gomock.Any(), 1, int64(100)).Return(int64(1100), true, nil)
+
+	response, err := uc.ClaimDailyBonus(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.ClaimBonusResponse{Amount: 100, Coins: 1100}, response)
+}
+
+func TestBonusUseCase_ClaimDailyBonus_AlreadyClaimed(t *testing.T) {
+	uc, mockUserDB := newTestBonusUseCase(t, 100)
+
+	// Бонус за сегодня уже начислен: повторное начисление не происходит.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "alice").Return(&models.DBUser{ID: 1, Username: "alice", Coins: 1100}, nil)
+	mockUserDB.EXPECT().ClaimDailyBonus(gomock.Any(), 1, int64(100)).Return(int64(0), false, nil)
+
+	response, err := uc.ClaimDailyBonus(context.Background(), "alice")
+	assert.ErrorIs(t, err, ErrBonusAlreadyClaimed)
+	assert.Nil(t, response)
+}
+
+func TestBonusUseCase_ClaimDailyBonus_UserNotFound(t *testing.T) {
+	uc, mockUserDB := newTestBonusUseCase(t, 100)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "ghost").Return(nil, nil)
+
+	response, err := uc.ClaimDailyBonus(context.Background(), "ghost")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.Nil(t, response)
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+
+	"shop/internal/models"
+)
+
+// contextKey приватный тип ключей контекста пакета usecase.
+type contextKey string
+
+const (
+	userKey  contextKey = "user"
+	actorKey contextKey = "actor"
+)
+
+// contextUser данные аутентифицированного пользователя, которые хранятся в контексте запроса.
+// Хеш пароля сюда не попадает: контекст доступен любому коду, обрабатывающему запрос.
+type contextUser struct {
+	id       int
+	username string
+	coins    int64
+}
+
+// WithUser добавляет в контекст аутентифицированного пользователя, загруженного middleware
+// одним запросом вместе с балансом, чтобы usecase'ы не искали его повторно.
+// Сохраняются только ID, имя и баланс.
+func WithUser(ctx context.Context, user models.DBUser) context.Context {
+	return context.WithValue(ctx, userKey, contextUser{id: user.ID, username: user.Username, coins: user.Coins})
+}
+
+// UserIDFromContext извлекает ID пользователя из контекста.
+// Второе значение равно false, если пользователь не был определен.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	user, ok := ctx.Value(userKey).(contextUser)
+	return user.id, ok
+}
+
+// lookupUser возвращает пользователя username: из контекста, если middleware уже загрузил его,
+// иначе из userDB. Баланс пользователя из контекста соответствует началу запроса, поэтому
+// операции, изменяющие баланс, перечитывают его под блокировкой. У пользователя из контекста
+// не заполнен PasswordHash. Как и GetUserByUsername, для несуществующего пользователя
+// возвращает nil без ошибки.
+func lookupUser(ctx context.Context, userDB userGetter, username string) (*models.DBUser, error) {
+	if user, ok := ctx.Value(userKey).(contextUser); ok && user.username == username {
+		return &models.DBUser{ID: user.id, Username: user.username, Coins: user.coins}, nil
+	}
+	return userDB.GetUserByUsername(ctx, username)
+}
+
+// WithActor добавляет в контекст имя администратора, выполняющего действие.
+func WithActor(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, actorKey, username)
+}
+
+// ActorFromContext извлекает имя администратора из контекста.
+// Пустая строка означает, что действие выполняется не через API.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"shop/internal/db"
+	"shop/pkg/logger"
+)
+
+// Ошибки
+var (
+	ErrNotEnoughItems = fmt.Errorf("%w: недостаточно предметов в инвентаре", ErrInvalidRequest)
+	ErrSelfGift       = fmt.Errorf("%w: нельзя подарить предмет самому себе", ErrInvalidRequest)
+)
+
+// GiftUseCaseInterface интерфейс для use case'а передачи предметов.
+type GiftUseCaseInterface interface {
+	GiftItem(ctx context.Context, senderUsername string, receiverUsername string, item string, quantity int) error
+}
+
+// GiftUseCase реализует GiftUseCaseInterface.
+type GiftUseCase struct {
+	// ReadOnly запрещает подарки.
+	ReadOnly      bool
+	userDB        giftUserDB
+	transactionDB giftTransactionDB
+	log           *logger.Logger
+}
+
+// NewGiftUseCase создает новый GiftUseCase.
+func NewGiftUseCase(userDB giftUserDB, transactionDB giftTransactionDB, log *logger.Logger) *GiftUseCase {
+	return &GiftUseCase{
+		userDB:        userDB,
+		transactionDB: transactionDB,
+		log:           log,
+	}
+}
+
+// GiftItem передает quantity предметов item из инвентаря отправителя получателю.
+// Списание, зачисление и запись о подарке выполняются в одной транзакции.
+func (uc *GiftUseCase) GiftItem(ctx context.Context, senderUsername string, receiverUsername string, item string, quantity int) (err error) {
+	uc.log.Debug("GiftItem", "senderUsername", senderUsername, "receiverUsername", receiverUsername, "item", item, "quantity", quantity)
+
+	if uc.ReadOnly {
+		return ErrReadOnly
+	}
+
+	if strings.TrimSpace(item) == "" {
+		uc.log.Warn("Название предмета не указано")
+		return ErrItemRequired
+	}
+	if utf8.RuneCountInString(item) > MaxItemNameLength {
+		uc.log.Warn("Слишком длинное название предмета", "length", utf8.RuneCountInString(item))
+		return ErrItemNameLength
+	}
+	if quantity <= 0 {
+		uc.log.Warn("Неверное количество предметов", "quantity", quantity)
+		return ErrInvalidQuantity
+	}
+
+	sender, err := lookupUser(ctx, uc.userDB, senderUsername)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserByUsername (sender)", "senderUsername", senderUsername, "error", err)
+		return fmt.Errorf("ошибка при получении отправителя: %w", err)
+	}
+	if sender == nil {
+		uc.log.Warn("Отправитель не найден", "senderUsername", senderUsername)
+		return ErrUserNotFound
+	}
+
+	receiver, err := uc.userDB.GetUserByUsername(ctx, receiverUsername)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserByUsername (receiver)", "receiverUsername", receiverUsername, "error", err)
+		return fmt.Errorf("ошибка при получении получателя: %w", err)
+	}
+	if receiver == nil {
+		uc.log.Warn("Получатель не найден", "receiverUsername", receiverUsername)
+		return ErrReceiverNotFound
+	}
+
+	if sender.ID == receiver.ID {
+		uc.log.Warn("Попытка подарить предмет самому себе", "senderUsername", senderUsername)
+		return ErrSelfGift
+	}
+
+	tx, err := uc.transactionDB.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		uc.log.Error("Ошибка начала транзакции", "error", err)
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			if err := tx.Rollback(); err != nil {
+				uc.log.Error("Ошибка отката транзакции", "error", err)
+			}
+			uc.log.Error("Паника во время транзакции, rollback", "panic", p)
+			panic(p) // Re-panic after rollback.
+		} else if err != nil {
+			if err := tx.Rollback(); err != nil {
+				uc.log.Error("Ошибка отката транзакции", "error", err)
+			}
+			uc.log.Error("Транзакция отменена из-за ошибки", "error", err)
+		} else if commitErr := tx.Commit(); commitErr != nil {
+			uc.log.Error("Ошибка коммита транзакции", "error", commitErr)
+			err = fmt.Errorf("ошибка коммита транзакции: %w", commitErr)
+		}
+	}()
+
+	err = uc.userDB.RemoveFromInventory(ctx, sender.ID, item, quantity, tx)
+	if errors.Is(err, db.ErrNotEnoughItems) {
+		uc.log.Warn("Недостаточно предметов для подарка", "senderUserID", sender.ID, "item", item, "quantity", quantity)
+		err = ErrNotEnoughItems
+		return err
+	}
+	if err != nil {
+		uc.log.Error("Ошибка RemoveFromInventory", "senderUserID", sender.ID, "item", item, "error", err)
+		return err
+	}
+
+	_, err = uc.userDB.UpdateUserInventory(ctx, receiver.ID, item, quantity, tx)
+	if errors.Is(err, db.ErrItemQuantityCapped) {
+		uc.log.Warn("Превышено максимальное количество предмета у получателя", "receiverUserID", receiver.ID, "item", item, "quantity", quantity)
+		err = ErrItemQuantityCapped
+		return err
+	}
+	if errors.Is(err, db.ErrItemNotFound) {
+		uc.log.Warn("Товар подарка отсутствует в каталоге", "receiverUserID", receiver.ID, "item", item)
+		err = ErrItemNotFound
+		return err
+	}
+	if err != nil {
+		uc.log.Error("Ошибка UpdateUserInventory", "receiverUserID", receiver.ID, "item", item, "error", err)
+		return err
+	}
+
+	err = uc.transactionDB.RecordGift(ctx, sender.ID, receiver.ID, item, quantity, tx)
+	if db.IsForeignKeyViolation(err) {
+		uc.log.Warn("Получатель удален во время передачи подарка", "receiverUserID", receiver.ID, "error", err)
+		err = ErrReceiverNotFound
+		return err
+	}
+	if err != nil {
+		uc.log.Error("Ошибка RecordGift", "senderUserID", sender.ID, "receiverUserID", receiver.ID, "error", err)
+		return err
+	}
+
+	uc.log.Info("Предмет подарен", "senderUsername", senderUsername, "receiverUsername", receiverUsername, "item", item, "quantity", quantity)
+	return nil
+}
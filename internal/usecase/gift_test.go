@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	dbpkg "shop/internal/db"
+	"shop/internal/models"
+)
+
+func TestGiftUseCase_GiftItem_Success(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestGiftUseCase(t)
+	db, sqlMock := newTestSQLMock(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "alice").Return(&models.DBUser{ID: 1, Username: "alice"}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "bob").Return(&models.DBUser{ID: 2, Username: "bob"}, nil)
+
+	// Списание, зачисление и запись о подарке выполняются в одной транзакции.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	gomock.InOrder(
+		mockUserDB.EXPECT().RemoveFromInventory(gomock.Any(), 1, "pen", 2, gomock.Not(gomock.Nil())).Return(nil),
+		mockUserDB.EXPECT().UpdateUserInventory(gomock.Any(), 2, "pen", 2, gomock.Not(gomock.Nil())).Return(2, nil),
+		mockTransactionDB.EXPECT().RecordGift(gomock.Any(), 1, 2, "pen", 2, gomock.Not(gomock.Nil())).Return(nil),
+	)
+
+	err := uc.GiftItem(context.Background(), "alice", "bob", "pen", 2)
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestGiftUseCase_GiftItem_NotEnoughItems(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestGiftUseCase(t)
+	db, sqlMock := newTestSQLMock(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "alice").Return(&models.DBUser{ID: 1, Username: "alice"}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "bob").Return(&models.DBUser{ID: 2, Username: "bob"}, nil)
+
+	// Предметов меньше, чем дарится: транзакция откатывается, получатель ничего не получает.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().RemoveFromInventory(gomock.Any(), 1, "pen", 5, gomock.Any()).
+		Return(fmt.Errorf("ошибка при списании предметов из инвентаря: %w", dbpkg.ErrNotEnoughItems))
+
+	err := uc.GiftItem(context.Background(), "alice", "bob", "pen", 5)
+	assert.ErrorIs(t, err, ErrNotEnoughItems)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestGiftUseCase_GiftItem_ReceiverNotFound(t *testing.T) {
+	uc, mockUserDB, _ := newTestGiftUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "alice").Return(&models.DBUser{ID: 1, Username: "alice"}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "ghost").Return(nil, nil)
+
+	err := uc.GiftItem(context.Background(), "alice", "ghost", "pen", 1)
+	assert.ErrorIs(t, err, ErrReceiverNotFound)
+}
+
+func TestGiftUseCase_GiftItem_SelfGift(t *testing.T) {
+	uc, mockUserDB, _ := newTestGiftUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "alice").Return(&models.DBUser{ID: 1, Username: "alice"}, nil).Times(2)
+
+	err := uc.GiftItem(context.Background(), "alice", "alice", "pen", 1)
+	assert.ErrorIs(t, err, ErrSelfGift)
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	dbmocks "shop/internal/db/mocks"
+)
+
+// testJWTSecret секрет для подписи токенов в тестах.
+const testJWTSecret = "secret"
+
+// newTestUserUseCase создает UserUseCase с моками хранилищ.
+// Ожидания моков проверяются автоматически по завершении теста.
+func newTestUserUseCase(t *testing.T) (*UserUseCase, *dbmocks.MockUserDBInterface, *dbmocks.MockTransactionDBInterface) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	userDB := dbmocks.NewMockUserDBInterface(ctrl)
+	transactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
+	return NewUserInfoUseCase(testJWTSecret, userDB, transactionDB, log), userDB, transactionDB
+}
+
+// newTestSendCoinUseCase создает SendCoinUseCase с моками хранилищ, разрешающий любую сумму перевода.
+func newTestSendCoinUseCase(t *testing.T) (*SendCoinUseCase, *dbmocks.MockUserDBInterface, *dbmocks.MockTransactionDBInterface) {
+	return newTestSendCoinUseCaseWithDenomination(t, 1)
+}
+
+// newTestSendCoinUseCaseWithDenomination создает SendCoinUseCase с заданным шагом суммы перевода.
+func newTestSendCoinUseCaseWithDenomination(t *testing.T, denomination int64) (*SendCoinUseCase, *dbmocks.MockUserDBInterface, *dbmocks.MockTransactionDBInterface) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	userDB := dbmocks.NewMockUserDBInterface(ctrl)
+	transactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
+	return NewSendCoinUseCase(denomination, userDB, transactionDB, log), userDB, transactionDB
+}
+
+// newTestBuyItemUseCase создает BuyItemUseCase с моками хранилищ.
+func newTestBuyItemUseCase(t *testing.T) (*BuyItemUseCase, *dbmocks.MockUserDBInterface, *dbmocks.MockItemDBInterface, *dbmocks.MockTransactionDBInterface) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	userDB := dbmocks.NewMockUserDBInterface(ctrl)
+	itemDB := dbmocks.NewMockItemDBInterface(ctrl)
+	transactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
+	return NewBuyItemUseCase(userDB, itemDB, transactionDB, log), userDB, itemDB, transactionDB
+}
+
+// newTestAdminUseCase создает AdminUseCase с моками хранилищ.
+func newTestAdminUseCase(t *testing.T) (*AdminUseCase, *dbmocks.MockUserDBInterface, *dbmocks.MockItemDBInterface, *dbmocks.MockTransactionDBInterface) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	userDB := dbmocks.NewMockUserDBInterface(ctrl)
+	itemDB := dbmocks.NewMockItemDBInterface(ctrl)
+	transactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
+	return NewAdminUseCase(userDB, itemDB, transactionDB, log), userDB, itemDB, transactionDB
+}
+
+// newTestBonusUseCase создает BonusUseCase с ежедневным бонусом dailyAmount.
+func newTestBonusUseCase(t *testing.T, dailyAmount int64) (*BonusUseCase, *dbmocks.MockUserDBInterface) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	userDB := dbmocks.NewMockUserDBInterface(ctrl)
+	return NewBonusUseCase(dailyAmount, userDB, log), userDB
+}
+
+// newTestSQLMock создает sqlmock базу данных, которая закрывается по завершении теста.
+func newTestSQLMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("не удалось создать sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, sqlMock
+}
+
+// newTestGiftUseCase создает GiftUseCase с моками хранилищ.
+func newTestGiftUseCase(t *testing.T) (*GiftUseCase, *dbmocks.MockUserDBInterface, *dbmocks.MockTransactionDBInterface) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	userDB := dbmocks.NewMockUserDBInterface(ctrl)
+	transactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
+	return NewGiftUseCase(userDB, transactionDB, log), userDB, transactionDB
+}
@@ -3,34 +3,66 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
 
 	"shop/internal/db"
+	"shop/internal/metrics"
+	"shop/internal/models"
 	"shop/pkg/logger"
 )
 
 // Ошибки
 var (
-	ErrItemNotFound   = fmt.Errorf("%w: товар не найден", ErrNotFound)
-	ErrItemRequired   = fmt.Errorf("%w: название предмета обязательно", ErrInvalidRequest)
-	ErrNotEnoughCoins = fmt.Errorf("%w: недостаточно монет", ErrInvalidRequest)
+	ErrItemNotFound       = fmt.Errorf("%w: товар не найден", ErrNotFound)
+	ErrItemRequired       = fmt.Errorf("%w: название предмета обязательно", ErrInvalidRequest)
+	ErrItemNameLength     = fmt.Errorf("%w: слишком длинное название предмета", ErrInvalidRequest)
+	ErrNotEnoughCoins     = fmt.Errorf("%w: недостаточно монет", ErrInvalidRequest)
+	ErrInvalidQuantity    = fmt.Errorf("%w: количество должно быть положительным", ErrInvalidRequest)
+	ErrItemQuantityCapped = fmt.Errorf("%w: превышено максимальное количество предмета в инвентаре", ErrInvalidRequest)
+	ErrEmptyCart          = fmt.Errorf("%w: корзина пуста", ErrInvalidRequest)
+	ErrQuantityTooLarge   = fmt.Errorf("%w: количество не может превышать %d", ErrInvalidRequest, MaxPurchaseQuantity)
+	ErrTotalTooLarge      = fmt.Errorf("%w: слишком большая стоимость покупки", ErrInvalidRequest)
+	ErrCartTooLarge       = fmt.Errorf("%w: слишком много позиций в корзине", ErrInvalidRequest)
 )
 
+// MaxItemNameLength максимальная длина названия предмета в символах.
+const MaxItemNameLength = 64
+
+// MaxPurchaseQuantity максимальное количество предметов в одной покупке.
+const MaxPurchaseQuantity = 1000
+
 // BuyItemUseCaseInterface интерфейс для use case'а покупки предмета.
 type BuyItemUseCaseInterface interface {
-	BuyItem(ctx context.Context, username string, itemName string) error
+	BuyItem(ctx context.Context, username string, itemName string, quantity int) (*models.BuyItemResponse, error)
+	QuoteCart(ctx context.Context, items []models.CartItem) (*models.CartQuoteResponse, error)
+	GetCatalog(ctx context.Context) (*models.Catalog, error)
 }
 
 // BuyItemUseCase реализует BuyItemUseCaseInterface.
 type BuyItemUseCase struct {
-	userDB        db.UserDBInterface
-	itemDB        db.ItemDBInterface
-	transactionDB db.TransactionDBInterface
+	// ReadOnly запрещает покупки; каталог и расчет корзины остаются доступны.
+	ReadOnly bool
+	// MinBalance минимальный баланс пользователя после покупки.
+	MinBalance int64
+	// MaxCartItems максимальное количество позиций в корзине. 0 снимает ограничение.
+	MaxCartItems int
+	// Transactions метрики транзакций; nil отключает их.
+	Transactions  *metrics.Transactions
+	userDB        buyItemUserDB
+	itemDB        buyItemItemDB
+	transactionDB txBeginner
 	log           *logger.Logger
 }
 
 // NewBuyItemUseCase создает новый BuyItemUseCase.
-func NewBuyItemUseCase(userDB db.UserDBInterface, itemDB db.ItemDBInterface, transactionDB db.TransactionDBInterface, log *logger.Logger) *BuyItemUseCase {
+func NewBuyItemUseCase(userDB buyItemUserDB, itemDB buyItemItemDB, transactionDB txBeginner, log *logger.Logger) *BuyItemUseCase {
 	return &BuyItemUseCase{
 		userDB:        userDB,
 		itemDB:        itemDB,
@@ -39,73 +71,207 @@ func NewBuyItemUseCase(userDB db.UserDBInterface, itemDB db.ItemDBInterface, tra
 	}
 }
 
-// BuyItem обрабатывает бизнес-логику покупки предмета.
-func (uc *BuyItemUseCase) BuyItem(ctx context.Context, username string, item string) error {
-	uc.log.Debug("BuyItem", "username", username, "item", item)
+// BuyItem обрабатывает бизнес-логику покупки предмета в указанном количестве.
+// Возвращает баланс пользователя и количество предмета в инвентаре после покупки.
+func (uc *BuyItemUseCase) BuyItem(ctx context.Context, username string, item string, quantity int) (response *models.BuyItemResponse, err error) {
+	uc.log.Debug("BuyItem", "username", username, "item", item, "quantity", quantity)
 
-	if item == "" {
-		uc.log.Warn("Название предмета не указано")
-		return ErrItemRequired
+	if uc.ReadOnly {
+		return nil, ErrReadOnly
 	}
 
-	price, err := uc.itemDB.GetItemPrice(ctx, item)
+	if err := uc.validatePurchase(item, quantity); err != nil {
+		return nil, err
+	}
+
+	price, err := uc.itemPrice(ctx, item)
 	if err != nil {
-		uc.log.Error("Ошибка GetItemPrice", "item", item, "error", err)
-		return ErrItemNotFound
+		return nil, err
+	}
+	total, ok := mulInt(price, int64(quantity))
+	if !ok {
+		uc.log.Warn("Переполнение стоимости покупки", "item", item, "price", price, "quantity", quantity)
+		return nil, ErrTotalTooLarge
 	}
 
-	user, err := uc.userDB.GetUserByUsername(ctx, username)
+	user, err := lookupUser(ctx, uc.userDB, username)
 	if err != nil {
 		uc.log.Error("Ошибка GetUserByUsername", "username", username, "error", err)
-		return fmt.Errorf("ошибка при получении пользователя: %w", err)
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
 	}
 	if user == nil {
 		uc.log.Warn("Пользователь не найден", "username", username)
-		return ErrUserNotFound
+		return nil, ErrUserNotFound
 	}
 	uc.log.Debug("Пользователь найден", "username", username, "userID", user.ID)
 
-	if user.Coins < price {
-		uc.log.Warn("Недостаточно монет", "username", username, "coins", user.Coins, "price", price, "item", item)
-		return ErrNotEnoughCoins
+	if user.Coins < total {
+		uc.log.Warn("Недостаточно монет", "username", username, "coins", user.Coins, "total", total, "item", item)
+		return nil, withDeficit(ErrNotEnoughCoins, total-user.Coins)
+	}
+	if err := checkMinBalance(user.Coins-total, uc.MinBalance); err != nil {
+		uc.log.Warn("Баланс опустится ниже минимального", "username", username, "coins", user.Coins, "total", total, "minBalance", uc.MinBalance)
+		return nil, err
 	}
 
-	tx, err := uc.transactionDB.GetDB().BeginTx(ctx, nil)
-	if err != nil {
-		uc.log.Error("Ошибка начала транзакции", "error", err)
-		return fmt.Errorf("ошибка начала транзакции: %w", err)
-	}
-	defer func() {
-		if p := recover(); p != nil {
-			if err := tx.Rollback(); err != nil {
-				uc.log.Error("Ошибка отката транзакции", "error", err)
-			}
-			uc.log.Error("Паника во время транзакции, rollback", "panic", p)
-			panic(p) // Re-panic after rollback.
-		} else if err != nil {
-			if err := tx.Rollback(); err != nil {
-				uc.log.Error("Ошибка отката транзакции", "error", err)
-			}
-			uc.log.Error("Транзакция отменена из-за ошибки", "error", err)
-		} else {
-			err = tx.Commit()
-			if err != nil {
-				uc.log.Error("Ошибка коммита транзакции", "error", err)
-			}
+	// Списание монет и пополнение инвентаря применяются вместе или не применяются вовсе.
+	// При конфликте сериализации покупка повторяется: DeductUserCoins заново проверяет баланс.
+	err = withTransactionRetry(ctx, uc.transactionDB, uc.log, uc.Transactions, txOperationBuyItem, func(tx *sql.Tx) error {
+		coins, err := uc.userDB.DeductUserCoins(ctx, user.ID, total, tx)
+		if errors.Is(err, db.ErrNotEnoughCoins) {
+			// Баланс изменился после проверки выше, например из-за параллельной покупки.
+			uc.log.Warn("Недостаточно монет при списании", "userID", user.ID, "total", total, "item", item)
+			return ErrNotEnoughCoins
+		}
+		if err != nil {
+			uc.log.Error("Ошибка DeductUserCoins", "userID", user.ID, "total", total, "error", err)
+			return err
+		}
+		if err := checkMinBalance(coins, uc.MinBalance); err != nil {
+			uc.log.Warn("Баланс опустился ниже минимального при списании", "userID", user.ID, "coins", coins, "minBalance", uc.MinBalance)
+			return err
 		}
-	}()
 
-	err = uc.userDB.UpdateUserCoins(ctx, user.ID, user.Coins-price)
+		itemQuantity, err := uc.userDB.UpdateUserInventory(ctx, user.ID, item, quantity, tx)
+		if errors.Is(err, db.ErrItemQuantityCapped) {
+			uc.log.Warn("Превышено максимальное количество предмета", "userID", user.ID, "item", item, "quantity", quantity)
+			return ErrItemQuantityCapped
+		}
+		if errors.Is(err, db.ErrItemNotFound) {
+			// Товар удален из каталога после получения цены.
+			uc.log.Warn("Товар отсутствует в каталоге", "userID", user.ID, "item", item)
+			return ErrItemNotFound
+		}
+		if err != nil {
+			uc.log.Error("Ошибка UpdateUserInventory", "userID", user.ID, "item", item, "error", err)
+			return err
+		}
+
+		response = &models.BuyItemResponse{Coins: coins, Quantity: itemQuantity}
+		return nil
+	})
 	if err != nil {
-		uc.log.Error("Ошибка UpdateUserCoins", "userID", user.ID, "price", price, "error", err)
-		return err
+		return nil, err
+	}
+	return response, nil
+}
+
+// QuoteCart рассчитывает стоимость корзины по текущим ценам без списания монет
+// и изменения инвентаря. Неизвестный товар в корзине возвращает ErrItemNotFound.
+func (uc *BuyItemUseCase) QuoteCart(ctx context.Context, items []models.CartItem) (*models.CartQuoteResponse, error) {
+	uc.log.Debug("QuoteCart", "count", len(items))
+
+	if len(items) == 0 {
+		return nil, ErrEmptyCart
+	}
+	if uc.MaxCartItems > 0 && len(items) > uc.MaxCartItems {
+		uc.log.Warn("Слишком много позиций в корзине", "count", len(items), "maxCartItems", uc.MaxCartItems)
+		return nil, fmt.Errorf("%w: не больше %d", ErrCartTooLarge, uc.MaxCartItems)
+	}
+
+	response := &models.CartQuoteResponse{Items: make([]models.CartQuoteItem, 0, len(items))}
+	for _, item := range items {
+		if err := uc.validatePurchase(item.Item, item.Quantity); err != nil {
+			return nil, fmt.Errorf("товар '%s': %w", item.Item, err)
+		}
+		price, err := uc.itemPrice(ctx, item.Item)
+		if err != nil {
+			return nil, fmt.Errorf("товар '%s': %w", item.Item, err)
+		}
+		lineTotal, ok := mulInt(price, int64(item.Quantity))
+		if !ok {
+			return nil, fmt.Errorf("товар '%s': %w", item.Item, ErrTotalTooLarge)
+		}
+		cartTotal, ok := addInt(response.Total, lineTotal)
+		if !ok {
+			return nil, ErrTotalTooLarge
+		}
+		response.Total = cartTotal
+		response.Items = append(response.Items, models.CartQuoteItem{
+			Item:     item.Item,
+			Quantity: item.Quantity,
+			Price:    price,
+			Total:    lineTotal,
+		})
 	}
+	return response, nil
+}
 
-	err = uc.userDB.UpdateUserInventory(ctx, user.ID, item, 1, tx)
+// GetCatalog возвращает каталог товаров с его версией и временем последнего изменения.
+// Версия это хеш названий и цен товаров, поэтому она меняется и при добавлении товара,
+// и при изменении цены.
+func (uc *BuyItemUseCase) GetCatalog(ctx context.Context) (*models.Catalog, error) {
+	uc.log.Debug("GetCatalog")
+
+	items, err := uc.itemDB.GetItems(ctx)
 	if err != nil {
-		uc.log.Error("Ошибка UpdateUserInventory", "userID", user.ID, "item", item, "error", err)
-		return err
+		uc.log.Error("Ошибка GetItems", "error", err)
+		return nil, fmt.Errorf("ошибка при получении каталога: %w", err)
 	}
 
+	catalog := &models.Catalog{Items: make([]models.CatalogItem, 0, len(items))}
+	hash := sha256.New()
+	for _, item := range items {
+		catalog.Items = append(catalog.Items, models.CatalogItem{ItemName: item.ItemName, Price: item.Price})
+		fmt.Fprintf(hash, "%s\x00%d\x00", item.ItemName, item.Price)
+		if item.UpdatedAt.After(catalog.UpdatedAt) {
+			catalog.UpdatedAt = item.UpdatedAt
+		}
+	}
+	catalog.Version = hex.EncodeToString(hash.Sum(nil)[:16])
+	return catalog, nil
+}
+
+// validatePurchase проверяет название и количество покупаемого предмета.
+func (uc *BuyItemUseCase) validatePurchase(item string, quantity int) error {
+	if strings.TrimSpace(item) == "" {
+		uc.log.Warn("Название предмета не указано")
+		return ErrItemRequired
+	}
+	if utf8.RuneCountInString(item) > MaxItemNameLength {
+		uc.log.Warn("Слишком длинное название предмета", "length", utf8.RuneCountInString(item))
+		return ErrItemNameLength
+	}
+	if quantity <= 0 {
+		uc.log.Warn("Неверное количество предметов", "quantity", quantity)
+		return ErrInvalidQuantity
+	}
+	if quantity > MaxPurchaseQuantity {
+		uc.log.Warn("Слишком большое количество предметов", "quantity", quantity)
+		return ErrQuantityTooLarge
+	}
 	return nil
 }
+
+// mulInt перемножает неотрицательные a и b, второе значение false при переполнении.
+func mulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	if a > math.MaxInt64/b {
+		return 0, false
+	}
+	return a * b, true
+}
+
+// addInt складывает неотрицательные a и b, второе значение false при переполнении.
+func addInt(a, b int64) (int64, bool) {
+	if a > math.MaxInt64-b {
+		return 0, false
+	}
+	return a + b, true
+}
+
+// itemPrice получает цену предмета, отличая отсутствующий товар от ошибки хранилища.
+func (uc *BuyItemUseCase) itemPrice(ctx context.Context, item string) (int64, error) {
+	price, err := uc.itemDB.GetItemPrice(ctx, item)
+	if errors.Is(err, db.ErrItemNotFound) {
+		uc.log.Warn("Товар не найден", "item", item)
+		return 0, ErrItemNotFound
+	}
+	if err != nil {
+		uc.log.Error("Ошибка GetItemPrice", "item", item, "error", err)
+		return 0, fmt.Errorf("ошибка при получении цены товара: %w", err)
+	}
+	return price, nil
+}
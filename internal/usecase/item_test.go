@@ -3,11 +3,18 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dbpkg "shop/internal/db"
 	dbmocks "shop/internal/db/mocks"
 	"shop/internal/models"
 	"shop/pkg/logger"
@@ -18,17 +25,11 @@ var (
 )
 
 func TestBuyItemUseCase_BuyItem_Success(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockItemDB := dbmocks.NewMockItemDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	uc := NewBuyItemUseCase(mockUserDB, mockItemDB, mockTransactionDB, log)
+	uc, mockUserDB, mockItemDB, mockTransactionDB := newTestBuyItemUseCase(t)
 
 	// Данные пользователя и цена товара.
 	user := &models.DBUser{ID: 1, Username: "testuser", Coins: 100}
-	itemPrice := 50
+	itemPrice := int64(50)
 
 	// Ожидаем получение цены товара.
 	mockItemDB.
@@ -61,55 +62,90 @@ func TestBuyItemUseCase_BuyItem_Success(t *testing.T) {
 
 	mockUserDB.
 		EXPECT().
-		UpdateUserCoins(gomock.Any(), 1, 50).
-		Return(nil)
+		DeductUserCoins(gomock.Any(), 1, int64(50), gomock.Not(gomock.Nil())). // Списание в транзакции.
+		Return(int64(50), nil)
 
 	mockUserDB.
 		EXPECT().
 		UpdateUserInventory(gomock.Any(), 1, "pen", 1, gomock.Any()).
-		Return(nil)
+		Return(1, nil)
 
-	err = uc.BuyItem(context.Background(), "testuser", "pen")
+	response, err := uc.BuyItem(context.Background(), "testuser", "pen", 1)
 	assert.NoError(t, err)
+	// Ответ содержит баланс и количество предметов после покупки.
+	assert.Equal(t, &models.BuyItemResponse{Coins: 50, Quantity: 1}, response)
 
 	if err := sqlMock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
 
-func TestBuyItemUseCase_BuyItem_ItemNotFound(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestBuyItemUseCase_BuyItem_CommitFailure(t *testing.T) {
+	uc, mockUserDB, mockItemDB, mockTransactionDB := newTestBuyItemUseCase(t)
+
+	user := &models.DBUser{ID: 1, Username: "testuser", Coins: 100}
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(user, nil)
+
+	db, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("не удалось создать sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	// Коммит транзакции завершается ошибкой.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit().WillReturnError(errors.New("commit failed"))
+
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	// Списание монет выполняется внутри транзакции, поэтому не применится без коммита.
+	mockUserDB.EXPECT().DeductUserCoins(gomock.Any(), 1, int64(10), gomock.Not(gomock.Nil())).Return(int64(90), nil)
+	mockUserDB.EXPECT().UpdateUserInventory(gomock.Any(), 1, "pen", 1, gomock.Any()).Return(1, nil)
+
+	// Ошибка коммита возвращается вызывающему, а не теряется.
+	response, err := uc.BuyItem(context.Background(), "testuser", "pen", 1)
+	assert.Error(t, err)
+	assert.Nil(t, response)
+	assert.Contains(t, err.Error(), "commit failed")
+
+	if err := sqlMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
 
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockItemDB := dbmocks.NewMockItemDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	uc := NewBuyItemUseCase(mockUserDB, mockItemDB, mockTransactionDB, log)
+func TestBuyItemUseCase_BuyItem_ItemNotFound(t *testing.T) {
+	uc, _, mockItemDB, _ := newTestBuyItemUseCase(t)
 
-	// Ожидаем, что GetItemPrice вернет ошибку.
+	// Ожидаем, что GetItemPrice сообщит об отсутствии товара.
 	mockItemDB.
 		EXPECT().
 		GetItemPrice(gomock.Any(), "nonexistent_item").
-		Return(0, errors.New("item not found"))
+		Return(int64(0), fmt.Errorf("товар 'nonexistent_item': %w", dbpkg.ErrItemNotFound))
 
 	// Проверяем, что метод возвращает ошибку.  Используем .Contains, чтобы проверить часть сообщения об ошибке.
-	err := uc.BuyItem(context.Background(), "testuser", "nonexistent_item")
+	_, err := uc.BuyItem(context.Background(), "testuser", "nonexistent_item", 1)
 	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrItemNotFound)
 	assert.Contains(t, err.Error(), ErrNotFound.Error(), "Error message")
 }
 
-func TestBuyItemUseCase_BuyItem_NotEnoughCoins(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestBuyItemUseCase_BuyItem_PriceLookupFailure(t *testing.T) {
+	uc, _, mockItemDB, _ := newTestBuyItemUseCase(t)
 
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockItemDB := dbmocks.NewMockItemDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	uc := NewBuyItemUseCase(mockUserDB, mockItemDB, mockTransactionDB, log)
+	// Ошибка БД не выдается за отсутствие товара.
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(0), errors.New("connection refused"))
+
+	_, err := uc.BuyItem(context.Background(), "testuser", "pen", 1)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrItemNotFound)
+}
+
+func TestBuyItemUseCase_BuyItem_NotEnoughCoins(t *testing.T) {
+	uc, mockUserDB, mockItemDB, _ := newTestBuyItemUseCase(t)
 
 	// У пользователя недостаточно монет.
 	user := &models.DBUser{ID: 1, Username: "testuser", Coins: 30}
-	itemPrice := 50
+	itemPrice := int64(50)
 
 	mockItemDB.
 		EXPECT().
@@ -122,22 +158,356 @@ func TestBuyItemUseCase_BuyItem_NotEnoughCoins(t *testing.T) {
 		Return(user, nil)
 
 	// Проверяем ошибку ErrNotEnoughCoins.
-	err := uc.BuyItem(context.Background(), "testuser", "pen")
+	_, err := uc.BuyItem(context.Background(), "testuser", "pen", 1)
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, ErrNotEnoughCoins))
+	assert.Contains(t, err.Error(), "не хватает 20 монет")
 }
 
-func TestBuyItemUseCase_BuyItem_ItemRequired(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestBuyItemUseCase_BuyItem_BelowMinBalance(t *testing.T) {
+	uc, mockUserDB, mockItemDB, _ := newTestBuyItemUseCase(t)
+	uc.MinBalance = 50
+
+	// Монет на покупку хватает, но баланс опустится ниже минимального.
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser", Coins: 55}, nil)
+
+	_, err := uc.BuyItem(context.Background(), "testuser", "pen", 1)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	assert.Contains(t, err.Error(), "ниже 50")
+}
+
+func TestBuyItemUseCase_BuyItem_BelowMinBalanceAfterDeduct(t *testing.T) {
+	uc, mockUserDB, mockItemDB, mockTransactionDB := newTestBuyItemUseCase(t)
+	uc.MinBalance = 50
+	db, sqlMock := newTestSQLMock(t)
+
+	// Параллельная операция уменьшила баланс: списание откатывается.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser", Coins: 100}, nil)
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().DeductUserCoins(gomock.Any(), 1, int64(10), gomock.Any()).Return(int64(45), nil)
 
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockItemDB := dbmocks.NewMockItemDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	uc := NewBuyItemUseCase(mockUserDB, mockItemDB, mockTransactionDB, log)
+	_, err := uc.BuyItem(context.Background(), "testuser", "pen", 1)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBuyItemUseCase_BuyItem_ItemRequired(t *testing.T) {
+	uc, _, _, _ := newTestBuyItemUseCase(t)
 
 	// Проверяем ошибку ErrItemRequired, если не указано название товара.
-	err := uc.BuyItem(context.Background(), "testuser", "")
+	_, err := uc.BuyItem(context.Background(), "testuser", "", 1)
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, ErrItemRequired))
+
+	// Название из одних пробелов тоже считается отсутствующим.
+	_, err = uc.BuyItem(context.Background(), "testuser", " \t ", 1)
+	assert.ErrorIs(t, err, ErrItemRequired)
+}
+
+func TestBuyItemUseCase_BuyItem_ItemNameTooLong(t *testing.T) {
+	// Моки без ожиданий: любое обращение к БД провалит тест.
+	uc, _, _, _ := newTestBuyItemUseCase(t)
+
+	_, err := uc.BuyItem(context.Background(), "testuser", strings.Repeat("a", MaxItemNameLength+1), 1)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrItemNameLength))
+}
+
+func TestBuyItemUseCase_BuyItem_Quantity(t *testing.T) {
+	uc, mockUserDB, mockItemDB, mockTransactionDB := newTestBuyItemUseCase(t)
+
+	db, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("не удалось создать sqlmock: %v", err)
+	}
+	defer db.Close()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+
+	// Списывается стоимость всех предметов, в инвентарь добавляется указанное количество.
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser", Coins: 100}, nil)
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().DeductUserCoins(gomock.Any(), 1, int64(30), gomock.Not(gomock.Nil())).Return(int64(70), nil)
+	mockUserDB.EXPECT().UpdateUserInventory(gomock.Any(), 1, "pen", 3, gomock.Any()).Return(5, nil)
+
+	// В ответе общее количество предметов с учетом ранее купленных.
+	response, err := uc.BuyItem(context.Background(), "testuser", "pen", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.BuyItemResponse{Coins: 70, Quantity: 5}, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBuyItemUseCase_BuyItem_InvalidQuantity(t *testing.T) {
+	// Моки без ожиданий: любое обращение к БД провалит тест.
+	uc, _, _, _ := newTestBuyItemUseCase(t)
+
+	for _, quantity := range []int{0, -1} {
+		_, err := uc.BuyItem(context.Background(), "testuser", "pen", quantity)
+		assert.ErrorIs(t, err, ErrInvalidQuantity)
+	}
+}
+
+func TestBuyItemUseCase_BuyItem_QuantityTooLarge(t *testing.T) {
+	// Моки без ожиданий: количество проверяется до обращения к БД.
+	uc, _, _, _ := newTestBuyItemUseCase(t)
+
+	for _, quantity := range []int{MaxPurchaseQuantity + 1, math.MaxInt} {
+		_, err := uc.BuyItem(context.Background(), "testuser", "pen", quantity)
+		assert.ErrorIs(t, err, ErrQuantityTooLarge)
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	}
+}
+
+func TestBuyItemUseCase_BuyItem_TotalOverflow(t *testing.T) {
+	uc, _, mockItemDB, _ := newTestBuyItemUseCase(t)
+
+	// Допустимое количество с огромной ценой не должно переполнять стоимость.
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(math.MaxInt64/2), nil)
+
+	_, err := uc.BuyItem(context.Background(), "testuser", "pen", 3)
+	assert.ErrorIs(t, err, ErrTotalTooLarge)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestBuyItemUseCase_BuyItem_QuantityNotEnoughCoins(t *testing.T) {
+	uc, mockUserDB, mockItemDB, _ := newTestBuyItemUseCase(t)
+
+	// Монет хватает на один предмет, но не на все.
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser", Coins: 25}, nil)
+
+	_, err := uc.BuyItem(context.Background(), "testuser", "pen", 3)
+	assert.ErrorIs(t, err, ErrNotEnoughCoins)
+	assert.Contains(t, err.Error(), "не хватает 5 монет")
+}
+
+func TestBuyItemUseCase_BuyItem_QuantityCapped(t *testing.T) {
+	uc, mockUserDB, mockItemDB, mockTransactionDB := newTestBuyItemUseCase(t)
+	db, sqlMock := newTestSQLMock(t)
+
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser", Coins: 100}, nil)
+
+	// Превышение лимита откатывает покупку вместе со списанием монет.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().DeductUserCoins(gomock.Any(), 1, int64(10), gomock.Any()).Return(int64(90), nil)
+	mockUserDB.EXPECT().UpdateUserInventory(gomock.Any(), 1, "pen", 1, gomock.Any()).
+		Return(0, fmt.Errorf("ошибка при обновлении инвентаря: %w", dbpkg.ErrItemQuantityCapped))
+
+	_, err := uc.BuyItem(context.Background(), "testuser", "pen", 1)
+	assert.ErrorIs(t, err, ErrItemQuantityCapped)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBuyItemUseCase_BuyItem_ItemRemovedFromCatalog(t *testing.T) {
+	uc, mockUserDB, mockItemDB, mockTransactionDB := newTestBuyItemUseCase(t)
+	db, sqlMock := newTestSQLMock(t)
+
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser", Coins: 100}, nil)
+
+	// Товар удален из каталога между получением цены и записью в инвентарь.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().DeductUserCoins(gomock.Any(), 1, int64(10), gomock.Any()).Return(int64(90), nil)
+	mockUserDB.EXPECT().UpdateUserInventory(gomock.Any(), 1, "pen", 1, gomock.Any()).
+		Return(0, fmt.Errorf("товар 'pen': %w", dbpkg.ErrItemNotFound))
+
+	_, err := uc.BuyItem(context.Background(), "testuser", "pen", 1)
+	assert.ErrorIs(t, err, ErrItemNotFound)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+// newSQLBuyItemUseCase создает BuyItemUseCase поверх реальных хранилищ db с sqlmock,
+// чтобы проверить запросы покупки внутри одной транзакции.
+func newSQLBuyItemUseCase(t *testing.T) (*BuyItemUseCase, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, sqlMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("не удалось создать sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	mockItemDB := dbmocks.NewMockItemDBInterface(gomock.NewController(t))
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+
+	sqlMock.ExpectPrepare("SELECT id, username, password_hash, coins FROM users WHERE username = $1 AND deleted_at IS NULL").
+		ExpectQuery().WithArgs("testuser").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash", "coins"}).AddRow(1, "testuser", "hash", 100))
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("UPDATE users SET coins = coins - $2 WHERE id = $1 AND coins >= $2 RETURNING coins").
+		WithArgs(1, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"coins"}).AddRow(80))
+
+	uc := NewBuyItemUseCase(dbpkg.NewUserDB(sqlDB, log), mockItemDB, dbpkg.NewTransactionDB(sqlDB, log), log)
+	return uc, sqlMock
+}
+
+func TestBuyItemUseCase_BuyItem_SQLTransaction(t *testing.T) {
+	uc, sqlMock := newSQLBuyItemUseCase(t)
+
+	// Списание монет и пополнение инвентаря фиксируются одним коммитом.
+	sqlMock.ExpectQuery("INSERT INTO inventory (user_id, item_type, quantity) VALUES ($1, $2, $3) ON CONFLICT (user_id, item_type) DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity WHERE $4 = 0 OR inventory.quantity + EXCLUDED.quantity <= $4 RETURNING quantity").
+		WithArgs(1, "pen", 2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(2))
+	sqlMock.ExpectCommit()
+
+	response, err := uc.BuyItem(context.Background(), "testuser", "pen", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.BuyItemResponse{Coins: 80, Quantity: 2}, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBuyItemUseCase_BuyItem_SQLSerializationRetry(t *testing.T) {
+	uc, sqlMock := newSQLBuyItemUseCase(t)
+
+	// Первая попытка прерывается конфликтом сериализации и откатывается.
+	sqlMock.ExpectQuery("INSERT INTO inventory (user_id, item_type, quantity) VALUES ($1, $2, $3) ON CONFLICT (user_id, item_type) DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity WHERE $4 = 0 OR inventory.quantity + EXCLUDED.quantity <= $4 RETURNING quantity").
+		WithArgs(1, "pen", 2, 0).
+		WillReturnError(&pq.Error{Code: "40001"})
+	sqlMock.ExpectRollback()
+
+	// Повторная попытка заново списывает монеты с текущего баланса и завершается успешно.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("UPDATE users SET coins = coins - $2 WHERE id = $1 AND coins >= $2 RETURNING coins").
+		WithArgs(1, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"coins"}).AddRow(70))
+	sqlMock.ExpectQuery("INSERT INTO inventory (user_id, item_type, quantity) VALUES ($1, $2, $3) ON CONFLICT (user_id, item_type) DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity WHERE $4 = 0 OR inventory.quantity + EXCLUDED.quantity <= $4 RETURNING quantity").
+		WithArgs(1, "pen", 2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(2))
+	sqlMock.ExpectCommit()
+
+	response, err := uc.BuyItem(context.Background(), "testuser", "pen", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.BuyItemResponse{Coins: 70, Quantity: 2}, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBuyItemUseCase_BuyItem_SQLTransactionRollback(t *testing.T) {
+	uc, sqlMock := newSQLBuyItemUseCase(t)
+
+	// Ошибка после списания монет откатывает всю покупку.
+	sqlMock.ExpectQuery("INSERT INTO inventory (user_id, item_type, quantity) VALUES ($1, $2, $3) ON CONFLICT (user_id, item_type) DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity WHERE $4 = 0 OR inventory.quantity + EXCLUDED.quantity <= $4 RETURNING quantity").
+		WithArgs(1, "pen", 2, 0).
+		WillReturnError(errors.New("insert failed"))
+	sqlMock.ExpectRollback()
+
+	response, err := uc.BuyItem(context.Background(), "testuser", "pen", 2)
+	assert.Error(t, err)
+	assert.Nil(t, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestBuyItemUseCase_QuoteCart(t *testing.T) {
+	// Моки пользователей и транзакций без ожиданий: расчет ничего не изменяет.
+	uc, _, mockItemDB, _ := newTestBuyItemUseCase(t)
+
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "cup").Return(int64(20), nil)
+
+	response, err := uc.QuoteCart(context.Background(), []models.CartItem{
+		{Item: "pen", Quantity: 3},
+		{Item: "cup", Quantity: 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.CartQuoteResponse{
+		Items: []models.CartQuoteItem{
+			{Item: "pen", Quantity: 3, Price: 10, Total: 30},
+			{Item: "cup", Quantity: 1, Price: 20, Total: 20},
+		},
+		Total: 50,
+	}, response)
+}
+
+func TestBuyItemUseCase_QuoteCart_UnknownItem(t *testing.T) {
+	uc, _, mockItemDB, _ := newTestBuyItemUseCase(t)
+
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil)
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "unicorn").Return(int64(0), fmt.Errorf("товар 'unicorn': %w", dbpkg.ErrItemNotFound))
+
+	response, err := uc.QuoteCart(context.Background(), []models.CartItem{
+		{Item: "pen", Quantity: 1},
+		{Item: "unicorn", Quantity: 1},
+	})
+	assert.ErrorIs(t, err, ErrItemNotFound)
+	assert.Contains(t, err.Error(), "unicorn", "Ошибка должна указывать неизвестный товар")
+	assert.Nil(t, response)
+}
+
+func TestBuyItemUseCase_QuoteCart_Invalid(t *testing.T) {
+	// Моки без ожиданий: некорректная корзина отклоняется до обращения к БД.
+	uc, _, _, _ := newTestBuyItemUseCase(t)
+
+	_, err := uc.QuoteCart(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrEmptyCart)
+
+	_, err = uc.QuoteCart(context.Background(), []models.CartItem{{Item: "pen", Quantity: 0}})
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+
+	_, err = uc.QuoteCart(context.Background(), []models.CartItem{{Quantity: 1}})
+	assert.ErrorIs(t, err, ErrItemRequired)
+}
+
+func TestBuyItemUseCase_QuoteCart_MaxCartItems(t *testing.T) {
+	uc, _, mockItemDB, _ := newTestBuyItemUseCase(t)
+	uc.MaxCartItems = 2
+
+	// Корзина на пределе допускается.
+	mockItemDB.EXPECT().GetItemPrice(gomock.Any(), "pen").Return(int64(10), nil).Times(2)
+	response, err := uc.QuoteCart(context.Background(), []models.CartItem{
+		{Item: "pen", Quantity: 1},
+		{Item: "pen", Quantity: 2},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(30), response.Total)
+
+	// Корзина сверх предела отклоняется до обращения к БД.
+	response, err = uc.QuoteCart(context.Background(), []models.CartItem{
+		{Item: "pen", Quantity: 1},
+		{Item: "pen", Quantity: 1},
+		{Item: "pen", Quantity: 1},
+	})
+	assert.ErrorIs(t, err, ErrCartTooLarge)
+	assert.Nil(t, response)
+}
+
+func TestBuyItemUseCase_GetCatalog(t *testing.T) {
+	uc, _, mockItemDB, _ := newTestBuyItemUseCase(t)
+	older := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	mockItemDB.EXPECT().GetItems(gomock.Any()).Return([]models.DBItem{
+		{ID: 1, ItemName: "cup", Price: 20, UpdatedAt: newer},
+		{ID: 2, ItemName: "pen", Price: 10, UpdatedAt: older},
+	}, nil)
+	catalog, err := uc.GetCatalog(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []models.CatalogItem{{ItemName: "cup", Price: 20}, {ItemName: "pen", Price: 10}}, catalog.Items)
+	assert.Equal(t, newer, catalog.UpdatedAt, "Время изменения каталога равно времени последнего изменения товара")
+	assert.NotEmpty(t, catalog.Version)
+
+	// Тот же каталог дает ту же версию, изменение цены меняет версию.
+	mockItemDB.EXPECT().GetItems(gomock.Any()).Return([]models.DBItem{
+		{ID: 1, ItemName: "cup", Price: 20, UpdatedAt: newer},
+		{ID: 2, ItemName: "pen", Price: 10, UpdatedAt: older},
+	}, nil)
+	same, err := uc.GetCatalog(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, catalog.Version, same.Version)
+
+	mockItemDB.EXPECT().GetItems(gomock.Any()).Return([]models.DBItem{
+		{ID: 1, ItemName: "cup", Price: 25, UpdatedAt: newer},
+		{ID: 2, ItemName: "pen", Price: 10, UpdatedAt: older},
+	}, nil)
+	changed, err := uc.GetCatalog(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, catalog.Version, changed.Version)
 }
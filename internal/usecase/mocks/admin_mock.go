@@ -0,0 +1,125 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: shop/internal/usecase (interfaces: AdminUseCaseInterface)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	models "shop/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAdminUseCaseInterface is a mock of AdminUseCaseInterface interface.
+type MockAdminUseCaseInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdminUseCaseInterfaceMockRecorder
+}
+
+// MockAdminUseCaseInterfaceMockRecorder is the mock recorder for MockAdminUseCaseInterface.
+type MockAdminUseCaseInterfaceMockRecorder struct {
+	mock *MockAdminUseCaseInterface
+}
+
+// NewMockAdminUseCaseInterface creates a new mock instance.
+func NewMockAdminUseCaseInterface(ctrl *gomock.Controller) *MockAdminUseCaseInterface {
+	mock := &MockAdminUseCaseInterface{ctrl: ctrl}
+	mock.recorder = &MockAdminUseCaseInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdminUseCaseInterface) EXPECT() *MockAdminUseCaseInterfaceMockRecorder {
+	return m.recorder
+}
+
+// DeactivateUser mocks base method.
+func (m *MockAdminUseCaseInterface) DeactivateUser(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeactivateUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeactivateUser indicates an expected call of DeactivateUser.
+func (mr *MockAdminUseCaseInterfaceMockRecorder) DeactivateUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeactivateUser", reflect.TypeOf((*MockAdminUseCaseInterface)(nil).DeactivateUser), arg0, arg1)
+}
+
+// DebugInfo mocks base method.
+func (m *MockAdminUseCaseInterface) DebugInfo(arg0 context.Context) (*models.DebugInfoResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DebugInfo", arg0)
+	ret0, _ := ret[0].(*models.DebugInfoResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DebugInfo indicates an expected call of DebugInfo.
+func (mr *MockAdminUseCaseInterfaceMockRecorder) DebugInfo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DebugInfo", reflect.TypeOf((*MockAdminUseCaseInterface)(nil).DebugInfo), arg0)
+}
+
+// GetAudit mocks base method.
+func (m *MockAdminUseCaseInterface) GetAudit(arg0 context.Context, arg1, arg2 int) (*models.AdminAuditResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAudit", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.AdminAuditResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAudit indicates an expected call of GetAudit.
+func (mr *MockAdminUseCaseInterfaceMockRecorder) GetAudit(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAudit", reflect.TypeOf((*MockAdminUseCaseInterface)(nil).GetAudit), arg0, arg1, arg2)
+}
+
+// GetTransactionsBetween mocks base method.
+func (m *MockAdminUseCaseInterface) GetTransactionsBetween(arg0 context.Context, arg1, arg2 string) (*models.TransactionsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactionsBetween", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.TransactionsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransactionsBetween indicates an expected call of GetTransactionsBetween.
+func (mr *MockAdminUseCaseInterfaceMockRecorder) GetTransactionsBetween(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionsBetween", reflect.TypeOf((*MockAdminUseCaseInterface)(nil).GetTransactionsBetween), arg0, arg1, arg2)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAdminUseCaseInterface) ResetPassword(arg0 context.Context, arg1, arg2 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAdminUseCaseInterfaceMockRecorder) ResetPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAdminUseCaseInterface)(nil).ResetPassword), arg0, arg1, arg2)
+}
+
+// UpdateItemPrices mocks base method.
+func (m *MockAdminUseCaseInterface) UpdateItemPrices(arg0 context.Context, arg1 []models.ItemPriceUpdate) (*models.ItemPricesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateItemPrices", arg0, arg1)
+	ret0, _ := ret[0].(*models.ItemPricesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateItemPrices indicates an expected call of UpdateItemPrices.
+func (mr *MockAdminUseCaseInterfaceMockRecorder) UpdateItemPrices(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateItemPrices", reflect.TypeOf((*MockAdminUseCaseInterface)(nil).UpdateItemPrices), arg0, arg1)
+}
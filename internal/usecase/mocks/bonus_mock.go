@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: shop/internal/usecase (interfaces: BonusUseCaseInterface)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	models "shop/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockBonusUseCaseInterface is a mock of BonusUseCaseInterface interface.
+type MockBonusUseCaseInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockBonusUseCaseInterfaceMockRecorder
+}
+
+// MockBonusUseCaseInterfaceMockRecorder is the mock recorder for MockBonusUseCaseInterface.
+type MockBonusUseCaseInterfaceMockRecorder struct {
+	mock *MockBonusUseCaseInterface
+}
+
+// NewMockBonusUseCaseInterface creates a new mock instance.
+func NewMockBonusUseCaseInterface(ctrl *gomock.Controller) *MockBonusUseCaseInterface {
+	mock := &MockBonusUseCaseInterface{ctrl: ctrl}
+	mock.recorder = &MockBonusUseCaseInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBonusUseCaseInterface) EXPECT() *MockBonusUseCaseInterfaceMockRecorder {
+	return m.recorder
+}
+
+// ClaimDailyBonus mocks base method.
+func (m *MockBonusUseCaseInterface) ClaimDailyBonus(arg0 context.Context, arg1 string) (*models.ClaimBonusResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDailyBonus", arg0, arg1)
+	ret0, _ := ret[0].(*models.ClaimBonusResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDailyBonus indicates an expected call of ClaimDailyBonus.
+func (mr *MockBonusUseCaseInterfaceMockRecorder) ClaimDailyBonus(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDailyBonus", reflect.TypeOf((*MockBonusUseCaseInterface)(nil).ClaimDailyBonus), arg0, arg1)
+}
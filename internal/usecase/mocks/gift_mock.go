@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: shop/internal/usecase (interfaces: GiftUseCaseInterface)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockGiftUseCaseInterface is a mock of GiftUseCaseInterface interface.
+type MockGiftUseCaseInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockGiftUseCaseInterfaceMockRecorder
+}
+
+// MockGiftUseCaseInterfaceMockRecorder is the mock recorder for MockGiftUseCaseInterface.
+type MockGiftUseCaseInterfaceMockRecorder struct {
+	mock *MockGiftUseCaseInterface
+}
+
+// NewMockGiftUseCaseInterface creates a new mock instance.
+func NewMockGiftUseCaseInterface(ctrl *gomock.Controller) *MockGiftUseCaseInterface {
+	mock := &MockGiftUseCaseInterface{ctrl: ctrl}
+	mock.recorder = &MockGiftUseCaseInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGiftUseCaseInterface) EXPECT() *MockGiftUseCaseInterfaceMockRecorder {
+	return m.recorder
+}
+
+// GiftItem mocks base method.
+func (m *MockGiftUseCaseInterface) GiftItem(arg0 context.Context, arg1, arg2, arg3 string, arg4 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GiftItem", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GiftItem indicates an expected call of GiftItem.
+func (mr *MockGiftUseCaseInterfaceMockRecorder) GiftItem(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GiftItem", reflect.TypeOf((*MockGiftUseCaseInterface)(nil).GiftItem), arg0, arg1, arg2, arg3, arg4)
+}
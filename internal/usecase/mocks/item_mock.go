@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	models "shop/internal/models"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,15 +36,46 @@ func (m *MockBuyItemUseCaseInterface) EXPECT() *MockBuyItemUseCaseInterfaceMockR
 }
 
 // BuyItem mocks base method.
-func (m *MockBuyItemUseCaseInterface) BuyItem(arg0 context.Context, arg1, arg2 string) error {
+func (m *MockBuyItemUseCaseInterface) BuyItem(arg0 context.Context, arg1, arg2 string, arg3 int) (*models.BuyItemResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BuyItem", arg0, arg1, arg2)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "BuyItem", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*models.BuyItemResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // BuyItem indicates an expected call of BuyItem.
-func (mr *MockBuyItemUseCaseInterfaceMockRecorder) BuyItem(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockBuyItemUseCaseInterfaceMockRecorder) BuyItem(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BuyItem", reflect.TypeOf((*MockBuyItemUseCaseInterface)(nil).BuyItem), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BuyItem", reflect.TypeOf((*MockBuyItemUseCaseInterface)(nil).BuyItem), arg0, arg1, arg2, arg3)
+}
+
+// GetCatalog mocks base method.
+func (m *MockBuyItemUseCaseInterface) GetCatalog(arg0 context.Context) (*models.Catalog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCatalog", arg0)
+	ret0, _ := ret[0].(*models.Catalog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCatalog indicates an expected call of GetCatalog.
+func (mr *MockBuyItemUseCaseInterfaceMockRecorder) GetCatalog(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCatalog", reflect.TypeOf((*MockBuyItemUseCaseInterface)(nil).GetCatalog), arg0)
+}
+
+// QuoteCart mocks base method.
+func (m *MockBuyItemUseCaseInterface) QuoteCart(arg0 context.Context, arg1 []models.CartItem) (*models.CartQuoteResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuoteCart", arg0, arg1)
+	ret0, _ := ret[0].(*models.CartQuoteResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuoteCart indicates an expected call of QuoteCart.
+func (mr *MockBuyItemUseCaseInterfaceMockRecorder) QuoteCart(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuoteCart", reflect.TypeOf((*MockBuyItemUseCaseInterface)(nil).QuoteCart), arg0, arg1)
 }
@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	models "shop/internal/models"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,16 +35,32 @@ func (m *MockSendCoinUseCaseInterface) EXPECT() *MockSendCoinUseCaseInterfaceMoc
 	return m.recorder
 }
 
+// ReverseTransfer mocks base method.
+func (m *MockSendCoinUseCaseInterface) ReverseTransfer(arg0 context.Context, arg1 string, arg2 int) (*models.ReverseTransferResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseTransfer", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.ReverseTransferResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReverseTransfer indicates an expected call of ReverseTransfer.
+func (mr *MockSendCoinUseCaseInterfaceMockRecorder) ReverseTransfer(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseTransfer", reflect.TypeOf((*MockSendCoinUseCaseInterface)(nil).ReverseTransfer), arg0, arg1, arg2)
+}
+
 // SendCoin mocks base method.
-func (m *MockSendCoinUseCaseInterface) SendCoin(arg0 context.Context, arg1, arg2 string, arg3 int) error {
+func (m *MockSendCoinUseCaseInterface) SendCoin(arg0 context.Context, arg1, arg2 string, arg3 int64, arg4, arg5 string) (*models.SendCoinResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendCoin", arg0, arg1, arg2, arg3)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "SendCoin", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(*models.SendCoinResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SendCoin indicates an expected call of SendCoin.
-func (mr *MockSendCoinUseCaseInterfaceMockRecorder) SendCoin(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockSendCoinUseCaseInterfaceMockRecorder) SendCoin(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendCoin", reflect.TypeOf((*MockSendCoinUseCaseInterface)(nil).SendCoin), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendCoin", reflect.TypeOf((*MockSendCoinUseCaseInterface)(nil).SendCoin), arg0, arg1, arg2, arg3, arg4, arg5)
 }
@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 	models "shop/internal/models"
+	usecase "shop/internal/usecase"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -36,12 +37,13 @@ func (m *MockUserUseCaseInterface) EXPECT() *MockUserUseCaseInterfaceMockRecorde
 }
 
 // Auth mocks base method.
-func (m *MockUserUseCaseInterface) Auth(arg0 context.Context, arg1, arg2 string) (string, error) {
+func (m *MockUserUseCaseInterface) Auth(arg0 context.Context, arg1, arg2 string) (string, *models.UserSummary, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Auth", arg0, arg1, arg2)
 	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(*models.UserSummary)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // Auth indicates an expected call of Auth.
@@ -65,19 +67,168 @@ func (mr *MockUserUseCaseInterfaceMockRecorder) GenerateJWTToken(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateJWTToken", reflect.TypeOf((*MockUserUseCaseInterface)(nil).GenerateJWTToken), arg0)
 }
 
+// GetBalance mocks base method.
+func (m *MockUserUseCaseInterface) GetBalance(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalance", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalance indicates an expected call of GetBalance.
+func (mr *MockUserUseCaseInterfaceMockRecorder) GetBalance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockUserUseCaseInterface)(nil).GetBalance), arg0, arg1)
+}
+
+// GetInventory mocks base method.
+func (m *MockUserUseCaseInterface) GetInventory(arg0 context.Context, arg1, arg2 string) (*models.InventoryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInventory", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.InventoryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInventory indicates an expected call of GetInventory.
+func (mr *MockUserUseCaseInterfaceMockRecorder) GetInventory(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInventory", reflect.TypeOf((*MockUserUseCaseInterface)(nil).GetInventory), arg0, arg1, arg2)
+}
+
+// GetLeaderboard mocks base method.
+func (m *MockUserUseCaseInterface) GetLeaderboard(arg0 context.Context, arg1 int) (*models.LeaderboardResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLeaderboard", arg0, arg1)
+	ret0, _ := ret[0].(*models.LeaderboardResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLeaderboard indicates an expected call of GetLeaderboard.
+func (mr *MockUserUseCaseInterfaceMockRecorder) GetLeaderboard(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeaderboard", reflect.TypeOf((*MockUserUseCaseInterface)(nil).GetLeaderboard), arg0, arg1)
+}
+
+// GetRank mocks base method.
+func (m *MockUserUseCaseInterface) GetRank(arg0 context.Context, arg1 string) (*models.RankResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRank", arg0, arg1)
+	ret0, _ := ret[0].(*models.RankResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRank indicates an expected call of GetRank.
+func (mr *MockUserUseCaseInterfaceMockRecorder) GetRank(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRank", reflect.TypeOf((*MockUserUseCaseInterface)(nil).GetRank), arg0, arg1)
+}
+
+// GetStatement mocks base method.
+func (m *MockUserUseCaseInterface) GetStatement(arg0 context.Context, arg1 string) (*models.StatementResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatement", arg0, arg1)
+	ret0, _ := ret[0].(*models.StatementResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatement indicates an expected call of GetStatement.
+func (mr *MockUserUseCaseInterfaceMockRecorder) GetStatement(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatement", reflect.TypeOf((*MockUserUseCaseInterface)(nil).GetStatement), arg0, arg1)
+}
+
+// GetUserID mocks base method.
+func (m *MockUserUseCaseInterface) GetUserID(arg0 context.Context, arg1 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserID", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserID indicates an expected call of GetUserID.
+func (mr *MockUserUseCaseInterfaceMockRecorder) GetUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserID", reflect.TypeOf((*MockUserUseCaseInterface)(nil).GetUserID), arg0, arg1)
+}
+
 // GetUserInfo mocks base method.
-func (m *MockUserUseCaseInterface) GetUserInfo(arg0 context.Context, arg1 string) (*models.InfoResponse, error) {
+func (m *MockUserUseCaseInterface) GetUserInfo(arg0 context.Context, arg1 string, arg2 usecase.InfoOptions) (*models.InfoResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserInfo", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetUserInfo", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.InfoResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserInfo indicates an expected call of GetUserInfo.
-func (mr *MockUserUseCaseInterfaceMockRecorder) GetUserInfo(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserUseCaseInterfaceMockRecorder) GetUserInfo(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserInfo", reflect.TypeOf((*MockUserUseCaseInterface)(nil).GetUserInfo), arg0, arg1, arg2)
+}
+
+// ParseJWTToken mocks base method.
+func (m *MockUserUseCaseInterface) ParseJWTToken(arg0 string) (*usecase.TokenClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseJWTToken", arg0)
+	ret0, _ := ret[0].(*usecase.TokenClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseJWTToken indicates an expected call of ParseJWTToken.
+func (mr *MockUserUseCaseInterfaceMockRecorder) ParseJWTToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseJWTToken", reflect.TypeOf((*MockUserUseCaseInterface)(nil).ParseJWTToken), arg0)
+}
+
+// RefreshToken mocks base method.
+func (m *MockUserUseCaseInterface) RefreshToken(arg0 context.Context, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshToken indicates an expected call of RefreshToken.
+func (mr *MockUserUseCaseInterfaceMockRecorder) RefreshToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockUserUseCaseInterface)(nil).RefreshToken), arg0, arg1)
+}
+
+// ResolveUser mocks base method.
+func (m *MockUserUseCaseInterface) ResolveUser(arg0 context.Context, arg1 string) (*models.DBUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveUser", arg0, arg1)
+	ret0, _ := ret[0].(*models.DBUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveUser indicates an expected call of ResolveUser.
+func (mr *MockUserUseCaseInterfaceMockRecorder) ResolveUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveUser", reflect.TypeOf((*MockUserUseCaseInterface)(nil).ResolveUser), arg0, arg1)
+}
+
+// StreamCoinHistory mocks base method.
+func (m *MockUserUseCaseInterface) StreamCoinHistory(arg0 context.Context, arg1, arg2 string, arg3 func(models.Transaction) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamCoinHistory", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamCoinHistory indicates an expected call of StreamCoinHistory.
+func (mr *MockUserUseCaseInterfaceMockRecorder) StreamCoinHistory(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserInfo", reflect.TypeOf((*MockUserUseCaseInterface)(nil).GetUserInfo), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamCoinHistory", reflect.TypeOf((*MockUserUseCaseInterface)(nil).StreamCoinHistory), arg0, arg1, arg2, arg3)
 }
 
 // VerifyJWTToken mocks base method.
@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// pepperPassword подмешивает в пароль секрет приложения pepper: пароль заменяется
+// HMAC-SHA256 от него с ключом pepper в base64. Хеши из утекшей базы без pepper
+// не подобрать. Пустой pepper оставляет пароль как есть, чтобы хеши, созданные
+// до его появления, продолжали проверяться.
+func pepperPassword(pepper []byte, password string) []byte {
+	if len(pepper) == 0 {
+		return []byte(password)
+	}
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(password))
+	sum := mac.Sum(nil)
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(sum)))
+	base64.StdEncoding.Encode(encoded, sum)
+	return encoded
+}
+
+// hashPassword вычисляет bcrypt-хеш пароля с подмешанным pepper.
+func hashPassword(pepper []byte, password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword(pepperPassword(pepper, password), bcrypt.DefaultCost)
+}
+
+// checkPassword проверяет пароль с подмешанным pepper по bcrypt-хешу.
+func checkPassword(pepper []byte, hash string, password string) error {
+	return compareHashAndPassword([]byte(hash), pepperPassword(pepper, password))
+}
@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword_Pepper(t *testing.T) {
+	pepper := []byte("pepper")
+
+	hash, err := hashPassword(pepper, "password")
+	require.NoError(t, err)
+
+	assert.NoError(t, checkPassword(pepper, string(hash), "password"))
+	assert.ErrorIs(t, checkPassword(pepper, string(hash), "wrong"), bcrypt.ErrMismatchedHashAndPassword)
+
+	// Хеш без pepper не сходится с паролем: в базе хранится не bcrypt самого пароля.
+	assert.ErrorIs(t, bcrypt.CompareHashAndPassword(hash, []byte("password")), bcrypt.ErrMismatchedHashAndPassword)
+
+	// Смена или удаление pepper делает существующие хеши недействительными.
+	assert.ErrorIs(t, checkPassword([]byte("other"), string(hash), "password"), bcrypt.ErrMismatchedHashAndPassword)
+	assert.ErrorIs(t, checkPassword(nil, string(hash), "password"), bcrypt.ErrMismatchedHashAndPassword)
+}
+
+func TestHashPassword_NoPepper(t *testing.T) {
+	// Без pepper хеши совместимы с созданными до его появления.
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	assert.NoError(t, checkPassword(nil, string(legacy), "password"))
+
+	hash, err := hashPassword(nil, "password")
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword(hash, []byte("password")))
+}
+
+func TestPepperPassword_LongPassword(t *testing.T) {
+	// bcrypt учитывает только первые 72 байта; после HMAC различаются и более длинные пароли.
+	prefix := string(make([]byte, 72))
+	assert.NotEqual(t, pepperPassword([]byte("pepper"), prefix+"a"), pepperPassword([]byte("pepper"), prefix+"b"))
+	assert.Len(t, pepperPassword([]byte("pepper"), prefix+"a"), 44)
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+
+	"shop/internal/models"
+)
+
+// Узкие интерфейсы хранилища: каждый use case зависит только от тех методов,
+// которые он действительно вызывает. Реализуются типами из пакета db.
+
+// userGetter получает пользователя по имени.
+type userGetter interface {
+	GetUserByUsername(ctx context.Context, username string) (*models.DBUser, error)
+}
+
+// coinWriter изменяет баланс пользователя.
+type coinWriter interface {
+	UpdateUserCoins(ctx context.Context, userID int, coins int64, tx *sql.Tx) error
+}
+
+// coinDeducter атомарно списывает монеты с баланса пользователя.
+type coinDeducter interface {
+	DeductUserCoins(ctx context.Context, userID int, amount int64, tx *sql.Tx) (int64, error)
+}
+
+// balanceLocker блокирует строки пользователей на время транзакции.
+type balanceLocker interface {
+	LockUserBalances(ctx context.Context, tx *sql.Tx, userIDs ...int) (map[int]int64, error)
+}
+
+// inventoryWriter изменяет инвентарь пользователя.
+type inventoryWriter interface {
+	UpdateUserInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) (int, error)
+}
+
+// txBeginner предоставляет соединение для начала транзакции.
+type txBeginner interface {
+	GetDB() *sql.DB
+}
+
+// transactionRecorder записывает переводы монет.
+type transactionRecorder interface {
+	RecordTransaction(ctx context.Context, senderUserID int, receiverUserID int, amount int64, memo string, category string, tx *sql.Tx) error
+}
+
+// coinHistoryReader получает историю переводов монет.
+type coinHistoryReader interface {
+	GetCoinHistory(ctx context.Context, userID int, category string) (*models.CoinHistory, error)
+	StreamCoinHistory(ctx context.Context, userID int, category string, fn func(models.Transaction) error) error
+}
+
+// userTransactionsReader получает все переводы пользователя.
+type userTransactionsReader interface {
+	GetUserTransactions(ctx context.Context, userID int) ([]models.DBTransaction, error)
+}
+
+// userInfoTransactionDB методы хранилища транзакций, необходимые UserUseCase.
+type userInfoTransactionDB interface {
+	coinHistoryReader
+	userTransactionsReader
+}
+
+// transactionsBetweenReader получает переводы между двумя пользователями.
+type transactionsBetweenReader interface {
+	GetTransactionsBetween(ctx context.Context, usernameA string, usernameB string) ([]models.DBTransaction, error)
+}
+
+// itemPriceGetter получает цену предмета.
+type itemPriceGetter interface {
+	GetItemPrice(ctx context.Context, itemName string) (int64, error)
+}
+
+// itemsReader получает все товары каталога.
+type itemsReader interface {
+	GetItems(ctx context.Context) ([]models.DBItem, error)
+}
+
+// itemPriceWriter изменяет цены товаров каталога.
+type itemPriceWriter interface {
+	UpsertItemPrice(ctx context.Context, itemName string, price int64, tx *sql.Tx) (bool, error)
+}
+
+// userInfoDB методы хранилища пользователей, необходимые UserUseCase.
+type userInfoDB interface {
+	userGetter
+	GetUserIDByUsername(ctx context.Context, username string) (int, error)
+	GetBalance(ctx context.Context, userID int) (int64, error)
+	GetUserRank(ctx context.Context, userID int) (int, error)
+	GetLeaderboard(ctx context.Context, limit int) ([]models.LeaderboardEntry, error)
+	GetUserInventory(ctx context.Context, userID int, limit int) ([]models.DBInventoryItem, error)
+	GetUserInventoryByPrefix(ctx context.Context, userID int, prefix string) ([]models.DBInventoryItem, error)
+	GetUserInventoryWithPrices(ctx context.Context, userID int) ([]models.DBInventoryItem, error)
+	GetInventoryItemCount(ctx context.Context, userID int) (int, error)
+	IsUserDeactivated(ctx context.Context, username string) (bool, error)
+	CreateUser(ctx context.Context, username string, passwordHash string) error
+	SetInitialCoins(ctx context.Context, userID int, initialCoins int64) error
+}
+
+// sendCoinUserDB методы хранилища пользователей, необходимые SendCoinUseCase.
+type sendCoinUserDB interface {
+	userGetter
+	coinWriter
+	balanceLocker
+	IsUserDeactivated(ctx context.Context, username string) (bool, error)
+}
+
+// sendCoinTransactionDB методы хранилища транзакций, необходимые SendCoinUseCase.
+type sendCoinTransactionDB interface {
+	txBeginner
+	transactionRecorder
+	GetTransactionForUpdate(ctx context.Context, transactionID int, tx *sql.Tx) (*models.DBTransaction, error)
+	RecordReversal(ctx context.Context, transactionID int, senderUserID int, receiverUserID int, amount int64, tx *sql.Tx) error
+}
+
+// buyItemUserDB методы хранилища пользователей, необходимые BuyItemUseCase.
+type buyItemUserDB interface {
+	userGetter
+	coinDeducter
+	inventoryWriter
+}
+
+// buyItemItemDB методы хранилища товаров, необходимые BuyItemUseCase.
+type buyItemItemDB interface {
+	itemPriceGetter
+	itemsReader
+}
+
+// adminUserDB методы хранилища пользователей, необходимые AdminUseCase.
+type adminUserDB interface {
+	userGetter
+	UpdateUserPassword(ctx context.Context, userID int, passwordHash string, tx *sql.Tx) error
+	DeactivateUser(ctx context.Context, userID int, tx *sql.Tx) error
+}
+
+// adminTransactionDB методы хранилища транзакций, необходимые AdminUseCase.
+type adminTransactionDB interface {
+	txBeginner
+	transactionsBetweenReader
+	RecordAdminAction(ctx context.Context, entry models.AdminAuditEntry, tx *sql.Tx) error
+	GetAdminAudit(ctx context.Context, limit int, offset int) ([]models.AdminAuditEntry, error)
+	CountAdminAudit(ctx context.Context) (int, error)
+	ServerVersion(ctx context.Context) (string, error)
+}
+
+// giftUserDB методы хранилища пользователей, необходимые GiftUseCase.
+type giftUserDB interface {
+	userGetter
+	inventoryWriter
+	RemoveFromInventory(ctx context.Context, userID int, itemType string, quantity int, tx *sql.Tx) error
+}
+
+// giftTransactionDB методы хранилища транзакций, необходимые GiftUseCase.
+type giftTransactionDB interface {
+	txBeginner
+	RecordGift(ctx context.Context, senderUserID int, receiverUserID int, itemType string, quantity int, tx *sql.Tx) error
+}
+
+// bonusUserDB методы хранилища пользователей, необходимые BonusUseCase.
+type bonusUserDB interface {
+	userGetter
+	ClaimDailyBonus(ctx context.Context, userID int, amount int64) (int64, bool, error)
+}
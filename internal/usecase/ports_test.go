@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"shop/internal/db"
+	"shop/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Реализации из пакета db должны удовлетворять узким интерфейсам use case'ов.
+var (
+	_ userInfoDB                = (*db.UserDB)(nil)
+	_ userInfoTransactionDB     = (*db.TransactionDB)(nil)
+	_ sendCoinUserDB            = (*db.UserDB)(nil)
+	_ sendCoinTransactionDB     = (*db.TransactionDB)(nil)
+	_ buyItemUserDB             = (*db.UserDB)(nil)
+	_ buyItemItemDB             = (*db.ItemDB)(nil)
+	_ txBeginner                = (*db.TransactionDB)(nil)
+	_ adminUserDB               = (*db.UserDB)(nil)
+	_ transactionsBetweenReader = (*db.TransactionDB)(nil)
+	_ adminTransactionDB        = (*db.TransactionDB)(nil)
+	_ itemPriceWriter           = (*db.ItemDB)(nil)
+	_ bonusUserDB               = (*db.UserDB)(nil)
+	_ giftUserDB                = (*db.UserDB)(nil)
+	_ giftTransactionDB         = (*db.TransactionDB)(nil)
+)
+
+// stubBonusUserDB реализует только методы, необходимые BonusUseCase.
+type stubBonusUserDB struct {
+	user    *models.DBUser
+	claimed bool
+}
+
+func (s *stubBonusUserDB) GetUserByUsername(_ context.Context, _ string) (*models.DBUser, error) {
+	return s.user, nil
+}
+
+func (s *stubBonusUserDB) ClaimDailyBonus(_ context.Context, _ int, amount int64) (int64, bool, error) {
+	if s.claimed {
+		return 0, false, nil
+	}
+	s.claimed = true
+	s.user.Coins += amount
+	return s.user.Coins, true, nil
+}
+
+func TestBonusUseCase_NarrowDependency(t *testing.T) {
+	// Use case работает с минимальной реализацией без полного UserDBInterface.
+	userDB := &stubBonusUserDB{user: &models.DBUser{ID: 1, Username: "alice", Coins: 10}}
+	uc := NewBonusUseCase(5, userDB, log)
+
+	response, err := uc.ClaimDailyBonus(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15), response.Coins)
+
+	_, err = uc.ClaimDailyBonus(context.Background(), "alice")
+	assert.ErrorIs(t, err, ErrBonusAlreadyClaimed)
+}
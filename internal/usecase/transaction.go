@@ -3,9 +3,17 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"shop/internal/db"
+	"shop/internal/metrics"
+	"shop/internal/models"
 	"shop/pkg/logger"
 )
 
@@ -14,24 +22,61 @@ var (
 	ErrInsufficientFunds = fmt.Errorf("%w: недостаточно монет для перевода", ErrInvalidRequest)
 	ErrSelfTransfer      = fmt.Errorf("%w: нельзя отправить монеты самому себе", ErrInvalidRequest)
 	ErrReceiverNotFound  = fmt.Errorf("%w: получатель не найден", ErrInvalidRequest)
-	ErrInvalidAmount     = fmt.Errorf("%w: сумма перевода должна быть положительной", ErrInvalidRequest)
+	ErrReceiverInactive  = fmt.Errorf("%w: получатель деактивирован", ErrInvalidRequest)
+	ErrInvalidAmount     = fmt.Errorf("%w: неверная сумма перевода", ErrInvalidRequest)
+	ErrMemoTooLong       = fmt.Errorf("%w: слишком длинный комментарий к переводу", ErrInvalidRequest)
+	ErrInvalidCategory   = fmt.Errorf("%w: недопустимая категория перевода", ErrInvalidRequest)
+
+	ErrTransferNotFound          = fmt.Errorf("%w: перевод не найден", ErrNotFound)
+	ErrNotTransferSender         = fmt.Errorf("%w: отменить перевод может только отправитель", ErrForbidden)
+	ErrTransferNotReversible     = fmt.Errorf("%w: перевод уже отменен или сам является отменой", ErrInvalidRequest)
+	ErrReversalWindowExpired     = fmt.Errorf("%w: время на отмену перевода истекло", ErrInvalidRequest)
+	ErrReceiverInsufficientFunds = fmt.Errorf("%w: у получателя недостаточно монет для отмены перевода", ErrInvalidRequest)
 )
 
+// MaxMemoLength максимальная длина комментария к переводу в символах.
+const MaxMemoLength = 140
+
+// TransferCategories допустимые категории переводов для отчетов. Категория необязательна.
+var TransferCategories = []string{"gift", "payment", "refund", "reward", "other"}
+
+// ValidateCategory проверяет, что category пуста или входит в TransferCategories.
+func ValidateCategory(category string) error {
+	if category != "" && !slices.Contains(TransferCategories, category) {
+		return fmt.Errorf("%w: '%s', допустимые значения: %s", ErrInvalidCategory, category, strings.Join(TransferCategories, ", "))
+	}
+	return nil
+}
+
 // SendCoinUseCaseInterface интерфейс для use case'а отправки монет.
 type SendCoinUseCaseInterface interface {
-	SendCoin(ctx context.Context, senderUsername string, receiverUsername string, amount int) error
+	SendCoin(ctx context.Context, senderUsername string, receiverUsername string, amount int64, memo string, category string) (*models.SendCoinResult, error)
+	ReverseTransfer(ctx context.Context, username string, transactionID int) (*models.ReverseTransferResponse, error)
 }
 
 // SendCoinUseCase реализует SendCoinUseCaseInterface.
 type SendCoinUseCase struct {
-	userDB        db.UserDBInterface
-	transactionDB db.TransactionDBInterface
+	// ReadOnly запрещает переводы и их отмену. Проверка выполняется здесь, а не только
+	// в HTTP middleware, чтобы режим не зависел от метода запроса.
+	ReadOnly bool
+	// MinBalance минимальный баланс отправителя после перевода.
+	MinBalance int64
+	// ReversalWindow время после перевода, в течение которого отправитель может его отменить.
+	// 0 запрещает отмену переводов.
+	ReversalWindow time.Duration
+	// Transactions метрики транзакций; nil отключает их.
+	Transactions  *metrics.Transactions
+	denomination  int64
+	userDB        sendCoinUserDB
+	transactionDB sendCoinTransactionDB
 	log           *logger.Logger
 }
 
 // NewSendCoinUseCase создает новый SendCoinUseCase.
-func NewSendCoinUseCase(userDB db.UserDBInterface, transactionDB db.TransactionDBInterface, log *logger.Logger) *SendCoinUseCase {
+// Сумма перевода должна быть кратна denomination; значение 1 и меньше разрешает любую сумму.
+func NewSendCoinUseCase(denomination int64, userDB sendCoinUserDB, transactionDB sendCoinTransactionDB, log *logger.Logger) *SendCoinUseCase {
 	return &SendCoinUseCase{
+		denomination:  denomination,
 		userDB:        userDB,
 		transactionDB: transactionDB,
 		log:           log,
@@ -39,86 +84,250 @@ func NewSendCoinUseCase(userDB db.UserDBInterface, transactionDB db.TransactionD
 }
 
 // SendCoin обрабатывает бизнес-логику перевода монет.
-func (uc *SendCoinUseCase) SendCoin(ctx context.Context, senderUsername string, receiverUsername string, amount int) error {
+// К переводу можно приложить необязательный комментарий memo и категорию category из TransferCategories.
+// Возвращает баланс отправителя после перевода, получателя и сумму перевода.
+func (uc *SendCoinUseCase) SendCoin(ctx context.Context, senderUsername string, receiverUsername string, amount int64, memo string, category string) (*models.SendCoinResult, error) {
 	uc.log.Debug("SendCoin", "senderUsername", senderUsername, "receiverUsername", receiverUsername, "amount", amount)
 
+	if uc.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
 	if amount <= 0 {
 		uc.log.Warn("Неверная сумма перевода", "amount", amount)
-		return ErrInvalidAmount
+		return nil, fmt.Errorf("%w: сумма должна быть положительной", ErrInvalidAmount)
+	}
+	if uc.denomination > 1 && amount%uc.denomination != 0 {
+		uc.log.Warn("Сумма перевода не кратна шагу", "amount", amount, "denomination", uc.denomination)
+		return nil, fmt.Errorf("%w: сумма должна быть кратна %d", ErrInvalidAmount, uc.denomination)
 	}
 
-	senderUser, err := uc.userDB.GetUserByUsername(ctx, senderUsername)
+	// Имя получателя из запроса нормализуется до поиска, чтобы перевод " alice " от alice
+	// распознавался как перевод самому себе, а не как перевод несуществующему получателю.
+	// Имя отправителя берется из токена и не изменяется: иначе токен пользователя " alice",
+	// зарегистрированного до запрета таких имен, списывал бы монеты alice.
+	receiverUsername = normalizeUsername(receiverUsername)
+
+	memo = sanitizeMemo(memo)
+	if utf8.RuneCountInString(memo) > MaxMemoLength {
+		uc.log.Warn("Слишком длинный комментарий к переводу", "length", utf8.RuneCountInString(memo))
+		return nil, ErrMemoTooLong
+	}
+	if err := ValidateCategory(category); err != nil {
+		uc.log.Warn("Недопустимая категория перевода", "category", category)
+		return nil, err
+	}
+
+	senderUser, err := lookupUser(ctx, uc.userDB, senderUsername)
 	if err != nil {
 		uc.log.Error("Ошибка GetUserByUsername (sender)", "senderUsername", senderUsername, "error", err)
-		return fmt.Errorf("ошибка при получении отправителя: %w", err)
+		return nil, fmt.Errorf("ошибка при получении отправителя: %w", err)
 	}
 	if senderUser == nil {
 		uc.log.Warn("Отправитель не найден", "senderUsername", senderUsername)
-		return ErrUserNotFound
+		return nil, ErrUserNotFound
 	}
 
 	receiverUser, err := uc.userDB.GetUserByUsername(ctx, receiverUsername)
 	if err != nil {
 		uc.log.Error("Ошибка GetUserByUsername (receiver)", "receiverUsername", receiverUsername, "error", err)
-		return fmt.Errorf("ошибка при получении получателя: %w", err)
+		return nil, fmt.Errorf("ошибка при получении получателя: %w", err)
 	}
 	if receiverUser == nil {
+		// Деактивированные пользователи не возвращаются GetUserByUsername,
+		// но для них нужна отдельная понятная ошибка.
+		deactivated, err := uc.userDB.IsUserDeactivated(ctx, receiverUsername)
+		if err != nil {
+			uc.log.Error("Ошибка IsUserDeactivated (receiver)", "receiverUsername", receiverUsername, "error", err)
+			return nil, fmt.Errorf("ошибка при проверке получателя: %w", err)
+		}
+		if deactivated {
+			uc.log.Warn("Перевод деактивированному получателю", "receiverUsername", receiverUsername)
+			return nil, ErrReceiverInactive
+		}
 		uc.log.Warn("Получатель не найден", "receiverUsername", receiverUsername)
-		return ErrReceiverNotFound
+		return nil, ErrReceiverNotFound
 	}
 	uc.log.Debug("Пользователи найдены", "senderUsername", senderUsername, "receiverUsername", receiverUsername)
 
 	if senderUser.ID == receiverUser.ID {
 		uc.log.Warn("Попытка отправить монеты самому себе", "senderUsername", senderUsername)
-		return ErrSelfTransfer
+		return nil, ErrSelfTransfer
 	}
 
 	if senderUser.Coins < amount {
 		uc.log.Warn("Недостаточно монет для перевода", "senderUsername", senderUsername, "coins", senderUser.Coins, "amount", amount)
-		return ErrInsufficientFunds
+		return nil, withDeficit(ErrInsufficientFunds, amount-senderUser.Coins)
+	}
+	if err := checkMinBalance(senderUser.Coins-amount, uc.MinBalance); err != nil {
+		uc.log.Warn("Баланс опустится ниже минимального", "senderUsername", senderUsername, "coins", senderUser.Coins, "amount", amount, "minBalance", uc.MinBalance)
+		return nil, err
 	}
 
-	tx, err := uc.transactionDB.GetDB().BeginTx(ctx, nil)
-	if err != nil {
-		uc.log.Error("Ошибка начала транзакции", "error", err)
-		return fmt.Errorf("ошибка начала транзакции: %w", err)
-	}
-	defer func() {
-		if p := recover(); p != nil {
-			if err := tx.Rollback(); err != nil {
-				uc.log.Error("Ошибка отката транзакции", "error", err)
-			}
-			uc.log.Error("Паника во время транзакции, rollback", "panic", p)
-			panic(p) // Re-panic after rollback.
-		} else if err != nil {
-			if err := tx.Rollback(); err != nil {
-				uc.log.Error("Ошибка отката транзакции", "error", err)
-			}
-			uc.log.Error("Транзакция отменена из-за ошибки", "error", err)
-		} else {
-			err = tx.Commit()
-			if err != nil {
-				uc.log.Error("Ошибка коммита транзакции", "error", err)
-			}
-		}
-	}()
-
-	err = uc.userDB.UpdateUserCoins(ctx, senderUser.ID, senderUser.Coins-amount)
+	var senderCoins int64
+	err = withTransaction(ctx, uc.transactionDB, uc.log, uc.Transactions, txOperationSendCoin, func(tx *sql.Tx) error {
+		// Балансы перечитываются под блокировкой: между проверкой выше и транзакцией
+		// они могли измениться. Порядок блокировок задает LockUserBalances.
+		balances, err := uc.userDB.LockUserBalances(ctx, tx, senderUser.ID, receiverUser.ID)
+		if err != nil {
+			uc.log.Error("Ошибка LockUserBalances", "senderUserID", senderUser.ID, "receiverUserID", receiverUser.ID, "error", err)
+			return err
+		}
+		senderCoins = balances[senderUser.ID]
+		if senderCoins < amount {
+			uc.log.Warn("Недостаточно монет для перевода", "senderUsername", senderUsername, "coins", senderCoins, "amount", amount)
+			return withDeficit(ErrInsufficientFunds, amount-senderCoins)
+		}
+		senderCoins -= amount
+		if err := checkMinBalance(senderCoins, uc.MinBalance); err != nil {
+			uc.log.Warn("Баланс опустится ниже минимального", "senderUsername", senderUsername, "coins", senderCoins+amount, "amount", amount, "minBalance", uc.MinBalance)
+			return err
+		}
+
+		if err := uc.userDB.UpdateUserCoins(ctx, senderUser.ID, senderCoins, tx); err != nil {
+			uc.log.Error("Ошибка UpdateUserCoins (sender)", "senderUserID", senderUser.ID, "amount", amount, "error", err)
+			return err
+		}
+		if err := uc.userDB.UpdateUserCoins(ctx, receiverUser.ID, balances[receiverUser.ID]+amount, tx); err != nil {
+			uc.log.Error("Ошибка UpdateUserCoins (receiver)", "receiverUserID", receiverUser.ID, "amount", amount, "error", err)
+			return err
+		}
+
+		err = uc.transactionDB.RecordTransaction(ctx, senderUser.ID, receiverUser.ID, amount, memo, category, tx)
+		if db.IsForeignKeyViolation(err) {
+			uc.log.Warn("Получатель удален во время перевода", "receiverUserID", receiverUser.ID, "error", err)
+			return ErrReceiverNotFound
+		}
+		if err != nil {
+			uc.log.Error("Ошибка RecordTransaction", "senderUserID", senderUser.ID, "receiverUserID", receiverUser.ID, "amount", amount, "error", err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		uc.log.Error("Ошибка UpdateUserCoins (sender)", "senderUserID", senderUser.ID, "amount", amount, "error", err)
-		return err
+		return nil, err
 	}
-	err = uc.userDB.UpdateUserCoins(ctx, receiverUser.ID, receiverUser.Coins+amount)
+
+	return &models.SendCoinResult{SenderBalance: senderCoins, ReceiverUsername: receiverUser.Username, Amount: amount}, nil
+}
+
+// ReverseTransfer отменяет перевод transactionID, отправленный пользователем username:
+// монеты возвращаются отправителю, а отмена записывается обратным переводом.
+// Отменить можно только свой перевод, не позже ReversalWindow после него и только если
+// у получателя еще есть переведенные монеты. Возвращает баланс отправителя после отмены.
+func (uc *SendCoinUseCase) ReverseTransfer(ctx context.Context, username string, transactionID int) (*models.ReverseTransferResponse, error) {
+	uc.log.Debug("ReverseTransfer", "username", username, "transactionID", transactionID)
+
+	if uc.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	user, err := lookupUser(ctx, uc.userDB, username)
 	if err != nil {
-		uc.log.Error("Ошибка UpdateUserCoins (receiver)", "receiverUserID", receiverUser.ID, "amount", amount, "error", err)
-		return err
+		uc.log.Error("Ошибка GetUserByUsername в ReverseTransfer", "username", username, "error", err)
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		uc.log.Warn("Пользователь не найден в ReverseTransfer", "username", username)
+		return nil, ErrUserNotFound
 	}
 
-	err = uc.transactionDB.RecordTransaction(ctx, senderUser.ID, receiverUser.ID, amount, tx)
+	var response *models.ReverseTransferResponse
+	err = withTransaction(ctx, uc.transactionDB, uc.log, uc.Transactions, txOperationReverseTransfer, func(tx *sql.Tx) error {
+		// Строка перевода блокируется, чтобы одновременные отмены выполнялись по очереди.
+		transfer, err := uc.transactionDB.GetTransactionForUpdate(ctx, transactionID, tx)
+		if err != nil {
+			uc.log.Error("Ошибка GetTransactionForUpdate", "transactionID", transactionID, "error", err)
+			return err
+		}
+		if transfer == nil {
+			uc.log.Warn("Перевод не найден", "transactionID", transactionID)
+			return ErrTransferNotFound
+		}
+		if transfer.SenderUserID != user.ID {
+			uc.log.Warn("Попытка отменить чужой перевод", "username", username, "transactionID", transactionID)
+			return ErrNotTransferSender
+		}
+		if transfer.Reversed || transfer.ReversalOf != nil {
+			uc.log.Warn("Перевод нельзя отменить повторно", "transactionID", transactionID)
+			return ErrTransferNotReversible
+		}
+		if time.Since(transfer.TransactionDate) > uc.ReversalWindow {
+			uc.log.Warn("Время на отмену перевода истекло", "transactionID", transactionID, "transactionDate", transfer.TransactionDate, "window", uc.ReversalWindow)
+			return ErrReversalWindowExpired
+		}
+
+		// Порядок блокировок задает LockUserBalances, как и при переводе.
+		balances, err := uc.userDB.LockUserBalances(ctx, tx, transfer.SenderUserID, transfer.ReceiverUserID)
+		if err != nil {
+			uc.log.Error("Ошибка LockUserBalances", "senderUserID", transfer.SenderUserID, "receiverUserID", transfer.ReceiverUserID, "error", err)
+			return err
+		}
+		if balances[transfer.ReceiverUserID] < transfer.Amount {
+			uc.log.Warn("У получателя недостаточно монет для отмены перевода", "transactionID", transactionID, "coins", balances[transfer.ReceiverUserID], "amount", transfer.Amount)
+			return withDeficit(ErrReceiverInsufficientFunds, transfer.Amount-balances[transfer.ReceiverUserID])
+		}
+
+		senderCoins := balances[transfer.SenderUserID] + transfer.Amount
+		if err := uc.userDB.UpdateUserCoins(ctx, transfer.ReceiverUserID, balances[transfer.ReceiverUserID]-transfer.Amount, tx); err != nil {
+			uc.log.Error("Ошибка UpdateUserCoins (receiver)", "receiverUserID", transfer.ReceiverUserID, "amount", transfer.Amount, "error", err)
+			return err
+		}
+		if err := uc.userDB.UpdateUserCoins(ctx, transfer.SenderUserID, senderCoins, tx); err != nil {
+			uc.log.Error("Ошибка UpdateUserCoins (sender)", "senderUserID", transfer.SenderUserID, "amount", transfer.Amount, "error", err)
+			return err
+		}
+
+		err = uc.transactionDB.RecordReversal(ctx, transfer.ID, transfer.ReceiverUserID, transfer.SenderUserID, transfer.Amount, tx)
+		if db.IsUniqueViolation(err) {
+			return ErrTransferNotReversible
+		}
+		if err != nil {
+			uc.log.Error("Ошибка RecordReversal", "transactionID", transactionID, "error", err)
+			return err
+		}
+		response = &models.ReverseTransferResponse{Coins: senderCoins, TransactionID: transfer.ID, Amount: transfer.Amount}
+		return nil
+	})
 	if err != nil {
-		uc.log.Error("Ошибка RecordTransaction", "senderUserID", senderUser.ID, "receiverUserID", receiverUser.ID, "amount", amount, "error", err)
-		return err
+		return nil, err
 	}
 
+	uc.log.Info("Перевод отменен", "username", username, "transactionID", transactionID, "amount", response.Amount)
+	return response, nil
+}
+
+// checkMinBalance возвращает ErrInsufficientFunds, если баланс после списания ниже minBalance.
+func checkMinBalance(balance int64, minBalance int64) error {
+	if balance < minBalance {
+		return fmt.Errorf("%w: баланс не может опуститься ниже %d", ErrInsufficientFunds, minBalance)
+	}
 	return nil
 }
+
+// withDeficit дополняет ошибку нехватки монет суммой, которой не хватает.
+func withDeficit(err error, deficit int64) error {
+	word := "монет"
+	if deficit%10 == 1 && deficit%100 != 11 {
+		word = "монеты"
+	}
+	return fmt.Errorf("%w: не хватает %d %s", err, deficit, word)
+}
+
+// normalizeUsername убирает пробелы по краям имени пользователя, введенного в запросе.
+// Новые имена с такими пробелами не регистрируются (см. ErrInvalidUsername).
+func normalizeUsername(username string) string {
+	return strings.TrimSpace(username)
+}
+
+// sanitizeMemo удаляет из комментария управляющие и невалидные символы,
+// а также пробелы по краям.
+func sanitizeMemo(memo string) string {
+	memo = strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, memo)
+	return strings.TrimSpace(memo)
+}
@@ -3,24 +3,21 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
-	dbmocks "shop/internal/db/mocks"
+	"github.com/stretchr/testify/require"
+	dbpkg "shop/internal/db"
 	"shop/internal/models"
-	"shop/pkg/logger"
 )
 
 func TestSendCoinUseCase_SendCoin_Success(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewSendCoinUseCase(mockUserDB, mockTransactionDB, log)
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
 
 	senderUser := &models.DBUser{ID: 1, Username: "sender", Coins: 100}
 	receiverUser := &models.DBUser{ID: 2, Username: "receiver", Coins: 50}
@@ -51,20 +48,26 @@ func TestSendCoinUseCase_SendCoin_Success(t *testing.T) {
 		Return(db)
 	mockUserDB.
 		EXPECT().
-		UpdateUserCoins(gomock.Any(), 1, 50). // У отправителя вычитаются монеты.
+		LockUserBalances(gomock.Any(), gomock.Any(), 1, 2). // Блокировка строк отправителя и получателя.
+		Return(map[int]int64{1: 100, 2: 50}, nil)
+	mockUserDB.
+		EXPECT().
+		UpdateUserCoins(gomock.Any(), 1, int64(50), gomock.Any()). // У отправителя вычитаются монеты.
 		Return(nil)
 	mockUserDB.
 		EXPECT().
-		UpdateUserCoins(gomock.Any(), 2, 100). // Получателю добавляются монеты.
+		UpdateUserCoins(gomock.Any(), 2, int64(100), gomock.Any()). // Получателю добавляются монеты.
 		Return(nil)
 	mockTransactionDB.
 		EXPECT().
-		RecordTransaction(gomock.Any(), 1, 2, 50, gomock.Any()). // Запись транзакции.
+		RecordTransaction(gomock.Any(), 1, 2, int64(50), "", "", gomock.Any()). // Запись транзакции.
 		Return(nil)
 
 	// Вызываем тестируемый метод.
-	err = uc.SendCoin(context.Background(), "sender", "receiver", 50)
+	response, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "")
 	assert.NoError(t, err)
+	// В результате баланс отправителя после перевода, получатель и сумма.
+	assert.Equal(t, &models.SendCoinResult{SenderBalance: 50, ReceiverUsername: "receiver", Amount: 50}, response)
 
 	// Проверяем, что все ожидания sqlmock были удовлетворены.
 	if err := sqlMock.ExpectationsWereMet(); err != nil {
@@ -73,13 +76,7 @@ func TestSendCoinUseCase_SendCoin_Success(t *testing.T) {
 }
 
 func TestSendCoinUseCase_SendCoin_InsufficientFunds(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewSendCoinUseCase(mockUserDB, mockTransactionDB, log)
+	uc, mockUserDB, _ := newTestSendCoinUseCase(t)
 
 	// У отправителя недостаточно монет.
 	senderUser := &models.DBUser{ID: 1, Username: "sender", Coins: 30}
@@ -95,19 +92,85 @@ func TestSendCoinUseCase_SendCoin_InsufficientFunds(t *testing.T) {
 		Return(receiverUser, nil)
 
 		// Проверяем ошибку ErrInsufficientFunds
-	err := uc.SendCoin(context.Background(), "sender", "receiver", 50)
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "")
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+	assert.Contains(t, err.Error(), "не хватает 20 монет")
 }
 
-func TestSendCoinUseCase_SendCoin_SelfTransfer(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestSendCoinUseCase_SendCoin_BalanceChangedBeforeLock(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+	db, sqlMock := newTestSQLMock(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: 100}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver", Coins: 50}, nil)
+
+	// Параллельный перевод успел списать монеты: решение принимается по балансу под блокировкой.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: 30, 2: 120}, nil)
+
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "")
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	assert.Contains(t, err.Error(), "не хватает 20 монет")
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
 
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewSendCoinUseCase(mockUserDB, mockTransactionDB, log)
+func TestSendCoinUseCase_SendCoin_BelowMinBalance(t *testing.T) {
+	uc, mockUserDB, _ := newTestSendCoinUseCase(t)
+	uc.MinBalance = 100
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: 120}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver", Coins: 50}, nil)
+
+	// После перевода у отправителя осталось бы 70 монет при минимуме 100.
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "")
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	assert.Contains(t, err.Error(), "ниже 100")
+}
+
+func TestSendCoinUseCase_SendCoin_MinBalanceReached(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+	uc.MinBalance = 50
+	db, sqlMock := newTestSQLMock(t)
+
+	// Баланс может опуститься ровно до минимального.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: 100}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver", Coins: 50}, nil)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: 100, 2: 50}, nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 1, int64(50), gomock.Any()).Return(nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 2, int64(100), gomock.Any()).Return(nil)
+	mockTransactionDB.EXPECT().RecordTransaction(gomock.Any(), 1, 2, int64(50), "", "", gomock.Any()).Return(nil)
+
+	response, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), response.SenderBalance)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestWithDeficit(t *testing.T) {
+	tests := []struct {
+		deficit  int64
+		expected string
+	}{
+		{1, "не хватает 1 монеты"},
+		{5, "не хватает 5 монет"},
+		{11, "не хватает 11 монет"},
+		{21, "не хватает 21 монеты"},
+	}
+	for _, tt := range tests {
+		err := withDeficit(ErrInsufficientFunds, tt.deficit)
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		assert.Equal(t, ErrInsufficientFunds.Error()+": "+tt.expected, err.Error())
+	}
+}
+
+func TestSendCoinUseCase_SendCoin_SelfTransfer(t *testing.T) {
+	uc, mockUserDB, _ := newTestSendCoinUseCase(t)
 
 	senderUser := &models.DBUser{ID: 1, Username: "sender", Coins: 100}
 
@@ -122,19 +185,48 @@ func TestSendCoinUseCase_SendCoin_SelfTransfer(t *testing.T) {
 		Return(senderUser, nil)
 
 		// Проверяем ошибку ErrSelfTransfer.
-	err := uc.SendCoin(context.Background(), "sender", "sender", 50)
+	_, err := uc.SendCoin(context.Background(), "sender", "sender", 50, "", "")
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, ErrSelfTransfer))
 }
 
-func TestSendCoinUseCase_SendCoin_ReceiverNotFound(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestSendCoinUseCase_SendCoin_SelfTransferAfterTrim(t *testing.T) {
+	uc, mockUserDB, _ := newTestSendCoinUseCase(t)
+
+	senderUser := &models.DBUser{ID: 1, Username: "alice", Coins: 100}
+
+	// Получатель " alice " после нормализации совпадает с отправителем.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "alice").Return(senderUser, nil).Times(2)
+
+	_, err := uc.SendCoin(context.Background(), "alice", " alice ", 50, "", "")
+	assert.ErrorIs(t, err, ErrSelfTransfer)
+}
+
+func TestSendCoinUseCase_SendCoin_SenderNotNormalized(t *testing.T) {
+	uc, mockUserDB, _ := newTestSendCoinUseCase(t)
+
+	// Имя отправителя из токена ищется как есть: " alice" и alice разные пользователи.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), " alice").Return(nil, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "alice").Times(0)
+
+	_, err := uc.SendCoin(context.Background(), " alice", "bob", 50, "", "")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestSendCoinUseCase_ReadOnly(t *testing.T) {
+	uc, _, _ := newTestSendCoinUseCase(t)
+	uc.ReadOnly = true
+
+	// Ни одного обращения к БД: gomock упадет на неожиданном вызове.
+	_, err := uc.SendCoin(context.Background(), "alice", "bob", 50, "", "")
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	_, err = uc.ReverseTransfer(context.Background(), "alice", 1)
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
 
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewSendCoinUseCase(mockUserDB, mockTransactionDB, log)
+func TestSendCoinUseCase_SendCoin_ReceiverNotFound(t *testing.T) {
+	uc, mockUserDB, _ := newTestSendCoinUseCase(t)
 
 	senderUser := &models.DBUser{ID: 1, Username: "sender", Coins: 100}
 
@@ -147,24 +239,332 @@ func TestSendCoinUseCase_SendCoin_ReceiverNotFound(t *testing.T) {
 		EXPECT().
 		GetUserByUsername(gomock.Any(), "receiver").
 		Return(nil, nil)
+	mockUserDB.
+		EXPECT().
+		IsUserDeactivated(gomock.Any(), "receiver").
+		Return(false, nil)
 
 		// Проверяем ошибку ErrReceiverNotFound
-	err := uc.SendCoin(context.Background(), "sender", "receiver", 50)
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "")
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, ErrReceiverNotFound))
 }
 
-func TestSendCoinUseCase_SendCoin_InvalidAmount(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestSendCoinUseCase_SendCoin_LargeAmount(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+
+	// Балансы и сумма перевода больше 2^31.
+	const senderCoins, receiverCoins, amount int64 = 5_000_000_000, 3_000_000_000, 2_500_000_000
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: senderCoins}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver", Coins: receiverCoins}, nil)
+
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
 
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewSendCoinUseCase(mockUserDB, mockTransactionDB, log)
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: senderCoins, 2: receiverCoins}, nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 1, senderCoins-amount, gomock.Any()).Return(nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 2, receiverCoins+amount, gomock.Any()).Return(nil)
+	mockTransactionDB.EXPECT().RecordTransaction(gomock.Any(), 1, 2, amount, "", "", gomock.Any()).Return(nil)
+
+	response, err := uc.SendCoin(context.Background(), "sender", "receiver", amount, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2_500_000_000), response.SenderBalance)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_SendCoin_ReceiverInactive(t *testing.T) {
+	// Моки транзакций без ожиданий: баланс не меняется.
+	uc, mockUserDB, _ := newTestSendCoinUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: 100}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(nil, nil)
+	mockUserDB.EXPECT().IsUserDeactivated(gomock.Any(), "receiver").Return(true, nil)
+
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "")
+	assert.ErrorIs(t, err, ErrReceiverInactive)
+	assert.NotErrorIs(t, err, ErrReceiverNotFound)
+}
+
+func TestSendCoinUseCase_SendCoin_InvalidAmount(t *testing.T) {
+	uc, _, _ := newTestSendCoinUseCase(t)
 
 	// Неверная сумма (0).
-	err := uc.SendCoin(context.Background(), "sender", "receiver", 0)
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 0, "", "")
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, ErrInvalidAmount))
 }
+
+func TestSendCoinUseCase_SendCoin_Category(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: 100}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver", Coins: 50}, nil)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: 100, 2: 50}, nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 1, int64(50), gomock.Any()).Return(nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 2, int64(100), gomock.Any()).Return(nil)
+	// Категория записывается вместе с переводом.
+	mockTransactionDB.EXPECT().RecordTransaction(gomock.Any(), 1, 2, int64(50), "", "payment", gomock.Any()).Return(nil)
+
+	_, err = uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "payment")
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_SendCoin_InvalidCategory(t *testing.T) {
+	uc, _, _ := newTestSendCoinUseCase(t)
+
+	// Недопустимая категория отклоняется до обращения к БД.
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "bribe")
+	assert.ErrorIs(t, err, ErrInvalidCategory)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestSendCoinUseCase_SendCoin_Memo(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: 100}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver", Coins: 50}, nil)
+
+	db, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("не удалось создать sqlmock: %v", err)
+	}
+	defer db.Close()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: 100, 2: 50}, nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 1, int64(50), gomock.Any()).Return(nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 2, int64(100), gomock.Any()).Return(nil)
+	// Комментарий записывается очищенным от управляющих символов и пробелов по краям.
+	mockTransactionDB.EXPECT().RecordTransaction(gomock.Any(), 1, 2, int64(50), "за обед", "", gomock.Any()).Return(nil)
+
+	_, err = uc.SendCoin(context.Background(), "sender", "receiver", 50, "  за\x00 обед\n", "")
+	assert.NoError(t, err)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_SendCoin_MemoTooLong(t *testing.T) {
+	// Моки без ожиданий: любое обращение к БД провалит тест.
+	uc, _, _ := newTestSendCoinUseCase(t)
+
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, strings.Repeat("я", MaxMemoLength+1), "")
+	assert.ErrorIs(t, err, ErrMemoTooLong)
+}
+
+func TestSendCoinUseCase_SendCoin_ReceiverDeleted(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: 100}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver", Coins: 50}, nil)
+
+	db, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("не удалось создать sqlmock: %v", err)
+	}
+	defer db.Close()
+	// Транзакция откатывается.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: 100, 2: 50}, nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 1, int64(50), gomock.Any()).Return(nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 2, int64(100), gomock.Any()).Return(nil)
+	mockTransactionDB.EXPECT().RecordTransaction(gomock.Any(), 1, 2, int64(50), "", "", gomock.Any()).
+		Return(fmt.Errorf("ошибка при записи транзакции: %w", &dbpkg.DBError{Kind: dbpkg.KindForeignKeyViolation}))
+
+	_, err = uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "")
+	assert.ErrorIs(t, err, ErrReceiverNotFound)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_SendCoin_UpdateFailureRollsBack(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+	db, sqlMock := newTestSQLMock(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: 100}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver", Coins: 50}, nil)
+
+	// Ошибка зачисления получателю откатывает транзакцию, перевод не записывается.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: 100, 2: 50}, nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 1, int64(50), gomock.Any()).Return(nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 2, int64(100), gomock.Any()).Return(errors.New("update failed"))
+
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 50, "", "")
+	assert.EqualError(t, err, "update failed")
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_SendCoin_RecordFailureRollsBackBalances(t *testing.T) {
+	sqlDB, sqlMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	uc := NewSendCoinUseCase(1, dbpkg.NewUserDB(sqlDB, log), dbpkg.NewTransactionDB(sqlDB, log), log)
+
+	userRows := func(id int, username string, coins int64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "username", "password_hash", "coins"}).AddRow(id, username, "hash", coins)
+	}
+	getUser := sqlMock.ExpectPrepare("SELECT id, username, password_hash, coins FROM users WHERE username = $1 AND deleted_at IS NULL")
+	getUser.ExpectQuery().WithArgs("sender").WillReturnRows(userRows(1, "sender", 100))
+	getUser.ExpectQuery().WithArgs("receiver").WillReturnRows(userRows(2, "receiver", 50))
+
+	// Оба изменения балансов выполняются в транзакции перевода, а не напрямую в базе:
+	// ошибка записи перевода откатывает их вместе с транзакцией, коммита нет.
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("SELECT coins FROM users WHERE id = $1 FOR UPDATE").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"coins"}).AddRow(100))
+	sqlMock.ExpectQuery("SELECT coins FROM users WHERE id = $1 FOR UPDATE").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"coins"}).AddRow(50))
+	// Подготовленное выражение кэшируется в базе и один раз привязывается к соединению транзакции.
+	sqlMock.ExpectPrepare("UPDATE users SET coins = $1 WHERE id = $2")
+	updateCoins := sqlMock.ExpectPrepare("UPDATE users SET coins = $1 WHERE id = $2")
+	updateCoins.ExpectExec().WithArgs(60, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	updateCoins.ExpectExec().WithArgs(90, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec("INSERT INTO coin_transactions (sender_user_id, receiver_user_id, amount, memo, category, transaction_date) VALUES ($1, $2, $3, $4, $5, $6)").
+		WithArgs(1, 2, 40, "", "", sqlmock.AnyArg()).
+		WillReturnError(errors.New("insert failed"))
+	sqlMock.ExpectRollback()
+
+	_, err = uc.SendCoin(context.Background(), "sender", "receiver", 40, "", "")
+	assert.ErrorContains(t, err, "insert failed")
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_SendCoin_DenominationMultiple(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCaseWithDenomination(t, 5)
+	db, sqlMock := newTestSQLMock(t)
+
+	// Сумма кратна шагу 5, перевод выполняется.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender", Coins: 100}, nil)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver", Coins: 50}, nil)
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: 100, 2: 50}, nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 1, int64(85), gomock.Any()).Return(nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 2, int64(65), gomock.Any()).Return(nil)
+	mockTransactionDB.EXPECT().RecordTransaction(gomock.Any(), 1, 2, int64(15), "", "", gomock.Any()).Return(nil)
+
+	response, err := uc.SendCoin(context.Background(), "sender", "receiver", 15, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(85), response.SenderBalance)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_SendCoin_DenominationMismatch(t *testing.T) {
+	// Моки без ожиданий: сумма отклоняется до обращения к БД.
+	uc, _, _ := newTestSendCoinUseCaseWithDenomination(t, 5)
+
+	_, err := uc.SendCoin(context.Background(), "sender", "receiver", 12, "", "")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+	assert.Contains(t, err.Error(), "кратна 5")
+}
+
+func TestSendCoinUseCase_ReverseTransfer_Success(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+	uc.ReversalWindow = 5 * time.Minute
+
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender"}, nil)
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockTransactionDB.EXPECT().GetTransactionForUpdate(gomock.Any(), 7, gomock.Any()).
+		Return(&models.DBTransaction{ID: 7, SenderUserID: 1, ReceiverUserID: 2, Amount: 40, TransactionDate: time.Now().Add(-time.Minute)}, nil)
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: 60, 2: 90}, nil)
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 2, int64(50), gomock.Any()).Return(nil)  // У получателя списываются монеты.
+	mockUserDB.EXPECT().UpdateUserCoins(gomock.Any(), 1, int64(100), gomock.Any()).Return(nil) // Отправителю монеты возвращаются.
+	mockTransactionDB.EXPECT().RecordReversal(gomock.Any(), 7, 2, 1, int64(40), gomock.Any()).Return(nil)
+
+	response, err := uc.ReverseTransfer(context.Background(), "sender", 7)
+	require.NoError(t, err)
+	assert.Equal(t, &models.ReverseTransferResponse{Coins: 100, TransactionID: 7, Amount: 40}, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_ReverseTransfer_WindowExpired(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+	uc.ReversalWindow = 5 * time.Minute
+
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender"}, nil)
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockTransactionDB.EXPECT().GetTransactionForUpdate(gomock.Any(), 7, gomock.Any()).
+		Return(&models.DBTransaction{ID: 7, SenderUserID: 1, ReceiverUserID: 2, Amount: 40, TransactionDate: time.Now().Add(-10 * time.Minute)}, nil)
+	// Балансы не блокируются и не меняются.
+
+	response, err := uc.ReverseTransfer(context.Background(), "sender", 7)
+	assert.ErrorIs(t, err, ErrReversalWindowExpired)
+	assert.Nil(t, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_ReverseTransfer_ReceiverInsufficientFunds(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+	uc.ReversalWindow = 5 * time.Minute
+
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "sender").Return(&models.DBUser{ID: 1, Username: "sender"}, nil)
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockTransactionDB.EXPECT().GetTransactionForUpdate(gomock.Any(), 7, gomock.Any()).
+		Return(&models.DBTransaction{ID: 7, SenderUserID: 1, ReceiverUserID: 2, Amount: 40, TransactionDate: time.Now()}, nil)
+	// Получатель уже потратил часть переведенных монет.
+	mockUserDB.EXPECT().LockUserBalances(gomock.Any(), gomock.Any(), 1, 2).Return(map[int]int64{1: 60, 2: 25}, nil)
+
+	response, err := uc.ReverseTransfer(context.Background(), "sender", 7)
+	assert.ErrorIs(t, err, ErrReceiverInsufficientFunds)
+	assert.Contains(t, err.Error(), "не хватает 15 монет")
+	assert.Nil(t, response)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestSendCoinUseCase_ReverseTransfer_NotSender(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestSendCoinUseCase(t)
+	uc.ReversalWindow = 5 * time.Minute
+
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	// Перевод пытается отменить его получатель.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "receiver").Return(&models.DBUser{ID: 2, Username: "receiver"}, nil)
+	mockTransactionDB.EXPECT().GetDB().Return(db)
+	mockTransactionDB.EXPECT().GetTransactionForUpdate(gomock.Any(), 7, gomock.Any()).
+		Return(&models.DBTransaction{ID: 7, SenderUserID: 1, ReceiverUserID: 2, Amount: 40, TransactionDate: time.Now()}, nil)
+
+	_, err = uc.ReverseTransfer(context.Background(), "receiver", 7)
+	assert.ErrorIs(t, err, ErrNotTransferSender)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"shop/internal/db"
+	"shop/internal/metrics"
+	"shop/pkg/logger"
+)
+
+// maxTransactionAttempts максимальное количество попыток транзакции, прерванной
+// конфликтом сериализации или взаимоблокировкой.
+const maxTransactionAttempts = 3
+
+// Операции, по которым разбиваются метрики транзакций.
+const (
+	txOperationSendCoin         = "send_coin"
+	txOperationReverseTransfer  = "reverse_transfer"
+	txOperationBuyItem          = "buy_item"
+	txOperationResetPassword    = "reset_password"
+	txOperationDeactivateUser   = "deactivate_user"
+	txOperationUpdateItemPrices = "update_item_prices"
+)
+
+// withTransaction выполняет fn в транзакции: при ошибке или панике fn транзакция
+// откатывается, иначе фиксируется. Ошибка коммита возвращается вызывающему.
+// Длительность и исход транзакции учитываются в txMetrics под именем operation.
+func withTransaction(ctx context.Context, beginner txBeginner, log *logger.Logger, txMetrics *metrics.Transactions, operation string, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := beginner.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Ошибка начала транзакции", "error", err)
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	start := time.Now()
+	committed := false
+	defer func() {
+		// Неудачный коммит и паника тоже считаются откатом.
+		outcome := metrics.TransactionRollback
+		if committed {
+			outcome = metrics.TransactionCommit
+		}
+		log.Debug("Транзакция завершена", "operation", operation, "outcome", outcome, "duration", time.Since(start))
+		txMetrics.Observe(operation, outcome, time.Since(start))
+	}()
+	defer func() {
+		if p := recover(); p != nil {
+			if err := tx.Rollback(); err != nil {
+				log.Error("Ошибка отката транзакции", "error", err)
+			}
+			log.Error("Паника во время транзакции, rollback", "panic", p)
+			panic(p) // Re-panic after rollback.
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Error("Ошибка отката транзакции", "error", rollbackErr)
+		}
+		log.Error("Транзакция отменена из-за ошибки", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Error("Ошибка коммита транзакции", "error", err)
+		return fmt.Errorf("ошибка коммита транзакции: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// withTransactionRetry выполняет fn через withTransaction и повторяет транзакцию целиком,
+// если она прервана конфликтом сериализации (40001) или взаимоблокировкой (40P01),
+// не более maxTransactionAttempts раз. Изменения прерванной попытки откатываются,
+// поэтому fn должна заново читать балансы и прочие данные в каждой попытке.
+// Каждая попытка учитывается в txMetrics отдельно.
+func withTransactionRetry(ctx context.Context, beginner txBeginner, log *logger.Logger, txMetrics *metrics.Transactions, operation string, fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = withTransaction(ctx, beginner, log, txMetrics, operation, fn)
+		if err == nil || !db.IsSerializationFailure(err) || ctx.Err() != nil {
+			return err
+		}
+		log.Warn("Конфликт сериализации, транзакция будет повторена", "attempt", attempt, "error", err)
+	}
+	return err
+}
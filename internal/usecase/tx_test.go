@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"shop/internal/metrics"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dbmocks "shop/internal/db/mocks"
+)
+
+func TestWithTransaction_Metrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
+	database, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer database.Close()
+	transactionDB.EXPECT().GetDB().Return(database).AnyTimes()
+
+	reg := prometheus.NewRegistry()
+	txMetrics, err := metrics.NewTransactions(reg)
+	require.NoError(t, err)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectRollback()
+
+	ctx := context.Background()
+	require.NoError(t, withTransaction(ctx, transactionDB, log, txMetrics, txOperationSendCoin, func(*sql.Tx) error { return nil }))
+	// Ошибка fn откатывает транзакцию и увеличивает счетчик откатов.
+	fnErr := errors.New("fn error")
+	for range 2 {
+		err = withTransaction(ctx, transactionDB, log, txMetrics, txOperationSendCoin, func(*sql.Tx) error { return fnErr })
+		assert.ErrorIs(t, err, fnErr)
+	}
+
+	expected := `
+# HELP shop_db_transactions_total Количество транзакций базы данных по операциям и исходам.
+# TYPE shop_db_transactions_total counter
+shop_db_transactions_total{operation="send_coin",outcome="commit"} 1
+shop_db_transactions_total{operation="send_coin",outcome="rollback"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "shop_db_transactions_total"))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -3,8 +3,13 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -19,54 +24,283 @@ var (
 	ErrInvalidRequest  = errors.New("неверный запрос")
 	ErrNotFound        = errors.New("не найдено")
 	ErrUnauthorized    = errors.New("не авторизован")
+	ErrForbidden       = errors.New("доступ запрещен")
 	ErrUserNotFound    = fmt.Errorf("%w: пользователь не найден", ErrNotFound)
 	ErrInvalidPassword = fmt.Errorf("%w: неверный пароль", ErrUnauthorized)
+	ErrUserDeactivated = fmt.Errorf("%w: пользователь деактивирован", ErrUnauthorized)
+	// ErrTokenUserNotFound пользователя из токена больше нет.
+	ErrTokenUserNotFound = fmt.Errorf("%w: пользователь из токена не найден", ErrUnauthorized)
+	// ErrRefreshTooEarly токен еще долго действителен и пока не может быть обновлен.
+	ErrRefreshTooEarly = fmt.Errorf("%w: токен еще действителен, обновить его можно только незадолго до истечения", ErrInvalidRequest)
+	// ErrSessionExpired сессия длится дольше MaxSessionAge: нужно войти заново с паролем.
+	ErrSessionExpired = fmt.Errorf("%w: сессия превысила максимальную длительность, войдите заново", ErrUnauthorized)
+	// ErrReadOnly изменение данных запрещено в режиме только для чтения.
+	ErrReadOnly = errors.New("сервис временно работает в режиме только для чтения")
+	// ErrTokenExpired срок действия токена истек.
+	ErrTokenExpired = fmt.Errorf("%w: срок действия токена истек", ErrUnauthorized)
+	// ErrTokenNoExpiration в токене нет срока действия. Такие токены выпускались до
+	// появления exp и больше не принимаются: клиент должен войти заново.
+	ErrTokenNoExpiration = fmt.Errorf("%w: в токене не указан срок действия", ErrUnauthorized)
+	// ErrUnknownKeyID токен подписан ключом, которого нет в наборе ключей.
+	ErrUnknownKeyID = fmt.Errorf("%w: неизвестный идентификатор ключа токена", ErrUnauthorized)
+	// ErrMissingKeyID в токене нет kid, а токены без kid при наборе ключей не принимаются.
+	ErrMissingKeyID = fmt.Errorf("%w: в токене не указан идентификатор ключа", ErrUnauthorized)
+	// ErrSigningKeyNotFound ключ подписи отсутствует в наборе ключей.
+	ErrSigningKeyNotFound = errors.New("ключ подписи отсутствует в наборе ключей")
+	// ErrReservedUsername имя зарезервировано (например, за администратором) и не может быть занято регистрацией.
+	ErrReservedUsername = fmt.Errorf("%w: имя пользователя зарезервировано", ErrForbidden)
+	// ErrInvalidUsername имя пользователя при регистрации начинается или заканчивается пробелами.
+	ErrInvalidUsername = fmt.Errorf("%w: имя пользователя не должно начинаться или заканчиваться пробелами", ErrInvalidRequest)
+	// ErrUnknownInfoSection запрошен неизвестный раздел ответа /api/info.
+	ErrUnknownInfoSection = fmt.Errorf("%w: неизвестный раздел информации", ErrInvalidRequest)
 )
 
+// InfoSection раздел ответа /api/info.
+type InfoSection string
+
+// Разделы ответа /api/info. Названия совпадают с полями JSON.
+const (
+	InfoSectionCoins       InfoSection = "coins"
+	InfoSectionInventory   InfoSection = "inventory"
+	InfoSectionItemCount   InfoSection = "itemCount"
+	InfoSectionCoinHistory InfoSection = "coinHistory"
+)
+
+// InfoOptions параметры запроса информации о пользователе.
+type InfoOptions struct {
+	// Sections выбранные разделы ответа. nil означает все разделы.
+	Sections map[InfoSection]bool
+	// ItemDetails дополняет предметы инвентаря текущими ценами из каталога.
+	ItemDetails bool
+	// InventoryLimit максимальное число предметов инвентаря в ответе. 0 снимает ограничение.
+	InventoryLimit int
+	// Category оставляет в истории переводов только переводы этой категории. Пустая строка — все переводы.
+	Category string
+}
+
+// Includes сообщает, выбран ли раздел section.
+func (o InfoOptions) Includes(section InfoSection) bool {
+	return o.Sections == nil || o.Sections[section]
+}
+
+// ParseInfoSections разбирает список разделов через запятую, например "coins,inventory".
+func ParseInfoSections(raw string) (map[InfoSection]bool, error) {
+	sections := map[InfoSection]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		section := InfoSection(strings.TrimSpace(name))
+		switch section {
+		case InfoSectionCoins, InfoSectionInventory, InfoSectionItemCount, InfoSectionCoinHistory:
+			sections[section] = true
+		default:
+			return nil, fmt.Errorf("%w: '%s'", ErrUnknownInfoSection, section)
+		}
+	}
+	return sections, nil
+}
+
+// Размер рейтинга пользователей.
+const (
+	DefaultLeaderboardLimit = 10
+	MaxLeaderboardLimit     = 100
+)
+
+// DefaultTokenTTL срок действия токена по умолчанию.
+const DefaultTokenTTL = 24 * time.Hour
+
+// DefaultRefreshGrace время после истечения токена, в течение которого его еще можно обменять на новый.
+const DefaultRefreshGrace = 5 * time.Minute
+
+// DefaultRefreshWindow время до истечения токена, начиная с которого его можно обменять на новый.
+const DefaultRefreshWindow = time.Hour
+
+// DefaultMaxSessionAge максимальная длительность сессии от входа по паролю по умолчанию.
+const DefaultMaxSessionAge = 7 * 24 * time.Hour
+
+// InitialCoins начальный баланс монет нового пользователя.
+const InitialCoins = 1000
+
 // UserUseCaseInterface интерфейс для use case'ов информации о пользователе и аутентификации.
 type UserUseCaseInterface interface {
-	GetUserInfo(ctx context.Context, username string) (*models.InfoResponse, error)
-	Auth(ctx context.Context, username string, password string) (string, error)
+	GetUserInfo(ctx context.Context, username string, opts InfoOptions) (*models.InfoResponse, error)
+	GetInventory(ctx context.Context, username string, prefix string) (*models.InventoryResponse, error)
+	StreamCoinHistory(ctx context.Context, username string, category string, fn func(models.Transaction) error) error
+	GetUserID(ctx context.Context, username string) (int, error)
+	ResolveUser(ctx context.Context, username string) (*models.DBUser, error)
+	GetRank(ctx context.Context, username string) (*models.RankResponse, error)
+	GetLeaderboard(ctx context.Context, limit int) (*models.LeaderboardResponse, error)
+	GetBalance(ctx context.Context, username string) (int64, error)
+	GetStatement(ctx context.Context, username string) (*models.StatementResponse, error)
+	Auth(ctx context.Context, username string, password string) (string, *models.UserSummary, error)
 	GenerateJWTToken(username string) (string, error)
 	VerifyJWTToken(tokenString string) (string, error)
+	ParseJWTToken(tokenString string) (*TokenClaims, error)
+	RefreshToken(ctx context.Context, tokenString string) (string, error)
 }
 
 // UserUseCase реализует UserInfoUseCaseInterface.
 type UserUseCase struct {
-	userDB        db.UserDBInterface
-	transactionDB db.TransactionDBInterface
-	jwtSecret     []byte
-	log           *logger.Logger
+	// ReadOnly запрещает регистрацию новых пользователей; вход существующих продолжает работать.
+	ReadOnly bool
+	// ReservedUsernames имена, которые нельзя занять регистрацией через Auth. Сюда передаются
+	// имена администраторов, чтобы первый вошедший под таким именем не получил его права.
+	ReservedUsernames []string
+	// TokenTTL срок действия выдаваемых токенов.
+	TokenTTL time.Duration
+	// RefreshGrace время после истечения токена, в течение которого RefreshToken еще принимает его.
+	RefreshGrace time.Duration
+	// RefreshWindow время до истечения токена, начиная с которого RefreshToken принимает его.
+	// Токены, которым осталось жить дольше, не обновляются. 0 снимает ограничение.
+	RefreshWindow time.Duration
+	// MaxSessionAge максимальное время от входа по паролю, в течение которого токен можно
+	// обновлять. Обновленный токен не живет дольше этого срока. 0 снимает ограничение.
+	MaxSessionAge time.Duration
+	// PasswordPepper секрет приложения, подмешиваемый в пароли перед bcrypt. Хранится вне базы;
+	// смена значения делает недействительными все существующие хеши паролей.
+	PasswordPepper []byte
+	// AcceptLegacyTokens при включенном наборе ключей разрешает проверять токены без kid основным
+	// секретом. Нужен только на время перехода на набор ключей: пока он включен, основной секрет
+	// нельзя вывести из оборота.
+	AcceptLegacyTokens bool
+	userDB             userInfoDB
+	transactionDB      userInfoTransactionDB
+	jwtSecret          []byte
+	// jwtKeys набор ключей подписи по kid, signingKeyID ключ для новых токенов.
+	jwtKeys      map[string][]byte
+	signingKeyID string
+	log          *logger.Logger
 }
 
 // NewUserInfoUseCase создает новый UserUseCase.
-func NewUserInfoUseCase(jwtSecretString string, userDB db.UserDBInterface, transactionDB db.TransactionDBInterface, log *logger.Logger) *UserUseCase {
+func NewUserInfoUseCase(jwtSecretString string, userDB userInfoDB, transactionDB userInfoTransactionDB, log *logger.Logger) *UserUseCase {
 	return &UserUseCase{
 		userDB:        userDB,
 		transactionDB: transactionDB,
 		jwtSecret:     []byte(jwtSecretString),
+		TokenTTL:      DefaultTokenTTL,
+		RefreshGrace:  DefaultRefreshGrace,
+		RefreshWindow: DefaultRefreshWindow,
+		MaxSessionAge: DefaultMaxSessionAge,
 		log:           log,
 	}
 }
 
 // GetUserInfo получает информацию о пользователе.
-func (uc *UserUseCase) GetUserInfo(ctx context.Context, username string) (*models.InfoResponse, error) {
-	uc.log.Debug("GetUserInfo", "username", username)
+// Запрашиваются только разделы, выбранные в opts; остальные остаются пустыми.
+func (uc *UserUseCase) GetUserInfo(ctx context.Context, username string, opts InfoOptions) (*models.InfoResponse, error) {
+	uc.log.Debug("GetUserInfo", "username", username, "sections", opts.Sections, "itemDetails", opts.ItemDetails)
 
-	user, err := uc.userDB.GetUserByUsername(ctx, username)
-	if err != nil {
-		uc.log.Error("Ошибка GetUserByUsername в GetUserInfo", "username", username, "error", err)
-		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	if err := ValidateCategory(opts.Category); err != nil {
+		return nil, err
 	}
-	if user == nil {
-		uc.log.Warn("Пользователь не найден в GetUserInfo", "username", username)
-		return nil, ErrUserNotFound
+
+	user, err := uc.currentUser(ctx, username)
+	if err != nil {
+		return nil, err
 	}
 	uc.log.Debug("Пользователь найден", "username", username, "userID", user.ID)
 
-	inventoryDB, err := uc.userDB.GetUserInventory(ctx, user.ID)
+	response := &models.InfoResponse{Coins: user.Coins}
+
+	if opts.Includes(InfoSectionInventory) {
+		response.Inventory, err = uc.userInventory(ctx, user.ID, opts.ItemDetails, opts.InventoryLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Includes(InfoSectionItemCount) {
+		response.ItemCount, err = uc.userDB.GetInventoryItemCount(ctx, user.ID)
+		if err != nil {
+			uc.log.Error("Ошибка GetInventoryItemCount в GetUserInfo", "userID", user.ID, "error", err)
+			return nil, fmt.Errorf("ошибка при подсчете предметов инвентаря: %w", err)
+		}
+	}
+
+	if opts.Includes(InfoSectionCoinHistory) {
+		history, err := uc.transactionDB.GetCoinHistory(ctx, user.ID, opts.Category)
+		if err != nil {
+			uc.log.Error("Ошибка GetCoinHistory в GetUserInfo", "userID", user.ID, "error", err)
+			return nil, fmt.Errorf("ошибка при получении истории транзакций: %w", err)
+		}
+		response.CoinHistory = *history
+	}
+
+	return response, nil
+}
+
+// userInventory получает инвентарь пользователя, упорядоченный по названию предмета,
+// при withItemDetails вместе с ценами из каталога. limit ограничивает число предметов, 0 снимает ограничение.
+func (uc *UserUseCase) userInventory(ctx context.Context, userID int, withItemDetails bool, limit int) ([]models.InventoryItem, error) {
+	if !withItemDetails {
+		inventoryDB, err := uc.userDB.GetUserInventory(ctx, userID, limit)
+		if err != nil {
+			uc.log.Error("Ошибка GetUserInventory в GetUserInfo", "userID", userID, "error", err)
+			return nil, fmt.Errorf("ошибка при получении инвентаря пользователя: %w", err)
+		}
+		inventory := []models.InventoryItem{}
+		for _, item := range inventoryDB {
+			inventory = append(inventory, models.InventoryItem{Type: item.ItemType, Quantity: item.Quantity})
+		}
+		return inventory, nil
+	}
+
+	inventoryDB, err := uc.userDB.GetUserInventoryWithPrices(ctx, userID)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserInventoryWithPrices в GetUserInfo", "userID", userID, "error", err)
+		return nil, fmt.Errorf("ошибка при получении инвентаря пользователя: %w", err)
+	}
+	if limit > 0 && len(inventoryDB) > limit {
+		inventoryDB = inventoryDB[:limit]
+	}
+	inventory := []models.InventoryItem{}
+	for _, item := range inventoryDB {
+		// Предмет мог быть удален из каталога после покупки: оставляем его в инвентаре без цены.
+		inventory = append(inventory, models.InventoryItem{
+			Type:         item.ItemType,
+			Quantity:     item.Quantity,
+			Price:        item.Price,
+			Discontinued: item.Price == nil,
+		})
+	}
+	return inventory, nil
+}
+
+// StreamCoinHistory передает переводы пользователя в fn по мере чтения из хранилища,
+// новые первыми, не собирая историю в памяти. Непустая category оставляет только
+// переводы этой категории. Ошибка fn прерывает чтение.
+func (uc *UserUseCase) StreamCoinHistory(ctx context.Context, username string, category string, fn func(models.Transaction) error) error {
+	if err := ValidateCategory(category); err != nil {
+		return err
+	}
+
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		var err error
+		if userID, err = uc.GetUserID(ctx, username); err != nil {
+			return err
+		}
+	}
+
+	if err := uc.transactionDB.StreamCoinHistory(ctx, userID, category, fn); err != nil {
+		uc.log.Error("Ошибка StreamCoinHistory", "userID", userID, "error", err)
+		return fmt.Errorf("ошибка при получении истории транзакций: %w", err)
+	}
+	return nil
+}
+
+// GetInventory получает предметы инвентаря пользователя, название которых начинается с prefix.
+// Пустой prefix возвращает весь инвентарь.
+func (uc *UserUseCase) GetInventory(ctx context.Context, username string, prefix string) (*models.InventoryResponse, error) {
+	uc.log.Debug("GetInventory", "username", username, "prefix", prefix)
+
+	user, err := uc.currentUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	inventoryDB, err := uc.userDB.GetUserInventoryByPrefix(ctx, user.ID, prefix)
 	if err != nil {
-		uc.log.Error("Ошибка GetUserInventory в GetUserInfo", "userID", user.ID, "error", err)
+		uc.log.Error("Ошибка GetUserInventoryByPrefix в GetInventory", "userID", user.ID, "prefix", prefix, "error", err)
 		return nil, fmt.Errorf("ошибка при получении инвентаря пользователя: %w", err)
 	}
 
@@ -74,63 +308,268 @@ func (uc *UserUseCase) GetUserInfo(ctx context.Context, username string) (*model
 	for _, item := range inventoryDB {
 		inventory = append(inventory, models.InventoryItem{Type: item.ItemType, Quantity: item.Quantity})
 	}
+	return &models.InventoryResponse{Inventory: inventory}, nil
+}
+
+// GetRank получает место пользователя в рейтинге по балансу монет.
+// Пользователи с равным балансом делят место, следующее место пропускается.
+func (uc *UserUseCase) GetRank(ctx context.Context, username string) (*models.RankResponse, error) {
+	uc.log.Debug("GetRank", "username", username)
 
-	history, err := uc.transactionDB.GetCoinHistory(ctx, user.ID)
+	user, err := uc.currentUser(ctx, username)
 	if err != nil {
-		uc.log.Error("Ошибка GetCoinHistory в GetUserInfo", "userID", user.ID, "error", err)
-		return nil, fmt.Errorf("ошибка при получении истории транзакций: %w", err)
+		return nil, err
 	}
 
-	response := &models.InfoResponse{
-		Coins:       user.Coins,
-		Inventory:   inventory,
-		CoinHistory: *history,
+	rank, err := uc.userDB.GetUserRank(ctx, user.ID)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserRank в GetRank", "userID", user.ID, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("ошибка при получении места в рейтинге: %w", err)
 	}
-	return response, nil
+	return &models.RankResponse{Rank: rank, Coins: user.Coins}, nil
+}
+
+// GetLeaderboard получает limit пользователей с наибольшим балансом и количеством предметов
+// в их инвентаре. Нулевой limit заменяется на DefaultLeaderboardLimit.
+func (uc *UserUseCase) GetLeaderboard(ctx context.Context, limit int) (*models.LeaderboardResponse, error) {
+	uc.log.Debug("GetLeaderboard", "limit", limit)
+
+	if limit == 0 {
+		limit = DefaultLeaderboardLimit
+	}
+	if limit < 0 || limit > MaxLeaderboardLimit {
+		return nil, fmt.Errorf("%w: limit должен быть от 1 до %d", ErrInvalidPagination, MaxLeaderboardLimit)
+	}
+
+	entries, err := uc.userDB.GetLeaderboard(ctx, limit)
+	if err != nil {
+		uc.log.Error("Ошибка GetLeaderboard", "limit", limit, "error", err)
+		return nil, fmt.Errorf("ошибка при получении рейтинга пользователей: %w", err)
+	}
+	return &models.LeaderboardResponse{Entries: entries}, nil
+}
+
+// GetBalance получает текущий баланс монет пользователя. Баланс всегда читается из хранилища:
+// поток /api/balance/stream вызывает GetBalance многократно в рамках одного запроса.
+func (uc *UserUseCase) GetBalance(ctx context.Context, username string) (int64, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		user, err := uc.currentUser(ctx, username)
+		if err != nil {
+			return 0, err
+		}
+		return user.Coins, nil
+	}
+
+	coins, err := uc.userDB.GetBalance(ctx, userID)
+	if err != nil {
+		uc.log.Error("Ошибка GetBalance", "userID", userID, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		return 0, fmt.Errorf("ошибка при получении баланса пользователя: %w", err)
+	}
+	return coins, nil
+}
+
+// GetStatement возвращает выписку по переводам пользователя в хронологическом порядке
+// с балансом после каждого перевода. Баланс восстанавливается от текущего назад,
+// поэтому после последнего перевода он равен текущему балансу. Покупки и бонусы
+// в истории не хранятся, и балансы до них восстанавливаются только по переводам.
+func (uc *UserUseCase) GetStatement(ctx context.Context, username string) (*models.StatementResponse, error) {
+	uc.log.Debug("GetStatement", "username", username)
+
+	user, err := uc.currentUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := uc.transactionDB.GetUserTransactions(ctx, user.ID)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserTransactions", "userID", user.ID, "error", err)
+		return nil, fmt.Errorf("ошибка при получении переводов пользователя: %w", err)
+	}
+
+	entries := make([]models.StatementEntry, len(transactions))
+	balance := user.Coins
+	for i := len(transactions) - 1; i >= 0; i-- {
+		t := transactions[i]
+		entry := models.StatementEntry{Date: t.TransactionDate, Counterparty: t.ReceiverUsername, Amount: -t.Amount, Memo: t.Memo, Balance: balance}
+		if t.ReceiverUserID == user.ID {
+			entry.Counterparty = t.SenderUsername
+			entry.Amount = t.Amount
+		}
+		entries[i] = entry
+		balance -= entry.Amount
+	}
+	return &models.StatementResponse{Coins: user.Coins, Entries: entries}, nil
+}
+
+// GetUserID получает ID пользователя по имени.
+func (uc *UserUseCase) GetUserID(ctx context.Context, username string) (int, error) {
+	uc.log.Debug("GetUserID", "username", username)
+
+	userID, err := uc.userDB.GetUserIDByUsername(ctx, username)
+	if err != nil {
+		uc.log.Warn("Ошибка GetUserIDByUsername в GetUserID", "username", username, "error", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		return 0, fmt.Errorf("ошибка при получении ID пользователя: %w", err)
+	}
+	return userID, nil
+}
+
+// ResolveUser получает ID и баланс пользователя одним запросом. Деактивированные
+// пользователи не возвращаются: для них и несуществующих возвращается ErrUserNotFound.
+func (uc *UserUseCase) ResolveUser(ctx context.Context, username string) (*models.DBUser, error) {
+	uc.log.Debug("ResolveUser", "username", username)
+
+	user, err := uc.userDB.GetUserByUsername(ctx, username)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserByUsername в ResolveUser", "username", username, "error", err)
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// currentUser получает ID и баланс текущего пользователя.
+// Если middleware уже загрузил пользователя, он берется из контекста без запроса к хранилищу.
+func (uc *UserUseCase) currentUser(ctx context.Context, username string) (*models.DBUser, error) {
+	user, err := lookupUser(ctx, uc.userDB, username)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserByUsername", "username", username, "error", err)
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user == nil {
+		uc.log.Warn("Пользователь не найден", "username", username)
+		return nil, ErrUserNotFound
+	}
+	return user, nil
 }
 
 // Auth аутентифицирует пользователя и возвращает JWT токен.
-func (uc *UserUseCase) Auth(ctx context.Context, username string, password string) (string, error) {
+// Если пользователь был зарегистрирован этим запросом, также возвращается
+// сводка о нем с начальным балансом, иначе сводка равна nil.
+func (uc *UserUseCase) Auth(ctx context.Context, username string, password string) (string, *models.UserSummary, error) {
 	uc.log.Debug("Auth", "username", username)
 
 	user, err := uc.userDB.GetUserByUsername(ctx, username)
 	if err != nil {
 		uc.log.Error("Ошибка GetUserByUsername в Auth", "username", username, "error", err)
-		return "", fmt.Errorf("ошибка сервера при поиске пользователя: %w", err)
+		return "", nil, fmt.Errorf("ошибка сервера при поиске пользователя: %w", err)
 	}
 
 	uc.log.Debug("Пользователь после GetUserByUsername", "username", username, "user", user)
 
 	if user == nil {
+		// Имя деактивированного пользователя нельзя зарегистрировать повторно.
+		deactivated, err := uc.userDB.IsUserDeactivated(ctx, username)
+		if err != nil {
+			uc.log.Error("Ошибка IsUserDeactivated в Auth", "username", username, "error", err)
+			return "", nil, fmt.Errorf("ошибка сервера при проверке пользователя: %w", err)
+		}
+		if deactivated {
+			uc.log.Warn("Попытка входа деактивированного пользователя", "username", username)
+			// Ответ без проверки пароля приходил бы заметно быстрее, чем для существующего
+			// пользователя, и по времени выдавал бы, что такое имя было зарегистрировано.
+			equalizeAuthTiming(uc.PasswordPepper, password)
+			return "", nil, ErrUserDeactivated
+		}
+
+		if uc.ReadOnly {
+			uc.log.Warn("Регистрация отклонена в режиме только для чтения", "username", username)
+			return "", nil, ErrReadOnly
+		}
+		if username != normalizeUsername(username) {
+			uc.log.Warn("Регистрация отклонена: пробелы по краям имени", "username", username)
+			return "", nil, ErrInvalidUsername
+		}
+		if slices.Contains(uc.ReservedUsernames, username) {
+			uc.log.Warn("Регистрация отклонена: имя зарезервировано", "username", username)
+			return "", nil, ErrReservedUsername
+		}
+
 		// Пользователь не найден, создаем нового (логика регистрации).
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		hashedPassword, err := hashPassword(uc.PasswordPepper, password)
 		if err != nil {
-			uc.log.Error("Ошибка bcrypt.GenerateFromPassword в Auth", "username", username, "error", err)
-			return "", fmt.Errorf("ошибка сервера при хешировании пароля: %w", err)
+			uc.log.Error("Ошибка hashPassword в Auth", "username", username, "error", err)
+			return "", nil, fmt.Errorf("ошибка сервера при хешировании пароля: %w", err)
 		}
 		err = uc.userDB.CreateUser(ctx, username, string(hashedPassword))
+		if db.IsUniqueViolation(err) {
+			// Пользователь создан параллельным запросом: повторно получаем его
+			// и проверяем пароль как для существующего пользователя.
+			uc.log.Warn("Пользователь создан параллельным запросом в Auth", "username", username)
+			user, err = uc.userDB.GetUserByUsername(ctx, username)
+			if err != nil {
+				uc.log.Error("Ошибка GetUserByUsername после конфликта в Auth", "username", username, "error", err)
+				return "", nil, fmt.Errorf("ошибка сервера при поиске пользователя: %w", err)
+			}
+			if user == nil {
+				uc.log.Error("Пользователь не найден после конфликта в Auth", "username", username)
+				return "", nil, fmt.Errorf("ошибка сервера при поиске пользователя: %w", ErrUserNotFound)
+			}
+			token, err := uc.authExisting(username, user, password)
+			return token, nil, err
+		}
 		if err != nil {
 			uc.log.Error("Ошибка CreateUser в Auth", "username", username, "error", err)
-			return "", fmt.Errorf("ошибка сервера при создании пользователя: %w", err)
+			return "", nil, fmt.Errorf("ошибка сервера при создании пользователя: %w", err)
 		}
 		user, err = uc.userDB.GetUserByUsername(ctx, username)
 		if err != nil {
 			uc.log.Error("Ошибка GetUserByUsername после создания в Auth", "username", username, "error", err)
-			return "", fmt.Errorf("ошибка сервера после создания пользователя: %w", err)
+			return "", nil, fmt.Errorf("ошибка сервера после создания пользователя: %w", err)
 		}
 		// Устанавливаем начальное количество монет для нового пользователя.
-		err = uc.userDB.SetInitialCoins(ctx, user.ID, 1000)
+		err = uc.userDB.SetInitialCoins(ctx, user.ID, InitialCoins)
 		if err != nil {
 			uc.log.Error("Ошибка SetInitialCoins в Auth", "userID", user.ID, "error", err)
-			return "", fmt.Errorf("ошибка сервера при установке начальных монет: %w", err)
+			return "", nil, fmt.Errorf("ошибка сервера при установке начальных монет: %w", err)
 		}
 	} else {
-		// Пользователь существует, проверяем пароль.
-		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
-		if err != nil {
-			uc.log.Error("Ошибка bcrypt.CompareHashAndPassword", "username", username, "error", err)
-			return "", ErrInvalidPassword
-		}
+		token, err := uc.authExisting(username, user, password)
+		return token, nil, err
+	}
+
+	token, err := uc.GenerateJWTToken(username)
+	if err != nil {
+		uc.log.Error("Ошибка GenerateJWTToken в Auth", "username", username, "error", err)
+		return "", nil, fmt.Errorf("ошибка сервера при генерации токена: %w", err)
+	}
+	return token, &models.UserSummary{Username: username, Coins: InitialCoins}, nil
+}
+
+// compareHashAndPassword проверяет пароль по bcrypt-хешу. Переменная, чтобы тесты могли
+// убедиться, что проверка выполняется.
+var compareHashAndPassword = bcrypt.CompareHashAndPassword
+
+// dummyPasswordHash хеш, с которым сравнивается пароль, когда проверять его не с чем.
+// Вычисляется один раз с той же стоимостью, что и хеши пользователей.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// equalizeAuthTiming выполняет проверку пароля впустую, чтобы отказ без проверки пароля
+// занимал столько же времени, сколько отказ из-за неверного пароля.
+func equalizeAuthTiming(pepper []byte, password string) {
+	_ = checkPassword(pepper, string(dummyPasswordHash()), password)
+}
+
+// authExisting проверяет пароль существующего пользователя и выдает токен.
+func (uc *UserUseCase) authExisting(username string, user *models.DBUser, password string) (string, error) {
+	err := checkPassword(uc.PasswordPepper, user.PasswordHash, password)
+	if err != nil {
+		uc.log.Error("Ошибка bcrypt.CompareHashAndPassword", "username", username, "error", err)
+		return "", ErrInvalidPassword
 	}
 
 	token, err := uc.GenerateJWTToken(username)
@@ -141,38 +580,176 @@ func (uc *UserUseCase) Auth(ctx context.Context, username string, password strin
 	return token, nil
 }
 
-// GenerateJWTToken генерирует JWT токен для заданного имени пользователя.
+// GenerateJWTToken генерирует JWT токен для заданного имени пользователя,
+// действительный в течение TokenTTL. Токен начинает новую сессию.
 func (uc *UserUseCase) GenerateJWTToken(username string) (string, error) {
+	return uc.generateJWTToken(username, time.Now())
+}
+
+// generateJWTToken генерирует токен сессии, начатой входом по паролю в authTime.
+// Срок действия токена не выходит за MaxSessionAge от начала сессии.
+func (uc *UserUseCase) generateJWTToken(username string, authTime time.Time) (string, error) {
+	now := time.Now()
+	exp := now.Add(uc.TokenTTL)
+	if uc.MaxSessionAge > 0 {
+		if sessionEnd := authTime.Add(uc.MaxSessionAge); sessionEnd.Before(exp) {
+			exp = sessionEnd
+		}
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,
+		"username":  username,
+		"iat":       now.Unix(),
+		"exp":       exp.Unix(),
+		"auth_time": authTime.Unix(),
 	})
 
-	tokenString, err := token.SignedString(uc.jwtSecret)
+	secret := uc.jwtSecret
+	if uc.signingKeyID != "" {
+		token.Header["kid"] = uc.signingKeyID
+		secret = uc.jwtKeys[uc.signingKeyID]
+	}
+
+	tokenString, err := token.SignedString(secret)
 	if err != nil {
 		return "", fmt.Errorf("ошибка подписи токена: %w", err)
 	}
 	return tokenString, nil
 }
 
+// UseKeySet включает подпись токенов набором ключей с идентификаторами kid.
+// Новые токены подписываются ключом signingKeyID, который должен быть в keys.
+func (uc *UserUseCase) UseKeySet(keys map[string]string, signingKeyID string) error {
+	if _, ok := keys[signingKeyID]; !ok {
+		return fmt.Errorf("%w: '%s'", ErrSigningKeyNotFound, signingKeyID)
+	}
+	uc.jwtKeys = make(map[string][]byte, len(keys))
+	for kid, secret := range keys {
+		uc.jwtKeys[kid] = []byte(secret)
+	}
+	uc.signingKeyID = signingKeyID
+	return nil
+}
+
+// verificationKey выбирает ключ проверки подписи по заголовку kid.
+// Без набора ключей все токены проверяются основным секретом. При наборе ключей токены без kid
+// отклоняются, если не включен AcceptLegacyTokens.
+func (uc *UserUseCase) verificationKey(token *jwt.Token) (any, error) {
+	rawKID, ok := token.Header["kid"]
+	if !ok {
+		if uc.jwtKeys != nil && !uc.AcceptLegacyTokens {
+			return nil, ErrMissingKeyID
+		}
+		return uc.jwtSecret, nil
+	}
+	kid, _ := rawKID.(string)
+	key, ok := uc.jwtKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%v'", ErrUnknownKeyID, rawKID)
+	}
+	return key, nil
+}
+
+// TokenClaims утверждения JWT токена, выдаваемого GenerateJWTToken.
+type TokenClaims struct {
+	Username string `json:"username"`
+	// AuthTime время входа по паролю, с которого началась сессия. При обновлении токена не меняется.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// sessionStart возвращает время начала сессии токена. В токенах, выпущенных до появления
+// auth_time, сессия отсчитывается от iat.
+func (c *TokenClaims) sessionStart() (time.Time, bool) {
+	if c.AuthTime != nil {
+		return c.AuthTime.Time, true
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time, true
+	}
+	return time.Time{}, false
+}
+
 // VerifyJWTToken проверяет JWT токен и возвращает имя пользователя, если токен действителен.
+// Просроченные токены отклоняются с ErrTokenExpired, токены без exp — с ErrTokenNoExpiration.
 func (uc *UserUseCase) VerifyJWTToken(tokenString string) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+	claims, err := uc.ParseJWTToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+	return claims.Username, nil
+}
+
+// ParseJWTToken проверяет JWT токен так же, как VerifyJWTToken, и возвращает его утверждения.
+func (uc *UserUseCase) ParseJWTToken(tokenString string) (*TokenClaims, error) {
+	return uc.parseJWTToken(tokenString, 0)
+}
+
+// parseJWTToken проверяет подпись и утверждения токена. Токен, истекший не более
+// leeway назад, считается действительным.
+func (uc *UserUseCase) parseJWTToken(tokenString string, leeway time.Duration) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
 		}
-		return uc.jwtSecret, nil
-	})
+		return uc.verificationKey(token)
+	}, jwt.WithExpirationRequired(), jwt.WithIssuedAt(), jwt.WithLeeway(leeway))
+
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return nil, ErrTokenNoExpiration
+	case err != nil:
+		return nil, fmt.Errorf("%w: ошибка парсинга токена: %w", ErrUnauthorized, err)
+	}
 
+	if !token.Valid {
+		return nil, fmt.Errorf("%w: неверный токен", ErrUnauthorized)
+	}
+	if claims.Username == "" {
+		return nil, fmt.Errorf("%w: неверное имя пользователя в токене", ErrUnauthorized)
+	}
+	return claims, nil
+}
+
+// RefreshToken выдает новый токен взамен истекающего в течение RefreshWindow или истекшего
+// не более RefreshGrace назад, не проверяя пароль. Новый токен продолжает ту же сессию:
+// после MaxSessionAge от входа по паролю токен не обновляется. Токены удаленных
+// пользователей не обновляются.
+func (uc *UserUseCase) RefreshToken(ctx context.Context, tokenString string) (string, error) {
+	claims, err := uc.parseJWTToken(tokenString, uc.RefreshGrace)
 	if err != nil {
-		return "", fmt.Errorf("ошибка парсинга токена: %w", err)
+		return "", err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		username, ok := claims["username"].(string)
-		if !ok {
-			return "", fmt.Errorf("неверное имя пользователя в токене")
-		}
-		return username, nil
+	now := time.Now()
+	if uc.RefreshWindow > 0 && claims.ExpiresAt.Sub(now) > uc.RefreshWindow {
+		return "", ErrRefreshTooEarly
+	}
+	authTime, ok := claims.sessionStart()
+	if uc.MaxSessionAge > 0 && (!ok || now.Sub(authTime) >= uc.MaxSessionAge) {
+		uc.log.Warn("Обновление токена после окончания сессии", "username", claims.Username)
+		return "", ErrSessionExpired
 	}
-	return "", fmt.Errorf("неверный токен")
+	if !ok {
+		authTime = now
+	}
+
+	user, err := uc.userDB.GetUserByUsername(ctx, claims.Username)
+	if err != nil {
+		uc.log.Error("Ошибка GetUserByUsername в RefreshToken", "username", claims.Username, "error", err)
+		return "", fmt.Errorf("ошибка сервера при поиске пользователя: %w", err)
+	}
+	if user == nil {
+		uc.log.Warn("Обновление токена несуществующего пользователя", "username", claims.Username)
+		return "", ErrTokenUserNotFound
+	}
+
+	token, err := uc.generateJWTToken(claims.Username, authTime)
+	if err != nil {
+		uc.log.Error("Ошибка GenerateJWTToken в RefreshToken", "username", claims.Username, "error", err)
+		return "", fmt.Errorf("ошибка сервера при генерации токена: %w", err)
+	}
+	return token, nil
 }
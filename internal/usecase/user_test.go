@@ -2,26 +2,24 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
-	dbmocks "shop/internal/db/mocks"
+	"shop/internal/db"
 	"shop/internal/models"
-	"shop/pkg/logger"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func TestUserUseCase_GetUserInfo_Success(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewUserInfoUseCase("secret", mockUserDB, mockTransactionDB, log)
+	uc, mockUserDB, mockTransactionDB := newTestUserUseCase(t)
 
 	// Ожидаемый ответ.
 	expectedResponse := &models.InfoResponse{
@@ -29,6 +27,7 @@ func TestUserUseCase_GetUserInfo_Success(t *testing.T) {
 		Inventory: []models.InventoryItem{
 			{Type: "pen", Quantity: 1},
 		},
+		ItemCount: 1,
 		CoinHistory: models.CoinHistory{
 			Received: []models.Transaction{},
 			Sent:     []models.Transaction{},
@@ -42,42 +41,164 @@ func TestUserUseCase_GetUserInfo_Success(t *testing.T) {
 
 	// Ожидаемые вызовы методов БД.
 	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(expectedUser, nil)
-	mockUserDB.EXPECT().GetUserInventory(gomock.Any(), 1).Return(expectedInventory, nil)
-	mockTransactionDB.EXPECT().GetCoinHistory(gomock.Any(), 1).Return(expectedHistory, nil)
+	mockUserDB.EXPECT().GetUserInventory(gomock.Any(), 1, 0).Return(expectedInventory, nil)
+	mockUserDB.EXPECT().GetInventoryItemCount(gomock.Any(), 1).Return(1, nil)
+	mockTransactionDB.EXPECT().GetCoinHistory(gomock.Any(), 1, "").Return(expectedHistory, nil)
 
 	// Вызываем тестируемый метод.
-	response, err := uc.GetUserInfo(context.Background(), "testuser")
+	response, err := uc.GetUserInfo(context.Background(), "testuser", InfoOptions{})
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResponse, response)
 }
 
-func TestUserUseCase_GetUserInfo_UserNotFound(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestUserUseCase_GetUserInfo_ItemCount(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestUserUseCase(t)
+
+	// Инвентарь из нескольких предметов.
+	expectedUser := &models.DBUser{ID: 1, Username: "testuser", Coins: 100}
+	expectedInventory := []models.DBInventoryItem{
+		{ItemType: "pen", Quantity: 2},
+		{ItemType: "cup", Quantity: 3},
+		{ItemType: "hoody", Quantity: 1},
+	}
+	expectedHistory := &models.CoinHistory{Received: []models.Transaction{}, Sent: []models.Transaction{}}
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(expectedUser, nil)
+	mockUserDB.EXPECT().GetUserInventory(gomock.Any(), 1, 0).Return(expectedInventory, nil)
+	mockUserDB.EXPECT().GetInventoryItemCount(gomock.Any(), 1).Return(6, nil)
+	mockTransactionDB.EXPECT().GetCoinHistory(gomock.Any(), 1, "").Return(expectedHistory, nil)
+
+	// Проверяем, что в ответе суммарное количество предметов.
+	response, err := uc.GetUserInfo(context.Background(), "testuser", InfoOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, response.Inventory, 3)
+	assert.Equal(t, 6, response.ItemCount)
+}
+
+func TestUserUseCase_GetUserInfo_WithItemDetails(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestUserUseCase(t)
+
+	price := int64(20)
+	expectedUser := &models.DBUser{ID: 1, Username: "testuser", Coins: 100}
+	// Предмета "relic" больше нет в каталоге, поэтому цена отсутствует.
+	inventoryDB := []models.DBInventoryItem{
+		{ItemType: "cup", Quantity: 2, Price: &price},
+		{ItemType: "relic", Quantity: 1},
+	}
+	expectedHistory := &models.CoinHistory{Received: []models.Transaction{}, Sent: []models.Transaction{}}
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(expectedUser, nil)
+	mockUserDB.EXPECT().GetUserInventoryWithPrices(gomock.Any(), 1).Return(inventoryDB, nil)
+	mockUserDB.EXPECT().GetInventoryItemCount(gomock.Any(), 1).Return(3, nil)
+	mockTransactionDB.EXPECT().GetCoinHistory(gomock.Any(), 1, "").Return(expectedHistory, nil)
+
+	response, err := uc.GetUserInfo(context.Background(), "testuser", InfoOptions{ItemDetails: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.InventoryItem{
+		{Type: "cup", Quantity: 2, Price: &price},
+		{Type: "relic", Quantity: 1, Discontinued: true},
+	}, response.Inventory)
+}
+
+func TestUserUseCase_GetUserInfo_Sections(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	// Выбран только баланс: инвентарь и история транзакций не запрашиваются,
+	// любые неожиданные вызовы моков провалят тест.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser", Coins: 100}, nil)
+
+	opts := InfoOptions{Sections: map[InfoSection]bool{InfoSectionCoins: true}}
+	response, err := uc.GetUserInfo(context.Background(), "testuser", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), response.Coins)
+}
+
+func TestParseInfoSections(t *testing.T) {
+	sections, err := ParseInfoSections("coins, inventory")
+	assert.NoError(t, err)
+	assert.Equal(t, map[InfoSection]bool{InfoSectionCoins: true, InfoSectionInventory: true}, sections)
+
+	_, err = ParseInfoSections("coins,password")
+	assert.ErrorIs(t, err, ErrUnknownInfoSection)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestUserUseCase_GetUserInfo_UserIDFromContext(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestUserUseCase(t)
+
+	expectedHistory := &models.CoinHistory{Received: []models.Transaction{}, Sent: []models.Transaction{}}
+
+	// Пользователь с балансом уже в контексте: ни GetUserByUsername, ни GetBalance не вызываются.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(0)
+	mockUserDB.EXPECT().GetBalance(gomock.Any(), gomock.Any()).Times(0)
+	mockUserDB.EXPECT().GetUserInventory(gomock.Any(), 7, 0).Return([]models.DBInventoryItem{}, nil)
+	mockUserDB.EXPECT().GetInventoryItemCount(gomock.Any(), 7).Return(0, nil)
+	mockTransactionDB.EXPECT().GetCoinHistory(gomock.Any(), 7, "").Return(expectedHistory, nil)
+
+	ctx := WithUser(context.Background(), models.DBUser{ID: 7, Username: "testuser", Coins: 250})
+	response, err := uc.GetUserInfo(ctx, "testuser", InfoOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), response.Coins)
+}
+
+func TestUserUseCase_GetBalance_UserFromContext(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	// Баланс в контексте соответствует началу запроса, поэтому GetBalance читает свежий по ID.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(0)
+	mockUserDB.EXPECT().GetBalance(gomock.Any(), 7).Return(int64(300), nil)
+
+	ctx := WithUser(context.Background(), models.DBUser{ID: 7, Username: "testuser", Coins: 250})
+	coins, err := uc.GetBalance(ctx, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), coins)
+}
+
+func TestWithUser_OmitsPasswordHash(t *testing.T) {
+	_, mockUserDB, _ := newTestUserUseCase(t)
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(0)
+
+	// Хеш пароля не должен оставаться в контексте запроса, даже если его вернула база.
+	ctx := WithUser(context.Background(), models.DBUser{ID: 7, Username: "testuser", PasswordHash: "$2a$10$secret", Coins: 250})
+	assert.NotContains(t, fmt.Sprintf("%+v", ctx.Value(userKey)), "$2a$10$secret")
 
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewUserInfoUseCase("secret", mockUserDB, mockTransactionDB, log)
+	user, err := lookupUser(ctx, mockUserDB, "testuser")
+	require.NoError(t, err)
+	assert.Equal(t, &models.DBUser{ID: 7, Username: "testuser", Coins: 250}, user)
+
+	id, ok := UserIDFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, 7, id)
+}
+
+func TestUserUseCase_ResolveUser(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 7, Username: "testuser", Coins: 250}, nil)
+	user, err := uc.ResolveUser(context.Background(), "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.DBUser{ID: 7, Username: "testuser", Coins: 250}, user)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "deleted").Return(nil, nil)
+	_, err = uc.ResolveUser(context.Background(), "deleted")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestUserUseCase_GetUserInfo_UserNotFound(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
 
 	// Ожидаем, что GetUserByUsername вернет nil, nil (пользователь не найден).
 	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(nil, nil)
 
 	// Проверяем, что возвращается ошибка ErrUserNotFound.
-	response, err := uc.GetUserInfo(context.Background(), "testuser")
+	response, err := uc.GetUserInfo(context.Background(), "testuser", InfoOptions{})
 	assert.Error(t, err)
 	assert.Nil(t, response)
 	assert.True(t, errors.Is(err, ErrUserNotFound))
 }
 
 func TestUserUseCase_Auth_Success_ExistingUser(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewUserInfoUseCase("secret", mockUserDB, mockTransactionDB, log)
+	uc, mockUserDB, _ := newTestUserUseCase(t)
 
 	// Хэш пароля.
 	validPasswordHashBytes, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
@@ -94,9 +215,10 @@ func TestUserUseCase_Auth_Success_ExistingUser(t *testing.T) {
 	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(expectedUser, nil)
 
 	// Вызываем Auth и проверяем, что токен сгенерирован.
-	token, err := uc.Auth(context.Background(), "testuser", "password")
+	token, registered, err := uc.Auth(context.Background(), "testuser", "password")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
+	assert.Nil(t, registered, "Вход существующего пользователя не является регистрацией")
 
 	// Проверяем токен.
 	username, verifyErr := uc.VerifyJWTToken(token)
@@ -105,42 +227,168 @@ func TestUserUseCase_Auth_Success_ExistingUser(t *testing.T) {
 }
 
 func TestUserUseCase_Auth_Success_NewUser(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewUserInfoUseCase("secret", mockUserDB, mockTransactionDB, log)
+	uc, mockUserDB, _ := newTestUserUseCase(t)
 
 	// Ожидаем вызов GetUserByUsername, который вернет nil, nil (пользователь не найден)
 	// Ожидаем вызов CreateUser для создания пользователя.
 	// Ожидаем повторный вызов GetUserByUsername, который вернет уже созданного пользователя
 	// Ожидаем вызов SetInitialCoins для установки начального количества монет.
 	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "newuser").Return(nil, nil)
+	mockUserDB.EXPECT().IsUserDeactivated(gomock.Any(), "newuser").Return(false, nil)
 	mockUserDB.EXPECT().CreateUser(gomock.Any(), "newuser", gomock.Any()).Return(nil)
 	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "newuser").Return(&models.DBUser{ID: 2, Username: "newuser", Coins: 0}, nil)
-	mockUserDB.EXPECT().SetInitialCoins(gomock.Any(), 2, 1000).Return(nil)
+	mockUserDB.EXPECT().SetInitialCoins(gomock.Any(), 2, int64(InitialCoins)).Return(nil)
 
 	// Вызываем Auth, проверяем, что токен сгенерирован.
-	token, err := uc.Auth(context.Background(), "newuser", "password")
+	token, registered, err := uc.Auth(context.Background(), "newuser", "password")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 
+	// Новый пользователь получает сводку с начальным балансом.
+	assert.Equal(t, &models.UserSummary{Username: "newuser", Coins: InitialCoins}, registered)
+
 	// Проверяем токен.
 	username, verifyErr := uc.VerifyJWTToken(token)
 	assert.NoError(t, verifyErr)
 	assert.Equal(t, "newuser", username)
 }
 
-func TestUserUseCase_Auth_InvalidPassword(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestUserUseCase_Auth_PaddedUsernameNotRegistered(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), " alice").Return(nil, nil)
+	mockUserDB.EXPECT().IsUserDeactivated(gomock.Any(), " alice").Return(false, nil)
+	mockUserDB.EXPECT().CreateUser(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	_, _, err := uc.Auth(context.Background(), " alice", "password")
+	assert.ErrorIs(t, err, ErrInvalidUsername)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestUserUseCase_Auth_ReservedUsernameNotRegistered(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+	uc.ReservedUsernames = []string{"admin"}
+
+	// Незанятое имя администратора нельзя получить первым входом.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "admin").Return(nil, nil)
+	mockUserDB.EXPECT().IsUserDeactivated(gomock.Any(), "admin").Return(false, nil)
+	mockUserDB.EXPECT().CreateUser(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	_, _, err := uc.Auth(context.Background(), "admin", "password")
+	assert.ErrorIs(t, err, ErrReservedUsername)
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestUserUseCase_Auth_Pepper(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+	uc.PasswordPepper = []byte("pepper")
+
+	// Регистрация сохраняет хеш пароля с подмешанным pepper.
+	var storedHash string
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "newuser").Return(nil, nil)
+	mockUserDB.EXPECT().IsUserDeactivated(gomock.Any(), "newuser").Return(false, nil)
+	mockUserDB.EXPECT().CreateUser(gomock.Any(), "newuser", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, passwordHash string) error {
+			storedHash = passwordHash
+			return nil
+		})
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "newuser").Return(&models.DBUser{ID: 2, Username: "newuser"}, nil)
+	mockUserDB.EXPECT().SetInitialCoins(gomock.Any(), 2, int64(InitialCoins)).Return(nil)
+	_, _, err := uc.Auth(context.Background(), "newuser", "password")
+	require.NoError(t, err)
+
+	user := &models.DBUser{ID: 2, Username: "newuser", PasswordHash: storedHash}
+
+	// Повторный вход с тем же pepper проходит.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "newuser").Return(user, nil)
+	token, _, err := uc.Auth(context.Background(), "newuser", "password")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	// После смены pepper прежний пароль не подходит.
+	uc.PasswordPepper = []byte("rotated")
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "newuser").Return(user, nil)
+	_, _, err = uc.Auth(context.Background(), "newuser", "password")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+}
+
+func TestUserUseCase_Auth_Deactivated(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	// Деактивированный пользователь не находится и не должен регистрироваться заново.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "olduser").Return(nil, nil)
+	mockUserDB.EXPECT().IsUserDeactivated(gomock.Any(), "olduser").Return(true, nil)
+
+	token, _, err := uc.Auth(context.Background(), "olduser", "password")
+	assert.ErrorIs(t, err, ErrUserDeactivated)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.Empty(t, token)
+}
+
+func TestUserUseCase_Auth_DeactivatedComparesHash(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	compared := 0
+	original := compareHashAndPassword
+	compareHashAndPassword = func(hash, password []byte) error {
+		compared++
+		return original(hash, password)
+	}
+	defer func() { compareHashAndPassword = original }()
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "olduser").Return(nil, nil)
+	mockUserDB.EXPECT().IsUserDeactivated(gomock.Any(), "olduser").Return(true, nil)
+
+	// Отказ деактивированному пользователю занимает столько же, сколько проверка пароля.
+	_, _, err := uc.Auth(context.Background(), "olduser", "password")
+	assert.ErrorIs(t, err, ErrUserDeactivated)
+	assert.Equal(t, 1, compared, "Для отказа без проверки пароля должно выполняться сравнение с фиктивным хешем")
+}
+
+func TestUserUseCase_Auth_ConcurrentRegistration(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
+	tests := []struct {
+		name        string
+		password    string
+		expectedErr error
+	}{
+		{"тот же пароль", "password", nil},
+		{"другой пароль", "wrong", ErrInvalidPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, mockUserDB, _ := newTestUserUseCase(t)
+
+			// Пользователь создан параллельным запросом между проверкой и вставкой:
+			// начальные монеты повторно не устанавливаются.
+			gomock.InOrder(
+				mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "newuser").Return(nil, nil),
+				mockUserDB.EXPECT().IsUserDeactivated(gomock.Any(), "newuser").Return(false, nil),
+				mockUserDB.EXPECT().CreateUser(gomock.Any(), "newuser", gomock.Any()).Return(fmt.Errorf("ошибка при создании пользователя: %w", &db.DBError{Kind: db.KindUniqueViolation})),
+				mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "newuser").Return(&models.DBUser{ID: 2, Username: "newuser", PasswordHash: string(hashedPassword), Coins: 1000}, nil),
+			)
+
+			token, registered, err := uc.Auth(context.Background(), "newuser", tt.password)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Empty(t, token)
+				return
+			}
+			assert.NoError(t, err)
+			// Пользователя зарегистрировал параллельный запрос, а не этот.
+			assert.Nil(t, registered)
+
+			username, verifyErr := uc.VerifyJWTToken(token)
+			assert.NoError(t, verifyErr)
+			assert.Equal(t, "newuser", username)
+		})
+	}
+}
 
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewUserInfoUseCase("secret", mockUserDB, mockTransactionDB, log)
+func TestUserUseCase_Auth_InvalidPassword(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
 
 	validPasswordHashBytes, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
 	validPasswordHash := string(validPasswordHashBytes)
@@ -154,20 +402,14 @@ func TestUserUseCase_Auth_InvalidPassword(t *testing.T) {
 	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(expectedUser, nil)
 
 	// Проверяем, что возвращается ошибка ErrInvalidPassword, если пароль неверный
-	token, err := uc.Auth(context.Background(), "testuser", "wrong_password")
+	token, _, err := uc.Auth(context.Background(), "testuser", "wrong_password")
 	assert.Error(t, err)
 	assert.Empty(t, token)
 	assert.True(t, errors.Is(err, ErrInvalidPassword))
 }
 
 func TestUserUseCase_GenerateJWTToken_VerifyJWTToken(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	mockUserDB := dbmocks.NewMockUserDBInterface(ctrl)
-	mockTransactionDB := dbmocks.NewMockTransactionDBInterface(ctrl)
-	log := logger.NewTestLogger()
-	uc := NewUserInfoUseCase("secret", mockUserDB, mockTransactionDB, log)
+	uc, _, _ := newTestUserUseCase(t)
 
 	// Генерация и проверка токена.
 	username := "testuser"
@@ -179,3 +421,382 @@ func TestUserUseCase_GenerateJWTToken_VerifyJWTToken(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, username, verifiedUsername)
 }
+
+func TestUserUseCase_ParseJWTToken(t *testing.T) {
+	uc, _, _ := newTestUserUseCase(t)
+	uc.TokenTTL = time.Hour
+
+	token, err := uc.GenerateJWTToken("testuser")
+	require.NoError(t, err)
+
+	claims, err := uc.ParseJWTToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "testuser", claims.Username)
+	assert.Equal(t, time.Hour, claims.ExpiresAt.Sub(claims.IssuedAt.Time))
+
+	// Изменение любого символа подписи делает токен недействительным.
+	tampered := token[:len(token)-2] + "AA"
+	if tampered == token {
+		tampered = token[:len(token)-2] + "BB"
+	}
+	_, err = uc.ParseJWTToken(tampered)
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestUserUseCase_GenerateJWTToken_Claims(t *testing.T) {
+	uc, _, _ := newTestUserUseCase(t)
+	uc.TokenTTL = time.Hour
+
+	token, err := uc.GenerateJWTToken("testuser")
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	iat, err := claims.GetIssuedAt()
+	require.NoError(t, err)
+	exp, err := claims.GetExpirationTime()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), iat.Time, time.Minute)
+	assert.Equal(t, time.Hour, exp.Sub(iat.Time))
+}
+
+func TestUserUseCase_VerifyJWTToken_Expiration(t *testing.T) {
+	uc, _, _ := newTestUserUseCase(t)
+
+	sign := func(claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+		require.NoError(t, err)
+		return signed
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr error
+	}{
+		{"действующий токен", sign(jwt.MapClaims{"username": "testuser", "exp": time.Now().Add(time.Minute).Unix()}), nil},
+		{"просроченный токен", sign(jwt.MapClaims{"username": "testuser", "exp": time.Now().Add(-time.Minute).Unix()}), ErrTokenExpired},
+		// Токены, выпущенные до появления exp, не принимаются.
+		{"токен без exp", sign(jwt.MapClaims{"username": "testuser"}), ErrTokenNoExpiration},
+		{"iat в будущем", sign(jwt.MapClaims{"username": "testuser", "exp": time.Now().Add(2 * time.Hour).Unix(), "iat": time.Now().Add(time.Hour).Unix()}), jwt.ErrTokenUsedBeforeIssued},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, err := uc.VerifyJWTToken(tt.token)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Empty(t, username)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "testuser", username)
+		})
+	}
+
+	// Токен с отрицательным сроком действия просрочен сразу после выпуска.
+	uc.TokenTTL = -time.Minute
+	token, err := uc.GenerateJWTToken("testuser")
+	require.NoError(t, err)
+	_, err = uc.VerifyJWTToken(token)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestUserUseCase_RefreshToken(t *testing.T) {
+	now := time.Now()
+	sign := func(claims jwt.MapClaims) string {
+		claims["username"] = "testuser"
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+		require.NoError(t, err)
+		return signed
+	}
+	// session подписывает токен сессии, начатой входом по паролю authTime назад.
+	session := func(exp time.Time, authTime time.Duration) string {
+		return sign(jwt.MapClaims{"exp": exp.Unix(), "iat": now.Add(-time.Minute).Unix(), "auth_time": now.Add(-authTime).Unix()})
+	}
+	user := &models.DBUser{ID: 1, Username: "testuser"}
+
+	tests := []struct {
+		name        string
+		token       string
+		user        *models.DBUser
+		lookup      bool
+		expectedErr error
+	}{
+		{"истекает в пределах окна", session(now.Add(time.Minute), time.Hour), user, true, nil},
+		{"истек в пределах grace", session(now.Add(-time.Minute), time.Hour), user, true, nil},
+		{"истек за пределами grace", session(now.Add(-time.Hour), time.Hour), nil, false, ErrTokenExpired},
+		// Действующий токен нельзя продлевать бесконечно: до окна обновления он отклоняется.
+		{"до окна обновления", session(now.Add(2*time.Hour), time.Hour), nil, false, ErrRefreshTooEarly},
+		{"сессия превысила MaxSessionAge", session(now.Add(time.Minute), 100*time.Hour), nil, false, ErrSessionExpired},
+		// Без auth_time сессия отсчитывается от iat.
+		{"старый токен без auth_time", sign(jwt.MapClaims{"exp": now.Add(time.Minute).Unix(), "iat": now.Add(-time.Hour).Unix()}), user, true, nil},
+		{"старый токен с давним iat", sign(jwt.MapClaims{"exp": now.Add(time.Minute).Unix(), "iat": now.Add(-100 * time.Hour).Unix()}), nil, false, ErrSessionExpired},
+		{"нет ни auth_time, ни iat", sign(jwt.MapClaims{"exp": now.Add(time.Minute).Unix()}), nil, false, ErrSessionExpired},
+		{"поврежденный токен", "not.a.token", nil, false, ErrUnauthorized},
+		{"пользователь удален", session(now.Add(time.Minute), time.Hour), nil, true, ErrTokenUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, mockUserDB, _ := newTestUserUseCase(t)
+			uc.RefreshGrace = 5 * time.Minute
+			uc.RefreshWindow = time.Hour
+			uc.MaxSessionAge = 72 * time.Hour
+			if tt.lookup {
+				mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(tt.user, nil)
+			}
+
+			token, err := uc.RefreshToken(context.Background(), tt.token)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Empty(t, token)
+				return
+			}
+			require.NoError(t, err)
+			claims, err := uc.ParseJWTToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "testuser", claims.Username)
+			assert.WithinDuration(t, time.Now().Add(uc.TokenTTL), claims.ExpiresAt.Time, time.Minute)
+		})
+	}
+}
+
+func TestUserUseCase_RefreshToken_KeepsSession(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+	uc.RefreshWindow = 0
+	uc.MaxSessionAge = 24 * time.Hour
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser"}, nil).Times(2)
+
+	token, err := uc.GenerateJWTToken("testuser")
+	require.NoError(t, err)
+	original, err := uc.ParseJWTToken(token)
+	require.NoError(t, err)
+	require.NotNil(t, original.AuthTime)
+
+	// Обновленный токен сохраняет время входа исходного токена.
+	refreshed, err := uc.RefreshToken(context.Background(), token)
+	require.NoError(t, err)
+	claims, err := uc.ParseJWTToken(refreshed)
+	require.NoError(t, err)
+	require.NotNil(t, claims.AuthTime)
+	assert.Equal(t, original.AuthTime.Unix(), claims.AuthTime.Unix())
+
+	// Срок действия обновленного токена не выходит за конец сессии.
+	uc.MaxSessionAge = time.Hour
+	refreshed, err = uc.RefreshToken(context.Background(), token)
+	require.NoError(t, err)
+	claims, err = uc.ParseJWTToken(refreshed)
+	require.NoError(t, err)
+	assert.Equal(t, original.AuthTime.Add(time.Hour).Unix(), claims.ExpiresAt.Unix())
+}
+
+// signTestToken подписывает токен секретом secret с заголовком kid.
+func signTestToken(t *testing.T, kid string, secret string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "testuser", "exp": time.Now().Add(time.Hour).Unix()})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString([]byte(secret))
+	assert.NoError(t, err)
+	return signed
+}
+
+func TestUserUseCase_VerifyJWTToken_KeySet(t *testing.T) {
+	keys := map[string]string{"2024": "old_secret", "2025": "new_secret"}
+
+	issuer, _, _ := newTestUserUseCase(t)
+	assert.NoError(t, issuer.UseKeySet(keys, "2024"))
+	oldToken, err := issuer.GenerateJWTToken("testuser")
+	assert.NoError(t, err)
+
+	// После ротации новые токены подписываются ключом "2025", а старые проверяются ключом по kid.
+	uc, _, _ := newTestUserUseCase(t)
+	assert.NoError(t, uc.UseKeySet(keys, "2025"))
+	newToken, err := uc.GenerateJWTToken("testuser")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr error
+	}{
+		{"токен старым ключом", oldToken, nil},
+		{"токен новым ключом", newToken, nil},
+		{"kid не соответствует ключу подписи", signTestToken(t, "2025", "old_secret"), jwt.ErrTokenSignatureInvalid},
+		{"неизвестный kid", signTestToken(t, "2023", "old_secret"), ErrUnknownKeyID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, err := uc.VerifyJWTToken(tt.token)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Empty(t, username)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "testuser", username)
+		})
+	}
+}
+
+func TestUserUseCase_VerifyJWTToken_KeySetLegacyToken(t *testing.T) {
+	// Токен без kid подписан основным секретом, например выпущен до включения набора ключей.
+	legacy, _, _ := newTestUserUseCase(t)
+	token, err := legacy.GenerateJWTToken("testuser")
+	assert.NoError(t, err)
+
+	t.Run("отклоняется по умолчанию", func(t *testing.T) {
+		uc, _, _ := newTestUserUseCase(t)
+		assert.NoError(t, uc.UseKeySet(map[string]string{"2025": "new_secret"}, "2025"))
+
+		username, err := uc.VerifyJWTToken(token)
+		assert.ErrorIs(t, err, ErrMissingKeyID)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		assert.Empty(t, username)
+	})
+
+	t.Run("принимается с AcceptLegacyTokens", func(t *testing.T) {
+		uc, _, _ := newTestUserUseCase(t)
+		assert.NoError(t, uc.UseKeySet(map[string]string{"2025": "new_secret"}, "2025"))
+		uc.AcceptLegacyTokens = true
+
+		username, err := uc.VerifyJWTToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "testuser", username)
+	})
+
+	t.Run("без набора ключей проверяется основным секретом", func(t *testing.T) {
+		uc, _, _ := newTestUserUseCase(t)
+
+		username, err := uc.VerifyJWTToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "testuser", username)
+	})
+}
+
+func TestUserUseCase_UseKeySet_MissingSigningKey(t *testing.T) {
+	uc, _, _ := newTestUserUseCase(t)
+
+	err := uc.UseKeySet(map[string]string{"2025": "new_secret"}, "2026")
+	assert.ErrorIs(t, err, ErrSigningKeyNotFound)
+}
+
+func TestUserUseCase_GetInventory(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser"}, nil)
+	mockUserDB.EXPECT().GetUserInventoryByPrefix(gomock.Any(), 1, "sw").Return([]models.DBInventoryItem{
+		{ID: 1, UserID: 1, ItemType: "sweater", Quantity: 2},
+	}, nil)
+
+	response, err := uc.GetInventory(context.Background(), "testuser", "sw")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.InventoryItem{{Type: "sweater", Quantity: 2}}, response.Inventory)
+}
+
+func TestUserUseCase_GetRank(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser", Coins: 500}, nil)
+	mockUserDB.EXPECT().GetUserRank(gomock.Any(), 1).Return(3, nil)
+
+	response, err := uc.GetRank(context.Background(), "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.RankResponse{Rank: 3, Coins: 500}, response)
+}
+
+func TestUserUseCase_GetRank_UserNotFound(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	// Пользователь деактивирован между проверкой токена и запросом рейтинга.
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser"}, nil)
+	mockUserDB.EXPECT().GetUserRank(gomock.Any(), 1).Return(0, fmt.Errorf("ошибка при получении места пользователя в рейтинге: %w", sql.ErrNoRows))
+
+	_, err := uc.GetRank(context.Background(), "testuser")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestUserUseCase_GetLeaderboard(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	entries := []models.LeaderboardEntry{{Username: "bob", Coins: 1000}, {Username: "alice", Coins: 970, ItemCount: 3}}
+	mockUserDB.EXPECT().GetLeaderboard(gomock.Any(), DefaultLeaderboardLimit).Return(entries, nil)
+
+	response, err := uc.GetLeaderboard(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, &models.LeaderboardResponse{Entries: entries}, response)
+
+	for _, limit := range []int{-1, MaxLeaderboardLimit + 1} {
+		_, err := uc.GetLeaderboard(context.Background(), limit)
+		assert.ErrorIs(t, err, ErrInvalidPagination, "limit %d", limit)
+	}
+}
+
+func TestUserUseCase_GetStatement(t *testing.T) {
+	uc, mockUserDB, mockTransactionDB := newTestUserUseCase(t)
+	now := time.Now()
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "alice").Return(&models.DBUser{ID: 1, Username: "alice", Coins: 935}, nil)
+	mockTransactionDB.EXPECT().GetUserTransactions(gomock.Any(), 1).Return([]models.DBTransaction{
+		{SenderUserID: 1, SenderUsername: "alice", ReceiverUserID: 2, ReceiverUsername: "bob", Amount: 100, Memo: "обед", TransactionDate: now.Add(-2 * time.Minute)},
+		{SenderUserID: 3, SenderUsername: "carol", ReceiverUserID: 1, ReceiverUsername: "alice", Amount: 50, TransactionDate: now.Add(-time.Minute)},
+		{SenderUserID: 1, SenderUsername: "alice", ReceiverUserID: 3, ReceiverUsername: "carol", Amount: 15, TransactionDate: now},
+	}, nil)
+
+	response, err := uc.GetStatement(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, &models.StatementResponse{
+		Coins: 935,
+		Entries: []models.StatementEntry{
+			{Date: now.Add(-2 * time.Minute), Counterparty: "bob", Amount: -100, Memo: "обед", Balance: 900},
+			{Date: now.Add(-time.Minute), Counterparty: "carol", Amount: 50, Balance: 950},
+			{Date: now, Counterparty: "carol", Amount: -15, Balance: 935},
+		},
+	}, response)
+	// Баланс после последнего перевода совпадает с текущим.
+	assert.Equal(t, response.Coins, response.Entries[len(response.Entries)-1].Balance)
+}
+
+func TestUserUseCase_GetBalance(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser", Coins: 750}, nil)
+
+	coins, err := uc.GetBalance(context.Background(), "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(750), coins)
+}
+
+func TestUserUseCase_GetUserID_NotFound(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	mockUserDB.EXPECT().GetUserIDByUsername(gomock.Any(), "deleted").Return(0, fmt.Errorf("пользователь не найден: %w", sql.ErrNoRows))
+
+	_, err := uc.GetUserID(context.Background(), "deleted")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestUserUseCase_GetInventory_NoMatches(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser"}, nil)
+	mockUserDB.EXPECT().GetUserInventoryByPrefix(gomock.Any(), 1, "zzz").Return([]models.DBInventoryItem{}, nil)
+
+	response, err := uc.GetInventory(context.Background(), "testuser", "zzz")
+	assert.NoError(t, err)
+	assert.NotNil(t, response.Inventory, "Пустой инвентарь должен сериализоваться как []")
+	assert.Empty(t, response.Inventory)
+}
+
+func TestUserUseCase_GetUserInfo_InventoryLimit(t *testing.T) {
+	uc, mockUserDB, _ := newTestUserUseCase(t)
+
+	mockUserDB.EXPECT().GetUserByUsername(gomock.Any(), "testuser").Return(&models.DBUser{ID: 1, Username: "testuser"}, nil)
+	// Ограничение передается в запрос к БД.
+	mockUserDB.EXPECT().GetUserInventory(gomock.Any(), 1, 1).Return([]models.DBInventoryItem{{ItemType: "book", Quantity: 1}}, nil)
+
+	opts := InfoOptions{Sections: map[InfoSection]bool{InfoSectionInventory: true}, InventoryLimit: 1}
+	response, err := uc.GetUserInfo(context.Background(), "testuser", opts)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InventoryItem{{Type: "book", Quantity: 1}}, response.Inventory)
+}
@@ -0,0 +1 @@
+ALTER TABLE coin_transactions ADD COLUMN memo VARCHAR(140) NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+CREATE TABLE item_gifts (
+    id SERIAL PRIMARY KEY,
+    sender_user_id INTEGER NOT NULL,
+    receiver_user_id INTEGER NOT NULL,
+    item_type VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    gift_date TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (sender_user_id) REFERENCES users(id),
+    FOREIGN KEY (receiver_user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_item_gifts_sender_user_id ON item_gifts (sender_user_id);
+CREATE INDEX idx_item_gifts_receiver_user_id ON item_gifts (receiver_user_id);
@@ -0,0 +1,4 @@
+-- Инвентарь может ссылаться только на товары из каталога.
+ALTER TABLE inventory
+    ADD CONSTRAINT inventory_item_type_fkey
+    FOREIGN KEY (item_type) REFERENCES items(item_name) ON UPDATE CASCADE;
@@ -0,0 +1,17 @@
+-- Учет примененных миграций. Каждая следующая миграция добавляет сюда свою версию
+-- (имя файла без .sql), по этой таблице shop migrate --dry-run находит неприменные.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version VARCHAR(255) PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO schema_migrations (version) VALUES
+    ('001-init'),
+    ('002-user-deactivation'),
+    ('003-daily-bonus'),
+    ('004-transaction-memo'),
+    ('005-item-gifts'),
+    ('006-inventory-item-fk'),
+    ('007-admin-audit'),
+    ('008-schema-migrations')
+ON CONFLICT (version) DO NOTHING;
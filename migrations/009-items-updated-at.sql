@@ -0,0 +1,4 @@
+-- Время последнего изменения товара: по нему /api/items отвечает на условные запросы.
+ALTER TABLE items ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+INSERT INTO schema_migrations (version) VALUES ('009-items-updated-at') ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,6 @@
+-- Балансы, суммы переводов и цены хранятся в BIGINT, чтобы не переполняться на больших значениях.
+ALTER TABLE users ALTER COLUMN coins TYPE BIGINT;
+ALTER TABLE coin_transactions ALTER COLUMN amount TYPE BIGINT;
+ALTER TABLE items ALTER COLUMN price TYPE BIGINT;
+
+INSERT INTO schema_migrations (version) VALUES ('010-coins-bigint') ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,5 @@
+-- Отмена перевода записывается обратным переводом со ссылкой на исходный.
+-- UNIQUE не дает отменить один перевод дважды.
+ALTER TABLE coin_transactions ADD COLUMN reversal_of INTEGER UNIQUE REFERENCES coin_transactions(id);
+
+INSERT INTO schema_migrations (version) VALUES ('011-transaction-reversals') ON CONFLICT (version) DO NOTHING;
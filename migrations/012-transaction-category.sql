@@ -0,0 +1,5 @@
+-- Необязательная категория перевода для отчетов, например gift или payment.
+-- Допустимые значения проверяет приложение; пустая строка означает перевод без категории.
+ALTER TABLE coin_transactions ADD COLUMN category VARCHAR(32) NOT NULL DEFAULT '';
+
+INSERT INTO schema_migrations (version) VALUES ('012-transaction-category') ON CONFLICT (version) DO NOTHING;
@@ -7,6 +7,9 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
 // contextKey is a private type to prevent collisions in context.
@@ -23,13 +26,34 @@ type Logger struct {
 
 // New создает новый экземпляр Logger.
 func New(level slog.Level) *Logger {
+	return newWithWriter(os.Stdout, level)
+}
+
+// NewFromString создает Logger по строковому уровню логирования.
+// При неверном уровне используется Info, а в созданный логгер пишется предупреждение.
+func NewFromString(levelStr string) *Logger {
+	return newFromString(os.Stdout, levelStr)
+}
+
+func newFromString(w io.Writer, levelStr string) *Logger {
+	level, err := ParseLogLevel(levelStr)
+	if err != nil {
+		level = slog.LevelInfo
+	}
+	log := newWithWriter(w, level)
+	if err != nil {
+		log.Warn("Неверный уровень логгирования, используется уровень по умолчанию Info", "error", err, "LogLevel", levelStr)
+	}
+	return log
+}
 
+func newWithWriter(w io.Writer, level slog.Level) *Logger {
 	addSource := false
 	if level == slog.LevelDebug {
 		addSource = true
 	}
 
-	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	handler := slog.NewTextHandler(w, &slog.HandlerOptions{
 		Level:     level,
 		AddSource: addSource,
 	})
@@ -67,6 +91,31 @@ func (l *Logger) With(args ...interface{}) *Logger {
 	return &Logger{Logger: l.Logger.With(args...)}
 }
 
+// Sanitize экранирует управляющие символы и разделители строк в значении, полученном от клиента,
+// например в имени пользователя или названии товара, чтобы оно не могло подделать строки лога.
+// TextHandler сам заключает такие значения в кавычки, но Sanitize защищает и при выводе
+// в другие обработчики и форматы. Значения без управляющих символов возвращаются без изменений.
+func Sanitize(value string) string {
+	if strings.IndexFunc(value, isUnsafeRune) < 0 {
+		return value
+	}
+	var b strings.Builder
+	for _, r := range value {
+		if isUnsafeRune(r) {
+			quoted := strconv.QuoteRuneToASCII(r)
+			b.WriteString(quoted[1 : len(quoted)-1])
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isUnsafeRune сообщает, может ли символ разорвать или исказить строку лога.
+func isUnsafeRune(r rune) bool {
+	return unicode.IsControl(r) || r == '\u2028' || r == '\u2029'
+}
+
 // ParseLogLevel преобразует строковое представление уровня логирования в slog.Level.
 func ParseLogLevel(levelStr string) (slog.Level, error) {
 	switch levelStr {
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFromString_InvalidLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := newFromString(&buf, "VERBOSE")
+
+	// Используется уровень Info: отладочные сообщения отбрасываются.
+	assert.True(t, log.Enabled(context.Background(), slog.LevelInfo))
+	assert.False(t, log.Enabled(context.Background(), slog.LevelDebug))
+
+	// Предупреждение о неверном уровне записано.
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Contains(t, buf.String(), "LogLevel=VERBOSE")
+}
+
+func TestNewFromString_ValidLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := newFromString(&buf, "DEBUG")
+
+	assert.True(t, log.Enabled(context.Background(), slog.LevelDebug))
+	assert.Empty(t, buf.String())
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "alice", Sanitize("alice"))
+	assert.Equal(t, "кружка", Sanitize("кружка"))
+	assert.Equal(t, `alice\nlevel=ERROR`, Sanitize("alice\nlevel=ERROR"))
+	assert.Equal(t, `a\r\tb\x1b[31m\u2028`, Sanitize("a\r\tb\x1b[31m\u2028"))
+}
+
+func TestSanitize_NoLogInjection(t *testing.T) {
+	var buf bytes.Buffer
+	// Сообщение пишется без кавычек, как в JSON-подобных форматах без экранирования.
+	log := &Logger{slog.New(slog.NewTextHandler(&buf, nil))}
+
+	username := "alice\ntime=2025-01-01 level=ERROR msg=\"поддельная запись\""
+	log.Warn("Ошибка аутентификации " + Sanitize(username))
+	log.Warn("Ошибка аутентификации", "username", Sanitize(username))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 2, "Каждая запись должна занимать одну строку")
+	for _, line := range lines {
+		assert.True(t, strings.HasPrefix(line, "time="), "Строка не должна начинаться с внедренных данных: %q", line)
+	}
+}
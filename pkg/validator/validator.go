@@ -0,0 +1,132 @@
+// pkg/validator/validator.go
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError описывает нарушение правила валидации для одного поля.
+type FieldError struct {
+	Field string
+	Rule  string
+	Param string
+}
+
+// Error возвращает сообщение об ошибке для клиента.
+func (e FieldError) Error() string {
+	switch e.Rule {
+	case "required":
+		return fmt.Sprintf("поле %s обязательно", e.Field)
+	case "min":
+		return fmt.Sprintf("поле %s должно быть не меньше %s", e.Field, e.Param)
+	case "max":
+		return fmt.Sprintf("поле %s должно быть не больше %s", e.Field, e.Param)
+	case "minlen":
+		return fmt.Sprintf("длина поля %s должна быть не меньше %s", e.Field, e.Param)
+	case "maxlen":
+		return fmt.Sprintf("длина поля %s должна быть не больше %s", e.Field, e.Param)
+	default:
+		return fmt.Sprintf("поле %s не прошло проверку %s", e.Field, e.Rule)
+	}
+}
+
+// Errors список ошибок валидации полей.
+type Errors []FieldError
+
+// Error объединяет сообщения об ошибках всех полей.
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate проверяет поля структуры по тегам validate.
+// Поддерживаемые правила:
+//   - required: значение не должно быть нулевым;
+//   - min, max: границы числового значения;
+//   - minlen, maxlen: границы длины строки в символах.
+//
+// В сообщениях об ошибках используется имя поля из тега json.
+func Validate(v any) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Pointer {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return errors.New("validator: ожидается структура")
+	}
+
+	var errs Errors
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if fieldErr, ok := validateField(fieldName(field), val.Field(i), tag); !ok {
+			errs = append(errs, fieldErr)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateField проверяет значение по правилам тега и возвращает первое нарушение.
+func validateField(name string, value reflect.Value, tag string) (FieldError, bool) {
+	for _, rule := range strings.Split(tag, ",") {
+		rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if !checkRule(value, rule, param) {
+			return FieldError{Field: name, Rule: rule, Param: param}, false
+		}
+	}
+	return FieldError{}, true
+}
+
+// checkRule проверяет одно правило для значения.
+func checkRule(value reflect.Value, rule string, param string) bool {
+	switch rule {
+	case "required":
+		return !value.IsZero()
+	case "min", "max":
+		limit, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || !value.CanInt() {
+			return false
+		}
+		if rule == "min" {
+			return value.Int() >= limit
+		}
+		return value.Int() <= limit
+	case "minlen", "maxlen":
+		limit, err := strconv.Atoi(param)
+		if err != nil || value.Kind() != reflect.String {
+			return false
+		}
+		length := utf8.RuneCountInString(value.String())
+		if rule == "minlen" {
+			return length >= limit
+		}
+		return length <= limit
+	default:
+		return false
+	}
+}
+
+// fieldName возвращает имя поля из тега json, а при его отсутствии имя поля структуры.
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
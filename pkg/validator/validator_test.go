@@ -0,0 +1,52 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRequest struct {
+	Name   string `json:"name" validate:"required,maxlen=5"`
+	Code   string `json:"code,omitempty" validate:"minlen=2"`
+	Amount int    `json:"amount" validate:"min=1,max=100"`
+	Note   string
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name            string
+		req             testRequest
+		expectedMessage string
+	}{
+		{"корректный запрос", testRequest{Name: "alice", Code: "ab", Amount: 10}, ""},
+		{"required", testRequest{Code: "ab", Amount: 10}, "поле name обязательно"},
+		{"maxlen", testRequest{Name: "алиса!", Code: "ab", Amount: 10}, "длина поля name должна быть не больше 5"},
+		{"minlen", testRequest{Name: "bob", Code: "a", Amount: 10}, "длина поля code должна быть не меньше 2"},
+		{"min", testRequest{Name: "bob", Code: "ab", Amount: 0}, "поле amount должно быть не меньше 1"},
+		{"max", testRequest{Name: "bob", Code: "ab", Amount: 101}, "поле amount должно быть не больше 100"},
+		{
+			"несколько полей", testRequest{Code: "ab", Amount: -5},
+			"поле name обязательно; поле amount должно быть не меньше 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.req)
+			if tt.expectedMessage == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.expectedMessage)
+
+			var fieldErrs Errors
+			assert.True(t, errors.As(err, &fieldErrs), "Ошибка должна содержать список полей")
+		})
+	}
+}
+
+func TestValidate_NotStruct(t *testing.T) {
+	assert.Error(t, Validate(42))
+}